@@ -0,0 +1,128 @@
+// Package budget enforces cost and token limits per request, per session and per rolling
+// day, and keeps the spend ledger the daily limits are measured against.
+package budget
+
+import (
+	"fmt"
+)
+
+// Scopes a limit applies to.
+const (
+	ScopeRequest = "request"
+	ScopeSession = "session"
+	ScopeDay     = "day"
+)
+
+// Units a limit is expressed in.
+const (
+	UnitUSD    = "USD"
+	UnitTokens = "tokens"
+)
+
+// Spend is an amount of money and tokens.
+type Spend struct {
+	USD    float64
+	Tokens int
+}
+
+// Add returns the sum of s and o.
+func (s Spend) Add(o Spend) Spend {
+	return Spend{USD: s.USD + o.USD, Tokens: s.Tokens + o.Tokens}
+}
+
+// Limits caps spend per scope; zero values are unlimited.
+type Limits struct {
+	RequestUSD    float64
+	SessionUSD    float64
+	DailyUSD      float64
+	RequestTokens int
+	SessionTokens int
+	DailyTokens   int
+}
+
+// IsZero reports whether no limit is set.
+func (l Limits) IsZero() bool {
+	return l == Limits{}
+}
+
+// HasUSD reports whether any USD limit is set.
+func (l Limits) HasUSD() bool {
+	return l.RequestUSD > 0 || l.SessionUSD > 0 || l.DailyUSD > 0
+}
+
+// HasTokens reports whether any token limit is set.
+func (l Limits) HasTokens() bool {
+	return l.RequestTokens > 0 || l.SessionTokens > 0 || l.DailyTokens > 0
+}
+
+// Status is the state of one limit: how much of it is used, or would be used by a request.
+type Status struct {
+	Scope string
+	Unit  string
+	Limit float64
+	Used  float64
+}
+
+// Percent returns Used as a percentage of Limit.
+func (s Status) Percent() float64 {
+	if s.Limit <= 0 {
+		return 0
+	}
+	return s.Used / s.Limit * 100
+}
+
+// Key identifies the limit, e.g. "session/USD".
+func (s Status) Key() string {
+	return s.Scope + "/" + s.Unit
+}
+
+func (s Status) String() string {
+	return fmt.Sprintf("%s budget (%s of %s)", s.Scope, formatAmount(s.Unit, s.Used), formatAmount(s.Unit, s.Limit))
+}
+
+// statuses lists every configured limit with its usage after adding request to what the
+// session and the day already spent.
+func (l Limits) statuses(session, day, request Spend) []Status {
+	var out []Status
+	add := func(scope, unit string, limit, used float64) {
+		if limit > 0 {
+			out = append(out, Status{Scope: scope, Unit: unit, Limit: limit, Used: used})
+		}
+	}
+	add(ScopeRequest, UnitUSD, l.RequestUSD, request.USD)
+	add(ScopeRequest, UnitTokens, float64(l.RequestTokens), float64(request.Tokens))
+	add(ScopeSession, UnitUSD, l.SessionUSD, session.USD+request.USD)
+	add(ScopeSession, UnitTokens, float64(l.SessionTokens), float64(session.Tokens+request.Tokens))
+	add(ScopeDay, UnitUSD, l.DailyUSD, day.USD+request.USD)
+	add(ScopeDay, UnitTokens, float64(l.DailyTokens), float64(day.Tokens+request.Tokens))
+	return out
+}
+
+// Check returns the first limit that request would exceed given what the session and the
+// rolling day already spent.
+func (l Limits) Check(session, day, request Spend) (Status, bool) {
+	for _, s := range l.statuses(session, day, request) {
+		if s.Used > s.Limit {
+			return s, true
+		}
+	}
+	return Status{}, false
+}
+
+// Reached returns the session and daily limits whose usage is at or above percent.
+func (l Limits) Reached(percent int, session, day Spend) []Status {
+	var out []Status
+	for _, s := range l.statuses(session, day, Spend{}) {
+		if s.Scope != ScopeRequest && s.Percent() >= float64(percent) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func formatAmount(unit string, v float64) string {
+	if unit == UnitUSD {
+		return fmt.Sprintf("$%.4f", v)
+	}
+	return fmt.Sprintf("%.0f tokens", v)
+}
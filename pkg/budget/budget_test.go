@@ -0,0 +1,139 @@
+package budget
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimitsCheck(t *testing.T) {
+	limits := Limits{RequestUSD: 0.5, SessionUSD: 2, DailyUSD: 10, SessionTokens: 100000}
+
+	tests := []struct {
+		name      string
+		session   Spend
+		day       Spend
+		request   Spend
+		wantOver  bool
+		wantScope string
+		wantUnit  string
+	}{
+		{name: "within all limits", session: Spend{USD: 1, Tokens: 1000}, day: Spend{USD: 5}, request: Spend{USD: 0.1, Tokens: 500}},
+		{name: "request limit", request: Spend{USD: 0.6}, wantOver: true, wantScope: ScopeRequest, wantUnit: UnitUSD},
+		{name: "session limit", session: Spend{USD: 1.95}, request: Spend{USD: 0.1}, wantOver: true, wantScope: ScopeSession, wantUnit: UnitUSD},
+		{name: "session tokens", session: Spend{Tokens: 99900}, request: Spend{Tokens: 200}, wantOver: true, wantScope: ScopeSession, wantUnit: UnitTokens},
+		{name: "daily limit", day: Spend{USD: 9.95}, request: Spend{USD: 0.1}, wantOver: true, wantScope: ScopeDay, wantUnit: UnitUSD},
+		{name: "exactly at limit", session: Spend{USD: 1.9}, request: Spend{USD: 0.1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, over := limits.Check(tt.session, tt.day, tt.request)
+			if over != tt.wantOver {
+				t.Fatalf("Check() over = %v, want %v (%s)", over, tt.wantOver, status)
+			}
+			if over && (status.Scope != tt.wantScope || status.Unit != tt.wantUnit) {
+				t.Errorf("Check() = %s/%s, want %s/%s", status.Scope, status.Unit, tt.wantScope, tt.wantUnit)
+			}
+		})
+	}
+
+	if _, over := (Limits{}).Check(Spend{USD: 1e6}, Spend{USD: 1e6}, Spend{USD: 1e6}); over {
+		t.Error("zero limits must be unlimited")
+	}
+}
+
+func TestLimitsReached(t *testing.T) {
+	limits := Limits{RequestUSD: 0.01, SessionUSD: 1, DailyTokens: 1000}
+	reached := limits.Reached(80, Spend{USD: 0.85}, Spend{Tokens: 500})
+	if len(reached) != 1 || reached[0].Key() != "session/USD" {
+		t.Fatalf("expected only the session limit to be reached, got %+v", reached)
+	}
+	if got := reached[0].String(); !strings.Contains(got, "$0.8500 of $1.0000") {
+		t.Errorf("unexpected status text %q", got)
+	}
+}
+
+func TestLedger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	ledger := NewLedger(path)
+	now := time.Now()
+
+	if spend, err := ledger.Since(now.Add(-DayWindow)); err != nil || spend != (Spend{}) {
+		t.Fatalf("missing ledger should be empty, got %+v, %v", spend, err)
+	}
+	entries := []Entry{
+		{Time: now.Add(-48 * time.Hour), Provider: "openai", Model: "gpt-5", InputTokens: 1000, OutputTokens: 100, USD: 1},
+		{Time: now.Add(-time.Hour), Provider: "openai", Model: "gpt-5", InputTokens: 2000, OutputTokens: 200, USD: 0.25},
+		{Time: now, Provider: "anthropic", Model: "claude-haiku-4-5", InputTokens: 300, OutputTokens: 30, USD: 0.05},
+	}
+	for _, e := range entries {
+		if err := ledger.Append(e); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	spend, err := ledger.Since(now.Add(-DayWindow))
+	if err != nil {
+		t.Fatalf("Since: %v", err)
+	}
+	if spend.Tokens != 2530 || spend.USD < 0.2999 || spend.USD > 0.3001 {
+		t.Errorf("unexpected daily spend %+v", spend)
+	}
+
+	if err := ledger.Prune(now.Add(-DayWindow)); err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	all, _ := ledger.Since(time.Time{})
+	if all.Tokens != spend.Tokens {
+		t.Errorf("prune should keep only recent entries, got %+v", all)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("ledger should be private, got %v %v", info.Mode(), err)
+	}
+}
+
+func TestLedgerPruneKeepsConcurrentAppends(t *testing.T) {
+	ledger := NewLedger(filepath.Join(t.TempDir(), "ledger.jsonl"))
+	now := time.Now()
+
+	// Writers keep adding expired entries, so every prune rewrites the file
+	var writers sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+			for i := 0; i < 100; i++ {
+				ledger.Append(Entry{Time: now.Add(-48 * time.Hour), InputTokens: 1000})
+				if err := ledger.Append(Entry{Time: now, InputTokens: 1}); err != nil {
+					t.Errorf("Append: %v", err)
+				}
+			}
+		}()
+	}
+	done := make(chan struct{})
+	pruned := make(chan struct{})
+	go func() {
+		defer close(pruned)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if err := ledger.Prune(now.Add(-DayWindow)); err != nil {
+				t.Errorf("Prune: %v", err)
+				return
+			}
+		}
+	}()
+	writers.Wait()
+	close(done)
+	<-pruned
+
+	if spend, _ := ledger.Since(now.Add(-DayWindow)); spend.Tokens != 800 {
+		t.Errorf("expected all 800 concurrent appends to survive pruning, got %d", spend.Tokens)
+	}
+}
@@ -0,0 +1,139 @@
+package budget
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// DayWindow is the rolling window daily limits are measured over.
+const DayWindow = 24 * time.Hour
+
+// Entry is one request in the spend ledger.
+type Entry struct {
+	Time         time.Time `json:"time"`
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model"`
+	Task         string    `json:"task,omitempty"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	USD          float64   `json:"usd"`
+}
+
+// Ledger is an append-only JSON lines file of entries shared by all sessions of a user, so
+// daily limits hold across concurrent and consecutive runs. Appends share a lock on a
+// sibling ".lock" file that pruning takes exclusively, so a prune never drops an entry
+// another session appends meanwhile.
+type Ledger struct {
+	path string
+}
+
+// NewLedger returns the ledger stored at path.
+func NewLedger(path string) *Ledger {
+	return &Ledger{path: path}
+}
+
+// Append adds e to the ledger.
+func (l *Ledger) Append(e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal ledger entry: %w", err)
+	}
+	unlock, err := l.lock(syscall.LOCK_SH)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("open ledger: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("write ledger: %w", err)
+	}
+	return nil
+}
+
+// Since sums the spend of entries recorded at or after t. Unreadable lines are skipped.
+func (l *Ledger) Since(t time.Time) (Spend, error) {
+	var total Spend
+	err := l.each(func(e Entry) {
+		if !e.Time.Before(t) {
+			total = total.Add(Spend{USD: e.USD, Tokens: e.InputTokens + e.OutputTokens})
+		}
+	})
+	return total, err
+}
+
+// Prune rewrites the ledger without entries older than t.
+func (l *Ledger) Prune(t time.Time) error {
+	unlock, err := l.lock(syscall.LOCK_EX)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	var kept bytes.Buffer
+	dropped := false
+	err = l.each(func(e Entry) {
+		if e.Time.Before(t) {
+			dropped = true
+			return
+		}
+		data, _ := json.Marshal(e)
+		kept.Write(append(data, '\n'))
+	})
+	if err != nil || !dropped {
+		return err
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, kept.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("write ledger: %w", err)
+	}
+	return os.Rename(tmp, l.path)
+}
+
+// lock takes a shared or exclusive flock on the ledger's lock file and returns a func that
+// releases it. The ledger itself cannot carry the lock because pruning replaces it.
+func (l *Ledger) lock(how int) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return nil, fmt.Errorf("create ledger dir: %w", err)
+	}
+	f, err := os.OpenFile(l.path+".lock", os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open ledger lock: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), how); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("lock ledger: %w", err)
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+func (l *Ledger) each(fn func(Entry)) error {
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open ledger: %w", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil {
+			fn(e)
+		}
+	}
+	return scanner.Err()
+}
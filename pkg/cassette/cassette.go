@@ -0,0 +1,428 @@
+// Package cassette records LLM requests, MCP tool calls and kubectl output to a JSON file
+// and replays them deterministically without network access. Cassettes turn real sessions
+// into regression fixtures and make bug reports reproducible offline.
+package cassette
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/filter"
+)
+
+// FileName is the cassette file written inside the --record / --replay directory.
+const FileName = "cassette.json"
+
+const CurrentVersion = 1
+
+// Interaction kinds.
+const (
+	KindLLM     = "llm"
+	KindTool    = "tool"
+	KindCommand = "command"
+)
+
+var ErrInvalidCassette = errors.New("invalid cassette")
+
+// File is the on-disk cassette format.
+type File struct {
+	Version      int           `json:"version"`
+	CreatedAt    time.Time     `json:"created_at"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded exchange; exactly one of LLM, Tool or Command is set.
+type Interaction struct {
+	Kind    string           `json:"kind"`
+	LLM     *LLMExchange     `json:"llm,omitempty"`
+	Tool    *ToolExchange    `json:"tool,omitempty"`
+	Command *CommandExchange `json:"command,omitempty"`
+}
+
+// ToolExchange is an MCP (or built-in) tool call and its result.
+type ToolExchange struct {
+	Name   string         `json:"name"`
+	Args   map[string]any `json:"args,omitempty"`
+	Result string         `json:"result,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// CommandExchange is a kubectl or shell command and its combined output.
+type CommandExchange struct {
+	Cmd   string `json:"cmd"`
+	Out   string `json:"out,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Redactor masks sensitive data before it is written to a cassette. *config.Config
+// implements it with the configured redaction chain.
+type Redactor interface {
+	Redact(text string) string
+	RedactValue(v any) any
+}
+
+// cassetteFooter closes the interactions array and the file object.
+const cassetteFooter = "\n  ]\n}\n"
+
+// Cassette is an open cassette in either record or replay mode. A nil *Cassette is valid
+// and neither records nor replays, so callers can use it unconditionally.
+type Cassette struct {
+	mu        sync.Mutex
+	path      string
+	replaying bool
+	file      File
+	used      []bool
+	redactor  Redactor
+
+	// Recording state: the open file, the offset of its footer and the number of
+	// interactions written so far
+	out      *os.File
+	end      int64
+	recorded int
+}
+
+// NewRecorder creates an empty cassette at dir/cassette.json. Every recorded interaction
+// is appended in place and the file stays valid JSON, so a crash still leaves a usable
+// cassette behind.
+func NewRecorder(dir string) (*Cassette, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create cassette dir: %w", err)
+	}
+	path := filepath.Join(dir, FileName)
+	created, err := json.Marshal(time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("marshal cassette: %w", err)
+	}
+	header := fmt.Sprintf("{\n  \"version\": %d,\n  \"created_at\": %s,\n  \"interactions\": [", CurrentVersion, created)
+	out, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("write cassette: %w", err)
+	}
+	if _, err := out.WriteString(header + cassetteFooter); err != nil {
+		out.Close()
+		return nil, fmt.Errorf("write cassette: %w", err)
+	}
+	return &Cassette{path: path, out: out, end: int64(len(header))}, nil
+}
+
+// Load opens dir/cassette.json for replay.
+func Load(dir string) (*Cassette, error) {
+	path := filepath.Join(dir, FileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read cassette: %w", err)
+	}
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCassette, err)
+	}
+	if f.Version != CurrentVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidCassette, f.Version)
+	}
+	return &Cassette{path: path, replaying: true, file: f, used: make([]bool, len(f.Interactions))}, nil
+}
+
+// SetRedactor replaces the built-in sensitive-data filter with r for everything recorded,
+// and for the commands and tool arguments looked up on replay so they match their
+// recorded form.
+func (c *Cassette) SetRedactor(r Redactor) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.redactor = r
+}
+
+// Close closes the file of a recording cassette.
+func (c *Cassette) Close() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.out == nil {
+		return nil
+	}
+	err := c.out.Close()
+	c.out = nil
+	return err
+}
+
+// Recording reports whether interactions are being captured.
+func (c *Cassette) Recording() bool {
+	return c != nil && !c.replaying
+}
+
+// Replaying reports whether interactions are served from the cassette.
+func (c *Cassette) Replaying() bool {
+	return c != nil && c.replaying
+}
+
+// Path returns the cassette file path.
+func (c *Cassette) Path() string {
+	if c == nil {
+		return ""
+	}
+	return c.path
+}
+
+// Remaining returns the number of recorded interactions not yet replayed.
+func (c *Cassette) Remaining() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, u := range c.used {
+		if !u {
+			n++
+		}
+	}
+	return n
+}
+
+// RecordCommand captures a redacted command result. Commands that read Secrets are not
+// recorded at all: cassettes are shared as bug reports and fixtures.
+func (c *Cassette) RecordCommand(cmd, out string, err error) {
+	if !c.Recording() || listsSecrets(cmd) {
+		return
+	}
+	c.record(Interaction{Kind: KindCommand, Command: &CommandExchange{Cmd: c.redact(cmd), Out: c.redact(out), Error: c.redact(errString(err))}})
+}
+
+// ReplayCommand returns the first unplayed recording of cmd. Commands are matched by text
+// rather than position because diagnostics run them in parallel.
+func (c *Cassette) ReplayCommand(cmd string) (string, error, bool) {
+	if !c.Replaying() {
+		return "", nil, false
+	}
+	cmd = c.redact(cmd)
+	i, ok := c.take(func(in Interaction) bool {
+		return in.Kind == KindCommand && in.Command != nil && in.Command.Cmd == cmd
+	})
+	if !ok {
+		return "", nil, false
+	}
+	ex := c.file.Interactions[i].Command
+	return ex.Out, errFromString(ex.Error), true
+}
+
+// RecordTool captures a redacted tool call result. Calls that run a command reading
+// Secrets are not recorded.
+func (c *Cassette) RecordTool(name string, args map[string]any, result string, err error) {
+	if !c.Recording() {
+		return
+	}
+	for _, v := range args {
+		if s, ok := v.(string); ok && listsSecrets(s) {
+			return
+		}
+	}
+	c.record(Interaction{Kind: KindTool, Tool: &ToolExchange{Name: name, Args: c.redactArgs(args), Result: c.redact(result), Error: c.redact(errString(err))}})
+}
+
+// ReplayTool returns the first unplayed recording of a call to name with the same arguments.
+func (c *Cassette) ReplayTool(name string, args map[string]any) (string, error, bool) {
+	if !c.Replaying() {
+		return "", nil, false
+	}
+	key := canonicalArgs(c.redactArgs(args))
+	i, ok := c.take(func(in Interaction) bool {
+		return in.Kind == KindTool && in.Tool != nil && in.Tool.Name == name && canonicalArgs(in.Tool.Args) == key
+	})
+	if !ok {
+		return "", nil, false
+	}
+	ex := c.file.Interactions[i].Tool
+	return ex.Result, errFromString(ex.Error), true
+}
+
+// record appends in to the cassette file, overwriting the footer and writing it again
+// after the new entry. Recording is best effort: a failed write must not break the
+// session being recorded.
+func (c *Cassette) record(in Interaction) {
+	data, err := json.MarshalIndent(in, "    ", "  ")
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.out == nil {
+		return
+	}
+	entry := "\n    " + string(data)
+	if c.recorded > 0 {
+		entry = "," + entry
+	}
+	if _, err := c.out.WriteAt([]byte(entry+cassetteFooter), c.end); err != nil {
+		return
+	}
+	c.end += int64(len(entry))
+	c.recorded++
+}
+
+func (c *Cassette) redact(text string) string {
+	if text == "" {
+		return text
+	}
+	if r := c.currentRedactor(); r != nil {
+		return r.Redact(text)
+	}
+	return filter.SensitiveData(text)
+}
+
+func (c *Cassette) redactArgs(args map[string]any) map[string]any {
+	if len(args) == 0 {
+		return args
+	}
+	// Redaction rewrites maps in place, so work on a copy of the caller's arguments
+	data, err := json.Marshal(args)
+	if err != nil {
+		return args
+	}
+	var copied any
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return args
+	}
+	redact := func(v any) any { return redactValue(v, c.redact) }
+	if r := c.currentRedactor(); r != nil {
+		redact = r.RedactValue
+	}
+	if redacted, ok := redact(copied).(map[string]any); ok {
+		return redacted
+	}
+	return args
+}
+
+func (c *Cassette) currentRedactor() Redactor {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.redactor
+}
+
+// redactValue masks every string in a decoded JSON value in place.
+func redactValue(v any, redact func(string) string) any {
+	switch t := v.(type) {
+	case string:
+		return redact(t)
+	case map[string]any:
+		for k, child := range t {
+			t[k] = redactValue(child, redact)
+		}
+	case []any:
+		for i, child := range t {
+			t[i] = redactValue(child, redact)
+		}
+	}
+	return v
+}
+
+// redactExchange masks the text of an LLM exchange in place.
+func (c *Cassette) redactExchange(ex *LLMExchange) {
+	for i := range ex.Messages {
+		for j := range ex.Messages[i].Parts {
+			part := &ex.Messages[i].Parts[j]
+			part.Text = c.redact(part.Text)
+			if part.ToolCall != nil {
+				part.ToolCall.Arguments = c.redact(part.ToolCall.Arguments)
+			}
+			if part.ToolResult != nil {
+				part.ToolResult.Content = c.redact(part.ToolResult.Content)
+			}
+		}
+	}
+	// A secret can span chunks; keep them apart only when that masks the same text
+	joined := c.redact(strings.Join(ex.Chunks, ""))
+	for i := range ex.Chunks {
+		ex.Chunks[i] = c.redact(ex.Chunks[i])
+	}
+	if len(ex.Chunks) > 0 && strings.Join(ex.Chunks, "") != joined {
+		ex.Chunks = []string{joined}
+	}
+	for i := range ex.Choices {
+		ch := &ex.Choices[i]
+		ch.Content = c.redact(ch.Content)
+		ch.ReasoningContent = c.redact(ch.ReasoningContent)
+		for j := range ch.ToolCalls {
+			ch.ToolCalls[j].Arguments = c.redact(ch.ToolCalls[j].Arguments)
+		}
+	}
+	ex.Error = c.redact(ex.Error)
+}
+
+// listsSecrets reports whether cmd reads Secret objects, such as
+// "kubectl get secrets -A -l owner=helm -o json".
+func listsSecrets(cmd string) bool {
+	fields := strings.Fields(strings.ToLower(cmd))
+	for i, f := range fields {
+		if f != "get" {
+			continue
+		}
+		for _, arg := range fields[i+1:] {
+			if strings.HasPrefix(arg, "-") {
+				continue
+			}
+			for _, res := range strings.Split(arg, ",") {
+				res, _, _ = strings.Cut(res, "/")
+				res, _, _ = strings.Cut(res, ".")
+				if res == "secret" || res == "secrets" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// take marks and returns the index of the first unplayed interaction accepted by match.
+func (c *Cassette) take(match func(Interaction) bool) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, in := range c.file.Interactions {
+		if !c.used[i] && match(in) {
+			c.used[i] = true
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// canonicalArgs encodes args with sorted keys so equal maps compare equal.
+func canonicalArgs(args map[string]any) string {
+	if len(args) == 0 {
+		return "{}"
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Sprint(args)
+	}
+	// Round-trip so recorded (float64) and live (int) numbers encode identically
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return string(data)
+	}
+	data, _ = json.Marshal(v)
+	return string(data)
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func errFromString(s string) error {
+	if s == "" {
+		return nil
+	}
+	return errors.New(s)
+}
@@ -0,0 +1,239 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// streamingModel streams its reply in two chunks and asks for one tool call.
+type streamingModel struct{ calls int }
+
+func (m *streamingModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func (m *streamingModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.calls++
+	if m.calls > 1 {
+		return nil, errors.New("503 Service Unavailable")
+	}
+	var opts llms.CallOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if opts.StreamingFunc != nil {
+		_ = opts.StreamingFunc(ctx, []byte("pods "))
+		_ = opts.StreamingFunc(ctx, []byte("look fine"))
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+		Content: "pods look fine",
+		ToolCalls: []llms.ToolCall{{ID: "call-1", Type: "function",
+			FunctionCall: &llms.FunctionCall{Name: "kubectl", Arguments: `{"cmd":"get pods"}`}}},
+	}}}, nil
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := NewRecorder(dir)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+
+	model := rec.Wrap(&streamingModel{})
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "sys"),
+		{Role: llms.ChatMessageTypeTool, Parts: []llms.ContentPart{llms.ToolCallResponse{ToolCallID: "0", Name: "kubectl", Content: "ok"}}},
+	}
+	var streamed []string
+	stream := llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+		streamed = append(streamed, string(chunk))
+		return nil
+	})
+	if _, err := model.GenerateContent(context.Background(), messages, stream); err != nil {
+		t.Fatalf("GenerateContent: %v", err)
+	}
+	if _, err := model.GenerateContent(context.Background(), messages); err == nil {
+		t.Fatal("expected recorded error")
+	}
+	rec.RecordCommand("kubectl get pods", "pod-a Running", nil)
+	rec.RecordCommand("kubectl get pods", "pod-a CrashLoopBackOff", nil)
+	rec.RecordTool("logs", map[string]any{"pod": "pod-a", "tail": 10}, "", errors.New("pod not found"))
+
+	if _, err := os.Stat(filepath.Join(dir, FileName)); err != nil {
+		t.Fatalf("cassette not written: %v", err)
+	}
+
+	play, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !play.Replaying() || play.Recording() {
+		t.Fatal("expected replay mode")
+	}
+
+	// Commands and tools are matched by content, not position
+	if _, err, ok := play.ReplayTool("logs", map[string]any{"tail": 10.0, "pod": "pod-a"}); !ok || err == nil || err.Error() != "pod not found" {
+		t.Errorf("unexpected tool replay: ok=%t err=%v", ok, err)
+	}
+	if out, _, _ := play.ReplayCommand("kubectl get pods"); out != "pod-a Running" {
+		t.Errorf("unexpected first command output: %q", out)
+	}
+	if out, _, _ := play.ReplayCommand("kubectl get pods"); out != "pod-a CrashLoopBackOff" {
+		t.Errorf("unexpected second command output: %q", out)
+	}
+	if _, _, ok := play.ReplayCommand("kubectl get nodes"); ok {
+		t.Error("unexpected replay of unrecorded command")
+	}
+
+	var replayed []string
+	replayStream := llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+		replayed = append(replayed, string(chunk))
+		return nil
+	})
+	resp, err := play.Model().GenerateContent(context.Background(), nil, replayStream)
+	if err != nil {
+		t.Fatalf("replay GenerateContent: %v", err)
+	}
+	if strings.Join(replayed, "|") != strings.Join(streamed, "|") {
+		t.Errorf("streamed chunks differ: %v vs %v", replayed, streamed)
+	}
+	choice := resp.Choices[0]
+	if choice.Content != "pods look fine" || len(choice.ToolCalls) != 1 || choice.FuncCall == nil || choice.FuncCall.Arguments != `{"cmd":"get pods"}` {
+		t.Errorf("unexpected replayed choice: %+v", choice)
+	}
+	if _, err := play.Model().GenerateContent(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("expected recorded error, got %v", err)
+	}
+	if _, err := play.Model().GenerateContent(context.Background(), nil); !errors.Is(err, ErrExhausted) {
+		t.Errorf("expected ErrExhausted, got %v", err)
+	}
+	if play.Remaining() != 0 {
+		t.Errorf("expected all interactions replayed, %d left", play.Remaining())
+	}
+
+	ex := play.file.Interactions[0].LLM
+	if len(ex.Messages) != 2 || ex.Messages[1].Parts[0].ToolResult == nil || ex.Messages[1].Parts[0].ToolResult.Content != "ok" {
+		t.Errorf("request messages not captured: %+v", ex.Messages)
+	}
+}
+
+func TestNilCassette(t *testing.T) {
+	var c *Cassette
+	if c.Recording() || c.Replaying() {
+		t.Fatal("nil cassette must be inactive")
+	}
+	c.RecordCommand("kubectl get pods", "", nil)
+	if _, _, ok := c.ReplayCommand("kubectl get pods"); ok {
+		t.Error("nil cassette must not replay")
+	}
+	m := &streamingModel{}
+	if c.Wrap(m) != llms.Model(m) {
+		t.Error("nil cassette must not wrap models")
+	}
+}
+
+func TestLoadRejectsInvalidCassette(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Load(dir); err == nil {
+		t.Error("expected error for missing cassette")
+	}
+	os.WriteFile(filepath.Join(dir, FileName), []byte(`{"version":99}`), 0o600)
+	if _, err := Load(dir); !errors.Is(err, ErrInvalidCassette) {
+		t.Errorf("expected ErrInvalidCassette, got %v", err)
+	}
+}
+
+func TestRecordRedactsAndSkipsSecrets(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := NewRecorder(dir)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+
+	rec.RecordCommand("kubectl get secrets -A -l owner=helm -o json", `{"data":{"password":"aHVudGVyMg=="}}`, nil)
+	rec.RecordCommand("kubectl -n prod get configmap,secret/db -o yaml", "password: hunter2", nil)
+	rec.RecordTool("kubectl", map[string]any{"command": "get secret db -o yaml"}, "password: hunter2", nil)
+	rec.RecordCommand("kubectl describe secret db", "password: 8 bytes", nil)
+	args := map[string]any{"env": []any{"token=hunter2"}}
+	rec.RecordTool("login", args, "token=hunter2 accepted", nil)
+	model := rec.Wrap(&streamingModel{})
+	messages := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "my password=hunter2")}
+	if _, err := model.GenerateContent(context.Background(), messages); err != nil {
+		t.Fatalf("GenerateContent: %v", err)
+	}
+	if args["env"].([]any)[0] != "token=hunter2" {
+		t.Error("redaction changed the caller's arguments")
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatalf("read cassette: %v", err)
+	}
+	if strings.Contains(string(data), "hunter2") || strings.Contains(string(data), "aHVudGVyMg") {
+		t.Errorf("cassette leaks a secret:\n%s", data)
+	}
+
+	play, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if n := len(play.file.Interactions); n != 3 {
+		t.Errorf("expected describe, login and the LLM call to be recorded, got %d interactions", n)
+	}
+	if _, _, ok := play.ReplayCommand("kubectl get secrets -A -l owner=helm -o json"); ok {
+		t.Error("a Secret listing was recorded")
+	}
+	// Lookups are redacted the same way, so they still match
+	if result, _, ok := play.ReplayTool("login", map[string]any{"env": []any{"token=hunter2"}}); !ok || result != "token=***FILTERED*** accepted" {
+		t.Errorf("unexpected tool replay: %q, %t", result, ok)
+	}
+}
+
+// maskRedactor masks the word "hunter2".
+type maskRedactor struct{}
+
+func (maskRedactor) Redact(text string) string {
+	return strings.ReplaceAll(text, "hunter2", "***")
+}
+
+func (r maskRedactor) RedactValue(v any) any {
+	if m, ok := v.(map[string]any); ok {
+		for k, child := range m {
+			if s, ok := child.(string); ok {
+				m[k] = r.Redact(s)
+			}
+		}
+	}
+	return v
+}
+
+func TestSetRedactorReplacesBuiltinFilter(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := NewRecorder(dir)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	rec.SetRedactor(maskRedactor{})
+	rec.RecordTool("login", map[string]any{"token": "hunter2"}, "my password is hunter2", nil)
+	if err := rec.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	play, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	play.SetRedactor(maskRedactor{})
+	if result, _, ok := play.ReplayTool("login", map[string]any{"token": "hunter2"}); !ok || result != "my password is ***" {
+		t.Errorf("unexpected tool replay: %q, %t", result, ok)
+	}
+}
@@ -0,0 +1,226 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// ErrExhausted is returned when replay needs an LLM response the cassette does not have.
+var ErrExhausted = errors.New("cassette has no more recorded LLM responses")
+
+// LLMExchange is one GenerateContent call: the request messages, any streamed chunks and
+// the final response or error.
+type LLMExchange struct {
+	Messages []Message `json:"messages"`
+	Chunks   []string  `json:"chunks,omitempty"`
+	Choices  []Choice  `json:"choices,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Message is a provider-neutral copy of llms.MessageContent.
+type Message struct {
+	Role  string `json:"role"`
+	Parts []Part `json:"parts"`
+}
+
+// Part is a provider-neutral copy of an llms.ContentPart.
+type Part struct {
+	Type       string          `json:"type"`
+	Text       string          `json:"text,omitempty"`
+	ToolCall   *ToolCall       `json:"tool_call,omitempty"`
+	ToolResult *ToolCallResult `json:"tool_result,omitempty"`
+}
+
+// ToolCall mirrors llms.ToolCall. llms.ToolCall has its own JSON encoding that does not
+// round-trip the function call, so it cannot be stored directly.
+type ToolCall struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// ToolCallResult mirrors llms.ToolCallResponse.
+type ToolCallResult struct {
+	ToolCallID string `json:"tool_call_id"`
+	Name       string `json:"name"`
+	Content    string `json:"content"`
+}
+
+// Choice mirrors llms.ContentChoice.
+type Choice struct {
+	Content          string         `json:"content,omitempty"`
+	ReasoningContent string         `json:"reasoning_content,omitempty"`
+	StopReason       string         `json:"stop_reason,omitempty"`
+	ToolCalls        []ToolCall     `json:"tool_calls,omitempty"`
+	GenerationInfo   map[string]any `json:"generation_info,omitempty"`
+}
+
+// Wrap returns a model that forwards to model and records every call. It returns model
+// unchanged when the cassette is not recording.
+func (c *Cassette) Wrap(model llms.Model) llms.Model {
+	if !c.Recording() {
+		return model
+	}
+	return &recordingModel{cassette: c, model: model}
+}
+
+// Model returns a model that serves recorded responses in order.
+func (c *Cassette) Model() llms.Model {
+	return &replayModel{cassette: c}
+}
+
+type recordingModel struct {
+	cassette *Cassette
+	model    llms.Model
+}
+
+func (m *recordingModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func (m *recordingModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	ex := &LLMExchange{Messages: fromMessages(messages)}
+
+	var opts llms.CallOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if stream := opts.StreamingFunc; stream != nil {
+		options = append(options, llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			ex.Chunks = append(ex.Chunks, string(chunk))
+			return stream(ctx, chunk)
+		}))
+	}
+
+	resp, err := m.model.GenerateContent(ctx, messages, options...)
+	// User cancellations are not part of the conversation and would poison replay
+	if ctx.Err() != nil {
+		return resp, err
+	}
+	ex.Error = errString(err)
+	if resp != nil {
+		ex.Choices = fromChoices(resp.Choices)
+	}
+	m.cassette.redactExchange(ex)
+	m.cassette.record(Interaction{Kind: KindLLM, LLM: ex})
+	return resp, err
+}
+
+type replayModel struct {
+	cassette *Cassette
+}
+
+func (m *replayModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// GenerateContent replays LLM exchanges strictly in recorded order; prompts embed live
+// data such as timestamps, so matching on request content would be brittle.
+func (m *replayModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	i, ok := m.cassette.take(func(in Interaction) bool {
+		return in.Kind == KindLLM && in.LLM != nil
+	})
+	if !ok {
+		return nil, ErrExhausted
+	}
+	ex := m.cassette.file.Interactions[i].LLM
+
+	var opts llms.CallOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if opts.StreamingFunc != nil {
+		for _, chunk := range ex.Chunks {
+			if err := opts.StreamingFunc(ctx, []byte(chunk)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if ex.Error != "" {
+		return nil, errors.New(ex.Error)
+	}
+	return &llms.ContentResponse{Choices: toChoices(ex.Choices)}, nil
+}
+
+func fromMessages(messages []llms.MessageContent) []Message {
+	out := make([]Message, 0, len(messages))
+	for _, msg := range messages {
+		m := Message{Role: string(msg.Role)}
+		for _, part := range msg.Parts {
+			switch p := part.(type) {
+			case llms.TextContent:
+				m.Parts = append(m.Parts, Part{Type: "text", Text: p.Text})
+			case llms.ToolCall:
+				tc := fromToolCall(p)
+				m.Parts = append(m.Parts, Part{Type: "tool_call", ToolCall: &tc})
+			case llms.ToolCallResponse:
+				m.Parts = append(m.Parts, Part{Type: "tool_result", ToolResult: &ToolCallResult{ToolCallID: p.ToolCallID, Name: p.Name, Content: p.Content}})
+			default:
+				m.Parts = append(m.Parts, Part{Type: fmt.Sprintf("%T", part)})
+			}
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+func fromChoices(choices []*llms.ContentChoice) []Choice {
+	out := make([]Choice, 0, len(choices))
+	for _, c := range choices {
+		if c == nil {
+			continue
+		}
+		out = append(out, Choice{
+			Content:          c.Content,
+			ReasoningContent: c.ReasoningContent,
+			StopReason:       c.StopReason,
+			ToolCalls:        fromToolCalls(c.ToolCalls),
+			GenerationInfo:   c.GenerationInfo,
+		})
+	}
+	return out
+}
+
+func toChoices(choices []Choice) []*llms.ContentChoice {
+	out := make([]*llms.ContentChoice, 0, len(choices))
+	for _, c := range choices {
+		choice := &llms.ContentChoice{
+			Content:          c.Content,
+			ReasoningContent: c.ReasoningContent,
+			StopReason:       c.StopReason,
+			GenerationInfo:   c.GenerationInfo,
+		}
+		for _, tc := range c.ToolCalls {
+			choice.ToolCalls = append(choice.ToolCalls, llms.ToolCall{
+				ID:           tc.ID,
+				Type:         tc.Type,
+				FunctionCall: &llms.FunctionCall{Name: tc.Name, Arguments: tc.Arguments},
+			})
+		}
+		if len(choice.ToolCalls) > 0 {
+			choice.FuncCall = choice.ToolCalls[0].FunctionCall
+		}
+		out = append(out, choice)
+	}
+	return out
+}
+
+func fromToolCalls(calls []llms.ToolCall) []ToolCall {
+	var out []ToolCall
+	for _, tc := range calls {
+		out = append(out, fromToolCall(tc))
+	}
+	return out
+}
+
+func fromToolCall(tc llms.ToolCall) ToolCall {
+	out := ToolCall{ID: tc.ID, Type: tc.Type}
+	if tc.FunctionCall != nil {
+		out.Name, out.Arguments = tc.FunctionCall.Name, tc.FunctionCall.Arguments
+	}
+	return out
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/cassette"
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+)
+
+// openCassette opens the --record or --replay cassette, if any, and stores it on cfg.
+func openCassette(cfg *config.Config) error {
+	recordDir := strings.TrimSpace(cfg.RecordDir)
+	replayDir := strings.TrimSpace(cfg.ReplayDir)
+	switch {
+	case recordDir != "" && replayDir != "":
+		return fmt.Errorf("--record and --replay cannot be used together")
+	case recordDir != "":
+		c, err := cassette.NewRecorder(recordDir)
+		if err != nil {
+			return err
+		}
+		c.SetRedactor(cfg)
+		cfg.Cassette = c
+		fmt.Fprintf(os.Stderr, "%s %s\n", config.Colors.Warn.Sprint("Recording session to"), c.Path())
+	case replayDir != "":
+		c, err := cassette.Load(replayDir)
+		if err != nil {
+			return err
+		}
+		c.SetRedactor(cfg)
+		cfg.Cassette = c
+		fmt.Fprintf(os.Stderr, "%s %s\n", config.Colors.Warn.Sprint("Replaying session from"), c.Path())
+	}
+	return nil
+}
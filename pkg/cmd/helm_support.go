@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/diag"
+	"github.com/mikhae1/kubectl-quackops/pkg/exec"
+)
+
+// printHelmReleases lists the latest revision of every Helm release, or the history and
+// last-upgrade diff of a single release when a name is given.
+func printHelmReleases(cfg *config.Config, args string) error {
+	res := exec.ExecKubectlCmd(cfg, diag.HelmSecretsCommand)
+	if res.Err != nil {
+		return res.Err
+	}
+	releases := diag.DecodeHelmReleases(res.Out)
+	if len(releases) == 0 {
+		fmt.Println(config.Colors.Dim.Sprint("No Helm releases found"))
+		return nil
+	}
+
+	name := strings.TrimSpace(args)
+	if name == "" {
+		latest := diag.LatestHelmReleases(releases)
+		fmt.Println(config.Colors.Accent.Sprintf("Helm releases (%d):", len(latest)))
+		for _, r := range latest {
+			fmt.Printf(" - %s %s\n",
+				config.Colors.Info.Sprintf("%s/%s", r.Namespace, r.Name),
+				config.Colors.AccentAlt.Sprintf("rev %d %s %s-%s", r.Revision, r.Status, r.Chart, r.ChartVersion))
+		}
+		return nil
+	}
+
+	namespace := ""
+	if ns, rel, ok := strings.Cut(name, "/"); ok {
+		namespace, name = ns, rel
+	}
+	found := false
+	for _, r := range diag.LatestHelmReleases(releases) {
+		if r.Name != name || (namespace != "" && r.Namespace != namespace) {
+			continue
+		}
+		found = true
+		fmt.Println(config.Colors.Accent.Sprintf("Helm release %s/%s:", r.Namespace, r.Name))
+		fmt.Println(config.Colors.AccentAlt.Sprint(diag.FormatHelmHistory(releases, r.Namespace, r.Name)))
+		if diff := diag.HelmLastUpgradeDiff(releases, r.Namespace, r.Name, cfg.Redact); diff != "" {
+			fmt.Println(config.Colors.Dim.Sprint("Rendered manifest changes in the last upgrade:"))
+			fmt.Println(diff)
+		}
+	}
+	if !found {
+		return fmt.Errorf("release %q not found", args)
+	}
+	return nil
+}
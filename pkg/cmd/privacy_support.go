@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/lib"
+)
+
+// beginPrivacyTurn starts the privacy audit of a prompt sent to the model and returns a
+// func that prints the report at the end of the turn when --privacy-report is set.
+func beginPrivacyTurn(cfg *config.Config, prompt string) func() {
+	cfg.Privacy.Reset(prompt)
+	return func() {
+		if cfg.PrivacyReport {
+			fmt.Print(formatPrivacyReport(cfg))
+		}
+	}
+}
+
+// formatPrivacyReport renders what was redacted in the last turn, per command and tool, and
+// what was sent to which provider endpoint.
+func formatPrivacyReport(cfg *config.Config) string {
+	accent := config.Colors.Accent
+	body := config.Colors.AccentAlt
+	dim := config.Colors.Dim
+	info := config.Colors.Info
+	warn := config.Colors.Warn
+
+	prompt, started := cfg.Privacy.Prompt()
+	if started.IsZero() {
+		return dim.Sprint("No turn has been audited yet.") + "\n"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", accent.Sprint("Privacy report:"), dim.Sprintf("%q at %s", truncatePrivacyPrompt(prompt, 80), started.Format("15:04:05")))
+	if cfg.DisableSecretFilter {
+		fmt.Fprintln(&b, warn.Sprint("Secret filter disabled: command and tool output was sent unmasked"))
+	}
+	if cfg.Pseudonymize {
+		fmt.Fprintln(&b, dim.Sprint("Namespace, pod, node, host and IP names were sent as tokens"))
+	}
+
+	sources := cfg.Privacy.Sources()
+	masked := 0
+	if len(sources) == 0 {
+		fmt.Fprintln(&b, dim.Sprint("No command or tool output was included"))
+	} else {
+		fmt.Fprintln(&b, accent.Sprint("Redacted output:"))
+		for _, s := range sources {
+			masked += s.Masked()
+			detail := dim.Sprint("nothing masked")
+			if s.Masked() > 0 {
+				filters := make([]string, 0, len(s.Hits))
+				for _, name := range s.Filters() {
+					filters = append(filters, fmt.Sprintf("%s ×%d", name, s.Hits[name]))
+				}
+				detail = warn.Sprintf("%d value(s) masked", s.Masked()) + dim.Sprintf(" (%s)", strings.Join(filters, ", "))
+			}
+			calls := ""
+			if s.Calls > 1 {
+				calls = fmt.Sprintf(", %d calls", s.Calls)
+			}
+			fmt.Fprintf(&b, " - %s %s: %s%s\n", dim.Sprint(s.Kind), info.Sprint(s.Name), detail, dim.Sprintf(", %s%s", formatByteSize(s.Bytes), calls))
+		}
+	}
+
+	uploads := cfg.Privacy.Uploads()
+	bytes, tokens := 0, 0
+	if len(uploads) == 0 {
+		fmt.Fprintln(&b, dim.Sprint("Nothing was sent to an LLM provider"))
+	} else {
+		fmt.Fprintln(&b, accent.Sprint("Sent to providers:"))
+		for _, u := range uploads {
+			bytes += u.Bytes
+			tokens += u.Tokens
+			endpoint := u.Endpoint
+			if endpoint == "" {
+				endpoint = "default endpoint"
+			}
+			fmt.Fprintf(&b, " - %s %s %s: %s\n",
+				info.Sprint(u.Provider),
+				body.Sprint(endpoint),
+				dim.Sprintf("(%s, %s)", u.Model, u.Purpose),
+				body.Sprintf("%d request(s), %s, ~%s tokens", u.Requests, formatByteSize(u.Bytes), lib.FormatCompactNumber(u.Tokens)))
+		}
+	}
+	fmt.Fprintf(&b, "%s %s\n", accent.Sprint("Total:"), body.Sprintf("%d value(s) masked; %s and ~%s tokens sent", masked, formatByteSize(bytes), lib.FormatCompactNumber(tokens)))
+	return b.String()
+}
+
+// formatByteSize renders n bytes as B, KB or MB.
+func formatByteSize(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+func truncatePrivacyPrompt(prompt string, max int) string {
+	prompt = strings.Join(strings.Fields(prompt), " ")
+	if len(prompt) > max {
+		return prompt[:max-3] + "..."
+	}
+	return prompt
+}
@@ -42,8 +42,14 @@ func NewRootCmd(streams genericiooptions.IOStreams) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVarP(&cfg.Provider, "provider", "p", cfg.Provider, "LLM model provider (e.g., 'ollama', 'openai', 'azopenai', 'google', 'anthropic')")
+	cmd.Flags().StringVarP(&cfg.Provider, "provider", "p", cfg.Provider, "LLM model provider (e.g., 'ollama', 'openai', 'openai-compatible', 'azopenai', 'google', 'anthropic')")
 	cmd.Flags().StringVarP(&cfg.Model, "model", "m", cfg.Model, "LLM model to use")
+	cmd.Flags().StringSliceVarP(&cfg.FallbackModels, "fallback-models", "", cfg.FallbackModels, "Comma-separated provider/model fallback chain used when the model keeps failing or its context window is exceeded (e.g. 'openai/gpt-5-mini,ollama/llama3.1')")
+	cmd.Flags().StringToStringVarP(&cfg.TaskModels, "task-model", "", cfg.TaskModels, "Model per task as task=provider/model or task=model (tasks: commands, plan, answer, compaction, sampling), e.g. 'commands=openai/gpt-5-nano'")
+	cmd.Flags().Float64VarP(&cfg.BudgetRequestUSD, "budget-request-usd", "", cfg.BudgetRequestUSD, "Maximum estimated cost in USD of a single LLM request (0 = unlimited)")
+	cmd.Flags().Float64VarP(&cfg.BudgetSessionUSD, "budget-session-usd", "", cfg.BudgetSessionUSD, "Maximum estimated LLM cost in USD per session (0 = unlimited)")
+	cmd.Flags().Float64VarP(&cfg.BudgetDailyUSD, "budget-daily-usd", "", cfg.BudgetDailyUSD, "Maximum estimated LLM cost in USD over a rolling 24 hours, across sessions (0 = unlimited)")
+	cmd.Flags().StringVarP(&cfg.BudgetAction, "budget-action", "", cfg.BudgetAction, "Action when a request would exceed a budget: 'prompt', 'downgrade' or 'block'")
 	cmd.Flags().StringVarP(&cfg.OllamaApiURL, "api-url", "u", cfg.OllamaApiURL, "URL for LLM API, used with 'ollama' provider")
 	cmd.Flags().BoolVarP(&cfg.SafeMode, "safe-mode", "s", cfg.SafeMode, "Enable safe mode to prevent executing commands without confirmation")
 	cmd.Flags().IntVarP(&cfg.Retries, "retries", "r", cfg.Retries, "Number of retries for kubectl commands")
@@ -51,10 +57,14 @@ func NewRootCmd(streams genericiooptions.IOStreams) *cobra.Command {
 	cmd.Flags().IntVarP(&cfg.UserMaxTokens, "max-tokens", "x", cfg.UserMaxTokens, "Maximum number of tokens in LLM context window (override; >0 disables auto-detect)")
 	cmd.Flags().BoolVarP(&cfg.Verbose, "verbose", "v", cfg.Verbose, "Enable verbose output")
 	cmd.Flags().BoolVarP(&cfg.DisableSecretFilter, "disable-secrets-filter", "c", cfg.DisableSecretFilter, "Disable filtering sensitive data in secrets from being sent to LLMs")
+	cmd.Flags().StringVarP(&cfg.RedactionRulesFile, "redaction-rules", "", cfg.RedactionRulesFile, "Redaction rules file applied on top of the built-in secret filters (default: ~/.quackops/redaction.yaml)")
+	cmd.Flags().BoolVarP(&cfg.Pseudonymize, "pseudonymize", "", cfg.Pseudonymize, "Send namespace, pod, node, host and IP names to the LLM as stable tokens and restore them in its answers and commands")
+	cmd.Flags().BoolVarP(&cfg.PrivacyReport, "privacy-report", "", cfg.PrivacyReport, "Print what was redacted and what was sent to which LLM endpoint after every turn")
 	cmd.Flags().BoolVarP(&cfg.DisableMarkdownFormat, "disable-markdown", "d", cfg.DisableMarkdownFormat, "Disable Markdown formatting and colorization of LLM outputs (by default, responses are formatted with Markdown)")
 	cmd.Flags().BoolVarP(&cfg.DisableAnimation, "disable-animation", "a", cfg.DisableAnimation, "Disable typewriter animation effect for LLM outputs")
 	cmd.Flags().IntVarP(&cfg.MaxCompletions, "max-completions", "", cfg.MaxCompletions, "Maximum number of completions to display")
 	cmd.Flags().BoolVarP(&cfg.DisableHistory, "disable-history", "", cfg.DisableHistory, "Disable storing prompt history in a file")
+	cmd.Flags().BoolVarP(&cfg.DisableResponseCache, "no-cache", "", cfg.DisableResponseCache, "Bypass the response cache for command suggestions and plans")
 	cmd.Flags().StringVarP(&cfg.HistoryFile, "history-file", "", cfg.HistoryFile, "Path to the history file (default: ~/.quackops/history)")
 	cmd.Flags().StringVarP(&cfg.KubectlBinaryPath, "kubectl-path", "k", cfg.KubectlBinaryPath, "Path to kubectl binary")
 	// MCP flags
@@ -65,6 +75,7 @@ func NewRootCmd(streams genericiooptions.IOStreams) *cobra.Command {
 	cmd.Flags().IntVarP(&cfg.MCPMaxToolCalls, "mcp-max-tool-calls", "", cfg.MCPMaxToolCalls, "Maximum iterative MCP tool-call rounds per model response")
 	// Diagnostics flags
 	cmd.Flags().BoolVarP(&cfg.DisableBaseline, "disable-baseline", "", cfg.DisableBaseline, "Disable baseline diagnostic pack before LLM")
+	cmd.Flags().StringVarP(&cfg.MetricsURL, "metrics-url", "", cfg.MetricsURL, "Prometheus-compatible API URL used for metrics in diagnostics (e.g. http://localhost:9090)")
 	cmd.Flags().IntVarP(&cfg.EventsWindowMinutes, "events-window-minutes", "", cfg.EventsWindowMinutes, "Events time window in minutes for summarization")
 	cmd.Flags().BoolVarP(&cfg.EventsWarningsOnly, "events-warn-only", "", cfg.EventsWarningsOnly, "Include only Warning events in summaries")
 	cmd.Flags().IntVarP(&cfg.LogsTail, "logs-tail", "", cfg.LogsTail, "Tail lines for log aggregation when triggered by playbooks")
@@ -74,6 +85,8 @@ func NewRootCmd(streams genericiooptions.IOStreams) *cobra.Command {
 	cmd.Flags().IntVarP(&cfg.AutoCompactTriggerPercent, "auto-compact-trigger-percent", "", cfg.AutoCompactTriggerPercent, "Trigger auto-compact at this percentage of context window")
 	cmd.Flags().IntVarP(&cfg.AutoCompactTargetPercent, "auto-compact-target-percent", "", cfg.AutoCompactTargetPercent, "Target post-compact percentage of context window")
 	cmd.Flags().IntVarP(&cfg.AutoCompactKeepMessages, "auto-compact-keep-messages", "", cfg.AutoCompactKeepMessages, "Number of most recent non-system messages to keep uncompressed")
+	cmd.Flags().StringVarP(&cfg.RecordDir, "record", "", cfg.RecordDir, "Record LLM, MCP tool and kubectl traffic to a cassette in this directory")
+	cmd.Flags().StringVarP(&cfg.ReplayDir, "replay", "", cfg.ReplayDir, "Replay a cassette recorded with --record from this directory without network access")
 	cmd.Flags().BoolVarP(&showEnv, "show-env", "", false, "Show information about environment variables used by the application")
 
 	// Add env subcommand
@@ -86,6 +99,7 @@ func NewRootCmd(streams genericiooptions.IOStreams) *cobra.Command {
 	}
 	cmd.AddCommand(envCmd)
 	cmd.AddCommand(newSessionCommand(cfg))
+	cmd.AddCommand(newRedactCommand(cfg))
 
 	return cmd
 }
@@ -122,11 +136,26 @@ func runQuackOps(cfg *config.Config, args []string) func(cmd *cobra.Command, arg
 	return func(cmd *cobra.Command, args []string) error {
 		logger.InitLoggers(os.Stderr, 0)
 
+		if err := cfg.ValidateTaskModels(); err != nil {
+			return err
+		}
+		if err := cfg.ValidateBudget(); err != nil {
+			return err
+		}
+		if err := cfg.LoadRedactionRules(); err != nil {
+			return err
+		}
+		if err := openCassette(cfg); err != nil {
+			return err
+		}
+		defer cfg.Cassette.Close()
+
 		// Apply auto-detection after CLI flags are parsed
 		cfg.ConfigDetectMaxTokens()
+		llm.WarnUnpricedBudget(cfg)
 
-		// Start MCP client mode if enabled
-		if cfg.MCPClientEnabled {
+		// Start MCP client mode if enabled; replayed tool calls need no servers
+		if cfg.MCPClientEnabled && !cfg.Cassette.Replaying() {
 			_ = mcp.Start(cfg)
 			defer mcp.Stop()
 		}
@@ -650,6 +679,26 @@ func handleSlashCommand(cfg *config.Config, userPrompt string) (bool, string) {
 			fmt.Println(dim.Sprint("MCP client: ") + warn.Sprint("disabled"))
 		}
 		return true, "prompts"
+	case "/resources":
+		if cfg.MCPClientEnabled {
+			printMCPResources(cfg, commandArgs)
+		} else {
+			fmt.Println(dim.Sprint("MCP client: ") + warn.Sprint("disabled"))
+		}
+		return true, "resources"
+	case "/helm":
+		if err := printHelmReleases(cfg, commandArgs); err != nil {
+			fmt.Printf("%s %v\n", warn.Sprint("Could not read Helm releases:"), err)
+		}
+		return true, "helm"
+	case "/privacy":
+		fmt.Print(formatPrivacyReport(cfg))
+		return true, "privacy"
+	case "/settings":
+		if err := handleSettingsCommand(cfg, commandArgs); err != nil {
+			fmt.Printf("%s %v\n", warn.Sprint("Could not update settings:"), err)
+		}
+		return true, "settings"
 	case "/history":
 
 		if len(cfg.SessionHistory) == 0 {
@@ -987,6 +1036,10 @@ func processUserPrompt(cfg *config.Config, userPrompt string, lastTextPrompt str
 	if handled {
 		return nil
 	}
+	// Commands typed by the user only run locally; everything else is a turn with the model
+	if !cfg.EditMode && !strings.HasPrefix(userPrompt, cfg.CommandPrefix) {
+		defer beginPrivacyTurn(cfg, userPrompt)()
+	}
 	if action == "plan" {
 		trimmed := strings.TrimSpace(userPrompt)
 		planQuery := ""
@@ -1242,6 +1295,17 @@ func processUserPrompt(cfg *config.Config, userPrompt string, lastTextPrompt str
 		augPrompt = userPrompt
 	}
 
+	// Content of @server:uri mentions and of attached resources updated since the last turn
+	if cfg.MCPClientEnabled {
+		resourceContext, err := mcp.ResourceContext(cfg, userPrompt)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", config.Colors.Warn.Sprintf("Warning: Failed to attach MCP resources: %v", err))
+		}
+		if resourceContext != "" {
+			augPrompt = resourceContext + "\n" + augPrompt
+		}
+	}
+
 	// Build role-separated prompts using MessageBuilder
 	mb := llm.NewMessageBuilder()
 
@@ -1405,7 +1469,7 @@ func printMCPDetails(cfg *config.Config) {
 			if len(desc) > 60 {
 				desc = desc[:57] + "..."
 			}
-			fmt.Printf("  · %s: %s\n", toolColor.Sprint(tool.Name), descColor.Sprint(desc))
+			fmt.Printf("  · %s %s: %s\n", toolColor.Sprint(tool.Name), dim.Sprintf("[%s]", tool.Safety()), descColor.Sprint(desc))
 		}
 	}
 	fmt.Println()
@@ -1438,6 +1502,80 @@ func printMCPPrompts(cfg *config.Config) {
 	}
 }
 
+// printMCPResources lists MCP resources, resource templates and the resources attached to
+// the session; "/resources detach" drops the attachments.
+func printMCPResources(cfg *config.Config, args string) {
+	accent := config.Colors.Accent
+	descColor := config.Colors.AccentAlt
+	serverColor := config.Colors.Label
+	dim := config.Colors.Dim
+	warn := config.Colors.Warn
+
+	if strings.EqualFold(args, "detach") {
+		n := mcp.DetachResources(cfg)
+		fmt.Println(descColor.Sprintf("Detached %d resource(s)", n))
+		return
+	}
+
+	filter := strings.ToLower(args)
+	matches := func(fields ...string) bool {
+		for _, f := range fields {
+			if strings.Contains(strings.ToLower(f), filter) {
+				return true
+			}
+		}
+		return false
+	}
+
+	resources := mcp.GetResourceInfos(cfg)
+	templates := mcp.GetResourceTemplateInfos(cfg)
+	if len(resources) == 0 && len(templates) == 0 {
+		fmt.Println(dim.Sprint("No MCP resources discovered"))
+	}
+	if len(resources) > 0 {
+		fmt.Println(accent.Sprintf("MCP resources (%d):", len(resources)))
+		for _, ri := range resources {
+			if !matches(ri.Server, ri.URI, ri.Name, ri.Description) {
+				continue
+			}
+			fmt.Printf(" - %s", accent.Sprint("@"+ri.Server+":"+ri.URI))
+			if ri.Name != "" && ri.Name != ri.URI {
+				fmt.Printf(" — %s", descColor.Sprint(ri.Name))
+			}
+			if ri.MIMEType != "" {
+				fmt.Printf(" %s", dim.Sprint(ri.MIMEType))
+			}
+			fmt.Printf(" %s\n", serverColor.Sprint("["+ri.Server+"]"))
+		}
+	}
+	if len(templates) > 0 {
+		fmt.Println(accent.Sprintf("MCP resource templates (%d):", len(templates)))
+		for _, t := range templates {
+			if !matches(t.Server, t.URITemplate, t.Name, t.Description) {
+				continue
+			}
+			fmt.Printf(" - %s", accent.Sprint("@"+t.Server+":"+t.URITemplate))
+			if t.Name != "" {
+				fmt.Printf(" — %s", descColor.Sprint(t.Name))
+			}
+			fmt.Printf(" %s\n", serverColor.Sprint("["+t.Server+"]"))
+		}
+	}
+
+	attached, updated := mcp.AttachedResources()
+	if len(attached) > 0 {
+		fmt.Println(accent.Sprintf("Attached to this session (%d):", len(attached)))
+		for i, m := range attached {
+			state := ""
+			if updated[i] {
+				state = " " + warn.Sprint("(updated, sent with the next message)")
+			}
+			fmt.Printf(" - %s%s\n", descColor.Sprint(m.String()), state)
+		}
+	}
+	fmt.Println(dim.Sprint("Mention @server:uri in a message to attach a resource (Tab completes); /resources detach drops them"))
+}
+
 // handleMCPDynamicPrompt shows details for a specific prompt when invoked as /$server/$prompt
 func handleMCPDynamicPrompt(cfg *config.Config, lowered string) bool {
 	path := strings.TrimPrefix(lowered, "/")
@@ -1639,6 +1777,7 @@ func showCostEstimation(cfg *config.Config) {
 		currentModel.OutputPrice,
 		cfg.Model,
 	)
+	summary.ApplyCachedInput(cfg.LastCachedTokens, lib.CachedInputPrice(cfg.Provider, currentModel))
 
 	// Format and display the cost estimation
 	costDisplay := lib.FormatTotalCostDisplay(summary)
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/diag"
+	"github.com/mikhae1/kubectl-quackops/pkg/filter"
+	"github.com/spf13/cobra"
+)
+
+func newRedactCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "redact",
+		Short: "Inspect the redaction applied before data is sent to the LLM",
+	}
+	cmd.AddCommand(newRedactTestCommand(cfg))
+	return cmd
+}
+
+func newRedactTestCommand(cfg *config.Config) *cobra.Command {
+	rulesFile := cfg.RedactionRulesFile
+
+	cmd := &cobra.Command{
+		Use:   "test <file>",
+		Short: "Show what the built-in filters and redaction rules would mask in a file (- for stdin)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rules, err := filter.LoadRules(rulesFile)
+			if err != nil {
+				return err
+			}
+			chain, err := filter.NewRedactionChain(rules)
+			if err != nil {
+				return err
+			}
+			input, err := readRedactInput(args[0])
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(config.Colors.Dim.Sprint(describeRedactionRules(rulesFile, rules)))
+			diff := redactionDiff(input, chain.Process(input))
+			if len(diff) == 0 {
+				fmt.Println(config.Colors.Ok.Sprint("Nothing would be masked."))
+				return nil
+			}
+			masked := 0
+			for _, line := range diff {
+				if strings.HasPrefix(line, "+") {
+					masked++
+					fmt.Println(config.Colors.Ok.Sprint(line))
+				} else {
+					fmt.Println(config.Colors.Error.Sprint(line))
+				}
+			}
+			fmt.Println(config.Colors.Warn.Sprintf("%d line(s) would be masked", masked))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&rulesFile, "rules", rulesFile, "Redaction rules file to test (default: QU_REDACTION_RULES or ~/.quackops/redaction.yaml)")
+	return cmd
+}
+
+func readRedactInput(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", path, err)
+	}
+	return string(data), nil
+}
+
+func describeRedactionRules(path string, rules *filter.RuleSet) string {
+	if len(rules.Rules) == 0 && len(rules.Allow) == 0 {
+		return "Built-in filters only (no rules in " + path + ")"
+	}
+	return fmt.Sprintf("Built-in filters + %d rule(s) and %d allow pattern(s) from %s", len(rules.Rules), len(rules.Allow), path)
+}
+
+// redactionDiff returns the lines that redaction changed. JSON is compared indented, since
+// the filters emit it compacted.
+func redactionDiff(input, redacted string) []string {
+	before := filter.Normalize(input)
+	var a, b bytes.Buffer
+	if json.Indent(&a, []byte(before), "", "  ") == nil && json.Indent(&b, []byte(redacted), "", "  ") == nil {
+		before, redacted = a.String(), b.String()
+	}
+	return diag.LineDiff(before, redacted)
+}
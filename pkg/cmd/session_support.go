@@ -14,6 +14,7 @@ import (
 
 	"github.com/ergochat/readline"
 	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/mcp"
 	quacksession "github.com/mikhae1/kubectl-quackops/pkg/session"
 	"github.com/spf13/cobra"
 )
@@ -158,16 +159,24 @@ func resetConversationContext(cfg *config.Config) {
 	cfg.StoredUserCmdResults = nil
 	cfg.LastOutgoingTokens = 0
 	cfg.LastIncomingTokens = 0
+	cfg.LastCachedTokens = 0
 	cfg.LastTextPrompt = ""
 	cfg.UserMsgCount = 0
 	cfg.SelectedPrompt = ""
 	cfg.MCPPromptServer = ""
+	// Attached resources live in the history that was just dropped
+	mcp.DetachResources(cfg)
 }
 
 func startFreshSession(cfg *config.Config) {
 	resetConversationContext(cfg)
 	cfg.SessionOutgoingTokens = 0
 	cfg.SessionIncomingTokens = 0
+	cfg.SessionCachedTokens = 0
+	cfg.ModelUsage = nil
+	cfg.SessionCostUSD = 0
+	cfg.BudgetWarned = map[string]bool{}
+	cfg.Pseudonyms = nil
 	cfg.SessionHistory = nil
 	cfg.CurrentSessionID = ""
 	cfg.CurrentSessionCreatedAt = time.Time{}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+)
+
+// handleSettingsCommand shows the generation settings, or changes them for the session with
+// "/settings [scope] key=value ...". Scope is a task type or provider name; without one the
+// global settings change. An empty value unsets a setting.
+func handleSettingsCommand(cfg *config.Config, args string) error {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		printGenerationSettings(cfg)
+		return nil
+	}
+
+	scope := ""
+	if !strings.Contains(fields[0], "=") {
+		scope = strings.ToLower(fields[0])
+		fields = fields[1:]
+		if _, ok := provider.Get(scope); !ok && !slices.Contains(config.Tasks, scope) {
+			return fmt.Errorf("unknown scope %q: use a task (%s) or a provider (%s)",
+				scope, strings.Join(config.Tasks, ", "), strings.Join(provider.Names(), ", "))
+		}
+		if len(fields) == 0 {
+			return fmt.Errorf("no settings given for %s", scope)
+		}
+	}
+
+	var target provider.Generation
+	switch {
+	case scope == "":
+		target = cfg.Generation
+	case slices.Contains(config.Tasks, scope):
+		target = cfg.TaskGeneration[scope]
+	default:
+		target = cfg.ProviderGeneration[scope]
+	}
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return fmt.Errorf("invalid setting %q: expected key=value", field)
+		}
+		if err := target.Set(key, value); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case scope == "":
+		cfg.Generation = target
+	case slices.Contains(config.Tasks, scope):
+		if cfg.TaskGeneration == nil {
+			cfg.TaskGeneration = map[string]provider.Generation{}
+		}
+		cfg.TaskGeneration[scope] = target
+	default:
+		if cfg.ProviderGeneration == nil {
+			cfg.ProviderGeneration = map[string]provider.Generation{}
+		}
+		cfg.ProviderGeneration[scope] = target
+	}
+	printGenerationSettings(cfg)
+	return nil
+}
+
+// printGenerationSettings prints the configured layers and the effective settings per task.
+func printGenerationSettings(cfg *config.Config) {
+	accent := config.Colors.Accent
+	info := config.Colors.Info
+	body := config.Colors.AccentAlt
+	dim := config.Colors.Dim
+
+	format := func(g provider.Generation) string {
+		if g.IsZero() {
+			return dim.Sprint("provider defaults")
+		}
+		return body.Sprint(strings.ReplaceAll(g.String(), ",", " "))
+	}
+
+	fmt.Println(accent.Sprint("Generation settings:"))
+	fmt.Printf(" - %s %s\n", info.Sprint("global:"), format(cfg.Generation))
+	fmt.Printf(" - %s %s\n", info.Sprintf("%s:", cfg.Provider), format(cfg.ProviderGeneration[cfg.Provider]))
+	fmt.Println(accent.Sprintf("Effective for %s/%s:", cfg.Provider, cfg.Model))
+	for _, task := range config.Tasks {
+		fmt.Printf(" - %s %s\n", info.Sprintf("%s:", task), format(cfg.GenerationFor(task)))
+	}
+	fmt.Println(dim.Sprintf("Change with /settings [task|provider] key=value (keys: %s)", strings.Join(provider.GenerationKeys(), ", ")))
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+)
+
+func TestHandleSettingsCommand(t *testing.T) {
+	cfg := createInteractiveTestConfig()
+	tests := []struct {
+		name    string
+		args    string
+		wantErr string
+		check   func(t *testing.T)
+	}{
+		{
+			name: "global",
+			args: "temperature=0.3 reasoning_effort=low",
+			check: func(t *testing.T) {
+				if got := cfg.Generation.String(); got != "temperature=0.3,reasoning_effort=low" {
+					t.Fatalf("unexpected global settings %q", got)
+				}
+			},
+		},
+		{
+			name: "task",
+			args: "commands temperature=0",
+			check: func(t *testing.T) {
+				if got := cfg.GenerationFor(config.TaskCommands).String(); got != "temperature=0,reasoning_effort=low" {
+					t.Fatalf("unexpected commands settings %q", got)
+				}
+			},
+		},
+		{
+			name: "provider",
+			args: "openai seed=3",
+			check: func(t *testing.T) {
+				if got := cfg.GenerationFor(config.TaskAnswer).String(); got != "temperature=0.3,reasoning_effort=low,seed=3" {
+					t.Fatalf("unexpected answer settings %q", got)
+				}
+			},
+		},
+		{
+			name: "unset",
+			args: "reasoning_effort=",
+			check: func(t *testing.T) {
+				if cfg.Generation.ReasoningEffort != "" {
+					t.Fatal("reasoning effort not unset")
+				}
+			},
+		},
+		{name: "unknown scope", args: "router temperature=1", wantErr: `unknown scope "router"`},
+		{name: "invalid value", args: "top_p=2", wantErr: "top_p must be a number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := handleSettingsCommand(cfg, tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t)
+		})
+	}
+}
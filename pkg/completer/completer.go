@@ -49,6 +49,21 @@ func findSlashPrefix(line []rune) (string, bool) {
 	return string(line[tokenStart:]), tokenStart == 0
 }
 
+// findMentionPrefix returns the current token when it starts with '@'.
+func findMentionPrefix(line []rune) (string, bool) {
+	tokenStart := 0
+	for i := len(line) - 1; i >= 0; i-- {
+		if unicode.IsSpace(line[i]) {
+			tokenStart = i + 1
+			break
+		}
+	}
+	if tokenStart >= len(line) || line[tokenStart] != '@' {
+		return "", false
+	}
+	return string(line[tokenStart:]), true
+}
+
 // Do implements the AutoCompleter interface for tab completion
 func (c *shellAutoCompleter) Do(line []rune, pos int) (newLine [][]rune, length int) {
 	// Handle potential index out of bounds
@@ -59,6 +74,13 @@ func (c *shellAutoCompleter) Do(line []rune, pos int) (newLine [][]rune, length
 	lineRunes := line[:pos]
 	lineStr := string(lineRunes)
 
+	// @server:uri resource mentions complete in all modes
+	if mention, ok := findMentionPrefix(lineRunes); ok && c.Cfg.MCPClientEnabled {
+		if completions, mentionLen := c.CompleteResourceMentions(mention); len(completions) > 0 {
+			return completions, mentionLen
+		}
+	}
+
 	// Handle slash commands autocomplete first - works in all modes
 	if slashPrefix, atLineStart := findSlashPrefix(lineRunes); slashPrefix != "" {
 		if completions, slashLen := c.CompleteSlashCommands(slashPrefix, atLineStart); len(completions) > 0 {
@@ -541,6 +563,40 @@ func (c *shellAutoCompleter) CompleteSlashCommands(input string, includeDefaults
 	return completions, len(input)
 }
 
+// CompleteResourceMentions completes "@server:" from the servers that expose resources, then
+// the resource URI, including template variables completed by the server.
+func (c *shellAutoCompleter) CompleteResourceMentions(input string) ([][]rune, int) {
+	var completions [][]rune
+	server, partial, hasColon := strings.Cut(strings.TrimPrefix(input, "@"), ":")
+	if !hasColon {
+		seen := make(map[string]bool)
+		var servers []string
+		for _, ri := range mcp.GetResourceInfos(c.Cfg) {
+			servers = append(servers, ri.Server)
+		}
+		for _, t := range mcp.GetResourceTemplateInfos(c.Cfg) {
+			servers = append(servers, t.Server)
+		}
+		for _, name := range servers {
+			mention := "@" + name + ":"
+			if seen[name] || !strings.HasPrefix(mention, input) {
+				continue
+			}
+			seen[name] = true
+			completions = append(completions, []rune(mention[len(input):]))
+		}
+		return completions, len(input)
+	}
+
+	for _, uri := range mcp.CompleteResourceURI(c.Cfg, server, partial) {
+		completions = append(completions, []rune(uri[len(partial):]))
+		if c.Cfg.MaxCompletions > 0 && len(completions) >= c.Cfg.MaxCompletions {
+			break
+		}
+	}
+	return completions, len(input)
+}
+
 // IsMCPPrompt checks if the input starts with an MCP prompt in format /$server/$prompt
 // Returns the prompt name, server name, and remaining text if found
 func IsMCPPrompt(cfg *config.Config, input string) (promptName string, userQuery string, isPrompt bool) {
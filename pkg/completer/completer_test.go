@@ -302,6 +302,26 @@ func TestAutoCompleterDoCompletesPlanTokenMidLine(t *testing.T) {
 	}
 }
 
+func TestFindMentionPrefix(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string
+		ok       bool
+	}{
+		{"", "", false},
+		{"@kub", "@kub", true},
+		{"why does @kube:k8s://pods/de", "@kube:k8s://pods/de", true},
+		{"ops@example", "", false},
+		{"@kube:k8s://config ", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := findMentionPrefix([]rune(tt.line))
+		if got != tt.expected || ok != tt.ok {
+			t.Errorf("findMentionPrefix(%q) = %q, %v; want %q, %v", tt.line, got, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
 func TestShellAutoCompleter_Do(t *testing.T) {
 	// Replace exec.Command with mock
 	execCommand = mockExecCommand
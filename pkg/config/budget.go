@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/budget"
+)
+
+// Actions taken when a request would exceed a budget.
+const (
+	BudgetActionPrompt    = "prompt"    // ask whether to continue, downgrade or stop
+	BudgetActionDowngrade = "downgrade" // switch to BudgetDowngradeModel, stop if that is over budget too
+	BudgetActionBlock     = "block"     // refuse the request
+)
+
+// BudgetActions lists the valid BudgetAction values.
+var BudgetActions = []string{BudgetActionPrompt, BudgetActionDowngrade, BudgetActionBlock}
+
+// BudgetLimits returns the configured spend limits.
+func (cfg *Config) BudgetLimits() budget.Limits {
+	return budget.Limits{
+		RequestUSD:    cfg.BudgetRequestUSD,
+		SessionUSD:    cfg.BudgetSessionUSD,
+		DailyUSD:      cfg.BudgetDailyUSD,
+		RequestTokens: cfg.BudgetRequestTokens,
+		SessionTokens: cfg.BudgetSessionTokens,
+		DailyTokens:   cfg.BudgetDailyTokens,
+	}
+}
+
+// ValidateBudget rejects an unknown budget action or negative limits.
+func (cfg *Config) ValidateBudget() error {
+	if !slices.Contains(BudgetActions, cfg.BudgetAction) {
+		return fmt.Errorf("invalid budget action %q (expected one of: %s)", cfg.BudgetAction, strings.Join(BudgetActions, ", "))
+	}
+	l := cfg.BudgetLimits()
+	if l.RequestUSD < 0 || l.SessionUSD < 0 || l.DailyUSD < 0 || l.RequestTokens < 0 || l.SessionTokens < 0 || l.DailyTokens < 0 {
+		return fmt.Errorf("budget limits must not be negative")
+	}
+	return nil
+}
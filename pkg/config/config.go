@@ -12,7 +12,11 @@ import (
 	"time"
 
 	"github.com/fatih/color"
+	"github.com/mikhae1/kubectl-quackops/pkg/cassette"
+	"github.com/mikhae1/kubectl-quackops/pkg/filter"
 	"github.com/mikhae1/kubectl-quackops/pkg/llm/metadata"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/mikhae1/kubectl-quackops/pkg/privacy"
 	"github.com/tmc/langchaingo/llms"
 )
 
@@ -42,10 +46,29 @@ type SlashCommand struct {
 
 // SessionEvent represents a single interaction in the session history
 type SessionEvent struct {
-	Timestamp  time.Time
-	UserPrompt string
-	ToolCalls  []ToolCallData
-	AIResponse string
+	Timestamp     time.Time
+	UserPrompt    string
+	ToolCalls     []ToolCallData
+	AIResponse    string
+	ModelSwitches []ModelSwitch `json:",omitempty"`
+}
+
+// ModelSwitch records an automatic failover to the next model in the fallback chain
+type ModelSwitch struct {
+	From   string // provider/model that failed
+	To     string // provider/model that took over
+	Reason string
+}
+
+// ModelUsage accumulates the requests and tokens a model used for one task type
+type ModelUsage struct {
+	Task         string
+	Provider     string
+	Model        string
+	Requests     int
+	InputTokens  int
+	OutputTokens int
+	CachedTokens int
 }
 
 // ToolCallData represents a recorded tool call
@@ -79,6 +102,12 @@ type Config struct {
 	SafeMode              bool
 	Verbose               bool
 	DisableSecretFilter   bool
+	RedactionRulesFile    string                // User redaction rules (YAML or JSON) applied on top of the built-in filters
+	Redactor              *filter.Chain         // Compiled redaction rules; nil uses the built-in filters only
+	Pseudonymize          bool                  // Send namespaces, pods, nodes, hosts and IPs to the model as stable tokens
+	Pseudonyms            *filter.Pseudonymizer // Session mapping of identifiers to tokens, kept in memory only
+	PrivacyReport         bool                  // Print the redaction and upload audit after every turn
+	Privacy               *privacy.Report       // Redaction and upload audit of the current turn
 	DisableMarkdownFormat bool
 	DisableAnimation      bool
 	MaxCompletions        int
@@ -86,8 +115,12 @@ type Config struct {
 	DisableHistory        bool
 	SessionsDir           string
 	MaxSavedSessions      int
-	KubectlBinaryPath     string
-	Theme                 string
+	// Local cache of responses to deterministic sub-requests (command suggestions, plans)
+	ResponseCacheDir     string
+	ResponseCacheTTL     time.Duration
+	DisableResponseCache bool
+	KubectlBinaryPath    string
+	Theme                string
 
 	// SuppressContentPrint prevents Chat from printing model message bodies
 	SuppressContentPrint bool
@@ -105,6 +138,10 @@ type Config struct {
 	// Callers should set and restore this around a single request.
 	SpinnerMessageOverride string
 
+	// ResponseSchema, when set, requests provider-native structured output for Chat requests.
+	// Callers should set and restore this around a single request.
+	ResponseSchema *provider.ResponseSchema
+
 	// Test-friendly switch to skip sleeps/backoffs/throttle waits
 	SkipWaits bool
 
@@ -124,6 +161,23 @@ type Config struct {
 	KubectlMaxSuggestions int  // Maximum number of kubectl commands the LLM should suggest
 	KubectlReturnJSON     bool // Prefer JSON array output for command suggestions
 
+	// Structured output for command suggestions and plans
+	StructuredOutput        bool // Use provider-native JSON schema enforcement when available
+	StructuredOutputRetries int  // Repair attempts after a response violates its schema
+
+	PromptCache bool // Mark stable prompt prefixes for provider-side prompt caching
+
+	// Generation settings, layered global < provider < task (see GenerationFor)
+	Generation         provider.Generation
+	ProviderGeneration map[string]provider.Generation
+	TaskGeneration     map[string]provider.Generation
+	// Task type of the request in flight (temporary, set around single requests); empty
+	// means TaskAnswer
+	ActiveTask string
+	// Model per task type as provider/model or a bare model on the active provider; tasks
+	// without an entry use Provider and Model
+	TaskModels map[string]string
+
 	KubectlPrompts       []KubectlPrompt
 	StoredUserCmdResults []CmdRes
 	SlashCommands        []SlashCommand
@@ -134,6 +188,28 @@ type Config struct {
 	// Cumulative token accounting for the active session.
 	SessionOutgoingTokens int
 	SessionIncomingTokens int
+	// Prompt tokens served from the provider's prompt cache (subset of the counts above).
+	LastCachedTokens    int
+	SessionCachedTokens int
+	// Whether the last LLM exchange was answered from the local response cache
+	LastResponseCached bool
+	// Per task and model breakdown of the session token counts
+	ModelUsage []ModelUsage
+	// Estimated session spend in USD, measured against BudgetSessionUSD
+	SessionCostUSD float64
+
+	// Spend limits per request, session and rolling day; zero means unlimited
+	BudgetRequestUSD     float64
+	BudgetSessionUSD     float64
+	BudgetDailyUSD       float64
+	BudgetRequestTokens  int
+	BudgetSessionTokens  int
+	BudgetDailyTokens    int
+	BudgetWarnPercent    int    // Warn once session or daily spend reaches this share of its limit
+	BudgetAction         string // What to do when a request would exceed a limit (see BudgetActions)
+	BudgetDowngradeModel string // provider/model (or model) used instead of an over-budget model
+	LedgerFile           string // Spend ledger shared by all sessions, used for daily limits
+	BudgetWarned         map[string]bool
 
 	// EditMode indicates the persistent shell edit mode toggled by '!'
 	EditMode       bool
@@ -165,6 +241,15 @@ type Config struct {
 	EventsWarningsOnly      bool
 	LogsTail                int
 	LogsAllContainers       bool
+	CRDAnalysisEnabled      bool     // Discover installed CRDs and analyze their status conditions
+	CRDResources            []string // Fully-qualified CRD resources to analyze (e.g. applications.argoproj.io)
+	HelmAnalysisEnabled     bool     // Decode Helm release secrets for status findings and upgrade diffs
+
+	// Metrics backend (Prometheus-compatible HTTP API)
+	MetricsURL     string // Base URL of the metrics API; empty disables metrics queries
+	MetricsToken   string // Optional bearer token for the metrics API
+	MetricsTimeout int    // Timeout in seconds for a single metrics query
+	MetricsMaxRows int    // Maximum rows per summarized metrics table
 
 	// MCP client mode
 	MCPClientEnabled bool
@@ -191,6 +276,13 @@ type Config struct {
 	MCPToolResultMaxCharsForModel int
 	// Maximum MCP tool calls executed concurrently per round (1 = sequential behavior)
 	MCPParallelToolCalls int
+	// Maximum bytes of an @server:uri resource sent to the model (0 = unlimited)
+	MCPResourceMaxBytes int
+	// Client capabilities offered to MCP servers: sampling with the configured model,
+	// elicitation forms and roots (working directory and kubeconfig)
+	MCPSampling    bool
+	MCPElicitation bool
+	MCPRoots       bool
 
 	// Last-request MCP loop metrics (used by benchmark/reporting)
 	LastMCPToolCallsTotal    int
@@ -199,6 +291,16 @@ type Config struct {
 	LastMCPCacheHits         int
 	LastMCPStopReason        string
 
+	// Ordered provider/model pairs tried when the active model keeps failing
+	FallbackModels []string
+	// Model switches made during the current request, attached to its session event
+	PendingModelSwitches []ModelSwitch
+
+	// Record/replay of LLM, MCP and kubectl traffic
+	RecordDir string
+	ReplayDir string
+	Cassette  *cassette.Cassette // Open cassette; nil when neither recording nor replaying
+
 	// MCP logging for debugging (raw server stdio)
 	MCPLogEnabled bool
 	MCPLogFile    string
@@ -207,6 +309,8 @@ type Config struct {
 	// Tool policy
 	AllowedTools []string
 	DeniedTools  []string
+	// Remembered "always allow" decisions for MCP tools (YAML or JSON)
+	MCPToolPolicyFile string
 
 	// Presentation
 	ToolOutputMaxLines       int
@@ -511,45 +615,11 @@ func envFirst(keys ...string) string {
 	return ""
 }
 
-// ProviderDefaults groups default values per provider
-type ProviderDefaults struct {
-	DefaultMaxTokens      int
-	DefaultModel          string
-	DefaultEmbeddingModel string
-}
-
-var providerDefaults = map[string]ProviderDefaults{
-	"google": {
-		DefaultMaxTokens:      128000,
-		DefaultModel:          "gemini-2.5-flash-preview-04-17",
-		DefaultEmbeddingModel: "models/text-embedding-004",
-	},
-	"ollama": {
-		DefaultMaxTokens:      4096,
-		DefaultModel:          "llama3.1",
-		DefaultEmbeddingModel: "models/text-embedding-large-exp",
-	},
-	"openai": {
-		DefaultMaxTokens:      128000,
-		DefaultModel:          "gpt-5-mini",
-		DefaultEmbeddingModel: "text-embedding-3-small",
-	},
-	"azopenai": {
-		DefaultMaxTokens:      128000,
-		DefaultModel:          "gpt-4o-mini",
-		DefaultEmbeddingModel: "text-embedding-3-small",
-	},
-	"anthropic": {
-		DefaultMaxTokens:      200000,
-		DefaultModel:          "claude-3-7-sonnet-latest",
-		DefaultEmbeddingModel: "nomic-embed-text",
-	},
-}
-
-var defaultProviderFallback = ProviderDefaults{
-	DefaultMaxTokens:      16000,
-	DefaultModel:          "llama3.1",
-	DefaultEmbeddingModel: "models/text-embedding-large-exp",
+// defaultProviderFallback applies when the configured provider is not registered.
+var defaultProviderFallback = provider.Defaults{
+	MaxTokens:      16000,
+	Model:          "llama3.1",
+	EmbeddingModel: "models/text-embedding-large-exp",
 }
 
 // GetOpenAIBaseURL returns the OpenAI base URL from environment variables
@@ -558,6 +628,28 @@ func GetOpenAIBaseURL() string {
 	return envFirst("QU_OPENAI_BASE_URL", "OPENAI_BASE_URL")
 }
 
+// GetOpenAICompatBaseURL returns the base URL of a self-hosted OpenAI-compatible server
+// (vLLM, llama.cpp, LM Studio) from QU_OPENAI_COMPAT_BASE_URL.
+func GetOpenAICompatBaseURL() string {
+	return envFirst("QU_OPENAI_COMPAT_BASE_URL")
+}
+
+// GetOpenAICompatAPIKey returns the optional API key of the OpenAI-compatible server.
+func GetOpenAICompatAPIKey() string {
+	return envFirst("QU_OPENAI_COMPAT_API_KEY")
+}
+
+// GetOpenAICompatQuirks returns the quirk flags set in QU_OPENAI_COMPAT_QUIRKS.
+func GetOpenAICompatQuirks() []string {
+	var flags []string
+	for _, f := range strings.Split(envFirst("QU_OPENAI_COMPAT_QUIRKS"), ",") {
+		if f = strings.ToLower(strings.TrimSpace(f)); f != "" {
+			flags = append(flags, f)
+		}
+	}
+	return flags
+}
+
 // GetAzOpenAIBaseURL returns the Azure OpenAI base URL from environment variables
 // Supports both QU_AZ_OPENAI_BASE_URL and OPENAI_BASE_URL (as alias)
 func GetAzOpenAIBaseURL() string {
@@ -581,17 +673,17 @@ func LoadConfig() *Config {
 	// Load configuration from config file first
 	configFileValues = loadConfigFile()
 
-	provider := getEnvArg("QU_LLM_PROVIDER", "ollama").(string)
+	providerName := getEnvArg("QU_LLM_PROVIDER", "ollama").(string)
 
-	// Centralized provider defaults
-	pd, ok := providerDefaults[provider]
-	if !ok {
-		pd = defaultProviderFallback
+	// Defaults come from the provider registry
+	pd := defaultProviderFallback
+	if p, ok := provider.Get(providerName); ok {
+		pd = p.Defaults()
 	}
 
-	defaultMaxTokens := pd.DefaultMaxTokens
-	defaultModel := pd.DefaultModel
-	defaultEmbeddingModel := pd.DefaultEmbeddingModel
+	defaultMaxTokens := pd.MaxTokens
+	defaultModel := pd.Model
+	defaultEmbeddingModel := pd.EmbeddingModel
 
 	// Get home directory for history file
 	homeDir, err := os.UserHomeDir()
@@ -600,19 +692,32 @@ func LoadConfig() *Config {
 	}
 	defaultHistoryFile := ""
 	defaultSessionsDir := ""
+	defaultResponseCacheDir := ""
+	defaultLedgerFile := ""
+	defaultRedactionRulesFile := ""
 	if homeDir != "" {
 		defaultHistoryFile = filepath.Join(homeDir, ".quackops", "history")
 		defaultSessionsDir = filepath.Join(homeDir, ".quackops", "sessions")
+		defaultResponseCacheDir = filepath.Join(homeDir, ".quackops", "cache", "responses")
+		defaultLedgerFile = filepath.Join(homeDir, ".quackops", "ledger.jsonl")
+		defaultRedactionRulesFile = filepath.Join(homeDir, ".quackops", "redaction.yaml")
 	}
 
 	config := &Config{
 		ChatMessages:          []llms.ChatMessage{},
 		DuckASCIIArt:          defaultDuckASCIIArt,
-		Provider:              provider,
+		Provider:              providerName,
 		Model:                 getEnvArg("QU_LLM_MODEL", defaultModel).(string),
+		FallbackModels:        getEnvArg("QU_FALLBACK_MODELS", []string{}).([]string),
+		RecordDir:             getEnvArg("QU_RECORD_DIR", "").(string),
+		ReplayDir:             getEnvArg("QU_REPLAY_DIR", "").(string),
 		OllamaApiURL:          getEnvArg("QU_OLLAMA_BASE_URL", "http://localhost:11434").(string),
 		AzOpenAIAPIVersion:    getEnvArg("QU_AZ_OPENAI_API_VERSION", "2025-05-01").(string),
 		SafeMode:              getEnvArg("QU_SAFE_MODE", false).(bool),
+		RedactionRulesFile:    getEnvArg("QU_REDACTION_RULES", defaultRedactionRulesFile).(string),
+		Pseudonymize:          getEnvArg("QU_PSEUDONYMIZE", false).(bool),
+		PrivacyReport:         getEnvArg("QU_PRIVACY_REPORT", false).(bool),
+		Privacy:               privacy.NewReport(),
 		Retries:               getEnvArg("QU_RETRIES", 3).(int),
 		Timeout:               getEnvArg("QU_TIMEOUT", 30).(int),
 		DefaultMaxTokens:      defaultMaxTokens,
@@ -626,6 +731,9 @@ func LoadConfig() *Config {
 		DisableHistory:        getEnvArg("QU_DISABLE_HISTORY", false).(bool),
 		SessionsDir:           getEnvArg("QU_SESSIONS_DIR", defaultSessionsDir).(string),
 		MaxSavedSessions:      getEnvArg("QU_MAX_SAVED_SESSIONS", 20).(int),
+		ResponseCacheDir:      getEnvArg("QU_RESPONSE_CACHE_DIR", defaultResponseCacheDir).(string),
+		ResponseCacheTTL:      time.Duration(getEnvArg("QU_RESPONSE_CACHE_TTL", 900).(int)) * time.Second,
+		DisableResponseCache:  getEnvArg("QU_DISABLE_RESPONSE_CACHE", false).(bool),
 		KubectlBinaryPath:     getEnvArg("QU_KUBECTL_BINARY", "kubectl").(string),
 		Theme:                 strings.ToLower(strings.TrimSpace(getEnvArg("QU_THEME", defaultTheme).(string))),
 		SuppressContentPrint:  false,
@@ -644,6 +752,13 @@ func LoadConfig() *Config {
 		EventsWarningsOnly:       getEnvArg("QU_EVENTS_WARN_ONLY", true).(bool),
 		LogsTail:                 getEnvArg("QU_LOGS_TAIL", 200).(int),
 		LogsAllContainers:        getEnvArg("QU_LOGS_ALL_CONTAINERS", false).(bool),
+		CRDAnalysisEnabled:       getEnvArg("QU_CRD_ANALYSIS", true).(bool),
+		CRDResources:             getEnvArg("QU_CRD_RESOURCES", defaultCRDResources).([]string),
+		HelmAnalysisEnabled:      getEnvArg("QU_HELM_ANALYSIS", true).(bool),
+		MetricsURL:               getEnvArg("QU_METRICS_URL", "").(string),
+		MetricsToken:             getEnvArg("QU_METRICS_TOKEN", "").(string),
+		MetricsTimeout:           getEnvArg("QU_METRICS_TIMEOUT", 10).(int),
+		MetricsMaxRows:           getEnvArg("QU_METRICS_MAX_ROWS", 10).(int),
 		ToolOutputMaxLines:       getEnvArg("QU_TOOL_OUTPUT_MAX_LINES", 40).(int),
 		ToolOutputMaxLineLen:     getEnvArg("QU_TOOL_OUTPUT_MAX_LINE_LEN", 140).(int),
 		DiagnosticResultMaxLines: getEnvArg("QU_DIAGNOSTIC_RESULT_MAX_LINES", 10).(int),
@@ -672,6 +787,10 @@ func LoadConfig() *Config {
 		MCPCacheToolResults:           getEnvArg("QU_MCP_CACHE_TOOL_RESULTS", true).(bool),
 		MCPToolResultMaxCharsForModel: getEnvArg("QU_MCP_TOOL_RESULT_MAX_CHARS_FOR_MODEL", 8000).(int),
 		MCPParallelToolCalls:          getEnvArg("QU_MCP_PARALLEL_TOOL_CALLS", 3).(int),
+		MCPResourceMaxBytes:           getEnvArg("QU_MCP_RESOURCE_MAX_BYTES", 32000).(int),
+		MCPSampling:                   getEnvArg("QU_MCP_SAMPLING", true).(bool),
+		MCPElicitation:                getEnvArg("QU_MCP_ELICITATION", true).(bool),
+		MCPRoots:                      getEnvArg("QU_MCP_ROOTS", true).(bool),
 		MCPLogEnabled:                 getEnvArg("QU_MCP_LOG", false).(bool),
 		MCPLogFile: func() string {
 			if homeDir != "" {
@@ -688,6 +807,12 @@ func LoadConfig() *Config {
 		// Tool policy
 		AllowedTools: getEnvArg("QU_ALLOWED_TOOLS", defaultAllowedTools).([]string),
 		DeniedTools:  getEnvArg("QU_DENIED_TOOLS", defaultDeniedTools).([]string),
+		MCPToolPolicyFile: func() string {
+			if homeDir == "" {
+				return getEnvArg("QU_MCP_TOOL_POLICY", "").(string)
+			}
+			return getEnvArg("QU_MCP_TOOL_POLICY", filepath.Join(homeDir, ".quackops", "tool-policy.yaml")).(string)
+		}(),
 
 		// LLM Request Throttling
 		ThrottleRequestsPerMinute: getEnvArg("QU_THROTTLE_REQUESTS_PER_MINUTE", 60).(int),
@@ -736,6 +861,25 @@ func LoadConfig() *Config {
 		KubectlMaxSuggestions: getEnvArg("QU_KUBECTL_MAX_SUGGESTIONS", 12).(int),
 		KubectlReturnJSON:     getEnvArg("QU_KUBECTL_RETURN_JSON", true).(bool),
 
+		StructuredOutput:        getEnvArg("QU_STRUCTURED_OUTPUT", true).(bool),
+		StructuredOutputRetries: getEnvArg("QU_STRUCTURED_OUTPUT_RETRIES", 2).(int),
+		PromptCache:             getEnvArg("QU_PROMPT_CACHE", true).(bool),
+		Generation:              getGenerationArg("QU_GENERATION"),
+		ProviderGeneration:      map[string]provider.Generation{},
+		TaskGeneration:          map[string]provider.Generation{},
+		TaskModels:              map[string]string{},
+		BudgetRequestUSD:        getEnvArg("QU_BUDGET_REQUEST_USD", 0.0).(float64),
+		BudgetSessionUSD:        getEnvArg("QU_BUDGET_SESSION_USD", 0.0).(float64),
+		BudgetDailyUSD:          getEnvArg("QU_BUDGET_DAILY_USD", 0.0).(float64),
+		BudgetRequestTokens:     getEnvArg("QU_BUDGET_REQUEST_TOKENS", 0).(int),
+		BudgetSessionTokens:     getEnvArg("QU_BUDGET_SESSION_TOKENS", 0).(int),
+		BudgetDailyTokens:       getEnvArg("QU_BUDGET_DAILY_TOKENS", 0).(int),
+		BudgetWarnPercent:       getEnvArg("QU_BUDGET_WARN_PERCENT", 80).(int),
+		BudgetAction:            strings.ToLower(strings.TrimSpace(getEnvArg("QU_BUDGET_ACTION", BudgetActionPrompt).(string))),
+		BudgetDowngradeModel:    getEnvArg("QU_BUDGET_DOWNGRADE_MODEL", "").(string),
+		LedgerFile:              getEnvArg("QU_LEDGER_FILE", defaultLedgerFile).(string),
+		BudgetWarned:            map[string]bool{},
+
 		SlashCommands: defaultSlashCommands(),
 
 		KubectlPrompts: []KubectlPrompt{
@@ -784,13 +928,16 @@ func LoadConfig() *Config {
 			},
 			// gateways, http routes
 			{
-				MatchRe: regexp.MustCompile(`\b(gateway|route|httproute)s?\b`),
+				MatchRe: regexp.MustCompile(`\b(gateway|route|httproute|grpcroute|referencegrant)s?\b`),
 				Prompt:  "Include commands to analyze Kubernetes gateways and routes.",
 				AllowedKubectls: []string{
 					"get gateway -A",
 					"get gatewayclasses -A",
 					"get httproute -A",
+					"get grpcroute -A",
+					"get referencegrant -A",
 					"describe gateway",
+					"describe httproute",
 				},
 				UseDefaultCmds: true,
 			},
@@ -883,6 +1030,9 @@ func LoadConfig() *Config {
 		},
 	}
 
+	loadScopedGeneration(config)
+	loadTaskModels(config)
+
 	// Auto-enable SkipWaits under `go test` without requiring env/flags
 	if !config.SkipWaits && isRunningUnderGoTest() {
 		config.SkipWaits = true
@@ -1143,6 +1293,15 @@ var defaultBlockedKubectlCmds = []string{
 	"mv",
 }
 
+// defaultCRDResources covers common GitOps, certificate and service-mesh operators.
+var defaultCRDResources = []string{
+	"applications.argoproj.io",
+	"kustomizations.kustomize.toolkit.fluxcd.io",
+	"helmreleases.helm.toolkit.fluxcd.io",
+	"certificates.cert-manager.io",
+	"virtualservices.networking.istio.io",
+}
+
 var defaultAllowedTools = []string{"*"}
 
 var defaultDeniedTools = []string{}
@@ -1190,13 +1349,13 @@ var defaultDiagnosticAnalysisPrompt = `# Kubernetes Diagnostic Analysis
 
 // ConfigDetectMaxTokens attempts to auto-detect and enhance config values using model metadata
 func (cfg *Config) ConfigDetectMaxTokens() {
-	// Skip if auto-detection is disabled
-	if !cfg.AutoDetectMaxTokens {
+	// Skip if auto-detection is disabled or responses come from a cassette
+	if !cfg.AutoDetectMaxTokens || cfg.Cassette.Replaying() {
 		return
 	}
 
-	// Only attempt auto-detection for supported providers
-	if cfg.Provider != "openai" && cfg.Provider != "azopenai" && cfg.Provider != "google" && cfg.Provider != "anthropic" && cfg.Provider != "ollama" {
+	// Only attempt auto-detection for registered providers
+	if _, ok := provider.Get(cfg.Provider); !ok {
 		return
 	}
 
@@ -1210,9 +1369,9 @@ func (cfg *Config) ConfigDetectMaxTokens() {
 
 	// Determine base URL for the API call depending on provider
 	baseURL := GetProviderBaseURL(cfg)
-	if cfg.Provider == "azopenai" && baseURL == "" {
-		// Azure OpenAI requires a custom endpoint, cannot use default
-		fmt.Fprintf(os.Stderr, "Warning: Azure OpenAI requires QU_AZ_OPENAI_BASE_URL or OPENAI_BASE_URL environment variable\n")
+	if baseURL == "" {
+		// Providers without a public endpoint (e.g. Azure OpenAI) need one configured
+		fmt.Fprintf(os.Stderr, "Warning: no API base URL configured for provider %s; set the provider's base URL environment variable\n", cfg.Provider)
 		return
 	}
 
@@ -1231,41 +1390,30 @@ func (cfg *Config) ConfigDetectMaxTokens() {
 
 // GetProviderBaseURL returns the base URL for the configured provider, applying env/config defaults
 func GetProviderBaseURL(cfg *Config) string {
-	switch cfg.Provider {
-	case "openai":
-		if baseURL := GetOpenAIBaseURL(); baseURL != "" {
-			return baseURL
-		}
-		if strings.Contains(cfg.Model, "/") || strings.Contains(cfg.Model, "openrouter") {
-			return "https://openrouter.ai/api/v1"
-		}
-		return "https://api.openai.com"
-	case "azopenai":
-		if baseURL := GetAzOpenAIBaseURL(); baseURL != "" {
-			return baseURL
-		}
-		// No sensible default for Azure — must be provided by user
+	p, ok := provider.Get(cfg.Provider)
+	if !ok {
 		return ""
-	case "google":
-		if baseURL := os.Getenv("QU_GOOGLE_BASE_URL"); baseURL != "" {
-			return baseURL
-		}
-		if baseURL := os.Getenv("GOOGLE_GEMINI_BASE_URL"); baseURL != "" {
-			return baseURL
-		}
-		return "https://generativelanguage.googleapis.com"
-	case "anthropic":
-		if baseURL := os.Getenv("QU_ANTHROPIC_BASE_URL"); baseURL != "" {
-			return baseURL
-		}
-		return "https://api.anthropic.com"
-	case "ollama":
-		if cfg.OllamaApiURL != "" {
-			return cfg.OllamaApiURL
+	}
+	return p.BaseURL(cfg.ProviderOptions())
+}
+
+// ProviderOptions returns the provider client options derived from the configuration.
+func (cfg *Config) ProviderOptions() provider.Options {
+	var embeddingModels []string
+	for _, m := range strings.Split(cfg.OllamaEmbeddingModels, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			embeddingModels = append(embeddingModels, m)
 		}
-		return "http://localhost:11434"
-	default:
-		return ""
+	}
+	return provider.Options{
+		Model:           cfg.Model,
+		EmbeddingModel:  cfg.EmbeddingModel,
+		ServerURL:       cfg.OllamaApiURL,
+		APIVersion:      cfg.AzOpenAIAPIVersion,
+		EmbeddingModels: embeddingModels,
+		ResponseSchema:  cfg.ResponseSchema,
+		Generation:      cfg.GenerationFor(cfg.ActiveTask),
+		PromptCache:     cfg.PromptCache,
 	}
 }
 
@@ -1322,6 +1470,26 @@ func defaultSlashCommands() []SlashCommand {
 			Primary:     "/prompts",
 			Description: "List MCP prompts",
 		},
+		{
+			Commands:    []string{"/resources"},
+			Primary:     "/resources",
+			Description: "List MCP resources and templates to mention as @server:uri; /resources detach drops attached ones",
+		},
+		{
+			Commands:    []string{"/helm"},
+			Primary:     "/helm",
+			Description: "List Helm releases, or show history and last upgrade diff for [namespace/]release",
+		},
+		{
+			Commands:    []string{"/privacy"},
+			Primary:     "/privacy",
+			Description: "Show what was redacted and what was sent to which LLM endpoint in the last turn",
+		},
+		{
+			Commands:    []string{"/settings"},
+			Primary:     "/settings",
+			Description: "Show or change generation settings: [task|provider] key=value",
+		},
 		{
 			Commands:    []string{"/history"},
 			Primary:     "/history",
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+)
+
+// Task types select per-task generation settings.
+const (
+	TaskCommands   = "commands"   // kubectl command suggestions
+	TaskPlan       = "plan"       // plan drafting
+	TaskAnswer     = "answer"     // diagnosis and final answers
+	TaskCompaction = "compaction" // conversation compaction summaries
+	TaskSampling   = "sampling"   // completions requested by MCP servers
+)
+
+// Tasks lists the task types in display order.
+var Tasks = []string{TaskCommands, TaskPlan, TaskAnswer, TaskCompaction, TaskSampling}
+
+const generationEnvPrefix = "QU_GENERATION_"
+
+// GenerationFor returns the effective generation settings for a task on the active provider:
+// global settings, overridden by provider settings, overridden by task settings.
+func (cfg *Config) GenerationFor(task string) provider.Generation {
+	if task == "" {
+		task = TaskAnswer
+	}
+	return cfg.Generation.Merge(cfg.ProviderGeneration[cfg.Provider]).Merge(cfg.TaskGeneration[task])
+}
+
+// getGenerationArg reads a generation spec such as "temperature=0.2,top_p=0.9" and exits on
+// invalid input, like getEnvArg.
+func getGenerationArg(key string) provider.Generation {
+	spec := getEnvArg(key, "").(string)
+	g, err := provider.ParseGeneration(spec)
+	if err != nil {
+		fmt.Printf("Error: Value '%s' is invalid: %v\n", key, err)
+		os.Exit(1)
+	}
+	return g
+}
+
+// loadScopedGeneration reads QU_GENERATION_<TASK> and QU_GENERATION_<PROVIDER> settings, e.g.
+// QU_GENERATION_PLAN or QU_GENERATION_OPENAI_COMPATIBLE, from the same sources as getEnvArg.
+func loadScopedGeneration(cfg *Config) {
+	seen := map[string]bool{}
+	var keys []string
+	addKey := func(key string) {
+		if strings.HasPrefix(key, generationEnvPrefix) && len(key) > len(generationEnvPrefix) && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	for _, arg := range os.Args[1:] {
+		if k, _, ok := strings.Cut(arg, "="); ok {
+			addKey(k)
+		}
+	}
+	for _, kv := range os.Environ() {
+		if k, _, ok := strings.Cut(kv, "="); ok {
+			addKey(k)
+		}
+	}
+	for k := range configFileValues {
+		addKey(k)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		scope := strings.ToLower(strings.TrimPrefix(key, generationEnvPrefix))
+		g := getGenerationArg(key)
+		if slices.Contains(Tasks, scope) {
+			cfg.TaskGeneration[scope] = g
+		} else {
+			cfg.ProviderGeneration[strings.ReplaceAll(scope, "_", "-")] = g
+		}
+	}
+}
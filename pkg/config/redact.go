@@ -0,0 +1,73 @@
+package config
+
+import (
+	"encoding/json"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/filter"
+)
+
+// LoadRedactionRules compiles RedactionRulesFile together with the built-in filters into
+// Redactor. A missing rules file leaves only the built-in filters.
+func (cfg *Config) LoadRedactionRules() error {
+	rules, err := filter.LoadRules(cfg.RedactionRulesFile)
+	if err != nil {
+		return err
+	}
+	chain, err := filter.NewRedactionChain(rules)
+	if err != nil {
+		return err
+	}
+	cfg.Redactor = chain
+	return nil
+}
+
+// redactor returns the compiled redaction chain, or the built-in filters alone.
+func (cfg *Config) redactor() *filter.Chain {
+	if cfg.Redactor == nil {
+		return filter.NewChain(filter.NewSensitiveDataFilter(false))
+	}
+	return cfg.Redactor
+}
+
+// Redact masks sensitive data in text before it is sent to the model, unless the secret
+// filter is disabled.
+func (cfg *Config) Redact(text string) string {
+	if cfg.DisableSecretFilter {
+		return text
+	}
+	return cfg.redactor().Process(text)
+}
+
+// RedactValue masks sensitive data in decoded JSON such as MCP structured tool content.
+func (cfg *Config) RedactValue(v any) any {
+	if cfg.DisableSecretFilter {
+		return v
+	}
+	return cfg.redactor().ProcessValue(v)
+}
+
+// RedactFrom is Redact for the output of a command or tool, recording what was masked in
+// the privacy report of the turn. kind is one of the privacy.Source* constants.
+func (cfg *Config) RedactFrom(kind, name, text string) string {
+	hits := filter.Hits{}
+	if !cfg.DisableSecretFilter {
+		text = cfg.redactor().ProcessAudit(text, hits)
+	}
+	cfg.Privacy.AddSource(kind, name, len(text), hits)
+	return text
+}
+
+// RedactValueFrom is RedactValue for structured tool content, recording what was masked in
+// the privacy report of the turn.
+func (cfg *Config) RedactValueFrom(kind, name string, v any) any {
+	hits := filter.Hits{}
+	if !cfg.DisableSecretFilter {
+		v = cfg.redactor().ProcessValueAudit(v, hits)
+	}
+	size := 0
+	if data, err := json.Marshal(v); err == nil {
+		size = len(data)
+	}
+	cfg.Privacy.AddSource(kind, name, size, hits)
+	return v
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const taskModelEnvPrefix = "QU_LLM_MODEL_"
+
+// loadTaskModels reads QU_LLM_MODEL_<TASK> settings, e.g. QU_LLM_MODEL_COMMANDS=openai/gpt-5-nano.
+func loadTaskModels(cfg *Config) {
+	for _, task := range Tasks {
+		if ref := strings.TrimSpace(getEnvArg(taskModelEnvPrefix+strings.ToUpper(task), "").(string)); ref != "" {
+			cfg.TaskModels[task] = ref
+		}
+	}
+}
+
+// ValidateTaskModels rejects task model entries for unknown tasks, e.g. from --task-model.
+func (cfg *Config) ValidateTaskModels() error {
+	for task := range cfg.TaskModels {
+		if !slices.Contains(Tasks, task) {
+			return fmt.Errorf("unknown task %q in task models (expected one of: %s)", task, strings.Join(Tasks, ", "))
+		}
+	}
+	return nil
+}
+
+// TaskModel returns the model reference configured for a task, or "" when the task uses the
+// main model. An empty task means TaskAnswer.
+func (cfg *Config) TaskModel(task string) string {
+	if task == "" {
+		task = TaskAnswer
+	}
+	return strings.TrimSpace(cfg.TaskModels[task])
+}
+
+// RecordModelUsage adds one request and its token counts to the session's usage for the
+// task and model.
+func (cfg *Config) RecordModelUsage(task, providerName, model string, input, output, cached int) {
+	if task == "" {
+		task = TaskAnswer
+	}
+	for i := range cfg.ModelUsage {
+		u := &cfg.ModelUsage[i]
+		if u.Task == task && u.Provider == providerName && u.Model == model {
+			u.Requests++
+			u.InputTokens += input
+			u.OutputTokens += output
+			u.CachedTokens += cached
+			return
+		}
+	}
+	cfg.ModelUsage = append(cfg.ModelUsage, ModelUsage{
+		Task:         task,
+		Provider:     providerName,
+		Model:        model,
+		Requests:     1,
+		InputTokens:  input,
+		OutputTokens: output,
+		CachedTokens: cached,
+	})
+}
@@ -0,0 +1,161 @@
+package diag
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// AnalyzeWebhooks inspects ValidatingWebhookConfigurations and MutatingWebhookConfigurations
+// for webhooks whose backing Service is missing or has no ready endpoints. Webhooks with
+// failurePolicy Fail (the admissionregistration/v1 default) block every matching request.
+func AnalyzeWebhooks(validatingJSON, mutatingJSON, servicesJSON, endpointsJSON, endpointSlicesJSON string) []Finding {
+	type Webhook struct {
+		Name          string `json:"name"`
+		FailurePolicy string `json:"failurePolicy"`
+		ClientConfig  struct {
+			URL     string `json:"url"`
+			Service *struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"service"`
+		} `json:"clientConfig"`
+	}
+	type Configuration struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Webhooks []Webhook `json:"webhooks"`
+	}
+	type list struct {
+		Items []Configuration `json:"items"`
+	}
+	type Service struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+	}
+	type listSvc struct {
+		Items []Service `json:"items"`
+	}
+
+	var svcs listSvc
+	if err := json.Unmarshal([]byte(servicesJSON), &svcs); err != nil {
+		return nil
+	}
+	svcExists := map[string]bool{}
+	for _, s := range svcs.Items {
+		svcExists[s.Metadata.Namespace+"/"+s.Metadata.Name] = true
+	}
+	hasAddresses := serviceEndpointIndex(endpointsJSON, endpointSlicesJSON)
+
+	var f []Finding
+	inspect := func(configJSON, configKind string) {
+		if strings.TrimSpace(configJSON) == "" {
+			return
+		}
+		var l list
+		if err := json.Unmarshal([]byte(configJSON), &l); err != nil {
+			return
+		}
+		for _, c := range l.Items {
+			for _, w := range c.Webhooks {
+				svc := w.ClientConfig.Service
+				if svc == nil {
+					// URL-based webhooks point outside the cluster; nothing to correlate
+					continue
+				}
+				policy := w.FailurePolicy
+				if policy == "" {
+					policy = "Fail"
+				}
+				key := svc.Namespace + "/" + svc.Name
+				var problem string
+				switch {
+				case !svcExists[key]:
+					problem = "backing service " + key + " not found"
+				case !hasAddresses[key]:
+					problem = "backing service " + key + " has no ready endpoints"
+				default:
+					continue
+				}
+				severity := "warn"
+				if policy == "Fail" {
+					severity = "error"
+					problem += "; failurePolicy=Fail rejects matching API requests"
+				} else {
+					problem += "; failurePolicy=" + policy + " skips the webhook"
+				}
+				summary := fmt.Sprintf("%s webhook %s: %s", configKind, w.Name, problem)
+				f = append(f, Finding{
+					Kind:     "Webhook",
+					ID:       c.Metadata.Name,
+					Severity: severity,
+					Priority: assignPriority(severity, "Webhook", summary),
+					Summary:  summary,
+				})
+			}
+		}
+	}
+	inspect(validatingJSON, "validating")
+	inspect(mutatingJSON, "mutating")
+	return f
+}
+
+// AnalyzeAPIServices inspects `kubectl get apiservices -o json` for aggregated APIs
+// (e.g. metrics.k8s.io) that report Available=False.
+func AnalyzeAPIServices(apiServicesJSON string) []Finding {
+	type APIService struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			Service *struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"service"`
+		} `json:"spec"`
+		Status struct {
+			Conditions []struct {
+				Type    string `json:"type"`
+				Status  string `json:"status"`
+				Reason  string `json:"reason"`
+				Message string `json:"message"`
+			} `json:"conditions"`
+		} `json:"status"`
+	}
+	type list struct {
+		Items []APIService `json:"items"`
+	}
+	var l list
+	if err := json.Unmarshal([]byte(apiServicesJSON), &l); err != nil {
+		return nil
+	}
+	var f []Finding
+	for _, a := range l.Items {
+		for _, c := range a.Status.Conditions {
+			if c.Type != "Available" || strings.ToLower(c.Status) == "true" {
+				continue
+			}
+			summary := "Available=" + c.Status
+			if c.Reason != "" {
+				summary += ": " + c.Reason
+			}
+			if c.Message != "" {
+				summary += " — " + c.Message
+			}
+			if a.Spec.Service != nil {
+				summary += fmt.Sprintf(" (service %s/%s)", a.Spec.Service.Namespace, a.Spec.Service.Name)
+			}
+			f = append(f, Finding{
+				Kind:     "APIService",
+				ID:       a.Metadata.Name,
+				Severity: "error",
+				Priority: assignPriority("error", "APIService", summary),
+				Summary:  summary,
+			})
+		}
+	}
+	return f
+}
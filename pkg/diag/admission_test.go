@@ -0,0 +1,77 @@
+package diag
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeWebhooks(t *testing.T) {
+	validating := `{"items":[{"metadata":{"name":"gatekeeper-validating"},"webhooks":[
+		{"name":"validation.gatekeeper.sh","clientConfig":{"service":{"name":"gatekeeper-webhook","namespace":"gatekeeper-system"}}},
+		{"name":"external.example.com","clientConfig":{"url":"https://example.com/validate"}}]}]}`
+	mutating := `{"items":[{"metadata":{"name":"istio-sidecar-injector"},"webhooks":[
+		{"name":"sidecar.istio.io","failurePolicy":"Ignore","clientConfig":{"service":{"name":"istiod","namespace":"istio-system"}}},
+		{"name":"healthy.example.com","failurePolicy":"Fail","clientConfig":{"service":{"name":"healthy","namespace":"default"}}}]}]}`
+	services := `{"items":[
+		{"metadata":{"name":"gatekeeper-webhook","namespace":"gatekeeper-system"}},
+		{"metadata":{"name":"healthy","namespace":"default"}}]}`
+	endpoints := `{"items":[{"metadata":{"name":"healthy","namespace":"default"},"subsets":[{"addresses":[{"ip":"10.0.0.1"}]}]}]}`
+
+	findings := AnalyzeWebhooks(validating, mutating, services, endpoints, "")
+	if len(findings) != 2 {
+		t.Fatalf("expected 2 findings, got %d: %+v", len(findings), findings)
+	}
+
+	gk := findings[0]
+	if gk.ID != "gatekeeper-validating" || gk.Severity != "error" {
+		t.Errorf("unexpected gatekeeper finding: %+v", gk)
+	}
+	if !strings.Contains(gk.Summary, "no ready endpoints") || !strings.Contains(gk.Summary, "failurePolicy=Fail") {
+		t.Errorf("expected fail-closed endpoints summary, got %q", gk.Summary)
+	}
+	if gk.Priority != 9 {
+		t.Errorf("expected priority 9 for fail-closed webhook, got %d", gk.Priority)
+	}
+
+	istio := findings[1]
+	if istio.Severity != "warn" || !strings.Contains(istio.Summary, "istio-system/istiod not found") {
+		t.Errorf("unexpected istio finding: %+v", istio)
+	}
+}
+
+func TestAnalyzeAPIServices(t *testing.T) {
+	input := `{"items":[
+		{"metadata":{"name":"v1beta1.metrics.k8s.io"},"spec":{"service":{"name":"metrics-server","namespace":"kube-system"}},
+		 "status":{"conditions":[{"type":"Available","status":"False","reason":"MissingEndpoints","message":"endpoints for service/metrics-server in \"kube-system\" have no addresses"}]}},
+		{"metadata":{"name":"v1.apps"},"status":{"conditions":[{"type":"Available","status":"True","reason":"Local"}]}}]}`
+
+	findings := AnalyzeAPIServices(input)
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %+v", findings)
+	}
+	if findings[0].ID != "v1beta1.metrics.k8s.io" || !strings.Contains(findings[0].Summary, "MissingEndpoints") {
+		t.Errorf("unexpected finding: %+v", findings[0])
+	}
+}
+
+func TestAnalyzeAPIServerHealthPerCheck(t *testing.T) {
+	raw := "[+]ping ok\n[-]etcd failed: reason withheld\n[-]informer-sync failed: 2 informers not started yet\n[+]poststarthook/start-informers ok\n[-]poststarthook/rbac/bootstrap-roles failed: not finished\nreadyz check failed"
+
+	findings := AnalyzeAPIServerHealth(raw, "readyz")
+	if len(findings) != 3 {
+		t.Fatalf("expected 3 findings, got %d: %+v", len(findings), findings)
+	}
+	if findings[0].Summary != "check etcd failing: reason withheld" || findings[0].Severity != "error" || findings[0].Priority != 10 {
+		t.Errorf("unexpected etcd finding: %+v", findings[0])
+	}
+	if findings[1].Severity != "error" || !strings.Contains(findings[1].Summary, "informer-sync") {
+		t.Errorf("unexpected informer finding: %+v", findings[1])
+	}
+	if findings[2].Severity != "warn" || !strings.Contains(findings[2].Summary, "poststarthook/rbac/bootstrap-roles") {
+		t.Errorf("unexpected poststarthook finding: %+v", findings[2])
+	}
+
+	if f := AnalyzeAPIServerHealth("[+]ping ok\nreadyz check passed", "readyz"); f != nil {
+		t.Errorf("expected no findings for healthy output, got %+v", f)
+	}
+}
@@ -42,8 +42,17 @@ func assignPriority(severity string, kind string, issue string) int {
 		return 8 // Service connectivity issues are high priority
 	}
 	if kind == "APIServer" {
+		if strings.Contains(issue, "etcd") {
+			return 10 // etcd failures take the whole control plane down
+		}
 		return 8 // API server health issues are critical
 	}
+	if kind == "Webhook" && strings.Contains(issue, "failurePolicy=Fail") {
+		return 9 // Fail-closed webhooks without backends block every matching request
+	}
+	if kind == "APIService" {
+		return 8 // Unavailable aggregated APIs break discovery and kubectl
+	}
 
 	return base
 }
@@ -135,71 +144,15 @@ func AnalyzeServices(servicesJSON, endpointsJSON, endpointSlicesJSON string) []F
 			Selector map[string]string `json:"selector"`
 		} `json:"spec"`
 	}
-	type Endpoints struct {
-		Metadata struct {
-			Name      string `json:"name"`
-			Namespace string `json:"namespace"`
-		} `json:"metadata"`
-		Subsets []struct {
-			Addresses []any `json:"addresses"`
-		} `json:"subsets"`
-	}
-	type EndpointSlice struct {
-		Metadata struct {
-			Name      string            `json:"name"`
-			Namespace string            `json:"namespace"`
-			Labels    map[string]string `json:"labels"`
-		} `json:"metadata"`
-		Endpoints []struct{} `json:"endpoints"`
-	}
 	type listS struct {
 		Items []Service `json:"items"`
 	}
-	type listE struct {
-		Items []Endpoints `json:"items"`
-	}
-	type listES struct {
-		Items []EndpointSlice `json:"items"`
-	}
-	// optional: we may extend to read pods JSON in future for selector checks
 
 	var svcs listS
-	var eps listE
-	var es listES
 	if servicesJSON != "" {
 		_ = json.Unmarshal([]byte(servicesJSON), &svcs)
 	}
-	if endpointsJSON != "" {
-		_ = json.Unmarshal([]byte(endpointsJSON), &eps)
-	}
-	if endpointSlicesJSON != "" {
-		_ = json.Unmarshal([]byte(endpointSlicesJSON), &es)
-	}
-
-	// Build quick lookup of endpoints and slices by namespace/name
-	hasAddresses := map[string]bool{}
-	for _, ep := range eps.Items {
-		key := ep.Metadata.Namespace + "/" + ep.Metadata.Name
-		for _, s := range ep.Subsets {
-			if len(s.Addresses) > 0 {
-				hasAddresses[key] = true
-				break
-			}
-		}
-	}
-
-	// Also mark via EndpointSlices (k8s >=1.21)
-	for _, slice := range es.Items {
-		// Endpointslice label kubernetes.io/service-name has the service name
-		svcName := ""
-		if slice.Metadata.Labels != nil {
-			svcName = slice.Metadata.Labels["kubernetes.io/service-name"]
-		}
-		if svcName != "" && len(slice.Endpoints) > 0 {
-			key := slice.Metadata.Namespace + "/" + svcName
-			hasAddresses[key] = true
-		}
-	}
+	hasAddresses := serviceEndpointIndex(endpointsJSON, endpointSlicesJSON)
 
 	var findings []Finding
 	for _, s := range svcs.Items {
@@ -340,29 +293,43 @@ func AnalyzeHPAs(hpaJSON string) []Finding {
 }
 
 // AnalyzeAPIServerHealth inspects the output of /readyz or /livez for failing checks.
+// Each failing check (e.g. etcd, informer-sync, poststarthook/*) becomes its own finding so
+// the model can reason about the specific component instead of a generic "not ready".
 func AnalyzeAPIServerHealth(raw string, kind string) []Finding {
 	if strings.TrimSpace(raw) == "" {
 		return nil
 	}
-	// Lines with "[-]" denote failing checks in verbose mode
+	// Lines with "[-]" denote failing checks in verbose mode, e.g. "[-]etcd failed: reason withheld"
 	lines := strings.Split(raw, "\n")
-	var failing []string
+	var f []Finding
 	for _, ln := range lines {
-		if strings.HasPrefix(strings.TrimSpace(ln), "[-]") {
-			failing = append(failing, strings.TrimSpace(ln))
+		ln = strings.TrimSpace(ln)
+		if !strings.HasPrefix(ln, "[-]") {
+			continue
 		}
+		check := strings.TrimPrefix(ln, "[-]")
+		reason := ""
+		if i := strings.Index(check, " failed"); i >= 0 {
+			reason = strings.TrimSpace(strings.TrimPrefix(check[i+len(" failed"):], ":"))
+			check = check[:i]
+		}
+		severity := "warn"
+		if check == "etcd" || strings.HasPrefix(check, "etcd-") || check == "informer-sync" {
+			severity = "error"
+		}
+		summary := "check " + check + " failing"
+		if reason != "" {
+			summary += ": " + reason
+		}
+		f = append(f, Finding{
+			Kind:     "APIServer",
+			ID:       kind,
+			Severity: severity,
+			Priority: assignPriority(severity, "APIServer", summary),
+			Summary:  summary,
+		})
 	}
-	if len(failing) == 0 {
-		return nil
-	}
-	summary := strings.Join(failing, "; ")
-	return []Finding{{
-		Kind:     "APIServer",
-		ID:       kind,
-		Severity: "warn",
-		Priority: assignPriority("warn", "APIServer", summary),
-		Summary:  summary,
-	}}
+	return f
 }
 
 // FormatFindings returns a compact human-readable list for inclusion in RAG prompts.
@@ -604,6 +571,68 @@ func AnalyzePVCsPVs(pvcJSON, pvJSON string) []Finding {
 	return f
 }
 
+// serviceEndpointIndex returns the set of "namespace/service" keys that have at least one
+// ready address, based on Endpoints and EndpointSlices (k8s >=1.21) JSON.
+func serviceEndpointIndex(endpointsJSON, endpointSlicesJSON string) map[string]bool {
+	type Endpoints struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Subsets []struct {
+			Addresses []any `json:"addresses"`
+		} `json:"subsets"`
+	}
+	type EndpointSlice struct {
+		Metadata struct {
+			Name      string            `json:"name"`
+			Namespace string            `json:"namespace"`
+			Labels    map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Endpoints []struct{} `json:"endpoints"`
+	}
+	type listE struct {
+		Items []Endpoints `json:"items"`
+	}
+	type listES struct {
+		Items []EndpointSlice `json:"items"`
+	}
+	var eps listE
+	var es listES
+	if endpointsJSON != "" {
+		_ = json.Unmarshal([]byte(endpointsJSON), &eps)
+	}
+	if endpointSlicesJSON != "" {
+		_ = json.Unmarshal([]byte(endpointSlicesJSON), &es)
+	}
+
+	// Build quick lookup of endpoints and slices by namespace/name
+	hasAddresses := map[string]bool{}
+	for _, ep := range eps.Items {
+		key := ep.Metadata.Namespace + "/" + ep.Metadata.Name
+		for _, s := range ep.Subsets {
+			if len(s.Addresses) > 0 {
+				hasAddresses[key] = true
+				break
+			}
+		}
+	}
+
+	// Also mark via EndpointSlices
+	for _, slice := range es.Items {
+		// Endpointslice label kubernetes.io/service-name has the service name
+		svcName := ""
+		if slice.Metadata.Labels != nil {
+			svcName = slice.Metadata.Labels["kubernetes.io/service-name"]
+		}
+		if svcName != "" && len(slice.Endpoints) > 0 {
+			key := slice.Metadata.Namespace + "/" + svcName
+			hasAddresses[key] = true
+		}
+	}
+	return hasAddresses
+}
+
 func selectorMatches(sel, labels map[string]string) bool {
 	if len(sel) == 0 {
 		return true
@@ -28,6 +28,11 @@ func BaselineCommands(cfg *config.Config) []string {
 		"kubectl get --raw='/readyz?verbose'",
 		"kubectl get --raw='/livez?verbose'",
 
+		// Admission webhooks and aggregated APIs that can silently block requests
+		"kubectl get validatingwebhookconfigurations -o json",
+		"kubectl get mutatingwebhookconfigurations -o json",
+		"kubectl get apiservices -o json",
+
 		// Core inventory
 		"kubectl get nodes -o json",
 		"kubectl get pods -A -o json",
@@ -50,6 +55,11 @@ func BaselineCommands(cfg *config.Config) []string {
 		"kubectl get pv -A -o json",
 	}
 
+	// Helm release records (decoded locally; raw secret payloads never reach the model)
+	if cfg.HelmAnalysisEnabled {
+		c = append(c, HelmSecretsCommand)
+	}
+
 	// Standard level: add StatefulSets, DaemonSets, Jobs, CronJobs
 	if level == "standard" || level == "comprehensive" {
 		c = append(c,
@@ -0,0 +1,338 @@
+package diag
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+)
+
+// CRDDiscoveryCommand lists every served resource (including CRDs and aggregated APIs)
+// as fully-qualified names, e.g. "applications.argoproj.io".
+const CRDDiscoveryCommand = "kubectl api-resources -o name"
+
+// certExpiryWarnWindow flags cert-manager Certificates that expire within this window.
+const certExpiryWarnWindow = 14 * 24 * time.Hour
+
+// crdIssue is a profile-specific problem detected on a single custom resource.
+type crdIssue struct {
+	Severity string
+	Summary  string
+}
+
+// crdObject is a minimal, schema-agnostic view of a custom resource instance.
+type crdObject struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name       string `json:"name"`
+		Namespace  string `json:"namespace"`
+		Generation int64  `json:"generation"`
+	} `json:"metadata"`
+	Spec   map[string]any `json:"spec"`
+	Status map[string]any `json:"status"`
+}
+
+// crdCondition mirrors metav1.Condition plus the looser shapes used by older operators.
+type crdCondition struct {
+	Type               string `json:"type"`
+	Status             string `json:"status"`
+	Reason             string `json:"reason"`
+	Message            string `json:"message"`
+	ObservedGeneration int64  `json:"observedGeneration"`
+}
+
+// CRDProfile describes how to inspect instances of a custom resource.
+// Resources without a built-in profile fall back to the generic condition checks.
+type CRDProfile struct {
+	Resource string // fully-qualified plural resource name, e.g. "certificates.cert-manager.io"
+	Kind     string // display kind used when objects omit it
+	inspect  func(o crdObject) []crdIssue
+}
+
+// builtinCRDProfiles are operator-specific checks layered on top of the generic conditions analysis.
+var builtinCRDProfiles = []CRDProfile{
+	{Resource: "applications.argoproj.io", Kind: "Application", inspect: inspectArgoApplication},
+	{Resource: "kustomizations.kustomize.toolkit.fluxcd.io", Kind: "Kustomization", inspect: inspectFluxSuspend},
+	{Resource: "helmreleases.helm.toolkit.fluxcd.io", Kind: "HelmRelease", inspect: inspectFluxSuspend},
+	{Resource: "certificates.cert-manager.io", Kind: "Certificate", inspect: inspectCertificate},
+	{Resource: "virtualservices.networking.istio.io", Kind: "VirtualService", inspect: inspectIstioValidation},
+}
+
+// CRDResources returns the normalized list of custom resources configured for analysis.
+func CRDResources(cfg *config.Config) []string {
+	if cfg == nil || !cfg.CRDAnalysisEnabled {
+		return nil
+	}
+	seen := map[string]bool{}
+	var out []string
+	for _, r := range cfg.CRDResources {
+		key := strings.ToLower(strings.TrimSpace(r))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, key)
+	}
+	return out
+}
+
+// CRDCommand returns the baseline command that fetches all instances of a custom resource.
+func CRDCommand(resource string) string {
+	return "kubectl get " + resource + " -A -o json"
+}
+
+// InstalledCRDCommands filters configured custom resources down to those present in the
+// output of CRDDiscoveryCommand and returns the commands needed to fetch their instances.
+func InstalledCRDCommands(cfg *config.Config, discoveryOut string) []string {
+	installed := parseDiscovery(discoveryOut)
+	var cmds []string
+	for _, r := range CRDResources(cfg) {
+		if installed[r] {
+			cmds = append(cmds, CRDCommand(r))
+		}
+	}
+	return cmds
+}
+
+// CRDProfileFor returns the profile for a resource, falling back to a generic one.
+func CRDProfileFor(resource string) CRDProfile {
+	resource = strings.ToLower(strings.TrimSpace(resource))
+	for _, p := range builtinCRDProfiles {
+		if p.Resource == resource {
+			return p
+		}
+	}
+	kind := resource
+	if i := strings.Index(kind, "."); i > 0 {
+		kind = kind[:i]
+	}
+	return CRDProfile{Resource: resource, Kind: kind}
+}
+
+// AnalyzeCustomResources inspects `kubectl get <resource> -A -o json` output for objects whose
+// status.conditions report Ready=False, Degraded, Stalled or a stale observedGeneration,
+// plus any profile-specific problems.
+func AnalyzeCustomResources(resource string, itemsJSON string) []Finding {
+	type list struct {
+		Items []crdObject `json:"items"`
+	}
+	var l list
+	if err := json.Unmarshal([]byte(itemsJSON), &l); err != nil {
+		return nil
+	}
+	profile := CRDProfileFor(resource)
+	var f []Finding
+	for _, o := range l.Items {
+		kind := o.Kind
+		if kind == "" {
+			kind = profile.Kind
+		}
+		id := o.Metadata.Name
+		if o.Metadata.Namespace != "" {
+			id = o.Metadata.Namespace + "/" + o.Metadata.Name
+		}
+		issues := inspectConditions(o)
+		if profile.inspect != nil {
+			issues = append(issues, profile.inspect(o)...)
+		}
+		for _, is := range issues {
+			f = append(f, Finding{
+				Kind:     kind,
+				ID:       id,
+				Severity: is.Severity,
+				Priority: assignPriority(is.Severity, kind, is.Summary),
+				Summary:  is.Summary,
+			})
+		}
+	}
+	return f
+}
+
+// inspectConditions applies the generic operator conventions to status.conditions.
+func inspectConditions(o crdObject) []crdIssue {
+	var issues []crdIssue
+	conds := statusConditions(o)
+	stale := false
+	for _, c := range conds {
+		detail := conditionDetail(c)
+		switch {
+		case c.Type == "Ready" && strings.EqualFold(c.Status, "False"):
+			issues = append(issues, crdIssue{"error", "Ready=False" + detail})
+		case (c.Type == "Degraded" || c.Type == "Stalled") && strings.EqualFold(c.Status, "True"):
+			issues = append(issues, crdIssue{"error", c.Type + detail})
+		}
+		if c.ObservedGeneration > 0 && o.Metadata.Generation > c.ObservedGeneration {
+			stale = true
+		}
+	}
+
+	observed := int64Field(o.Status, "observedGeneration")
+	if observed > 0 && o.Metadata.Generation > observed {
+		stale = true
+	}
+	if stale {
+		if observed == 0 {
+			observed = minConditionGeneration(conds)
+		}
+		issues = append(issues, crdIssue{"warn", fmt.Sprintf("stale status: observedGeneration %d < generation %d; controller may not be reconciling", observed, o.Metadata.Generation)})
+	}
+	return issues
+}
+
+// inspectArgoApplication reports Argo CD sync/health state and error conditions.
+func inspectArgoApplication(o crdObject) []crdIssue {
+	var issues []crdIssue
+	if sync, ok := o.Status["sync"].(map[string]any); ok {
+		if s, _ := sync["status"].(string); s != "" && s != "Synced" {
+			issues = append(issues, crdIssue{"warn", "sync status: " + s})
+		}
+	}
+	if health, ok := o.Status["health"].(map[string]any); ok {
+		s, _ := health["status"].(string)
+		msg, _ := health["message"].(string)
+		switch s {
+		case "Degraded", "Missing":
+			summary := "health: " + s
+			if msg != "" {
+				summary += " — " + msg
+			}
+			issues = append(issues, crdIssue{"error", summary})
+		case "Unknown":
+			issues = append(issues, crdIssue{"warn", "health: Unknown"})
+		}
+	}
+	if op, ok := o.Status["operationState"].(map[string]any); ok {
+		phase, _ := op["phase"].(string)
+		if phase == "Failed" || phase == "Error" {
+			summary := "last sync operation " + phase
+			if msg, _ := op["message"].(string); msg != "" {
+				summary += ": " + msg
+			}
+			issues = append(issues, crdIssue{"error", summary})
+		}
+	}
+	// Argo CD conditions carry only a type (e.g. SyncError, ComparisonError) and a message
+	for _, c := range statusConditions(o) {
+		if strings.HasSuffix(c.Type, "Error") {
+			issues = append(issues, crdIssue{"error", c.Type + conditionDetail(c)})
+		}
+	}
+	return issues
+}
+
+// inspectFluxSuspend surfaces suspended Flux objects, which silently stop reconciling.
+func inspectFluxSuspend(o crdObject) []crdIssue {
+	if suspended, _ := o.Spec["suspend"].(bool); suspended {
+		return []crdIssue{{"warn", "reconciliation suspended (spec.suspend=true)"}}
+	}
+	return nil
+}
+
+// inspectCertificate warns about cert-manager Certificates that are close to expiry.
+func inspectCertificate(o crdObject) []crdIssue {
+	notAfter, _ := o.Status["notAfter"].(string)
+	if notAfter == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, notAfter)
+	if err != nil {
+		return nil
+	}
+	left := time.Until(t)
+	if left <= 0 {
+		return []crdIssue{{"error", "certificate expired at " + notAfter}}
+	}
+	if left < certExpiryWarnWindow {
+		return []crdIssue{{"warn", fmt.Sprintf("certificate expires in %dh (%s)", int(left.Hours()), notAfter)}}
+	}
+	return nil
+}
+
+// inspectIstioValidation reports istiod analysis messages written to status.validationMessages.
+func inspectIstioValidation(o crdObject) []crdIssue {
+	msgs, _ := o.Status["validationMessages"].([]any)
+	var issues []crdIssue
+	for _, m := range msgs {
+		vm, ok := m.(map[string]any)
+		if !ok {
+			continue
+		}
+		level, _ := vm["level"].(string)
+		code := ""
+		if t, ok := vm["type"].(map[string]any); ok {
+			code, _ = t["code"].(string)
+			if name, _ := t["name"].(string); name != "" {
+				code = strings.TrimSpace(code + " " + name)
+			}
+		}
+		severity := "warn"
+		if strings.EqualFold(level, "Error") {
+			severity = "error"
+		} else if strings.EqualFold(level, "Info") {
+			severity = "info"
+		}
+		issues = append(issues, crdIssue{severity, "validation: " + code})
+	}
+	return issues
+}
+
+func statusConditions(o crdObject) []crdCondition {
+	raw, ok := o.Status["conditions"]
+	if !ok {
+		return nil
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var conds []crdCondition
+	if err := json.Unmarshal(b, &conds); err != nil {
+		return nil
+	}
+	return conds
+}
+
+func conditionDetail(c crdCondition) string {
+	var parts []string
+	if c.Reason != "" {
+		parts = append(parts, c.Reason)
+	}
+	if c.Message != "" {
+		parts = append(parts, c.Message)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return ": " + strings.Join(parts, " — ")
+}
+
+func minConditionGeneration(conds []crdCondition) int64 {
+	var min int64
+	for _, c := range conds {
+		if c.ObservedGeneration > 0 && (min == 0 || c.ObservedGeneration < min) {
+			min = c.ObservedGeneration
+		}
+	}
+	return min
+}
+
+func int64Field(m map[string]any, key string) int64 {
+	if v, ok := m[key].(float64); ok {
+		return int64(v)
+	}
+	return 0
+}
+
+// parseDiscovery turns CRDDiscoveryCommand output into a set of served resource names.
+func parseDiscovery(discoveryOut string) map[string]bool {
+	installed := map[string]bool{}
+	for _, ln := range strings.Split(discoveryOut, "\n") {
+		name := strings.ToLower(strings.TrimSpace(ln))
+		if name != "" {
+			installed[name] = true
+		}
+	}
+	return installed
+}
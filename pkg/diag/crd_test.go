@@ -0,0 +1,115 @@
+package diag
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+)
+
+func TestInstalledCRDCommands(t *testing.T) {
+	cfg := &config.Config{
+		CRDAnalysisEnabled: true,
+		CRDResources:       []string{"applications.argoproj.io", " Certificates.cert-manager.io ", "widgets.example.com"},
+	}
+	discovery := "pods\nservices\napplications.argoproj.io\ncertificates.cert-manager.io\n"
+
+	cmds := InstalledCRDCommands(cfg, discovery)
+	want := []string{
+		"kubectl get applications.argoproj.io -A -o json",
+		"kubectl get certificates.cert-manager.io -A -o json",
+	}
+	if len(cmds) != len(want) {
+		t.Fatalf("expected %d commands, got %v", len(want), cmds)
+	}
+	for i := range want {
+		if cmds[i] != want[i] {
+			t.Errorf("command %d: expected %q, got %q", i, want[i], cmds[i])
+		}
+	}
+
+	cfg.CRDAnalysisEnabled = false
+	if cmds := InstalledCRDCommands(cfg, discovery); len(cmds) != 0 {
+		t.Errorf("expected no commands when CRD analysis is disabled, got %v", cmds)
+	}
+}
+
+func TestAnalyzeCustomResourcesGenericConditions(t *testing.T) {
+	input := `{"items":[
+		{"kind":"Kustomization","metadata":{"name":"apps","namespace":"flux-system","generation":4},
+		 "spec":{"suspend":true},
+		 "status":{"observedGeneration":3,"conditions":[
+			{"type":"Ready","status":"False","reason":"BuildFailed","message":"kustomize build failed"},
+			{"type":"Stalled","status":"True","reason":"InvalidPath"}]}},
+		{"kind":"Kustomization","metadata":{"name":"infra","namespace":"flux-system","generation":2},
+		 "status":{"observedGeneration":2,"conditions":[{"type":"Ready","status":"True"}]}}
+	]}`
+
+	findings := AnalyzeCustomResources("kustomizations.kustomize.toolkit.fluxcd.io", input)
+	summaries := map[string]Finding{}
+	for _, f := range findings {
+		if f.ID != "flux-system/apps" {
+			t.Errorf("unexpected finding for healthy object: %+v", f)
+		}
+		summaries[f.Summary] = f
+	}
+
+	expect := map[string]string{
+		"Ready=False: BuildFailed — kustomize build failed": "error",
+		"Stalled: InvalidPath":                              "error",
+		"stale status: observedGeneration 3 < generation 4; controller may not be reconciling": "warn",
+		"reconciliation suspended (spec.suspend=true)":                                         "warn",
+	}
+	for summary, severity := range expect {
+		f, ok := summaries[summary]
+		if !ok {
+			t.Errorf("missing finding %q in %+v", summary, findings)
+			continue
+		}
+		if f.Severity != severity {
+			t.Errorf("finding %q: expected severity %s, got %s", summary, severity, f.Severity)
+		}
+		if f.Kind != "Kustomization" {
+			t.Errorf("finding %q: expected kind Kustomization, got %s", summary, f.Kind)
+		}
+	}
+}
+
+func TestAnalyzeCustomResourcesArgoApplication(t *testing.T) {
+	input := `{"items":[{"metadata":{"name":"shop","namespace":"argocd"},
+		"status":{"sync":{"status":"OutOfSync"},"health":{"status":"Degraded","message":"Deployment has timed out"},
+		"operationState":{"phase":"Failed","message":"one or more objects failed to apply"},
+		"conditions":[{"type":"ComparisonError","message":"repo not reachable"}]}}]}`
+
+	findings := AnalyzeCustomResources("applications.argoproj.io", input)
+	if len(findings) != 4 {
+		t.Fatalf("expected 4 findings, got %d: %+v", len(findings), findings)
+	}
+	joined := FormatFindings(findings)
+	for _, want := range []string{
+		"Application argocd/shop: sync status: OutOfSync",
+		"health: Degraded — Deployment has timed out",
+		"last sync operation Failed",
+		"ComparisonError: repo not reachable",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected %q in findings:\n%s", want, joined)
+		}
+	}
+}
+
+func TestAnalyzeCustomResourcesCertificateExpiry(t *testing.T) {
+	input := `{"items":[{"kind":"Certificate","metadata":{"name":"web-tls","namespace":"default"},
+		"status":{"notAfter":"2000-01-01T00:00:00Z","conditions":[{"type":"Ready","status":"True"}]}}]}`
+
+	findings := AnalyzeCustomResources("certificates.cert-manager.io", input)
+	if len(findings) != 1 || findings[0].Severity != "error" || !strings.Contains(findings[0].Summary, "expired") {
+		t.Fatalf("expected one expired-certificate error, got %+v", findings)
+	}
+}
+
+func TestAnalyzeCustomResourcesInvalidJSON(t *testing.T) {
+	if f := AnalyzeCustomResources("widgets.example.com", "error: the server doesn't have a resource type"); f != nil {
+		t.Errorf("expected nil findings for non-JSON output, got %+v", f)
+	}
+}
@@ -0,0 +1,435 @@
+package diag
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const gatewayAPIGroup = "gateway.networking.k8s.io"
+
+// gatewayAPIResources are fetched by fully-qualified name so they never resolve to
+// similarly named kinds from other projects (e.g. Istio's networking.istio.io Gateway).
+var gatewayAPIResources = []string{
+	"gatewayclasses." + gatewayAPIGroup,
+	"gateways." + gatewayAPIGroup,
+	"httproutes." + gatewayAPIGroup,
+	"grpcroutes." + gatewayAPIGroup,
+	"referencegrants." + gatewayAPIGroup,
+}
+
+// GatewayAPIResources returns the Gateway API resources inspected by AnalyzeGatewayAPI.
+func GatewayAPIResources() []string {
+	return append([]string(nil), gatewayAPIResources...)
+}
+
+// InstalledGatewayAPICommands returns baseline commands for the Gateway API resources present
+// in the output of CRDDiscoveryCommand. Nothing is returned when Gateways are not served.
+func InstalledGatewayAPICommands(discoveryOut string) []string {
+	installed := parseDiscovery(discoveryOut)
+	if !installed["gateways."+gatewayAPIGroup] {
+		return nil
+	}
+	var cmds []string
+	for _, r := range gatewayAPIResources {
+		if installed[r] {
+			cmds = append(cmds, CRDCommand(r))
+		}
+	}
+	return cmds
+}
+
+type gwCondition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+type gwParentRef struct {
+	Group       *string `json:"group"`
+	Kind        *string `json:"kind"`
+	Namespace   string  `json:"namespace"`
+	Name        string  `json:"name"`
+	SectionName string  `json:"sectionName"`
+}
+
+type gwObjectRef struct {
+	Group     *string `json:"group"`
+	Kind      *string `json:"kind"`
+	Namespace string  `json:"namespace"`
+	Name      string  `json:"name"`
+}
+
+type gwListener struct {
+	Name          string `json:"name"`
+	Port          int    `json:"port"`
+	Protocol      string `json:"protocol"`
+	Hostname      string `json:"hostname"`
+	AllowedRoutes struct {
+		Namespaces struct {
+			From string `json:"from"`
+		} `json:"namespaces"`
+	} `json:"allowedRoutes"`
+	TLS *struct {
+		CertificateRefs []gwObjectRef `json:"certificateRefs"`
+	} `json:"tls"`
+}
+
+type gwGateway struct {
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Spec struct {
+		GatewayClassName string       `json:"gatewayClassName"`
+		Listeners        []gwListener `json:"listeners"`
+	} `json:"spec"`
+	Status struct {
+		Conditions []gwCondition `json:"conditions"`
+		Listeners  []struct {
+			Name       string        `json:"name"`
+			Conditions []gwCondition `json:"conditions"`
+		} `json:"listeners"`
+	} `json:"status"`
+}
+
+type gwRoute struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Spec struct {
+		ParentRefs []gwParentRef `json:"parentRefs"`
+		Rules      []struct {
+			BackendRefs []gwObjectRef `json:"backendRefs"`
+		} `json:"rules"`
+	} `json:"spec"`
+	Status struct {
+		Parents []struct {
+			ParentRef  gwParentRef   `json:"parentRef"`
+			Conditions []gwCondition `json:"conditions"`
+		} `json:"parents"`
+	} `json:"status"`
+}
+
+type gwReferenceGrant struct {
+	Metadata struct {
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Spec struct {
+		From []struct {
+			Group     string `json:"group"`
+			Kind      string `json:"kind"`
+			Namespace string `json:"namespace"`
+		} `json:"from"`
+		To []struct {
+			Group string  `json:"group"`
+			Kind  string  `json:"kind"`
+			Name  *string `json:"name"`
+		} `json:"to"`
+	} `json:"spec"`
+}
+
+// AnalyzeGatewayAPI resolves Gateway → GatewayClass and HTTPRoute/GRPCRoute parentRefs and
+// backendRefs, reporting routes not accepted by their parent, unresolved backends, listener
+// conflicts and cross-namespace references without a matching ReferenceGrant.
+// It mirrors AnalyzeIngress for clusters that use the Gateway API.
+func AnalyzeGatewayAPI(gatewayClassesJSON, gatewaysJSON, httpRoutesJSON, grpcRoutesJSON, referenceGrantsJSON, servicesJSON string) []Finding {
+	type listClasses struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Status struct {
+				Conditions []gwCondition `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	type listGateways struct {
+		Items []gwGateway `json:"items"`
+	}
+	type listRoutes struct {
+		Items []gwRoute `json:"items"`
+	}
+	type listGrants struct {
+		Items []gwReferenceGrant `json:"items"`
+	}
+	type listSvc struct {
+		Items []struct {
+			Metadata struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+
+	var gws listGateways
+	if err := json.Unmarshal([]byte(gatewaysJSON), &gws); err != nil {
+		return nil
+	}
+	var classes listClasses
+	var grants listGrants
+	var svcs listSvc
+	classesKnown := gatewayClassesJSON != "" && json.Unmarshal([]byte(gatewayClassesJSON), &classes) == nil
+	if referenceGrantsJSON != "" {
+		_ = json.Unmarshal([]byte(referenceGrantsJSON), &grants)
+	}
+	svcsKnown := servicesJSON != "" && json.Unmarshal([]byte(servicesJSON), &svcs) == nil
+
+	var f []Finding
+	add := func(kind, id, severity, summary string) {
+		f = append(f, Finding{Kind: kind, ID: id, Severity: severity, Priority: assignPriority(severity, kind, summary), Summary: summary})
+	}
+
+	classConds := map[string][]gwCondition{}
+	for _, c := range classes.Items {
+		classConds[c.Metadata.Name] = c.Status.Conditions
+		if cond := findCondition(c.Status.Conditions, "Accepted"); cond != nil && strings.EqualFold(cond.Status, "False") {
+			add("GatewayClass", c.Metadata.Name, "error", "not accepted by controller"+gwConditionDetail(cond))
+		}
+	}
+	svcExists := map[string]bool{}
+	for _, s := range svcs.Items {
+		svcExists[s.Metadata.Namespace+"/"+s.Metadata.Name] = true
+	}
+
+	gateways := map[string]gwGateway{}
+	for _, g := range gws.Items {
+		id := g.Metadata.Namespace + "/" + g.Metadata.Name
+		gateways[id] = g
+
+		if _, ok := classConds[g.Spec.GatewayClassName]; classesKnown && !ok {
+			add("Gateway", id, "error", "gatewayClass not found: "+g.Spec.GatewayClassName)
+		}
+		for _, t := range []string{"Accepted", "Programmed"} {
+			if cond := findCondition(g.Status.Conditions, t); cond != nil && strings.EqualFold(cond.Status, "False") {
+				add("Gateway", id, "error", t+"=False"+gwConditionDetail(cond))
+			}
+		}
+		for _, ls := range g.Status.Listeners {
+			for _, c := range ls.Conditions {
+				bad := (c.Type == "Conflicted" && strings.EqualFold(c.Status, "True")) ||
+					((c.Type == "Accepted" || c.Type == "ResolvedRefs" || c.Type == "Programmed") && strings.EqualFold(c.Status, "False"))
+				if bad {
+					add("Gateway", id, "error", fmt.Sprintf("listener %s: %s=%s%s", ls.Name, c.Type, c.Status, gwConditionDetail(&c)))
+				}
+			}
+		}
+		for _, conflict := range listenerConflicts(g.Spec.Listeners) {
+			add("Gateway", id, "error", conflict)
+		}
+		for _, l := range g.Spec.Listeners {
+			if l.TLS == nil {
+				continue
+			}
+			for _, ref := range l.TLS.CertificateRefs {
+				ns := ref.Namespace
+				if ns == "" || ns == g.Metadata.Namespace {
+					continue
+				}
+				if !referenceGranted(grants.Items, "Gateway", g.Metadata.Namespace, "", refKind(ref.Kind, "Secret"), ns, ref.Name) {
+					add("Gateway", id, "error", fmt.Sprintf("listener %s: certificateRef %s/%s needs a ReferenceGrant in namespace %s", l.Name, ns, ref.Name, ns))
+				}
+			}
+		}
+	}
+
+	inspectRoutes := func(routesJSON, defaultKind string) {
+		if strings.TrimSpace(routesJSON) == "" {
+			return
+		}
+		var routes listRoutes
+		if err := json.Unmarshal([]byte(routesJSON), &routes); err != nil {
+			return
+		}
+		for _, r := range routes.Items {
+			kind := r.Kind
+			if kind == "" {
+				kind = defaultKind
+			}
+			ns := r.Metadata.Namespace
+			id := ns + "/" + r.Metadata.Name
+
+			for _, p := range r.Spec.ParentRefs {
+				if refGroup(p.Group, gatewayAPIGroup) != gatewayAPIGroup || refKind(p.Kind, "Gateway") != "Gateway" {
+					continue
+				}
+				pns := p.Namespace
+				if pns == "" {
+					pns = ns
+				}
+				gw, ok := gateways[pns+"/"+p.Name]
+				if !ok {
+					add(kind, id, "error", "parent gateway not found: "+pns+"/"+p.Name)
+					continue
+				}
+				if p.SectionName != "" && !gatewayHasListener(gw, p.SectionName) {
+					add(kind, id, "error", fmt.Sprintf("parent gateway %s/%s has no listener named %s", pns, p.Name, p.SectionName))
+					continue
+				}
+				if pns != ns && !gatewayAllowsNamespace(gw, p.SectionName) {
+					add(kind, id, "warn", fmt.Sprintf("gateway %s/%s listeners only allow routes from their own namespace (allowedRoutes.namespaces.from)", pns, p.Name))
+				}
+			}
+
+			backendIssues := 0
+			for _, rule := range r.Spec.Rules {
+				for _, b := range rule.BackendRefs {
+					if refGroup(b.Group, "") != "" || refKind(b.Kind, "Service") != "Service" {
+						continue
+					}
+					bns := b.Namespace
+					if bns == "" {
+						bns = ns
+					}
+					key := bns + "/" + b.Name
+					if svcsKnown && !svcExists[key] {
+						add(kind, id, "error", "backend service not found: "+key)
+						backendIssues++
+						continue
+					}
+					if bns != ns && !referenceGranted(grants.Items, kind, ns, "", "Service", bns, b.Name) {
+						add(kind, id, "error", fmt.Sprintf("backend %s needs a ReferenceGrant in namespace %s allowing %s from %s", key, bns, kind, ns))
+						backendIssues++
+					}
+				}
+			}
+
+			for _, ps := range r.Status.Parents {
+				parent := ps.ParentRef.Name
+				if ps.ParentRef.Namespace != "" {
+					parent = ps.ParentRef.Namespace + "/" + parent
+				}
+				if cond := findCondition(ps.Conditions, "Accepted"); cond != nil && strings.EqualFold(cond.Status, "False") {
+					add(kind, id, "error", "not accepted by parent "+parent+gwConditionDetail(cond))
+				}
+				// Skip controller-reported ResolvedRefs when we already pinpointed the broken backend
+				if cond := findCondition(ps.Conditions, "ResolvedRefs"); cond != nil && strings.EqualFold(cond.Status, "False") && backendIssues == 0 {
+					add(kind, id, "error", "unresolved references on parent "+parent+gwConditionDetail(cond))
+				}
+			}
+		}
+	}
+	inspectRoutes(httpRoutesJSON, "HTTPRoute")
+	inspectRoutes(grpcRoutesJSON, "GRPCRoute")
+	return f
+}
+
+// listenerConflicts reports listeners that share a port and hostname, or mix
+// incompatible protocols on the same port.
+func listenerConflicts(listeners []gwListener) []string {
+	var out []string
+	byKey := map[string]string{}
+	protoByPort := map[int]gwListener{}
+	for _, l := range listeners {
+		host := l.Hostname
+		if host == "" {
+			host = "*"
+		}
+		key := strconv.Itoa(l.Port) + "/" + host
+		if other, ok := byKey[key]; ok {
+			out = append(out, fmt.Sprintf("listener conflict: %s and %s both use port %d hostname %s", other, l.Name, l.Port, host))
+		} else {
+			byKey[key] = l.Name
+		}
+		if prev, ok := protoByPort[l.Port]; ok && !protocolsCompatible(prev.Protocol, l.Protocol) {
+			out = append(out, fmt.Sprintf("listener conflict: %s (%s) and %s (%s) share port %d", prev.Name, prev.Protocol, l.Name, l.Protocol, l.Port))
+		} else if !ok {
+			protoByPort[l.Port] = l
+		}
+	}
+	return out
+}
+
+func protocolsCompatible(a, b string) bool {
+	a, b = strings.ToUpper(a), strings.ToUpper(b)
+	if a == b {
+		return true
+	}
+	// HTTPS and TLS listeners may share a port since both are routed by SNI
+	tlsFamily := map[string]bool{"HTTPS": true, "TLS": true}
+	return tlsFamily[a] && tlsFamily[b]
+}
+
+func gatewayHasListener(gw gwGateway, name string) bool {
+	for _, l := range gw.Spec.Listeners {
+		if l.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// gatewayAllowsNamespace reports whether any relevant listener admits routes from other
+// namespaces. Selector-based policies are treated as allowed since labels aren't available here.
+func gatewayAllowsNamespace(gw gwGateway, sectionName string) bool {
+	for _, l := range gw.Spec.Listeners {
+		if sectionName != "" && l.Name != sectionName {
+			continue
+		}
+		from := l.AllowedRoutes.Namespaces.From
+		if from == "All" || from == "Selector" {
+			return true
+		}
+	}
+	return false
+}
+
+// referenceGranted checks whether a ReferenceGrant in toNamespace permits fromKind objects in
+// fromNamespace to reference the toKind object named toName.
+func referenceGranted(grants []gwReferenceGrant, fromKind, fromNamespace, toGroup, toKind, toNamespace, toName string) bool {
+	for _, g := range grants {
+		if g.Metadata.Namespace != toNamespace {
+			continue
+		}
+		fromOK := false
+		for _, fr := range g.Spec.From {
+			if fr.Group == gatewayAPIGroup && fr.Kind == fromKind && fr.Namespace == fromNamespace {
+				fromOK = true
+				break
+			}
+		}
+		if !fromOK {
+			continue
+		}
+		for _, to := range g.Spec.To {
+			if to.Group == toGroup && to.Kind == toKind && (to.Name == nil || *to.Name == "" || *to.Name == toName) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func findCondition(conds []gwCondition, t string) *gwCondition {
+	for i := range conds {
+		if conds[i].Type == t {
+			return &conds[i]
+		}
+	}
+	return nil
+}
+
+func gwConditionDetail(c *gwCondition) string {
+	if c == nil {
+		return ""
+	}
+	return conditionDetail(crdCondition{Reason: c.Reason, Message: c.Message})
+}
+
+func refGroup(g *string, def string) string {
+	if g == nil {
+		return def
+	}
+	return *g
+}
+
+func refKind(k *string, def string) string {
+	if k == nil || *k == "" {
+		return def
+	}
+	return *k
+}
@@ -0,0 +1,76 @@
+package diag
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInstalledGatewayAPICommands(t *testing.T) {
+	if cmds := InstalledGatewayAPICommands("pods\ngateways.networking.istio.io\n"); cmds != nil {
+		t.Errorf("expected no commands without Gateway API, got %v", cmds)
+	}
+	cmds := InstalledGatewayAPICommands("gatewayclasses.gateway.networking.k8s.io\ngateways.gateway.networking.k8s.io\nhttproutes.gateway.networking.k8s.io\n")
+	if len(cmds) != 3 || cmds[1] != "kubectl get gateways.gateway.networking.k8s.io -A -o json" {
+		t.Errorf("unexpected commands: %v", cmds)
+	}
+}
+
+func TestAnalyzeGatewayAPI(t *testing.T) {
+	classes := `{"items":[{"metadata":{"name":"envoy"},"status":{"conditions":[{"type":"Accepted","status":"True"}]}}]}`
+	gateways := `{"items":[
+		{"metadata":{"name":"public","namespace":"infra"},
+		 "spec":{"gatewayClassName":"envoy","listeners":[
+			{"name":"http","port":80,"protocol":"HTTP","allowedRoutes":{"namespaces":{"from":"All"}}},
+			{"name":"http-dup","port":80,"protocol":"HTTP"},
+			{"name":"tcp","port":443,"protocol":"TCP"},
+			{"name":"https","port":443,"protocol":"HTTPS","hostname":"shop.example.com","tls":{"certificateRefs":[{"name":"shop-tls","namespace":"certs"}]}}]},
+		 "status":{"listeners":[{"name":"http-dup","conditions":[{"type":"Conflicted","status":"True","reason":"HostnameConflict"}]}]}},
+		{"metadata":{"name":"internal","namespace":"infra"},
+		 "spec":{"gatewayClassName":"missing","listeners":[{"name":"http","port":8080,"protocol":"HTTP"}]}}]}`
+	httpRoutes := `{"items":[
+		{"metadata":{"name":"shop","namespace":"shop"},
+		 "spec":{"parentRefs":[{"name":"public","namespace":"infra"},{"name":"internal","namespace":"infra"},{"name":"ghost"}],
+		         "rules":[{"backendRefs":[{"name":"shop-api","port":8080},{"name":"payments","namespace":"payments","port":80},{"name":"nope","port":80}]}]},
+		 "status":{"parents":[{"parentRef":{"name":"public","namespace":"infra"},"conditions":[{"type":"Accepted","status":"False","reason":"NotAllowedByListeners"}]}]}},
+		{"metadata":{"name":"granted","namespace":"shop"},
+		 "spec":{"parentRefs":[{"name":"public","namespace":"infra"}],"rules":[{"backendRefs":[{"name":"ledger","namespace":"payments"}]}]}}]}`
+	grants := `{"items":[{"metadata":{"namespace":"payments"},"spec":{
+		"from":[{"group":"gateway.networking.k8s.io","kind":"HTTPRoute","namespace":"shop"}],
+		"to":[{"group":"","kind":"Service","name":"ledger"}]}}]}`
+	services := `{"items":[
+		{"metadata":{"name":"shop-api","namespace":"shop"}},
+		{"metadata":{"name":"payments","namespace":"payments"}},
+		{"metadata":{"name":"ledger","namespace":"payments"}}]}`
+
+	findings := AnalyzeGatewayAPI(classes, gateways, httpRoutes, "", grants, services)
+	got := FormatFindings(findings)
+
+	for _, want := range []string{
+		"Gateway infra/public: listener http-dup: Conflicted=True: HostnameConflict",
+		"Gateway infra/public: listener conflict: http and http-dup both use port 80 hostname *",
+		"Gateway infra/public: listener conflict: tcp (TCP) and https (HTTPS) share port 443",
+		"Gateway infra/public: listener https: certificateRef certs/shop-tls needs a ReferenceGrant in namespace certs",
+		"Gateway infra/internal: gatewayClass not found: missing",
+		"HTTPRoute shop/shop: parent gateway not found: shop/ghost",
+		"HTTPRoute shop/shop: gateway infra/internal listeners only allow routes from their own namespace",
+		"HTTPRoute shop/shop: backend payments/payments needs a ReferenceGrant in namespace payments allowing HTTPRoute from shop",
+		"HTTPRoute shop/shop: backend service not found: shop/nope",
+		"HTTPRoute shop/shop: not accepted by parent infra/public: NotAllowedByListeners",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing finding %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "shop/granted") {
+		t.Errorf("route with a matching ReferenceGrant should be clean:\n%s", got)
+	}
+	if strings.Contains(got, "infra/public listeners only allow") {
+		t.Errorf("listener with allowedRoutes from All should admit cross-namespace routes:\n%s", got)
+	}
+}
+
+func TestAnalyzeGatewayAPIInvalidJSON(t *testing.T) {
+	if f := AnalyzeGatewayAPI("", "not json", "", "", "", ""); f != nil {
+		t.Errorf("expected nil findings for invalid gateways JSON, got %+v", f)
+	}
+}
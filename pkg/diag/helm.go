@@ -0,0 +1,466 @@
+package diag
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/filter"
+)
+
+// HelmSecretsCommand fetches Helm v3 release records stored by the default "secret" driver.
+// Its raw output is never sent to the model; only decoded metadata and redacted diffs are.
+const HelmSecretsCommand = "kubectl get secrets -A -l owner=helm -o json"
+
+// helmMaxDiffLines caps the rendered manifest diff included in prompts and terminal output.
+const helmMaxDiffLines = 200
+
+// helmMaxLCSCells bounds the memory used when diffing a single manifest document.
+const helmMaxLCSCells = 1_000_000
+
+var helmPromptRe = regexp.MustCompile(`(?i)\bhelm\b`)
+
+// HelmRelease is the decoded, value-free subset of a Helm release record.
+type HelmRelease struct {
+	Name         string
+	Namespace    string
+	Revision     int
+	Status       string
+	Chart        string
+	ChartVersion string
+	AppVersion   string
+	Description  string
+	Updated      time.Time
+	Manifest     string
+}
+
+// helmReleaseRecord mirrors the JSON stored (gzip+base64) in the "release" secret key.
+// The user-supplied values ("config") are deliberately not decoded.
+type helmReleaseRecord struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Version   int    `json:"version"`
+	Manifest  string `json:"manifest"`
+	Info      struct {
+		Status       string    `json:"status"`
+		Description  string    `json:"description"`
+		LastDeployed time.Time `json:"last_deployed"`
+	} `json:"info"`
+	Chart struct {
+		Metadata struct {
+			Name       string `json:"name"`
+			Version    string `json:"version"`
+			AppVersion string `json:"appVersion"`
+		} `json:"metadata"`
+	} `json:"chart"`
+}
+
+// DecodeHelmReleases decodes `kubectl get secrets -l owner=helm -o json` output into releases
+// sorted by namespace, name and revision. Records that cannot be decoded are skipped.
+func DecodeHelmReleases(secretsJSON string) []HelmRelease {
+	type secret struct {
+		Type     string `json:"type"`
+		Metadata struct {
+			Namespace string            `json:"namespace"`
+			Labels    map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Data map[string]string `json:"data"`
+	}
+	type list struct {
+		Items []secret `json:"items"`
+	}
+	var l list
+	if err := json.Unmarshal([]byte(secretsJSON), &l); err != nil {
+		return nil
+	}
+	var out []HelmRelease
+	for _, s := range l.Items {
+		if s.Type != "" && s.Type != "helm.sh/release.v1" {
+			continue
+		}
+		rec, err := decodeHelmRecord(s.Data["release"])
+		if err != nil {
+			continue
+		}
+		ns := rec.Namespace
+		if ns == "" {
+			ns = s.Metadata.Namespace
+		}
+		out = append(out, HelmRelease{
+			Name:         rec.Name,
+			Namespace:    ns,
+			Revision:     rec.Version,
+			Status:       rec.Info.Status,
+			Chart:        rec.Chart.Metadata.Name,
+			ChartVersion: rec.Chart.Metadata.Version,
+			AppVersion:   rec.Chart.Metadata.AppVersion,
+			Description:  rec.Info.Description,
+			Updated:      rec.Info.LastDeployed,
+			Manifest:     rec.Manifest,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Revision < out[j].Revision
+	})
+	return out
+}
+
+// decodeHelmRecord reverses Kubernetes' base64 encoding and Helm's base64+gzip encoding.
+func decodeHelmRecord(data string) (*helmReleaseRecord, error) {
+	if data == "" {
+		return nil, fmt.Errorf("empty release data")
+	}
+	outer, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	inner, err := base64.StdEncoding.DecodeString(string(outer))
+	if err != nil {
+		return nil, err
+	}
+	// Helm gzips records since v3; fall back to plain JSON for older ones
+	if len(inner) > 2 && inner[0] == 0x1f && inner[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(inner))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		if inner, err = io.ReadAll(zr); err != nil {
+			return nil, err
+		}
+	}
+	var rec helmReleaseRecord
+	if err := json.Unmarshal(inner, &rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// LatestHelmReleases returns the highest revision of every release.
+func LatestHelmReleases(releases []HelmRelease) []HelmRelease {
+	latest := map[string]HelmRelease{}
+	var keys []string
+	for _, r := range releases {
+		key := r.Namespace + "/" + r.Name
+		cur, ok := latest[key]
+		if !ok {
+			keys = append(keys, key)
+		}
+		if !ok || r.Revision > cur.Revision {
+			latest[key] = r
+		}
+	}
+	sort.Strings(keys)
+	out := make([]HelmRelease, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, latest[k])
+	}
+	return out
+}
+
+// AnalyzeHelmReleases flags releases whose latest revision is failed or stuck in a pending
+// state; a pending release blocks further upgrades until it is rolled back.
+func AnalyzeHelmReleases(secretsJSON string) []Finding {
+	var f []Finding
+	for _, r := range LatestHelmReleases(DecodeHelmReleases(secretsJSON)) {
+		id := r.Namespace + "/" + r.Name
+		chart := r.Chart
+		if r.ChartVersion != "" {
+			chart += "-" + r.ChartVersion
+		}
+		var severity, summary string
+		switch r.Status {
+		case "failed":
+			severity = "error"
+			summary = fmt.Sprintf("revision %d (%s) failed", r.Revision, chart)
+		case "pending-install", "pending-upgrade", "pending-rollback":
+			severity = "error"
+			summary = fmt.Sprintf("revision %d (%s) stuck in %s; further upgrades are blocked", r.Revision, chart, r.Status)
+		case "uninstalling":
+			severity = "warn"
+			summary = fmt.Sprintf("revision %d (%s) stuck uninstalling", r.Revision, chart)
+		default:
+			continue
+		}
+		if r.Description != "" {
+			summary += ": " + r.Description
+		}
+		f = append(f, Finding{Kind: "Helm", ID: id, Severity: severity, Priority: assignPriority(severity, "Helm", summary), Summary: summary})
+	}
+	return f
+}
+
+// MentionsHelm reports whether a user prompt explicitly mentions Helm or names one of
+// the given releases. Generic words such as "release" or "upgrade" are not enough: they
+// would list and decode every release secret on unrelated turns.
+func MentionsHelm(prompt string, releases []string) bool {
+	if helmPromptRe.MatchString(prompt) {
+		return true
+	}
+	lowered := strings.ToLower(prompt)
+	for _, name := range releases {
+		if containsWord(lowered, strings.ToLower(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// HelmReleaseNames returns the release names of Helm release secrets from their "name"
+// label, without decoding the records.
+func HelmReleaseNames(secretsJSON string) []string {
+	var l struct {
+		Items []struct {
+			Metadata struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(secretsJSON), &l); err != nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var names []string
+	for _, item := range l.Items {
+		if name := item.Metadata.Labels["name"]; name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// containsWord reports whether word occurs in text with no letter, digit or underscore
+// directly before or after it, like a regexp \b match.
+func containsWord(text, word string) bool {
+	if word == "" {
+		return false
+	}
+	isWordByte := func(b byte) bool {
+		return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+	}
+	for from := 0; from <= len(text)-len(word); {
+		i := strings.Index(text[from:], word)
+		if i < 0 {
+			return false
+		}
+		start, end := from+i, from+i+len(word)
+		if (start == 0 || !isWordByte(text[start-1])) && (end == len(text) || !isWordByte(text[end])) {
+			return true
+		}
+		from = start + 1
+	}
+	return false
+}
+
+// HelmReleaseContext builds a prompt section with revision history and the rendered
+// manifest diff of the last upgrade for every release named in the prompt. Manifests are
+// masked with redact, or the built-in secret filter when it is nil.
+func HelmReleaseContext(secretsJSON, prompt string, redact func(string) string) string {
+	releases := DecodeHelmReleases(secretsJSON)
+	if len(releases) == 0 {
+		return ""
+	}
+	lowered := strings.ToLower(prompt)
+	var sections []string
+	for _, latest := range LatestHelmReleases(releases) {
+		if !containsWord(lowered, strings.ToLower(latest.Name)) {
+			continue
+		}
+		var b strings.Builder
+		b.WriteString(fmt.Sprintf("### Helm release %s/%s\n", latest.Namespace, latest.Name))
+		b.WriteString(FormatHelmHistory(releases, latest.Namespace, latest.Name))
+		if diff := HelmLastUpgradeDiff(releases, latest.Namespace, latest.Name, redact); diff != "" {
+			b.WriteString("\n\nRendered manifest changes in the last upgrade:\n```diff\n")
+			b.WriteString(diff)
+			b.WriteString("\n```")
+		}
+		sections = append(sections, b.String())
+	}
+	if len(sections) == 0 {
+		return ""
+	}
+	return "## Helm releases\n\n" + strings.Join(sections, "\n\n")
+}
+
+// FormatHelmHistory renders a compact revision history for one release.
+func FormatHelmHistory(releases []HelmRelease, namespace, name string) string {
+	var b strings.Builder
+	for _, r := range releases {
+		if r.Namespace != namespace || r.Name != name {
+			continue
+		}
+		b.WriteString(fmt.Sprintf("- revision %d: %s chart=%s-%s app=%s", r.Revision, r.Status, r.Chart, r.ChartVersion, r.AppVersion))
+		if !r.Updated.IsZero() {
+			b.WriteString(" updated=" + r.Updated.UTC().Format(time.RFC3339))
+		}
+		if r.Description != "" {
+			b.WriteString(" — " + r.Description)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// HelmLastUpgradeDiff diffs the rendered manifests of the two most recent revisions of a
+// release. Secret payloads are redacted with redact before diffing.
+func HelmLastUpgradeDiff(releases []HelmRelease, namespace, name string, redact func(string) string) string {
+	var revs []HelmRelease
+	for _, r := range releases {
+		if r.Namespace == namespace && r.Name == name {
+			revs = append(revs, r)
+		}
+	}
+	if len(revs) < 2 {
+		return ""
+	}
+	prev, cur := revs[len(revs)-2], revs[len(revs)-1]
+	return HelmManifestDiff(prev.Manifest, cur.Manifest, redact)
+}
+
+// HelmManifestDiff compares two rendered manifests document by document and returns a
+// unified-style diff limited to helmMaxDiffLines lines.
+func HelmManifestDiff(oldManifest, newManifest string, redact func(string) string) string {
+	if redact == nil {
+		redact = filter.SensitiveData
+	}
+	oldDocs, oldOrder := splitManifest(oldManifest, redact)
+	newDocs, newOrder := splitManifest(newManifest, redact)
+
+	var lines []string
+	for _, key := range newOrder {
+		before, existed := oldDocs[key]
+		after := newDocs[key]
+		switch {
+		case !existed:
+			lines = append(lines, "+++ added "+key)
+			for _, ln := range strings.Split(after, "\n") {
+				lines = append(lines, "+"+ln)
+			}
+		case before != after:
+			lines = append(lines, "~~~ changed "+key)
+			lines = append(lines, LineDiff(before, after)...)
+		}
+	}
+	for _, key := range oldOrder {
+		if _, ok := newDocs[key]; !ok {
+			lines = append(lines, "--- removed "+key)
+		}
+	}
+	if len(lines) > helmMaxDiffLines {
+		omitted := len(lines) - helmMaxDiffLines
+		lines = append(lines[:helmMaxDiffLines], fmt.Sprintf("... %d more diff line(s) omitted", omitted))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// splitManifest splits a rendered manifest into redacted documents keyed by kind/name and
+// the chart template that produced them.
+func splitManifest(manifest string, redact func(string) string) (map[string]string, []string) {
+	docs := map[string]string{}
+	var order []string
+	for _, doc := range strings.Split(manifest, "\n---") {
+		doc = strings.TrimSpace(strings.TrimPrefix(doc, "---"))
+		if doc == "" {
+			continue
+		}
+		source := ""
+		var body []string
+		for _, ln := range strings.Split(doc, "\n") {
+			if strings.HasPrefix(ln, "# Source: ") {
+				source = strings.TrimPrefix(ln, "# Source: ")
+				continue
+			}
+			body = append(body, ln)
+		}
+		raw := strings.Join(body, "\n")
+		key := manifestDocKey(raw)
+		text := strings.TrimSpace(redact(raw))
+		if source != "" {
+			key += " (" + source + ")"
+		}
+		if _, dup := docs[key]; dup {
+			key = fmt.Sprintf("%s #%d", key, len(order))
+		}
+		docs[key] = text
+		order = append(order, key)
+	}
+	return docs, order
+}
+
+var (
+	manifestKindRe = regexp.MustCompile(`(?m)^kind:\s*(\S+)`)
+	manifestNameRe = regexp.MustCompile(`(?m)^  name:\s*(\S+)`)
+)
+
+func manifestDocKey(doc string) string {
+	kind, name := "Unknown", "unnamed"
+	if m := manifestKindRe.FindStringSubmatch(doc); m != nil {
+		kind = m[1]
+	}
+	if m := manifestNameRe.FindStringSubmatch(doc); m != nil {
+		name = strings.Trim(m[1], `"'`)
+	}
+	return kind + "/" + name
+}
+
+// LineDiff returns changed lines between two documents using an LCS table.
+// Very large documents (e.g. bundled CRDs) are summarized instead of diffed.
+func LineDiff(a, b string) []string {
+	al := strings.Split(a, "\n")
+	bl := strings.Split(b, "\n")
+	n, m := len(al), len(bl)
+	if n*m > helmMaxLCSCells {
+		return []string{fmt.Sprintf("~ content changed (%d → %d lines; too large to diff)", n, m)}
+	}
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if al[i] == bl[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var out []string
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case al[i] == bl[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, "-"+al[i])
+			i++
+		default:
+			out = append(out, "+"+bl[j])
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		out = append(out, "-"+al[i])
+	}
+	for ; j < m; j++ {
+		out = append(out, "+"+bl[j])
+	}
+	return out
+}
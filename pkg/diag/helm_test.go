@@ -0,0 +1,196 @@
+package diag
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// helmSecret builds a release secret the way Helm's secret driver stores it:
+// JSON -> gzip -> base64, then base64 again by the Kubernetes API.
+func helmSecret(t *testing.T, name, namespace string, revision int, status, chartVersion, manifest string) string {
+	t.Helper()
+	rec := map[string]interface{}{
+		"name":      name,
+		"namespace": namespace,
+		"version":   revision,
+		"manifest":  manifest,
+		"config":    map[string]interface{}{"dbPassword": "hunter2"},
+		"info":      map[string]interface{}{"status": status, "description": "Upgrade complete", "last_deployed": "2026-01-02T03:04:05Z"},
+		"chart":     map[string]interface{}{"metadata": map[string]interface{}{"name": "shop", "version": chartVersion, "appVersion": "1.0"}},
+	}
+	raw, err := json.Marshal(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(raw)
+	zw.Close()
+	helmEncoded := base64.StdEncoding.EncodeToString(gz.Bytes())
+	data := base64.StdEncoding.EncodeToString([]byte(helmEncoded))
+	return fmt.Sprintf(`{"type":"helm.sh/release.v1","metadata":{"namespace":%q,"labels":{"owner":"helm","name":%q}},"data":{"release":%q}}`, namespace, name, data)
+}
+
+const helmManifestV1 = `---
+# Source: shop/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: shop-db
+data:
+  password: b2xkLXBhc3M=
+---
+# Source: shop/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: shop
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - image: shop:1.0
+---
+# Source: shop/templates/legacy.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: shop-legacy
+`
+
+const helmManifestV2 = `---
+# Source: shop/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: shop-db
+data:
+  password: bmV3LXBhc3M=
+---
+# Source: shop/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: shop
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - image: shop:1.1
+---
+# Source: shop/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: shop
+`
+
+func helmSecretsList(t *testing.T) string {
+	items := []string{
+		helmSecret(t, "shop", "web", 2, "failed", "1.1.0", helmManifestV2),
+		helmSecret(t, "shop", "web", 1, "superseded", "1.0.0", helmManifestV1),
+		helmSecret(t, "cache", "data", 4, "pending-upgrade", "2.0.0", ""),
+		helmSecret(t, "ok", "data", 1, "deployed", "0.1.0", ""),
+	}
+	return `{"items":[` + strings.Join(items, ",") + `]}`
+}
+
+func TestDecodeHelmReleases(t *testing.T) {
+	releases := DecodeHelmReleases(helmSecretsList(t))
+	if len(releases) != 4 {
+		t.Fatalf("expected 4 releases, got %d", len(releases))
+	}
+	if releases[0].Name != "cache" || releases[2].Name != "shop" || releases[2].Revision != 1 {
+		t.Errorf("unexpected release order: %+v", releases)
+	}
+	if releases[3].Chart != "shop" || releases[3].ChartVersion != "1.1.0" || releases[3].Status != "failed" {
+		t.Errorf("unexpected decoded metadata: %+v", releases[3])
+	}
+	if DecodeHelmReleases("not json") != nil {
+		t.Error("expected nil for invalid JSON")
+	}
+}
+
+func TestAnalyzeHelmReleases(t *testing.T) {
+	findings := AnalyzeHelmReleases(helmSecretsList(t))
+	if len(findings) != 2 {
+		t.Fatalf("expected 2 findings, got %+v", findings)
+	}
+	if findings[0].ID != "data/cache" || !strings.Contains(findings[0].Summary, "stuck in pending-upgrade") {
+		t.Errorf("unexpected pending finding: %+v", findings[0])
+	}
+	if findings[1].ID != "web/shop" || findings[1].Severity != "error" || !strings.Contains(findings[1].Summary, "revision 2 (shop-1.1.0) failed") {
+		t.Errorf("unexpected failed finding: %+v", findings[1])
+	}
+}
+
+func TestHelmReleaseContext(t *testing.T) {
+	secrets := helmSecretsList(t)
+	if ctx := HelmReleaseContext(secrets, "why are pods crashing?", nil); ctx != "" {
+		t.Errorf("expected no context when no release is named, got %q", ctx)
+	}
+
+	ctx := HelmReleaseContext(secrets, "What changed in the last helm upgrade of shop?", nil)
+	for _, want := range []string{
+		"### Helm release web/shop",
+		"- revision 1: superseded chart=shop-1.0.0",
+		"- revision 2: failed chart=shop-1.1.0",
+		"~~~ changed Deployment/shop (shop/templates/deployment.yaml)",
+		"replicas: 2",
+		"replicas: 3",
+		"- image: shop:1.1",
+		"+++ added Service/shop (shop/templates/service.yaml)",
+		"--- removed ConfigMap/shop-legacy (shop/templates/legacy.yaml)",
+	} {
+		if !strings.Contains(ctx, want) {
+			t.Errorf("missing %q in:\n%s", want, ctx)
+		}
+	}
+	for _, secret := range []string{"b2xkLXBhc3M=", "bmV3LXBhc3M=", "hunter2"} {
+		if strings.Contains(ctx, secret) {
+			t.Errorf("context leaked secret value %q:\n%s", secret, ctx)
+		}
+	}
+	if strings.Contains(ctx, "data/cache") {
+		t.Errorf("unrelated release included:\n%s", ctx)
+	}
+}
+
+func TestMentionsHelm(t *testing.T) {
+	names := HelmReleaseNames(helmSecretsList(t))
+	if len(names) != 3 {
+		t.Fatalf("expected three release names, got %v", names)
+	}
+	for prompt, want := range map[string]bool{
+		"roll back the last Helm release":       true,
+		"why is shop returning 502s?":           true,
+		"why is shopping-cart returning 502s?":  false,
+		"what changed in the latest release?":   false,
+		"should I upgrade the ingress chart?":   false,
+		"pods in the shop_v2 namespace pending": false,
+	} {
+		if got := MentionsHelm(prompt, names); got != want {
+			t.Errorf("MentionsHelm(%q) = %t, want %t", prompt, got, want)
+		}
+	}
+}
+
+func TestHelmManifestDiffTruncates(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("kind: ConfigMap\nmetadata:\n  name: big\ndata:\n")
+	for i := 0; i < helmMaxDiffLines*2; i++ {
+		fmt.Fprintf(&b, "  k%d: v%d\n", i, i)
+	}
+	diff := HelmManifestDiff("", b.String(), nil)
+	lines := strings.Split(diff, "\n")
+	if len(lines) != helmMaxDiffLines+1 || !strings.Contains(lines[len(lines)-1], "more diff line(s) omitted") {
+		t.Errorf("expected truncated diff, got %d lines ending %q", len(lines), lines[len(lines)-1])
+	}
+}
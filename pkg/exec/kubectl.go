@@ -627,8 +627,33 @@ func processResults(cfg *config.Config, results []config.CmdRes) error {
 	return err
 }
 
-// ExecKubectlCmd executes a kubectl command and returns its result
-func ExecKubectlCmd(cfg *config.Config, command string) (result config.CmdRes) {
+// ExecKubectlCmd executes a kubectl command and returns its result. With an open cassette
+// the result is recorded, or served from the cassette when replaying.
+func ExecKubectlCmd(cfg *config.Config, command string) config.CmdRes {
+	if cfg != nil && cfg.Cassette.Replaying() {
+		return replayKubectlCmd(cfg, command)
+	}
+	result := execKubectlCmd(cfg, command)
+	if cfg != nil {
+		cfg.Cassette.RecordCommand(result.Cmd, result.Out, result.Err)
+	}
+	return result
+}
+
+// replayKubectlCmd returns the recorded result of command without running it.
+func replayKubectlCmd(cfg *config.Config, command string) (result config.CmdRes) {
+	result.Cmd = strings.TrimSpace(command)
+	out, err, ok := cfg.Cassette.ReplayCommand(result.Cmd)
+	if !ok {
+		result.Err = fmt.Errorf("command not found in cassette: %s", result.Cmd)
+		return result
+	}
+	logger.Log("info", "Replaying command from cassette: %s", result.Cmd)
+	result.Out, result.Err = out, err
+	return result
+}
+
+func execKubectlCmd(cfg *config.Config, command string) (result config.CmdRes) {
 	// Trim the command to avoid empty commands
 	command = strings.TrimSpace(command)
 	result.Cmd = command
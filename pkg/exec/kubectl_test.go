@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/mikhae1/kubectl-quackops/pkg/cassette"
 	"github.com/mikhae1/kubectl-quackops/pkg/config"
 	"github.com/mikhae1/kubectl-quackops/pkg/lib"
 )
@@ -156,3 +157,29 @@ func TestShellCommandsSkippedInSafeMode(t *testing.T) {
 		t.Errorf("Expected 0 completed commands, got %d", data.completedCount)
 	}
 }
+
+func TestExecKubectlCmdCassette(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := cassette.NewRecorder(dir)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	cfg := &config.Config{Timeout: 5, KubectlBinaryPath: "echo", CommandPrefix: "!", Cassette: rec}
+	live := ExecKubectlCmd(cfg, "kubectl get pods")
+	if live.Err != nil || !strings.Contains(live.Out, "get pods") {
+		t.Fatalf("unexpected live result: %+v", live)
+	}
+
+	play, err := cassette.Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	// A binary that cannot run proves the output comes from the cassette
+	cfg = &config.Config{Timeout: 5, KubectlBinaryPath: "/nonexistent/kubectl", CommandPrefix: "!", Cassette: play}
+	if got := ExecKubectlCmd(cfg, " kubectl get pods "); got.Err != nil || got.Out != live.Out {
+		t.Errorf("unexpected replayed result: %+v", got)
+	}
+	if got := ExecKubectlCmd(cfg, "kubectl get nodes"); got.Err == nil || !strings.Contains(got.Err.Error(), "not found in cassette") {
+		t.Errorf("expected missing-command error, got %+v", got)
+	}
+}
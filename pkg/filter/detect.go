@@ -0,0 +1,190 @@
+package filter
+
+import (
+	"math"
+	"regexp"
+)
+
+// Detector masks one well-known credential format.
+type Detector struct {
+	Name        string
+	Pattern     *regexp.Regexp
+	Replacement string // may keep submatches, such as the field name in front of the value
+}
+
+// Detectors lists the built-in credential formats. Field names may appear JSON-escaped
+// inside other values, e.g. in the last-applied-configuration annotation, hence the
+// optional backslashes.
+var Detectors = []Detector{
+	{
+		Name:        "pem-private-key",
+		Pattern:     regexp.MustCompile(`(?s)-----BEGIN[A-Z ]*PRIVATE KEY-----.*?(?:-----END[A-Z ]*PRIVATE KEY-----|$)`),
+		Replacement: "***FILTERED***",
+	},
+	{
+		Name:        "aws-access-key-id",
+		Pattern:     regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[A-Z0-9]{16}\b`),
+		Replacement: "***FILTERED***",
+	},
+	{
+		Name:        "aws-secret-access-key",
+		Pattern:     regexp.MustCompile(`(?i)(aws_?secret_?access_?key\\?["']?\s*[:=]\s*\\?["']?)[A-Za-z0-9/+]{40}`),
+		Replacement: "${1}***FILTERED***",
+	},
+	{
+		Name:        "gcp-private-key-id",
+		Pattern:     regexp.MustCompile(`(\\?"private_key_id\\?"\s*:\s*\\?")[0-9a-f]{40}`),
+		Replacement: "${1}***FILTERED***",
+	},
+	{
+		Name:        "github-token",
+		Pattern:     regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`),
+		Replacement: "***FILTERED***",
+	},
+	{
+		Name:        "gitlab-token",
+		Pattern:     regexp.MustCompile(`\bgl(?:pat|dt|rt|ptt|soat|cbt)-[A-Za-z0-9_\-]{20,}`),
+		Replacement: "***FILTERED***",
+	},
+	{
+		Name:        "slack-token",
+		Pattern:     regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9\-]{10,}`),
+		Replacement: "***FILTERED***",
+	},
+	{
+		Name:        "slack-webhook",
+		Pattern:     regexp.MustCompile(`(https://hooks\.slack\.com/services/)[A-Za-z0-9/]+`),
+		Replacement: "${1}***FILTERED***",
+	},
+	{
+		Name:        "jwt",
+		Pattern:     regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]{5,}\.eyJ[A-Za-z0-9_\-]{5,}\.[A-Za-z0-9_\-]{10,}`),
+		Replacement: "***FILTERED***",
+	},
+	{
+		Name:        "kubeconfig-client-key",
+		Pattern:     regexp.MustCompile(`(client-key-data\\?["']?\s*:\s*\\?["']?)[A-Za-z0-9+/]{16,}=*`),
+		Replacement: "${1}***FILTERED***",
+	},
+	{
+		Name:        "docker-config-auth",
+		Pattern:     regexp.MustCompile(`(\\?"(?:auth|identitytoken)\\?"\s*:\s*\\?")[A-Za-z0-9+/\-_.]{8,}=*`),
+		Replacement: "${1}***FILTERED***",
+	},
+}
+
+// DetectSecrets masks well-known credential formats in text.
+func DetectSecrets(input string) string {
+	return detectSecrets(input, nil)
+}
+
+func detectSecrets(input string, h Hits) string {
+	result := input
+	for _, d := range Detectors {
+		result = replaceCounting(d.Pattern, result, d.Replacement, h, d.Name)
+	}
+	return result
+}
+
+// Thresholds of the entropy detector. Random base64 reaches over 4 bits per character at
+// 20 characters, while hex digests and commit SHAs stay below 4.
+const (
+	EntropyMinLength = 20
+	EntropyThreshold = 4.0
+)
+
+var (
+	entropyTokenPattern = regexp.MustCompile(`[A-Za-z0-9+/=_\-]{20,}`)
+	hasUpper            = regexp.MustCompile(`[A-Z]`)
+	hasLower            = regexp.MustCompile(`[a-z]`)
+	hasDigit            = regexp.MustCompile(`[0-9]`)
+	// envAssignmentPattern matches "NAME=value" and "NAME: value" lines of env output and
+	// the Environment section of kubectl describe.
+	envAssignmentPattern = regexp.MustCompile(`(?m)^(\s*(?:export\s+)?[A-Z][A-Z0-9_]*\s*[=:]\s*)(.+)$`)
+	envNamePattern       = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+)
+
+// ShannonEntropy returns the Shannon entropy of s in bits per character.
+func ShannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := map[rune]int{}
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+	entropy := 0.0
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// MaskHighEntropy masks random-looking tokens in s: at least EntropyMinLength characters
+// mixing upper case, lower case and digits, with entropy of at least EntropyThreshold.
+func MaskHighEntropy(s string) string {
+	return maskHighEntropy(s, nil)
+}
+
+func maskHighEntropy(s string, h Hits) string {
+	return entropyTokenPattern.ReplaceAllStringFunc(s, func(token string) string {
+		if len(token) < EntropyMinLength || !hasUpper.MatchString(token) || !hasLower.MatchString(token) || !hasDigit.MatchString(token) {
+			return token
+		}
+		if ShannonEntropy(token) < EntropyThreshold {
+			return token
+		}
+		h.add("high-entropy", 1)
+		return "***FILTERED***"
+	})
+}
+
+// maskEnvEntropy applies the entropy detector to env var assignments in plain text.
+func maskEnvEntropy(input string, h Hits) string {
+	return envAssignmentPattern.ReplaceAllStringFunc(input, func(line string) string {
+		m := envAssignmentPattern.FindStringSubmatch(line)
+		return m[1] + maskHighEntropy(m[2], h)
+	})
+}
+
+// maskEntropyFields applies the entropy detector to container env values, env-style
+// UPPER_CASE fields and annotations in decoded JSON or YAML.
+func maskEntropyFields(v any, h Hits) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for key, child := range t {
+			if s, ok := child.(string); ok && envNamePattern.MatchString(key) {
+				t[key] = maskHighEntropy(s, h)
+				continue
+			}
+			switch key {
+			case "env":
+				if items, ok := child.([]interface{}); ok {
+					for _, item := range items {
+						if env, ok := item.(map[string]interface{}); ok {
+							if s, ok := env["value"].(string); ok {
+								env["value"] = maskHighEntropy(s, h)
+							}
+						}
+					}
+				}
+			case "annotations":
+				if annotations, ok := child.(map[string]interface{}); ok {
+					for k, val := range annotations {
+						if s, ok := val.(string); ok {
+							annotations[k] = maskHighEntropy(s, h)
+						}
+					}
+				}
+			}
+			maskEntropyFields(child, h)
+		}
+	case []interface{}:
+		for _, child := range t {
+			maskEntropyFields(child, h)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package filter
 
 import (
+	"fmt"
+	"regexp"
 	"strings"
 )
 
@@ -9,6 +11,74 @@ type Filter interface {
 	Process(input string) string
 }
 
+// ValueFilter is implemented by filters that can redact decoded JSON values directly
+// instead of only their strings, e.g. to mask a field by name.
+type ValueFilter interface {
+	ProcessValue(v any) any
+}
+
+// Hits counts the values masked per filter, e.g. "aws-access-key-id" or "rule:acme-token".
+// A nil Hits counts nothing.
+type Hits map[string]int
+
+func (h Hits) add(name string, n int) {
+	if h == nil || n <= 0 {
+		return
+	}
+	h[name] += n
+}
+
+// Total returns the number of masked values.
+func (h Hits) Total() int {
+	total := 0
+	for _, n := range h {
+		total += n
+	}
+	return total
+}
+
+// auditFilter is implemented by the built-in filters to count what they mask.
+type auditFilter interface {
+	processAudit(input string, h Hits) string
+	processValueAudit(v any, h Hits) any
+}
+
+func processAudit(f Filter, input string, h Hits) string {
+	if af, ok := f.(auditFilter); ok {
+		return af.processAudit(input, h)
+	}
+	return f.Process(input)
+}
+
+func processValueAudit(f Filter, v any, h Hits) any {
+	if af, ok := f.(auditFilter); ok {
+		return af.processValueAudit(v, h)
+	}
+	return processValue(f, v)
+}
+
+// replaceCounting works like re.ReplaceAllString and counts the matches it changed in h
+// under name. Matches that were already masked by an earlier filter are not counted again.
+func replaceCounting(re *regexp.Regexp, s, repl string, h Hits, name string) string {
+	if h == nil {
+		return re.ReplaceAllString(s, repl)
+	}
+	var b []byte
+	last, n := 0, 0
+	for _, m := range re.FindAllStringSubmatchIndex(s, -1) {
+		b = append(b, s[last:m[0]]...)
+		start := len(b)
+		b = re.ExpandString(b, repl, s, m)
+		if match := s[m[0]:m[1]]; string(b[start:]) != match && !strings.Contains(match, DefaultReplacement) {
+			n++
+		}
+		last = m[1]
+	}
+	b = append(b, s[last:]...)
+	h.add(name, n)
+	return string(b)
+}
+
 // Chain represents a chain of filters that will be applied in sequence
 type Chain struct {
 	filters []Filter
@@ -28,13 +98,64 @@ func (c *Chain) AddFilter(filter Filter) {
 
 // Process applies all filters in the chain to the input
 func (c *Chain) Process(input string) string {
+	return c.processAudit(input, nil)
+}
+
+// ProcessValue applies all filters in the chain to a decoded JSON value. Filters without
+// ProcessValue are applied to every string in it.
+func (c *Chain) ProcessValue(v any) any {
+	return c.processValueAudit(v, nil)
+}
+
+// ProcessAudit is Process that also counts the masked values per filter in h.
+func (c *Chain) ProcessAudit(input string, h Hits) string {
+	return c.processAudit(input, h)
+}
+
+// ProcessValueAudit is ProcessValue that also counts the masked values per filter in h.
+func (c *Chain) ProcessValueAudit(v any, h Hits) any {
+	return c.processValueAudit(v, h)
+}
+
+func (c *Chain) processAudit(input string, h Hits) string {
 	result := input
 	for _, filter := range c.filters {
-		result = filter.Process(result)
+		result = processAudit(filter, result, h)
 	}
 	return result
 }
 
+func (c *Chain) processValueAudit(v any, h Hits) any {
+	for _, filter := range c.filters {
+		v = processValueAudit(filter, v, h)
+	}
+	return v
+}
+
+func processValue(f Filter, v any) any {
+	if vf, ok := f.(ValueFilter); ok {
+		return vf.ProcessValue(v)
+	}
+	return mapStrings(v, f.Process)
+}
+
+// mapStrings replaces every string in v, including map values and slice items, with fn(s).
+func mapStrings(v any, fn func(string) string) any {
+	switch t := v.(type) {
+	case string:
+		return fn(t)
+	case map[string]interface{}:
+		for key, child := range t {
+			t[key] = mapStrings(child, fn)
+		}
+	case []interface{}:
+		for i, child := range t {
+			t[i] = mapStrings(child, fn)
+		}
+	}
+	return v
+}
+
 // SensitiveDataFilter implements Filter for sensitive data removal
 type SensitiveDataFilter struct {
 	disabled bool
@@ -49,38 +170,76 @@ func NewSensitiveDataFilter(disabled bool) *SensitiveDataFilter {
 
 // Process applies the sensitive data filter
 func (f *SensitiveDataFilter) Process(input string) string {
+	return f.processAudit(input, nil)
+}
+
+// ProcessValue applies the sensitive data filter to decoded JSON
+func (f *SensitiveDataFilter) ProcessValue(v any) any {
+	return f.processValueAudit(v, nil)
+}
+
+func (f *SensitiveDataFilter) processAudit(input string, h Hits) string {
 	if f.disabled {
 		return input
 	}
-	return SensitiveData(input)
+	return sensitiveData(input, h)
+}
+
+func (f *SensitiveDataFilter) processValueAudit(v any, h Hits) any {
+	if f.disabled {
+		return v
+	}
+	return sensitiveStructured(v, h)
 }
 
 // RegexReplacementFilter implements a regex-based replacement filter
 type RegexReplacementFilter struct {
-	patterns map[string]string
+	name     string // reported in Hits; "pattern" when empty
+	patterns []regexReplacement
+}
+
+type regexReplacement struct {
+	pattern     *regexp.Regexp
+	replacement string
 }
 
 // NewRegexReplacementFilter creates a new regex replacement filter
 func NewRegexReplacementFilter() *RegexReplacementFilter {
-	return &RegexReplacementFilter{
-		patterns: make(map[string]string),
-	}
+	return &RegexReplacementFilter{}
 }
 
-// AddPattern adds a pattern to replace in the input
-func (f *RegexReplacementFilter) AddPattern(pattern, replacement string) {
-	f.patterns[pattern] = replacement
+// AddPattern adds a regular expression whose matches are replaced, in the order added.
+// The replacement may refer to submatches as in regexp.Expand, e.g. "$1".
+func (f *RegexReplacementFilter) AddPattern(pattern, replacement string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	f.patterns = append(f.patterns, regexReplacement{pattern: re, replacement: replacement})
+	return nil
 }
 
 // Process applies regex replacements
 func (f *RegexReplacementFilter) Process(input string) string {
+	return f.processAudit(input, nil)
+}
+
+func (f *RegexReplacementFilter) processAudit(input string, h Hits) string {
+	name := f.name
+	if name == "" {
+		name = "pattern"
+	}
 	result := input
-	for pattern, replacement := range f.patterns {
-		result = strings.ReplaceAll(result, pattern, replacement)
+	for _, p := range f.patterns {
+		result = replaceCounting(p.pattern, result, p.replacement, h, name)
 	}
 	return result
 }
 
+func (f *RegexReplacementFilter) processValueAudit(v any, h Hits) any {
+	return mapStrings(v, func(s string) string { return f.processAudit(s, h) })
+}
+
 // LineFilter implements a filter that works on individual lines
 type LineFilter struct {
 	predicate func(line string) bool
@@ -0,0 +1,182 @@
+package filter
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Identifier classes; each maps to tokens of the form "<class>-<n>".
+const (
+	PseudonymNamespace = "ns"
+	PseudonymPod       = "pod"
+	PseudonymNode      = "node"
+	PseudonymHost      = "host"
+	PseudonymIP        = "ip"
+)
+
+// builtinNamespaces are the same in every cluster and reveal nothing, so they are sent as is.
+var builtinNamespaces = map[string]bool{
+	"default":         true,
+	"kube-system":     true,
+	"kube-public":     true,
+	"kube-node-lease": true,
+}
+
+// identifierPattern matches the DNS-style words identifiers are made of; dotted words are
+// also mapped label by label, so "web.payments.svc" hides the namespace.
+var identifierPattern = regexp.MustCompile(`[A-Za-z0-9](?:[A-Za-z0-9.\-]*[A-Za-z0-9])?`)
+
+// ipv6Pattern matches IPv6 address candidates, which identifierPattern splits at the colons.
+var ipv6Pattern = regexp.MustCompile(`[0-9A-Fa-f]{0,4}(?::[0-9A-Fa-f]{0,4}){2,7}`)
+
+// Pseudonymizer consistently maps cluster identifiers to stable tokens such as "ns-1" or
+// "ip-3", and maps them back. The mapping lives in memory only.
+type Pseudonymizer struct {
+	mu        sync.Mutex
+	tokens    map[string]string // identifier -> token
+	names     map[string]string // token -> identifier
+	next      map[string]int
+	learnedAt time.Time
+}
+
+// NewPseudonymizer returns an empty mapping.
+func NewPseudonymizer() *Pseudonymizer {
+	return &Pseudonymizer{
+		tokens: map[string]string{},
+		names:  map[string]string{},
+		next:   map[string]int{},
+	}
+}
+
+// Learn registers name as an identifier of class and returns its token. Built-in
+// namespaces are not mapped.
+func (p *Pseudonymizer) Learn(class, name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" || class == PseudonymNamespace && builtinNamespaces[name] {
+		return name
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.learnLocked(class, name)
+}
+
+func (p *Pseudonymizer) learnLocked(class, name string) string {
+	if token, ok := p.tokens[name]; ok {
+		return token
+	}
+	var token string
+	for {
+		p.next[class]++
+		token = class + "-" + strconv.Itoa(p.next[class])
+		// Never hand out a token that is itself a real identifier.
+		if _, taken := p.tokens[token]; !taken && token != name {
+			break
+		}
+	}
+	p.tokens[name] = token
+	p.names[token] = name
+	return token
+}
+
+// MarkLearned records that the identifiers of the cluster were just learned.
+func (p *Pseudonymizer) MarkLearned() {
+	p.mu.Lock()
+	p.learnedAt = time.Now()
+	p.mu.Unlock()
+}
+
+// Stale reports whether the identifiers were learned more than ttl ago, or never.
+func (p *Pseudonymizer) Stale(ttl time.Duration) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.learnedAt.IsZero() || time.Since(p.learnedAt) > ttl
+}
+
+// Len returns the number of mapped identifiers.
+func (p *Pseudonymizer) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.tokens)
+}
+
+// Pseudonymize replaces known identifiers and every IPv4 and IPv6 address in text with
+// tokens.
+func (p *Pseudonymizer) Pseudonymize(text string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if strings.Contains(text, ":") {
+		text = ipv6Pattern.ReplaceAllStringFunc(text, func(word string) string {
+			if isIPv6(word) {
+				return p.learnLocked(PseudonymIP, word)
+			}
+			return word
+		})
+	}
+	return p.replace(text, func(word string) (string, bool) {
+		if token, ok := p.tokens[word]; ok {
+			return token, true
+		}
+		if isIPv4(word) {
+			return p.learnLocked(PseudonymIP, word), true
+		}
+		return "", false
+	})
+}
+
+// Restore replaces tokens in text with the identifiers they stand for.
+func (p *Pseudonymizer) Restore(text string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.replace(text, func(word string) (string, bool) {
+		name, ok := p.names[word]
+		return name, ok
+	})
+}
+
+func (p *Pseudonymizer) replace(text string, lookup func(string) (string, bool)) string {
+	if len(p.tokens) == 0 && !strings.Contains(text, ".") {
+		return text
+	}
+	return identifierPattern.ReplaceAllStringFunc(text, func(word string) string {
+		if mapped, ok := lookup(word); ok {
+			return mapped
+		}
+		if !strings.Contains(word, ".") {
+			return word
+		}
+		labels := strings.Split(word, ".")
+		// A known dotted suffix, such as the domain of a wildcard ingress host, maps whole
+		var suffix []string
+		for i := 1; i < len(labels)-1; i++ {
+			if mapped, ok := lookup(strings.Join(labels[i:], ".")); ok {
+				labels, suffix = labels[:i], []string{mapped}
+				break
+			}
+		}
+		for i, label := range labels {
+			if mapped, ok := lookup(label); ok {
+				labels[i] = mapped
+			}
+		}
+		return strings.Join(append(labels, suffix...), ".")
+	})
+}
+
+// isIPv4 reports whether s is a routable-looking IPv4 address; loopback and unspecified
+// addresses carry no information and are left alone.
+func isIPv4(s string) bool {
+	if strings.Count(s, ".") != 3 {
+		return false
+	}
+	ip := net.ParseIP(s)
+	return ip != nil && ip.To4() != nil && !ip.IsLoopback() && !ip.IsUnspecified()
+}
+
+func isIPv6(s string) bool {
+	ip := net.ParseIP(s)
+	return ip != nil && ip.To4() == nil && !ip.IsLoopback() && !ip.IsUnspecified()
+}
@@ -0,0 +1,55 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPseudonymizer(t *testing.T) {
+	p := NewPseudonymizer()
+	p.Learn(PseudonymNamespace, "payments")
+	p.Learn(PseudonymNamespace, "kube-system")
+	p.Learn(PseudonymPod, "pod-1") // a real pod whose name looks like a token
+	p.Learn(PseudonymPod, "checkout-7d9f8c6b5-x2x9z")
+	p.Learn(PseudonymNode, "ip-10-0-1-23.ec2.internal")
+	p.Learn(PseudonymHost, "shop.example.com")
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "pod and namespace", in: "kubectl logs checkout-7d9f8c6b5-x2x9z -n payments", want: "kubectl logs pod-3 -n ns-1"},
+		{name: "built-in namespace kept", in: "pods in kube-system", want: "pods in kube-system"},
+		{name: "token-like real name gets its own token", in: "pod-1 is pending", want: "pod-2 is pending"},
+		{name: "node and ip", in: "scheduled on ip-10-0-1-23.ec2.internal (10.0.1.23:10250)", want: "scheduled on node-1 (ip-1:10250)"},
+		{name: "service DNS name", in: "curl api.payments.svc.cluster.local.", want: "curl api.ns-1.svc.cluster.local."},
+		{name: "ingress host", in: "curl https://shop.example.com/cart", want: "curl https://host-1/cart"},
+		{name: "subdomain of a known host", in: "api.shop.example.com is down", want: "api.host-1 is down"},
+		{name: "ipv6 address", in: "pod IPs 10.0.1.23 and fd00:10:244::1f, node 2001:db8::5", want: "pod IPs ip-1 and ip-2, node ip-3"},
+		{name: "ipv6 loopback and times kept", in: "listening on ::1 since 12:30:45", want: "listening on ::1 since 12:30:45"},
+		{name: "loopback kept", in: "listening on 127.0.0.1", want: "listening on 127.0.0.1"},
+		{name: "prose untouched", in: "Restart the deployment to pick up the change.", want: "Restart the deployment to pick up the change."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := p.Pseudonymize(tt.in)
+			if got != tt.want {
+				t.Fatalf("Pseudonymize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if back := p.Restore(got); back != tt.in {
+				t.Errorf("Restore(%q) = %q, want %q", got, back, tt.in)
+			}
+		})
+	}
+
+	if got := p.Pseudonymize("again 10.0.1.23 in payments"); got != "again ip-1 in ns-1" {
+		t.Errorf("tokens must be stable across calls, got %q", got)
+	}
+	if got := p.Restore("check node-9 and ns-1"); got != "check node-9 and payments" {
+		t.Errorf("unknown tokens must be left alone, got %q", got)
+	}
+	if !strings.HasPrefix(p.Learn(PseudonymHost, "worker-1"), "host-") {
+		t.Error("hosts should get host tokens")
+	}
+}
@@ -0,0 +1,435 @@
+package filter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultReplacement is the mask written in place of redacted values.
+const DefaultReplacement = "***FILTERED***"
+
+// Rule is a user-defined redaction rule loaded from the rules file.
+//
+// Pattern masks every match of a regular expression in text and in string values. Fields
+// masks the values of object fields: a bare name ("licenseKey") matches the field at any
+// depth, a dotted path ("spec.auth.token" or "$.spec.auth.token") is anchored at the
+// document and at every Kubernetes object in it, and "*" matches any single key. Annotations
+// masks metadata annotations whose keys match a glob ("vault.hashicorp.com/*"). Kinds limits
+// Fields and Annotations to objects of those kinds; on its own it masks their data,
+// stringData and spec.encryptedData like Secrets.
+type Rule struct {
+	Name        string   `yaml:"name" json:"name"`
+	Pattern     string   `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	Replacement string   `yaml:"replacement,omitempty" json:"replacement,omitempty"`
+	Fields      []string `yaml:"fields,omitempty" json:"fields,omitempty"`
+	Kinds       []string `yaml:"kinds,omitempty" json:"kinds,omitempty"`
+	Annotations []string `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+}
+
+// RuleSet is the content of a redaction rules file. Allow lists regular expressions whose
+// matches are never masked by pattern or field rules; values under credential keys and the
+// data of Secrets stay masked regardless.
+type RuleSet struct {
+	Rules []Rule   `yaml:"rules" json:"rules"`
+	Allow []string `yaml:"allow,omitempty" json:"allow,omitempty"`
+}
+
+// LoadRules reads a YAML or JSON rules file. A missing file yields an empty rule set.
+func LoadRules(file string) (*RuleSet, error) {
+	rules := &RuleSet{}
+	if strings.TrimSpace(file) == "" {
+		return rules, nil
+	}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return rules, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read redaction rules: %w", err)
+	}
+	if err := yaml.Unmarshal(data, rules); err != nil {
+		return nil, fmt.Errorf("parse redaction rules %s: %w", file, err)
+	}
+	return rules, nil
+}
+
+// NewRedactionChain composes the rules in rs with the built-in sensitive data filter. Rules
+// run first, while documents are still intact: the built-in pattern pass rewrites lines.
+// When rs has allow entries, the whole chain runs behind an AllowFilter.
+func NewRedactionChain(rs *RuleSet) (*Chain, error) {
+	chain := NewChain()
+	if rs == nil {
+		rs = &RuleSet{}
+	}
+	for i, rule := range rs.Rules {
+		name := rule.Name
+		if name == "" {
+			name = "#" + strconv.Itoa(i+1)
+		}
+		filters, err := rule.compile(name)
+		if err != nil {
+			return nil, fmt.Errorf("redaction rule %s: %w", name, err)
+		}
+		for _, f := range filters {
+			chain.AddFilter(f)
+		}
+	}
+	chain.AddFilter(NewSensitiveDataFilter(false))
+	if len(rs.Allow) == 0 {
+		return chain, nil
+	}
+	allow := make([]*regexp.Regexp, 0, len(rs.Allow))
+	for _, pattern := range rs.Allow {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allow pattern %q: %w", pattern, err)
+		}
+		allow = append(allow, re)
+	}
+	return NewChain(NewAllowFilter(chain, allow...)), nil
+}
+
+// compile returns the filters of the rule, reporting their hits as "rule:<name>".
+func (r Rule) compile(name string) ([]Filter, error) {
+	hitName := "rule:" + name
+	replacement := r.Replacement
+	if replacement == "" {
+		replacement = DefaultReplacement
+	}
+	if r.Pattern == "" && len(r.Fields) == 0 && len(r.Kinds) == 0 && len(r.Annotations) == 0 {
+		return nil, errors.New("needs a pattern, fields, kinds or annotations")
+	}
+
+	var filters []Filter
+	if r.Pattern != "" {
+		f := &RegexReplacementFilter{name: hitName}
+		if err := f.AddPattern(r.Pattern, replacement); err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if len(r.Fields) == 0 && len(r.Kinds) == 0 && len(r.Annotations) == 0 {
+		return filters, nil
+	}
+
+	ff := &fieldFilter{name: hitName, replacement: replacement, annotations: r.Annotations}
+	for _, kind := range r.Kinds {
+		if ff.kinds == nil {
+			ff.kinds = map[string]bool{}
+		}
+		ff.kinds[kind] = true
+	}
+	var textKeys []string
+	for _, field := range r.Fields {
+		segments, anchored := parseFieldPath(field)
+		if len(segments) == 0 {
+			return nil, fmt.Errorf("invalid field %q", field)
+		}
+		for _, seg := range segments {
+			if _, err := path.Match(seg, ""); err != nil {
+				return nil, fmt.Errorf("invalid field %q: %w", field, err)
+			}
+		}
+		if !anchored && len(segments) == 1 {
+			ff.names = append(ff.names, segments[0])
+			textKeys = append(textKeys, segments[0])
+		} else {
+			ff.paths = append(ff.paths, segments)
+		}
+	}
+	for _, a := range r.Annotations {
+		if _, err := path.Match(a, ""); err != nil {
+			return nil, fmt.Errorf("invalid annotation %q: %w", a, err)
+		}
+		textKeys = append(textKeys, a)
+	}
+	// Kinds are unknown in plain text such as describe output, so only unscoped keys apply.
+	if len(ff.kinds) == 0 && len(textKeys) > 0 {
+		ff.text = textKeyPattern(textKeys)
+	}
+	return append(filters, ff), nil
+}
+
+// parseFieldPath splits "spec.auth.token", "$.spec.auth.token" or "$.items[*].spec" into
+// path segments; anchored reports a leading "$".
+func parseFieldPath(field string) ([]string, bool) {
+	field = strings.TrimSpace(field)
+	anchored := strings.HasPrefix(field, "$")
+	field = strings.TrimPrefix(strings.TrimPrefix(field, "$"), ".")
+	field = strings.NewReplacer("[*]", "", "[]", "").Replace(field)
+	var segments []string
+	for _, seg := range strings.Split(field, ".") {
+		if seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+	return segments, anchored || len(segments) > 1
+}
+
+// textKeyPattern matches "key: value" and "key=value" lines for the given key globs.
+func textKeyPattern(keys []string) *regexp.Regexp {
+	alts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		alts = append(alts, strings.ReplaceAll(regexp.QuoteMeta(key), `\*`, `[^\s:="']*`))
+	}
+	return regexp.MustCompile(`(?m)((?:^|\s)["']?(?:` + strings.Join(alts, "|") + `)["']?\s*[:=]\s*)\S.*$`)
+}
+
+// fieldFilter masks object fields and annotations by name in JSON and YAML documents, and
+// the same keys in plain text.
+type fieldFilter struct {
+	name        string
+	replacement string
+	kinds       map[string]bool
+	names       []string
+	paths       [][]string
+	annotations []string
+	text        *regexp.Regexp
+}
+
+// Process masks matching fields in a JSON or YAML document, or matching keys in plain text.
+func (f *fieldFilter) Process(input string) string {
+	return f.processAudit(input, nil)
+}
+
+// ProcessValue masks matching fields in decoded JSON.
+func (f *fieldFilter) ProcessValue(v any) any {
+	return f.processValueAudit(v, nil)
+}
+
+func (f *fieldFilter) processAudit(input string, h Hits) string {
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(input), &data); err == nil {
+		f.processValueAudit(data, h)
+		if out, err := json.Marshal(data); err == nil {
+			return string(out)
+		}
+		return input
+	}
+	if err := yaml.Unmarshal([]byte(input), &data); err == nil && data != nil {
+		f.processValueAudit(data, h)
+		if out, err := yaml.Marshal(data); err == nil {
+			return string(out)
+		}
+		return input
+	}
+	if f.text == nil {
+		return input
+	}
+	return replaceCounting(f.text, input, "${1}"+strings.ReplaceAll(f.replacement, "$", "$$"), h, f.name)
+}
+
+func (f *fieldFilter) processValueAudit(v any, h Hits) any {
+	f.redact(v, true, h)
+	return v
+}
+
+func (f *fieldFilter) redact(v any, root bool, h Hits) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		kind, isObject := t["kind"].(string)
+		if f.kinds == nil && (root || isObject) {
+			f.redactObject(t, root, h)
+		} else if f.kinds[kind] {
+			f.redactObject(t, true, h)
+		}
+		for _, child := range t {
+			f.redact(child, false, h)
+		}
+	case []interface{}:
+		for _, child := range t {
+			f.redact(child, root, h)
+		}
+	}
+}
+
+// redactObject masks the paths and annotations of obj, and with deep the named fields
+// anywhere below it.
+func (f *fieldFilter) redactObject(obj map[string]interface{}, deep bool, h Hits) {
+	if deep {
+		for _, name := range f.names {
+			f.maskName(obj, name, h)
+		}
+	}
+	for _, p := range f.paths {
+		f.maskPath(obj, p, h)
+	}
+	if len(f.annotations) > 0 {
+		if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+			if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+				for key, val := range annotations {
+					for _, glob := range f.annotations {
+						if ok, _ := path.Match(glob, key); ok {
+							annotations[key] = f.mask(val, h)
+							break
+						}
+					}
+				}
+			}
+		}
+	}
+	if len(f.names) == 0 && len(f.paths) == 0 && len(f.annotations) == 0 {
+		for _, p := range [][]string{{"data"}, {"stringData"}, {"spec", "encryptedData"}} {
+			f.maskPath(obj, p, h)
+		}
+	}
+}
+
+// maskName masks every field called name in v.
+func (f *fieldFilter) maskName(v any, name string, h Hits) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for key, child := range t {
+			if ok, _ := path.Match(name, key); ok {
+				t[key] = f.mask(child, h)
+				continue
+			}
+			f.maskName(child, name, h)
+		}
+	case []interface{}:
+		for _, child := range t {
+			f.maskName(child, name, h)
+		}
+	}
+}
+
+// maskPath masks the fields at segments below v; lists are traversed transparently.
+func (f *fieldFilter) maskPath(v any, segments []string, h Hits) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for key, child := range t {
+			if ok, _ := path.Match(segments[0], key); !ok {
+				continue
+			}
+			if len(segments) == 1 {
+				t[key] = f.mask(child, h)
+			} else {
+				f.maskPath(child, segments[1:], h)
+			}
+		}
+	case []interface{}:
+		for _, child := range t {
+			f.maskPath(child, segments, h)
+		}
+	}
+}
+
+// mask replaces every scalar in v; empty strings and allowlisted values are kept.
+func (f *fieldFilter) mask(v any, h Hits) any {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case string:
+		if t == "" || t == f.replacement || allowedValue.MatchString(t) {
+			return t
+		}
+		h.add(f.name, 1)
+		return f.replacement
+	case map[string]interface{}:
+		for key, child := range t {
+			t[key] = f.mask(child, h)
+		}
+		return t
+	case []interface{}:
+		for i, child := range t {
+			t[i] = f.mask(child, h)
+		}
+		return t
+	default:
+		h.add(f.name, 1)
+		return f.replacement
+	}
+}
+
+// Allowlisted text is swapped for placeholders while the wrapped filters run.
+var (
+	allowPlaceholder = regexp.MustCompile(`⟦allow:(\d+)⟧`)
+	allowedValue     = regexp.MustCompile(`^(?:⟦allow:\d+⟧)+$`)
+)
+
+// AllowFilter runs a filter with allowlisted text hidden from it, so rules never mask it.
+// Text a filter removes together with its surroundings, such as a password value, stays
+// removed.
+type AllowFilter struct {
+	inner Filter
+	allow []*regexp.Regexp
+}
+
+// NewAllowFilter wraps inner so that matches of allow pass through it unchanged.
+func NewAllowFilter(inner Filter, allow ...*regexp.Regexp) *AllowFilter {
+	return &AllowFilter{inner: inner, allow: allow}
+}
+
+// Process applies the wrapped filter to input, keeping allowlisted text.
+func (f *AllowFilter) Process(input string) string {
+	return f.processAudit(input, nil)
+}
+
+// ProcessValue applies the wrapped filter to decoded JSON, keeping allowlisted text.
+func (f *AllowFilter) ProcessValue(v any) any {
+	return f.processValueAudit(v, nil)
+}
+
+func (f *AllowFilter) processAudit(input string, h Hits) string {
+	p := &allowProtector{allow: f.allow}
+	return p.restore(processAudit(f.inner, p.protect(input), h))
+}
+
+func (f *AllowFilter) processValueAudit(v any, h Hits) any {
+	p := &allowProtector{allow: f.allow}
+	v = mapStrings(v, p.protect)
+	v = processValueAudit(f.inner, v, h)
+	return mapStrings(v, p.restore)
+}
+
+type allowProtector struct {
+	allow    []*regexp.Regexp
+	original []string
+}
+
+func (p *allowProtector) protect(s string) string {
+	for _, re := range p.allow {
+		s = re.ReplaceAllStringFunc(s, func(m string) string {
+			if m == "" || allowPlaceholder.MatchString(m) {
+				return m
+			}
+			p.original = append(p.original, m)
+			return "⟦allow:" + strconv.Itoa(len(p.original)-1) + "⟧"
+		})
+	}
+	return s
+}
+
+func (p *allowProtector) restore(s string) string {
+	return allowPlaceholder.ReplaceAllStringFunc(s, func(m string) string {
+		i, err := strconv.Atoi(allowPlaceholder.FindStringSubmatch(m)[1])
+		if err != nil || i >= len(p.original) {
+			return DefaultReplacement
+		}
+		return p.original[i]
+	})
+}
+
+// Normalize re-serializes a JSON or YAML document the way the filters do, so that it can be
+// compared line by line with their output. Plain text is returned unchanged.
+func Normalize(input string) string {
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(input), &data); err == nil {
+		if out, err := json.Marshal(data); err == nil {
+			return string(out)
+		}
+	} else if err := yaml.Unmarshal([]byte(input), &data); err == nil && data != nil {
+		if out, err := yaml.Marshal(data); err == nil {
+			return string(out)
+		}
+	}
+	return input
+}
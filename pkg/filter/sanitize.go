@@ -10,6 +10,10 @@ import (
 
 // SensitiveData hides sensitive data from Kubectl outputs
 func SensitiveData(input string) string {
+	return sensitiveData(input, nil)
+}
+
+func sensitiveData(input string, h Hits) string {
 	var data map[string]interface{}
 
 	// Try to parse as JSON, if fails, try YAML
@@ -20,36 +24,14 @@ func SensitiveData(input string) string {
 		inputType = "yaml"
 	} else {
 		// Handle plain text for describe commands
-		input = DescribeOutput(input)
+		input = maskEnvEntropy(describeOutput(input, h), h)
 		// Apply pattern filtering after handling describe output
-		return PatternSanitizer(input)
-	}
-
-	// Check if the kind is "Secret" or "ConfigMap"
-	if kind, ok := data["kind"].(string); ok {
-		// Check for data or stringData fields
-		for _, field := range []string{"data", "stringData"} {
-			if _, found := data[field]; found {
-				section := data[field].(map[string]interface{})
-				newSection := make(map[string]interface{})
-				for key, val := range section {
-					if strVal, ok := val.(string); ok && strVal != "" {
-						// For ConfigMaps, apply pattern sanitization instead of full filtering
-						if kind == "ConfigMap" {
-							newSection[key] = PatternSanitizer(strVal)
-						} else {
-							// For Secrets, completely filter the values
-							newSection[key] = "***FILTERED***"
-						}
-					} else {
-						newSection[key] = val
-					}
-				}
-				data[field] = newSection
-			}
-		}
+		return patternSanitizer(detectSecrets(input, h), h)
 	}
 
+	redactObject(data, h)
+	maskEntropyFields(data, h)
+
 	// Serialize back
 	var output []byte
 	var err error
@@ -60,15 +42,123 @@ func SensitiveData(input string) string {
 	}
 
 	if err != nil {
-		return PatternSanitizer(input) // Apply pattern filtering before returning original input
+		return patternSanitizer(detectSecrets(input, h), h) // Apply pattern filtering before returning original input
 	}
 
 	// Apply pattern filtering after handling structured data
-	return PatternSanitizer(string(output))
+	return patternSanitizer(detectSecrets(string(output), h), h)
+}
+
+// lastAppliedAnnotation holds the full manifest of an object applied with kubectl apply,
+// including the data of Secrets.
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// sensitiveKeyPattern matches field names whose values are credentials in structured data.
+var sensitiveKeyPattern = regexp.MustCompile(`(?i)^(password|passwd|secret|token|access[_-]?token|refresh[_-]?token|api[_-]?key|client[_-]?secret|private[_-]?key|credentials?)$`)
+
+// redactObject masks Secret data and sanitizes ConfigMap data in v and in every object
+// nested in it, such as the items of a List.
+func redactObject(v any, h Hits) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if kind, ok := t["kind"].(string); ok {
+			redactKindData(t, kind, h)
+		}
+		for _, child := range t {
+			redactObject(child, h)
+		}
+	case []interface{}:
+		for _, child := range t {
+			redactObject(child, h)
+		}
+	}
+}
+
+// redactKindData filters the data and stringData fields of Secrets and ConfigMaps.
+func redactKindData(obj map[string]interface{}, kind string, h Hits) {
+	for _, field := range []string{"data", "stringData"} {
+		if section, ok := obj[field].(map[string]interface{}); ok {
+			newSection := make(map[string]interface{})
+			for key, val := range section {
+				if strVal, ok := val.(string); ok && strVal != "" {
+					// For ConfigMaps, apply pattern sanitization instead of full filtering
+					if kind == "ConfigMap" {
+						newSection[key] = patternSanitizer(strVal, h)
+					} else {
+						// For Secrets, completely filter the values
+						newSection[key] = "***FILTERED***"
+						h.add("secret-data", 1)
+					}
+				} else {
+					newSection[key] = val
+				}
+			}
+			obj[field] = newSection
+		}
+	}
+	if kind != "Secret" {
+		return
+	}
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			if _, ok := annotations[lastAppliedAnnotation]; ok {
+				annotations[lastAppliedAnnotation] = "***FILTERED***"
+				h.add("secret-data", 1)
+			}
+		}
+	}
+}
+
+// SensitiveStructured redacts decoded JSON such as MCP structured tool content: Secret and
+// ConfigMap objects are handled like in SensitiveData, credential-named fields are masked
+// and every other string is checked by the detectors and pattern-sanitized.
+func SensitiveStructured(v any) any {
+	return sensitiveStructured(v, nil)
+}
+
+func sensitiveStructured(v any, h Hits) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var plain any
+	if err := json.Unmarshal(data, &plain); err != nil {
+		return v
+	}
+	redactObject(plain, h)
+	maskEntropyFields(plain, h)
+	return sanitizeStrings(plain, h)
+}
+
+func sanitizeStrings(v any, h Hits) any {
+	switch t := v.(type) {
+	case string:
+		return patternSanitizer(detectSecrets(t, h), h)
+	case map[string]interface{}:
+		for key, child := range t {
+			if s, ok := child.(string); ok && s != "" && sensitiveKeyPattern.MatchString(key) {
+				if s != "***FILTERED***" {
+					h.add("credential-field", 1)
+				}
+				t[key] = "***FILTERED***"
+				continue
+			}
+			t[key] = sanitizeStrings(child, h)
+		}
+	case []interface{}:
+		for i, child := range t {
+			t[i] = sanitizeStrings(child, h)
+		}
+	}
+	return v
 }
 
 // DescribeOutput filters sections from the kubectl describe output for Data section
 func DescribeOutput(input string) string {
+	return describeOutput(input, nil)
+}
+
+func describeOutput(input string, h Hits) string {
 	var isConfigHeader = func(lines []string, index int) bool {
 		if index+1 < len(lines) {
 			return strings.HasPrefix(strings.TrimSpace(lines[index]), "Name:") &&
@@ -97,6 +187,7 @@ func DescribeOutput(input string) string {
 		if edit && isSectionHeader(lines, i) && strings.HasPrefix(lines[i], "Data") {
 			// Append the field name, the '====', and the filter placeholder
 			filteredOutput = append(filteredOutput, lines[i], lines[i+1], "***FILTERED***", "")
+			h.add("describe-data", 1)
 			i += 2 // Move past the header and '===='
 			// Skip the content under the current header
 			for i < len(lines) && !isSectionHeader(lines, i) && !isConfigHeader(lines, i) {
@@ -112,63 +203,78 @@ func DescribeOutput(input string) string {
 	return strings.Join(filteredOutput, "\n")
 }
 
+// sanitizerPatterns are the common credential patterns masked by PatternSanitizer, named
+// as reported in Hits.
+var sanitizerPatterns = []struct {
+	name        string
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	// Passwords
+	{
+		"password",
+		regexp.MustCompile(`(?i)(password|passwd)\s*[=:]\s*[^\s]+`),
+		"$1=***FILTERED***",
+	},
+	// YAML formatted values (with or without quotes)
+	{
+		"password",
+		regexp.MustCompile(`(?i)(DB_PASS|password|passwd|secret):\s*['"]?([^'\"\s]+)['"]?`),
+		"$1: '***FILTERED***'",
+	},
+	// Usernames with credentials
+	{
+		"username",
+		regexp.MustCompile(`(?i)(username|user)\s*[=:]\s*[^\s]+`),
+		"$1=***FILTERED***",
+	},
+	// API keys and tokens
+	{
+		"api-key",
+		regexp.MustCompile(`(?i)(api[_-]?key|token|secret|auth)\s*[=:]\s*[^\s]+`),
+		"$1=***FILTERED***",
+	},
+	// Connection strings with underscore
+	{
+		"connection-string",
+		regexp.MustCompile(`(?i)(connection_string|conn_string|jdbc_url)\s*[=:]\s*[^\s]+`),
+		"$1=***FILTERED***",
+	},
+	// Connection strings with space
+	{
+		"connection-string",
+		regexp.MustCompile(`(?i)(connection|conn)\s*[=:]\s*[^\s]+`),
+		"$1=***FILTERED***",
+	},
+	// Generic credential pattern
+	{
+		"credential",
+		regexp.MustCompile(`(?i)(credential|cred)\s*[=:]\s*[^\s]+`),
+		"$1=***FILTERED***",
+	},
+	// Bearer tokens
+	{
+		"bearer-token",
+		regexp.MustCompile(`(?i)(bearer)\s+[a-zA-Z0-9_\-\.]+`),
+		"$1 ***FILTERED***",
+	},
+	// Basic auth
+	{
+		"basic-auth",
+		regexp.MustCompile(`(?i)(basic)\s+[a-zA-Z0-9+/=]+`),
+		"$1 ***FILTERED***",
+	},
+}
+
 // PatternSanitizer filters sensitive information based on common patterns
 func PatternSanitizer(input string) string {
-	// Define patterns for sensitive information with their replacements
-	patternReplacements := []struct {
-		pattern     *regexp.Regexp
-		replacement string
-	}{
-		// Passwords
-		{
-			regexp.MustCompile(`(?i)(password|passwd)\s*[=:]\s*[^\s]+`),
-			"$1=***FILTERED***",
-		},
-		// YAML formatted values (with or without quotes)
-		{
-			regexp.MustCompile(`(?i)(DB_PASS|password|passwd|secret):\s*['"]?([^'\"\s]+)['"]?`),
-			"$1: '***FILTERED***'",
-		},
-		// Usernames with credentials
-		{
-			regexp.MustCompile(`(?i)(username|user)\s*[=:]\s*[^\s]+`),
-			"$1=***FILTERED***",
-		},
-		// API keys and tokens
-		{
-			regexp.MustCompile(`(?i)(api[_-]?key|token|secret|auth)\s*[=:]\s*[^\s]+`),
-			"$1=***FILTERED***",
-		},
-		// Connection strings with underscore
-		{
-			regexp.MustCompile(`(?i)(connection_string|conn_string|jdbc_url)\s*[=:]\s*[^\s]+`),
-			"$1=***FILTERED***",
-		},
-		// Connection strings with space
-		{
-			regexp.MustCompile(`(?i)(connection|conn)\s*[=:]\s*[^\s]+`),
-			"$1=***FILTERED***",
-		},
-		// Generic credential pattern
-		{
-			regexp.MustCompile(`(?i)(credential|cred)\s*[=:]\s*[^\s]+`),
-			"$1=***FILTERED***",
-		},
-		// Bearer tokens
-		{
-			regexp.MustCompile(`(?i)(bearer)\s+[a-zA-Z0-9_\-\.]+`),
-			"$1 ***FILTERED***",
-		},
-		// Basic auth
-		{
-			regexp.MustCompile(`(?i)(basic)\s+[a-zA-Z0-9+/=]+`),
-			"$1 ***FILTERED***",
-		},
-	}
+	return patternSanitizer(input, nil)
+}
 
+func patternSanitizer(input string, h Hits) string {
 	result := input
-	for _, pr := range patternReplacements {
-		result = pr.pattern.ReplaceAllString(result, pr.replacement)
+	for _, pr := range sanitizerPatterns {
+		result = replaceCounting(pr.pattern, result, pr.replacement, h, pr.name)
 	}
 
 	return result
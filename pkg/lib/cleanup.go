@@ -6,6 +6,7 @@ import (
 
 	"github.com/mikhae1/kubectl-quackops/pkg/config"
 	"github.com/mikhae1/kubectl-quackops/pkg/llm/metadata"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
 )
 
 // CleanupOptions defines options for CleanupAndExit behavior.
@@ -44,6 +45,10 @@ func showCostEstimation(cfg *config.Config) {
 	if cfg == nil || cfg.Model == "" {
 		return
 	}
+	if usedModels(cfg.ModelUsage) > 1 {
+		showTaskCostEstimation(cfg)
+		return
+	}
 
 	metadataService := metadata.NewMetadataService(cfg.ModelMetadataTimeout, cfg.ModelMetadataCacheTTL)
 	baseURL := config.GetProviderBaseURL(cfg)
@@ -66,9 +71,11 @@ func showCostEstimation(cfg *config.Config) {
 
 	inputTokens := cfg.SessionOutgoingTokens
 	outputTokens := cfg.SessionIncomingTokens
+	cachedTokens := cfg.SessionCachedTokens
 	if inputTokens == 0 && outputTokens == 0 {
 		inputTokens = cfg.LastOutgoingTokens
 		outputTokens = cfg.LastIncomingTokens
+		cachedTokens = cfg.LastCachedTokens
 	}
 
 	summary := CalculateTotalCost(
@@ -78,6 +85,7 @@ func showCostEstimation(cfg *config.Config) {
 		currentModel.OutputPrice,
 		cfg.Model,
 	)
+	summary.ApplyCachedInput(cachedTokens, CachedInputPrice(cfg.Provider, currentModel))
 
 	costDisplay := FormatTotalCostDisplay(summary)
 	if costDisplay != "" {
@@ -86,3 +94,52 @@ func showCostEstimation(cfg *config.Config) {
 		fmt.Println()
 	}
 }
+
+// usedModels counts the distinct provider/model pairs in the session's usage.
+func usedModels(usage []config.ModelUsage) int {
+	seen := map[string]bool{}
+	for _, u := range usage {
+		seen[u.Provider+"/"+u.Model] = true
+	}
+	return len(seen)
+}
+
+// showTaskCostEstimation prices each task's tokens with the model that served them.
+func showTaskCostEstimation(cfg *config.Config) {
+	metadataService := metadata.NewMetadataService(cfg.ModelMetadataTimeout, cfg.ModelMetadataCacheTTL)
+	modelLists := map[string][]*metadata.ModelMetadata{}
+
+	var summaries []*CostSummary
+	for _, u := range cfg.ModelUsage {
+		models, fetched := modelLists[u.Provider]
+		if !fetched {
+			if p, ok := provider.Get(u.Provider); ok {
+				opts := cfg.ProviderOptions()
+				opts.Model = u.Model
+				models, _ = metadataService.GetModelList(u.Provider, p.BaseURL(opts))
+			}
+			modelLists[u.Provider] = models
+		}
+
+		var inputPrice, outputPrice float64
+		var model *metadata.ModelMetadata
+		for _, m := range models {
+			if m.ID == u.Model {
+				model = m
+				inputPrice, outputPrice = m.InputPrice, m.OutputPrice
+				break
+			}
+		}
+		summary := CalculateTotalCost(u.InputTokens, u.OutputTokens, inputPrice, outputPrice, u.Provider+"/"+u.Model)
+		summary.ApplyCachedInput(u.CachedTokens, CachedInputPrice(u.Provider, model))
+		summary.Task = u.Task
+		summaries = append(summaries, summary)
+	}
+
+	costDisplay := FormatTaskCostDisplay(summaries)
+	if costDisplay != "" {
+		fmt.Println()
+		fmt.Println(costDisplay)
+		fmt.Println()
+	}
+}
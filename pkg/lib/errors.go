@@ -110,6 +110,31 @@ func IsRetryableError(err error) bool {
 	return true
 }
 
+// IsContextLengthError reports whether the provider rejected the request because the
+// prompt does not fit the model's context window.
+func IsContextLengthError(err error) bool {
+	if err == nil {
+		return false
+	}
+	es := strings.ToLower(err.Error())
+	markers := []string{
+		"context length exceeded",
+		"context_length_exceeded",
+		"context window exceeded",
+		"maximum context length",
+		"prompt is too long",
+		"input is too long",
+		"exceeds the context window",
+		"too many tokens",
+	}
+	for _, marker := range markers {
+		if strings.Contains(es, marker) {
+			return true
+		}
+	}
+	return false
+}
+
 // ParseRetryDelay attempts to extract retry delay from 429 error messages
 // Returns the parsed delay and nil error on success, or zero duration and error on failure
 func ParseRetryDelay(err error) (time.Duration, error) {
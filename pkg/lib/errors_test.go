@@ -109,6 +109,24 @@ func TestIsRetryableError(t *testing.T) {
 	}
 }
 
+func TestIsContextLengthError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("This model's maximum context length is 128000 tokens"), true},
+		{errors.New(`400 {"error":{"code":"context_length_exceeded"}}`), true},
+		{errors.New("prompt is too long: 210000 tokens > 200000 maximum"), true},
+		{errors.New("503 Service Unavailable"), false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := IsContextLengthError(tt.err); got != tt.want {
+			t.Errorf("IsContextLengthError(%v) = %t, want %t", tt.err, got, tt.want)
+		}
+	}
+}
+
 func TestHTTPStatusCode(t *testing.T) {
 	if code := HTTPStatusCode(errors.New("request failed: 502 bad gateway")); code != 502 {
 		t.Fatalf("expected 502, got %d", code)
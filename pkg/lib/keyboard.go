@@ -2,6 +2,8 @@ package lib
 
 import (
 	"os"
+	"slices"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -17,11 +19,95 @@ import (
 // Ctrl-T triggers the onCtrlT callback if provided (for toggling spinner details).
 // Returns a stop function that restores terminal state and stops the watcher.
 // The stop function blocks until the watcher goroutine has fully exited.
+// Watchers nest: starting one pauses the watcher already running until the new one stops,
+// so only one of them reads stdin at a time.
 func StartEscWatcher(cancel func(), spinnerManager *SpinnerManager, cfg *config.Config, onCtrlR func(), onCtrlT func()) func() {
-	fd := int(os.Stdin.Fd())
-	if !term.IsTerminal(fd) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return func() {}
+	}
+	return pushEscWatcher(func() func() {
+		return startRawEscWatcher(cancel, spinnerManager, cfg, onCtrlR, onCtrlT)
+	})
+}
+
+// pushEscWatcher starts a watcher with start on top of the watcher stack and returns its
+// stop function.
+func pushEscWatcher(start func() func()) func() {
+	w := &escWatcher{start: start}
+	escWatchers.mu.Lock()
+	if n := len(escWatchers.stack); n > 0 {
+		escWatchers.stack[n-1].suspend()
+	}
+	w.resume()
+	escWatchers.stack = append(escWatchers.stack, w)
+	escWatchers.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			escWatchers.mu.Lock()
+			defer escWatchers.mu.Unlock()
+			w.suspend()
+			idx := slices.Index(escWatchers.stack, w)
+			if idx < 0 {
+				return
+			}
+			top := idx == len(escWatchers.stack)-1
+			escWatchers.stack = slices.Delete(escWatchers.stack, idx, idx+1)
+			if n := len(escWatchers.stack); top && n > 0 {
+				escWatchers.stack[n-1].resume()
+			}
+		})
+	}
+}
+
+// escWatchers is the stack of started watchers; only the top one reads stdin.
+var escWatchers struct {
+	mu    sync.Mutex
+	stack []*escWatcher
+}
+
+type escWatcher struct {
+	start func() func()
+	stop  func() // nil while suspended
+}
+
+func (w *escWatcher) suspend() {
+	if w.stop != nil {
+		w.stop()
+		w.stop = nil
+	}
+}
+
+func (w *escWatcher) resume() {
+	if w.stop == nil {
+		w.stop = w.start()
+	}
+}
+
+// SuspendEscWatcher pauses the running ESC watcher, if any, so a prompt can read stdin,
+// and returns a func that restarts it.
+func SuspendEscWatcher() (resume func()) {
+	escWatchers.mu.Lock()
+	defer escWatchers.mu.Unlock()
+	n := len(escWatchers.stack)
+	if n == 0 || escWatchers.stack[n-1].stop == nil {
 		return func() {}
 	}
+	w := escWatchers.stack[n-1]
+	w.suspend()
+	return func() {
+		escWatchers.mu.Lock()
+		defer escWatchers.mu.Unlock()
+		if n := len(escWatchers.stack); n > 0 && escWatchers.stack[n-1] == w {
+			w.resume()
+		}
+	}
+}
+
+// startRawEscWatcher runs one watcher goroutine for StartEscWatcher.
+func startRawEscWatcher(cancel func(), spinnerManager *SpinnerManager, cfg *config.Config, onCtrlR func(), onCtrlT func()) func() {
+	fd := int(os.Stdin.Fd())
 
 	oldState, err := term.MakeRaw(fd)
 	if err != nil {
@@ -0,0 +1,40 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEscWatcherStack(t *testing.T) {
+	var events []string
+	fake := func(name string) func() func() {
+		return func() func() {
+			events = append(events, "start "+name)
+			return func() { events = append(events, "stop "+name) }
+		}
+	}
+
+	stopOuter := pushEscWatcher(fake("outer"))
+	stopInner := pushEscWatcher(fake("inner"))
+	resume := SuspendEscWatcher()
+	SuspendEscWatcher()() // nested prompt: nothing left to pause
+	resume()
+	stopInner()
+	stopInner() // idempotent
+	stopOuter()
+	SuspendEscWatcher()() // no watcher running
+
+	expected := []string{
+		"start outer",
+		"stop outer", "start inner", // the inner watcher takes over stdin
+		"stop inner", "start inner", // a prompt pauses it
+		"stop inner", "start outer", // the outer one resumes when the inner one stops
+		"stop outer",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("events = %v, want %v", events, expected)
+	}
+	if len(escWatchers.stack) != 0 {
+		t.Errorf("expected an empty watcher stack, got %d", len(escWatchers.stack))
+	}
+}
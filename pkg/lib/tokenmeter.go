@@ -9,11 +9,13 @@ import (
 )
 
 // TokenMeter renders a compact real-time token counter to stderr.
-// It shows outgoing (prompt/history) and incoming (streamed) token counts using arrows.
+// It shows outgoing (prompt/history) and incoming (streamed) token counts using arrows,
+// plus the prompt tokens the provider served from its prompt cache.
 type TokenMeter struct {
 	cfg          *config.Config
 	outgoing     int
 	incoming     AtomicInt
+	cached       AtomicInt
 	mu           sync.Mutex
 	lastRendered string
 }
@@ -40,6 +42,14 @@ func (tm *TokenMeter) AddIncomingSilent(delta int) {
 	tm.incoming.Add(delta)
 }
 
+// SetCached records the prompt tokens read from the provider's prompt cache.
+func (tm *TokenMeter) SetCached(n int) {
+	tm.cached.Set(n)
+}
+
+// Cached returns the prompt tokens read from the provider's prompt cache.
+func (tm *TokenMeter) Cached() int { return tm.cached.Load() }
+
 // Outgoing returns the initial outgoing token count.
 func (tm *TokenMeter) Outgoing() int { return tm.outgoing }
 
@@ -81,8 +91,12 @@ func (tm *TokenMeter) Render() {
 
 	outNum := config.Colors.TokenOut.Sprint(FormatCompactNumber(tm.outgoing))
 	inNum := config.Colors.TokenIn.Sprint(FormatCompactNumber(tm.incoming.Load()))
-	// Compact form: [3%|↑2.9k|↓2.0k]
-	line := fmt.Sprintf("%s%s%s%s%s%s%s", leftBracket, pctStr, sep, up+outNum, sep, down+inNum, rightBracket)
+	// Compact form: [3%|↑2.9k|↓2.0k], with |⚡1.8k when part of the prompt was cached
+	line := fmt.Sprintf("%s%s%s%s%s%s", leftBracket, pctStr, sep, up+outNum, sep, down+inNum)
+	if cached := tm.cached.Load(); cached > 0 {
+		line += sep + config.Colors.TokenOut.Sprint("⚡"+FormatCompactNumber(cached))
+	}
+	line += rightBracket
 
 	// Erase line and rewrite on stderr only
 	fmt.Fprint(os.Stderr, "\r\033[2K")
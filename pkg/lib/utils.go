@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/metadata"
 	"golang.org/x/sys/unix"
 	"golang.org/x/term"
 )
@@ -164,7 +165,9 @@ func FormatPricingInfo(promptPrice, completionPrice float64, detailed bool) stri
 // ConfirmWithSingleKey prompts the user and returns true only for 'y'/'Y'.
 // It accepts ESC as an immediate "no" without requiring Enter. Enter defaults to "no".
 // Falls back to line mode if raw mode is unavailable.
+// A running ESC watcher is paused while it waits.
 func ConfirmWithSingleKey(prompt string) bool {
+	defer SuspendEscWatcher()()
 	fmt.Print(prompt)
 
 	fd := int(os.Stdin.Fd())
@@ -199,7 +202,9 @@ func ConfirmWithSingleKey(prompt string) bool {
 
 // ReadSingleKey prints a prompt and returns the first key pressed (raw mode if possible).
 // Returns lowercase letter for alphabetic keys. ESC is returned as byte 27.
+// A running ESC watcher is paused while it waits.
 func ReadSingleKey(prompt string) byte {
+	defer SuspendEscWatcher()()
 	fmt.Print(prompt)
 
 	fd := int(os.Stdin.Fd())
@@ -237,7 +242,9 @@ func ReadSingleKey(prompt string) byte {
 // ReadKey reads a single key or ANSI escape sequence (e.g., arrow keys) in raw mode and
 // returns a normalized code string: "up", "down", "left", "right", "enter", "esc",
 // "space", or a lowercased single-letter string (e.g., "y", "n", "e", "j", "k", "a").
+// A running ESC watcher is paused while it waits.
 func ReadKey(prompt string) string {
+	defer SuspendEscWatcher()()
 	if prompt != "" {
 		fmt.Print(prompt)
 	}
@@ -370,6 +377,14 @@ type CostSummary struct {
 	TotalCost      float64 // Total cost in USD
 	ModelID        string
 	HasPricingData bool
+
+	// Prompt tokens served from the provider's prompt cache; part of InputTokens but
+	// billed at CachedInputPrice instead of InputPrice
+	CachedInputTokens int
+	CachedInputPrice  float64 // USD per token
+	CachedInputCost   float64 // Cached input cost in USD
+
+	Task string // Task type the tokens were spent on, for per-task breakdowns
 }
 
 // CalculateTotalCost calculates the total cost for a session given token counts and per-token prices
@@ -392,6 +407,71 @@ func CalculateTotalCost(inputTokens, outputTokens int, inputPrice, outputPrice f
 	return summary
 }
 
+// ApplyCachedInput bills cachedTokens of the summary's input at cachedPrice and the rest at
+// the regular input price.
+func (s *CostSummary) ApplyCachedInput(cachedTokens int, cachedPrice float64) {
+	if cachedTokens <= 0 {
+		return
+	}
+	if cachedTokens > s.InputTokens {
+		cachedTokens = s.InputTokens
+	}
+	s.CachedInputTokens = cachedTokens
+	s.CachedInputPrice = cachedPrice
+	if s.HasPricingData {
+		s.InputCost = float64(s.InputTokens-cachedTokens) * s.InputPrice
+		s.CachedInputCost = float64(cachedTokens) * cachedPrice
+		s.TotalCost = s.InputCost + s.CachedInputCost + s.OutputCost
+	}
+}
+
+// cachedInputDiscount is the share of the input price providers charge for prompt cache
+// reads, used when model metadata does not publish a cached price.
+var cachedInputDiscount = map[string]float64{
+	"anthropic": 0.1,
+	"openai":    0.5,
+	"azopenai":  0.5,
+	"google":    0.25,
+}
+
+// CachedInputPrice returns the USD per token price of cached prompt tokens for a model.
+func CachedInputPrice(providerName string, model *metadata.ModelMetadata) float64 {
+	if model == nil {
+		return 0
+	}
+	if model.CachedInputPrice > 0 {
+		return model.CachedInputPrice
+	}
+	if ratio, ok := cachedInputDiscount[providerName]; ok {
+		return model.InputPrice * ratio
+	}
+	return model.InputPrice
+}
+
+// formatCostColored formats a USD amount, colored by how expensive it is
+func formatCostColored(cost float64) string {
+	var costStr string
+	if cost >= 0.001 {
+		costStr = fmt.Sprintf("$%.3f", cost)
+	} else if cost >= 0.0001 {
+		costStr = fmt.Sprintf("$%.4f", cost)
+	} else if cost >= 0.00001 {
+		costStr = fmt.Sprintf("$%.5f", cost)
+	} else if cost > 0 {
+		costStr = fmt.Sprintf("$%.6f", cost)
+	} else {
+		costStr = "$0.000"
+	}
+
+	if cost > 0.10 { // > $0.10
+		return config.Colors.Error.Sprint(costStr)
+	} else if cost > 0.01 { // > $0.01
+		return config.Colors.Warn.Sprint(costStr)
+	} else {
+		return config.Colors.Primary.Sprint(costStr)
+	}
+}
+
 // FormatTotalCostDisplay creates a pretty formatted cost estimation display block
 func FormatTotalCostDisplay(summary *CostSummary) string {
 	if summary == nil || (!summary.HasPricingData || (summary.InputTokens == 0 && summary.OutputTokens == 0)) {
@@ -405,30 +485,6 @@ func FormatTotalCostDisplay(summary *CostSummary) string {
 	outputArrow := config.Colors.Accent.Sprint("↓")
 	dim := config.Colors.Dim
 
-	// Format costs with color based on amount
-	formatCostColored := func(cost float64) string {
-		var costStr string
-		if cost >= 0.001 {
-			costStr = fmt.Sprintf("$%.3f", cost)
-		} else if cost >= 0.0001 {
-			costStr = fmt.Sprintf("$%.4f", cost)
-		} else if cost >= 0.00001 {
-			costStr = fmt.Sprintf("$%.5f", cost)
-		} else if cost > 0 {
-			costStr = fmt.Sprintf("$%.6f", cost)
-		} else {
-			costStr = "$0.000"
-		}
-
-		if cost > 0.10 { // > $0.10
-			return config.Colors.Error.Sprint(costStr)
-		} else if cost > 0.01 { // > $0.01
-			return config.Colors.Warn.Sprint(costStr)
-		} else {
-			return config.Colors.Primary.Sprint(costStr)
-		}
-	}
-
 	var lines []string
 	boxWidth := 45
 
@@ -443,8 +499,8 @@ func FormatTotalCostDisplay(summary *CostSummary) string {
 	}
 
 	// Input cost line
-	if summary.InputTokens > 0 {
-		tokenCount := FormatCompactNumber(summary.InputTokens)
+	if uncached := summary.InputTokens - summary.CachedInputTokens; uncached > 0 {
+		tokenCount := FormatCompactNumber(uncached)
 		priceStr := FormatPrice(summary.InputPrice * 1_000_000) // Convert to per-1M format
 		costStr := formatCostColored(summary.InputCost)
 
@@ -460,6 +516,22 @@ func FormatTotalCostDisplay(summary *CostSummary) string {
 		lines = append(lines, line)
 	}
 
+	// Cached input cost line
+	if summary.CachedInputTokens > 0 {
+		tokenCount := FormatCompactNumber(summary.CachedInputTokens)
+		priceStr := FormatPrice(summary.CachedInputPrice * 1_000_000)
+		costStr := formatCostColored(summary.CachedInputCost)
+
+		plainLine := fmt.Sprintf("⚡ Cached: %s tokens × %s → $%.6f", tokenCount, priceStr, summary.CachedInputCost)
+		contentPadding := boxWidth - 2 - len(plainLine)
+		if contentPadding < 0 {
+			contentPadding = 0
+		}
+
+		line := "│ " + config.Colors.Info.Sprint("⚡") + fmt.Sprintf(" Cached: %s tokens × %s → ", tokenCount, priceStr) + costStr + strings.Repeat(" ", contentPadding)
+		lines = append(lines, line)
+	}
+
 	// Output cost line
 	if summary.OutputTokens > 0 {
 		tokenCount := FormatCompactNumber(summary.OutputTokens)
@@ -489,6 +561,45 @@ func FormatTotalCostDisplay(summary *CostSummary) string {
 	return strings.Join(lines, "\n")
 }
 
+// FormatTaskCostDisplay creates the cost estimation block for sessions that used several
+// models, with one line per task and model
+func FormatTaskCostDisplay(summaries []*CostSummary) string {
+	var total float64
+	priced := false
+	for _, s := range summaries {
+		if s != nil && s.HasPricingData {
+			total += s.TotalCost
+			priced = true
+		}
+	}
+	if !priced {
+		return ""
+	}
+
+	borderText := config.Colors.Dim.Sprint("│ ")
+	lines := []string{borderText + config.Colors.Accent.Sprint("Session Cost Estimate")}
+	for _, s := range summaries {
+		if s == nil || (s.InputTokens == 0 && s.OutputTokens == 0) {
+			continue
+		}
+		tokens := config.Colors.Info.Sprint("↑") + FormatCompactNumber(s.InputTokens) + " " +
+			config.Colors.Accent.Sprint("↓") + FormatCompactNumber(s.OutputTokens)
+		if s.CachedInputTokens > 0 {
+			tokens += " " + config.Colors.Info.Sprint("⚡") + FormatCompactNumber(s.CachedInputTokens)
+		}
+		cost := config.Colors.Dim.Sprint("n/a")
+		if s.HasPricingData {
+			cost = formatCostColored(s.TotalCost)
+		}
+		lines = append(lines, fmt.Sprintf("%s%s %s %s → %s", borderText,
+			config.Colors.Info.Sprintf("%-10s", s.Task), config.Colors.Model.Sprint(s.ModelID), tokens, cost))
+	}
+	lines = append(lines, borderText+config.Colors.Info.Sprint("Total: ")+formatCostColored(total))
+	lines = append(lines, borderText+config.Colors.Dim.Sprint("(Estimate - actual costs may vary)"))
+
+	return strings.Join(lines, "\n")
+}
+
 // getTerminalWidth detects the terminal width, defaulting to 80 if detection fails
 func getTerminalWidth() int {
 	fd := int(os.Stdout.Fd())
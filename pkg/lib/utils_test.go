@@ -1,8 +1,12 @@
 package lib
 
 import (
+	"fmt"
 	"math"
+	"strings"
 	"testing"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/metadata"
 )
 
 func TestCosineSimilarity(t *testing.T) {
@@ -152,3 +156,80 @@ func TestCosineSimilarityMatrix(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatTaskCostDisplay(t *testing.T) {
+	commands := CalculateTotalCost(2000, 100, 1e-7, 4e-7, "openai/gpt-5-nano")
+	commands.Task = "commands"
+	answer := CalculateTotalCost(10000, 1500, 3e-6, 15e-6, "anthropic/claude-sonnet-4-5")
+	answer.Task = "answer"
+	unpriced := CalculateTotalCost(500, 50, 0, 0, "ollama/llama3.1")
+	unpriced.Task = "compaction"
+
+	out := FormatTaskCostDisplay([]*CostSummary{commands, answer, unpriced})
+	for _, want := range []string{"commands", "openai/gpt-5-nano", "answer", "anthropic/claude-sonnet-4-5", "compaction", "n/a", "Total: "} {
+		if !strings.Contains(out, want) {
+			t.Errorf("display missing %q:\n%s", want, out)
+		}
+	}
+	if total := fmt.Sprintf("$%.3f", commands.TotalCost+answer.TotalCost); !strings.Contains(out, total) {
+		t.Errorf("display missing total %s:\n%s", total, out)
+	}
+
+	if out := FormatTaskCostDisplay([]*CostSummary{unpriced}); out != "" {
+		t.Errorf("expected no display without pricing, got %q", out)
+	}
+}
+
+func TestCostSummaryApplyCachedInput(t *testing.T) {
+	testCases := []struct {
+		name        string
+		cached      int
+		wantCached  int
+		wantInput   float64
+		wantTotal   float64
+		cachedPrice float64
+	}{
+		{name: "no cache", cached: 0, wantCached: 0, wantInput: 1000e-6, wantTotal: 1000e-6 + 100e-5, cachedPrice: 1e-7},
+		{name: "partial cache", cached: 800, wantCached: 800, wantInput: 200e-6, wantTotal: 200e-6 + 800e-7 + 100e-5, cachedPrice: 1e-7},
+		{name: "clamped to input", cached: 5000, wantCached: 1000, wantInput: 0, wantTotal: 1000e-7 + 100e-5, cachedPrice: 1e-7},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			summary := CalculateTotalCost(1000, 100, 1e-6, 1e-5, "model")
+			summary.ApplyCachedInput(tc.cached, tc.cachedPrice)
+			if summary.CachedInputTokens != tc.wantCached {
+				t.Errorf("CachedInputTokens = %d, want %d", summary.CachedInputTokens, tc.wantCached)
+			}
+			if math.Abs(summary.InputCost-tc.wantInput) > 1e-12 {
+				t.Errorf("InputCost = %g, want %g", summary.InputCost, tc.wantInput)
+			}
+			if math.Abs(summary.TotalCost-tc.wantTotal) > 1e-12 {
+				t.Errorf("TotalCost = %g, want %g", summary.TotalCost, tc.wantTotal)
+			}
+		})
+	}
+}
+
+func TestCachedInputPrice(t *testing.T) {
+	testCases := []struct {
+		name     string
+		provider string
+		model    *metadata.ModelMetadata
+		expected float64
+	}{
+		{name: "published price", provider: "openrouter", model: &metadata.ModelMetadata{InputPrice: 3e-6, CachedInputPrice: 3e-7}, expected: 3e-7},
+		{name: "anthropic discount", provider: "anthropic", model: &metadata.ModelMetadata{InputPrice: 3e-6}, expected: 3e-7},
+		{name: "google discount", provider: "google", model: &metadata.ModelMetadata{InputPrice: 4e-6}, expected: 1e-6},
+		{name: "unknown provider", provider: "ollama", model: &metadata.ModelMetadata{InputPrice: 1e-6}, expected: 1e-6},
+		{name: "no metadata", provider: "openai", expected: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := CachedInputPrice(tc.provider, tc.model); math.Abs(got-tc.expected) > 1e-15 {
+				t.Errorf("CachedInputPrice() = %g, want %g", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/budget"
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/lib"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/metadata"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/mikhae1/kubectl-quackops/pkg/logger"
+	"golang.org/x/term"
+)
+
+// ErrBudgetExceeded is returned when a request is refused because it would exceed a budget.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// ledgerRetention is how long spend ledger entries are kept.
+const ledgerRetention = 7 * budget.DayWindow
+
+var (
+	pricingCache sync.Map // provider/model -> *metadata.ModelMetadata, nil when unpriced
+	ledgerPrune  sync.Once
+)
+
+// modelPricing returns the pricing metadata of a model, or nil when the provider publishes
+// none. Lookups are cached for the lifetime of the process.
+var modelPricing = func(cfg *config.Config, providerName, model string) *metadata.ModelMetadata {
+	key := modelRef(providerName, model)
+	if v, ok := pricingCache.Load(key); ok {
+		return v.(*metadata.ModelMetadata)
+	}
+	var found *metadata.ModelMetadata
+	if p, ok := provider.Get(providerName); ok && !cfg.Cassette.Replaying() {
+		opts := cfg.ProviderOptions()
+		opts.Model = model
+		ms := metadata.NewMetadataService(cfg.ModelMetadataTimeout, cfg.ModelMetadataCacheTTL)
+		if models, err := ms.GetModelList(providerName, p.BaseURL(opts)); err == nil {
+			for _, m := range models {
+				if m.ID == model {
+					found = m
+					break
+				}
+			}
+		}
+	}
+	pricingCache.Store(key, found)
+	return found
+}
+
+// requestCost estimates the USD cost of a request; it is 0 for models without pricing.
+func requestCost(cfg *config.Config, providerName, model string, input, output, cached int) float64 {
+	m := modelPricing(cfg, providerName, model)
+	if m == nil {
+		return 0
+	}
+	summary := lib.CalculateTotalCost(input, output, m.InputPrice, m.OutputPrice, model)
+	summary.ApplyCachedInput(cached, lib.CachedInputPrice(providerName, m))
+	return summary.TotalCost
+}
+
+func sessionSpend(cfg *config.Config) budget.Spend {
+	return budget.Spend{USD: cfg.SessionCostUSD, Tokens: cfg.SessionOutgoingTokens + cfg.SessionIncomingTokens}
+}
+
+// dailySpend sums the ledger over the rolling day, dropping expired entries on first use.
+func dailySpend(cfg *config.Config) budget.Spend {
+	if cfg.LedgerFile == "" {
+		return budget.Spend{}
+	}
+	ledger := budget.NewLedger(cfg.LedgerFile)
+	ledgerPrune.Do(func() {
+		if err := ledger.Prune(time.Now().Add(-ledgerRetention)); err != nil {
+			logger.Log("warn", "[Budget] Failed to prune ledger: %v", err)
+		}
+	})
+	spend, err := ledger.Since(time.Now().Add(-budget.DayWindow))
+	if err != nil {
+		logger.Log("warn", "[Budget] Failed to read ledger: %v", err)
+	}
+	return spend
+}
+
+// enforceBudget checks the request about to be sent against the configured budgets. When
+// it would exceed one, BudgetAction decides whether to ask, switch to the downgrade model
+// or refuse. It returns a func that restores the model after a downgrade.
+func enforceBudget(cfg *config.Config, inputTokens int) (func(), error) {
+	noop := func() {}
+	limits := cfg.BudgetLimits()
+	if limits.IsZero() {
+		return noop, nil
+	}
+	estimate := func() budget.Spend {
+		output := cfg.GenerationFor(cfg.ActiveTask).MaxOutputTokens
+		if output <= 0 {
+			output = cfg.MinOutputTokens
+		}
+		return budget.Spend{
+			USD:    requestCost(cfg, cfg.Provider, cfg.Model, inputTokens, output, 0),
+			Tokens: inputTokens + output,
+		}
+	}
+
+	session, day := sessionSpend(cfg), dailySpend(cfg)
+	check := func() (string, bool) {
+		if unpricedBudget(cfg, limits) {
+			return fmt.Sprintf("USD budget cannot be checked: %s has no published pricing", modelRef(cfg.Provider, cfg.Model)), true
+		}
+		status, over := limits.Check(session, day, estimate())
+		return status.String() + " would be exceeded", over
+	}
+	problem, over := check()
+	if !over {
+		return noop, nil
+	}
+	logger.Log("warn", "[Budget] %s/%s: %s", cfg.Provider, cfg.Model, problem)
+
+	action := cfg.BudgetAction
+	if action == config.BudgetActionPrompt {
+		action = promptBudgetAction(cfg, problem)
+	}
+	switch action {
+	case "":
+		return noop, nil
+	case config.BudgetActionDowngrade:
+		from := modelRef(cfg.Provider, cfg.Model)
+		restore, ok := useBudgetDowngrade(cfg)
+		if !ok {
+			return noop, fmt.Errorf("%w: %s and no cheaper model is configured", ErrBudgetExceeded, problem)
+		}
+		if problem, over := check(); over {
+			restore()
+			return noop, fmt.Errorf("%w: %s even with %s", ErrBudgetExceeded, problem, cfg.BudgetDowngradeModel)
+		}
+		lib.GetSpinnerManager(cfg).Hide()
+		fmt.Printf("%s %s %s %s\n",
+			config.Colors.Warn.Sprint("⚠ Budget: switching model:"),
+			config.Colors.Dim.Sprint(from),
+			config.Colors.Dim.Sprint("→"),
+			config.Colors.Model.Sprint(modelRef(cfg.Provider, cfg.Model)))
+		return restore, nil
+	default:
+		return noop, fmt.Errorf("%w: %s", ErrBudgetExceeded, problem)
+	}
+}
+
+// unpricedBudget reports whether only USD limits are set and the active model has no
+// published pricing to measure them against. With token limits set those apply instead.
+func unpricedBudget(cfg *config.Config, limits budget.Limits) bool {
+	return limits.HasUSD() && !limits.HasTokens() && modelPricing(cfg, cfg.Provider, cfg.Model) == nil
+}
+
+// WarnUnpricedBudget warns at startup when USD limits are set but the model has no
+// published pricing, so its cost is not known.
+func WarnUnpricedBudget(cfg *config.Config) {
+	limits := cfg.BudgetLimits()
+	if !limits.HasUSD() || modelPricing(cfg, cfg.Provider, cfg.Model) != nil {
+		return
+	}
+	fallback := "token limits apply instead"
+	if !limits.HasTokens() {
+		fallback = fmt.Sprintf("every request is handled by QU_BUDGET_ACTION=%s", cfg.BudgetAction)
+	}
+	fmt.Fprintln(os.Stderr, config.Colors.Warn.Sprintf("⚠ Budget: %s has no published pricing, so USD limits cannot be enforced; %s",
+		modelRef(cfg.Provider, cfg.Model), fallback))
+}
+
+// promptBudgetAction asks whether to send an over-budget request anyway. It returns "" to
+// continue, or the budget action to take; without a terminal the request is blocked.
+func promptBudgetAction(cfg *config.Config, problem string) string {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return config.BudgetActionBlock
+	}
+	lib.GetSpinnerManager(cfg).Hide()
+	choices := "y/N"
+	if strings.TrimSpace(cfg.BudgetDowngradeModel) != "" {
+		choices = "y/N/d=use " + cfg.BudgetDowngradeModel
+	}
+	key := lib.ReadSingleKey(config.Colors.Warn.Sprintf("⚠ Budget: %s. Continue anyway (%s)? ", problem, choices))
+	switch key {
+	case 'y', 'Y':
+		return ""
+	case 'd', 'D':
+		return config.BudgetActionDowngrade
+	default:
+		return config.BudgetActionBlock
+	}
+}
+
+// useBudgetDowngrade switches to BudgetDowngradeModel for one request.
+func useBudgetDowngrade(cfg *config.Config) (func(), bool) {
+	ref := strings.TrimSpace(cfg.BudgetDowngradeModel)
+	if ref == "" {
+		return nil, false
+	}
+	providerName, model := resolveTaskModel(cfg, ref)
+	if providerName == cfg.Provider && model == cfg.Model {
+		return nil, false
+	}
+	return swapModel(cfg, providerName, model), true
+}
+
+// recordBudgetSpend adds the cost of the request that just finished to the session and the
+// ledger, and warns once per limit when spend reaches BudgetWarnPercent of it.
+func recordBudgetSpend(cfg *config.Config) {
+	limits := cfg.BudgetLimits()
+	if limits.IsZero() {
+		return
+	}
+	usd := requestCost(cfg, cfg.Provider, cfg.Model, cfg.LastOutgoingTokens, cfg.LastIncomingTokens, cfg.LastCachedTokens)
+	sessionUsageMu.Lock()
+	defer sessionUsageMu.Unlock()
+	cfg.SessionCostUSD += usd
+	if cfg.LedgerFile != "" {
+		err := budget.NewLedger(cfg.LedgerFile).Append(budget.Entry{
+			Time:         time.Now().UTC(),
+			Provider:     cfg.Provider,
+			Model:        cfg.Model,
+			Task:         taskName(cfg.ActiveTask),
+			InputTokens:  cfg.LastOutgoingTokens,
+			OutputTokens: cfg.LastIncomingTokens,
+			USD:          usd,
+		})
+		if err != nil {
+			logger.Log("warn", "[Budget] Failed to record spend: %v", err)
+		}
+	}
+
+	if cfg.BudgetWarnPercent <= 0 {
+		return
+	}
+	for _, s := range limits.Reached(cfg.BudgetWarnPercent, sessionSpend(cfg), dailySpend(cfg)) {
+		if cfg.BudgetWarned[s.Key()] {
+			continue
+		}
+		if cfg.BudgetWarned == nil {
+			cfg.BudgetWarned = map[string]bool{}
+		}
+		cfg.BudgetWarned[s.Key()] = true
+		fmt.Println(config.Colors.Warn.Sprintf("⚠ Budget: %s reached %.0f%%", s, s.Percent()))
+	}
+}
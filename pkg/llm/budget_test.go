@@ -0,0 +1,131 @@
+package llm
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/budget"
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/metadata"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+)
+
+// stubModelPricing prices models by name for the duration of a test.
+func stubModelPricing(t *testing.T, prices map[string]float64) {
+	t.Helper()
+	orig := modelPricing
+	modelPricing = func(cfg *config.Config, providerName, model string) *metadata.ModelMetadata {
+		price, ok := prices[modelRef(providerName, model)]
+		if !ok {
+			return nil
+		}
+		return &metadata.ModelMetadata{ID: model, InputPrice: price, OutputPrice: price}
+	}
+	t.Cleanup(func() { modelPricing = orig })
+}
+
+func TestBudgetEnforcement(t *testing.T) {
+	stubModelPricing(t, map[string]float64{
+		"fake-budget-strong/large": 1e-4,
+		"fake-budget-cheap/nano":   1e-8,
+	})
+
+	tests := []struct {
+		name       string
+		action     string
+		downgrade  string
+		wantErr    bool
+		wantStrong int
+		wantCheap  int
+	}{
+		{name: "block refuses the request", action: config.BudgetActionBlock, wantErr: true},
+		{name: "downgrade switches to the cheaper model", action: config.BudgetActionDowngrade, downgrade: "fake-budget-cheap/nano", wantCheap: 1},
+		{name: "downgrade without a model blocks", action: config.BudgetActionDowngrade, wantErr: true},
+		{name: "downgrade to an over-budget model blocks", action: config.BudgetActionDowngrade, downgrade: "fake-budget-strong/large", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strong := NewMockLLMClient([]MockResponse{{Content: "expensive"}})
+			cheap := NewMockLLMClient([]MockResponse{{Content: "cheap"}})
+			provider.Register(namedFakeProvider{fakeProvider{client: strong}, "fake-budget-strong"})
+			provider.Register(namedFakeProvider{fakeProvider{client: cheap}, "fake-budget-cheap"})
+
+			cfg := CreateTestConfig()
+			cfg.AutoDetectMaxTokens = false
+			cfg.Provider = "fake-budget-strong"
+			cfg.Model = "large"
+			cfg.BudgetSessionUSD = 0.05
+			cfg.BudgetAction = tt.action
+			cfg.BudgetDowngradeModel = tt.downgrade
+
+			_, err := RequestWithSystem(cfg, "", "why is the pod crashing?", false, false)
+			if tt.wantErr {
+				if !errors.Is(err, ErrBudgetExceeded) {
+					t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := len(strong.GetCallHistory()); got != tt.wantStrong {
+				t.Errorf("expected %d calls to the strong model, got %d", tt.wantStrong, got)
+			}
+			if got := len(cheap.GetCallHistory()); got != tt.wantCheap {
+				t.Errorf("expected %d calls to the cheap model, got %d", tt.wantCheap, got)
+			}
+			if cfg.Provider != "fake-budget-strong" || cfg.Model != "large" {
+				t.Errorf("model not restored: %s/%s", cfg.Provider, cfg.Model)
+			}
+		})
+	}
+}
+
+func TestBudgetUnpricedModel(t *testing.T) {
+	stubModelPricing(t, nil)
+	client := NewMockLLMClient([]MockResponse{{Content: "answer"}})
+	provider.Register(namedFakeProvider{fakeProvider{client: client}, "fake-budget-unpriced"})
+
+	cfg := CreateTestConfig()
+	cfg.AutoDetectMaxTokens = false
+	cfg.Provider = "fake-budget-unpriced"
+	cfg.Model = "large"
+	cfg.BudgetDailyUSD = 1
+	cfg.BudgetAction = config.BudgetActionBlock
+
+	// A USD limit alone cannot be measured, so the request is handled as over budget
+	if _, err := RequestWithSystem(cfg, "", "hi", false, false); !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded for an unpriced model, got %v", err)
+	}
+	// With a token limit set, that limit applies instead
+	cfg.BudgetDailyTokens = 1_000_000
+	if _, err := RequestWithSystem(cfg, "", "hi", false, false); err != nil {
+		t.Fatalf("unexpected error with a token limit: %v", err)
+	}
+	if got := len(client.GetCallHistory()); got != 1 {
+		t.Errorf("expected one call, got %d", got)
+	}
+}
+
+func TestRecordBudgetSpend(t *testing.T) {
+	stubModelPricing(t, map[string]float64{"openai/gpt-4": 1e-3})
+
+	cfg := CreateTestConfig()
+	cfg.BudgetDailyUSD = 1
+	cfg.BudgetWarnPercent = 50
+	cfg.LedgerFile = filepath.Join(t.TempDir(), "ledger.jsonl")
+	cfg.LastOutgoingTokens = 400
+	cfg.LastIncomingTokens = 200
+
+	recordBudgetSpend(cfg)
+	if cfg.SessionCostUSD < 0.5999 || cfg.SessionCostUSD > 0.6001 {
+		t.Fatalf("unexpected session cost %f", cfg.SessionCostUSD)
+	}
+	day, err := budget.NewLedger(cfg.LedgerFile).Since(time.Now().Add(-budget.DayWindow))
+	if err != nil || day.Tokens != 600 {
+		t.Fatalf("expected the request in the ledger, got %+v, %v", day, err)
+	}
+	if !cfg.BudgetWarned["day/USD"] {
+		t.Error("expected a warning once daily spend passed 50%")
+	}
+}
@@ -0,0 +1,142 @@
+package llm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/cassette"
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/mikhae1/kubectl-quackops/pkg/mcp"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestCassetteRecordThenReplayToolSession(t *testing.T) {
+	dir := t.TempDir()
+	toolCall := llms.ToolCall{ID: "call-1", Type: "function", FunctionCall: &llms.FunctionCall{Name: "get_pods", Arguments: `{"namespace":"shop"}`}}
+	live := NewMockLLMClient([]MockResponse{
+		{ToolCalls: []llms.ToolCall{toolCall}},
+		{Content: "api-2 is crash looping"},
+	})
+	provider.Register(namedFakeProvider{fakeProvider{client: live}, "fake-cassette"})
+
+	origExecute := executeMCPTool
+	t.Cleanup(func() { executeMCPTool = origExecute })
+	liveToolCalls := 0
+	executeMCPTool = func(cfg *config.Config, toolName string, args map[string]any) (string, error) {
+		liveToolCalls++
+		return "api-2 CrashLoopBackOff", nil
+	}
+
+	newCfg := func() *config.Config {
+		cfg := CreateTestConfig()
+		cfg.AutoDetectMaxTokens = false
+		cfg.Provider = "fake-cassette"
+		cfg.Model = "m"
+		cfg.MCPClientEnabled = true
+		return cfg
+	}
+
+	cfg := newCfg()
+	rec, err := cassette.NewRecorder(dir)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	cfg.Cassette = rec
+	recorded, err := RequestWithSystem(cfg, "system", "what is failing in shop?", false, true)
+	if err != nil {
+		t.Fatalf("recording run failed: %v", err)
+	}
+	if liveToolCalls != 1 {
+		t.Fatalf("expected one live tool call, got %d", liveToolCalls)
+	}
+
+	// Replay must not touch the provider or MCP
+	executeMCPTool = func(cfg *config.Config, toolName string, args map[string]any) (string, error) {
+		return "", errors.New("MCP must not be called during replay")
+	}
+	provider.Register(namedFakeProvider{fakeProvider{client: NewMockLLMClient(nil)}, "fake-cassette"})
+
+	cfg = newCfg()
+	play, err := cassette.Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	cfg.Cassette = play
+	replayed, err := RequestWithSystem(cfg, "system", "what is failing in shop?", false, true)
+	if err != nil {
+		t.Fatalf("replay run failed: %v", err)
+	}
+	if !strings.Contains(replayed, "api-2 is crash looping") || strings.TrimSpace(replayed) != strings.TrimSpace(recorded) {
+		t.Errorf("replayed answer %q differs from recorded %q", replayed, recorded)
+	}
+	if play.Remaining() != 0 {
+		t.Errorf("expected the whole cassette to be replayed, %d interactions left", play.Remaining())
+	}
+}
+
+func TestCassetteReplayIgnoresSampling(t *testing.T) {
+	dir := t.TempDir()
+	toolCall := llms.ToolCall{ID: "call-1", Type: "function", FunctionCall: &llms.FunctionCall{Name: "size_deployment", Arguments: `{"name":"web"}`}}
+	live := NewMockLLMClient([]MockResponse{
+		{ToolCalls: []llms.ToolCall{toolCall}},
+		{Content: "web needs 3 replicas"},
+	})
+	provider.Register(namedFakeProvider{fakeProvider{client: live}, "fake-cassette-sampling"})
+	provider.Register(namedFakeProvider{fakeProvider{client: NewMockLLMClient([]MockResponse{{Content: "sampled answer"}})}, "fake-sampler"})
+
+	origExecute := executeMCPTool
+	t.Cleanup(func() { executeMCPTool = origExecute })
+	executeMCPTool = func(cfg *config.Config, toolName string, args map[string]any) (string, error) {
+		// The server asks the client's model before answering
+		text, _, err := sampleForMCP(cfg, &mcp.SampleRequest{Server: "guide", Messages: []mcp.SampleMessage{{Role: "user", Text: "How many replicas?"}}})
+		if err != nil {
+			return "", err
+		}
+		return "server says: " + text, nil
+	}
+
+	newCfg := func() *config.Config {
+		cfg := CreateTestConfig()
+		cfg.AutoDetectMaxTokens = false
+		cfg.Provider = "fake-cassette-sampling"
+		cfg.Model = "m"
+		cfg.MCPClientEnabled = true
+		cfg.TaskModels = map[string]string{config.TaskSampling: "fake-sampler/s"}
+		return cfg
+	}
+
+	cfg := newCfg()
+	rec, err := cassette.NewRecorder(dir)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	cfg.Cassette = rec
+	recorded, err := RequestWithSystem(cfg, "system", "how big should web be?", false, true)
+	if err != nil {
+		t.Fatalf("recording run failed: %v", err)
+	}
+
+	executeMCPTool = func(cfg *config.Config, toolName string, args map[string]any) (string, error) {
+		return "", errors.New("MCP must not be called during replay")
+	}
+	provider.Register(namedFakeProvider{fakeProvider{client: NewMockLLMClient(nil)}, "fake-cassette-sampling"})
+
+	cfg = newCfg()
+	play, err := cassette.Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	cfg.Cassette = play
+	replayed, err := RequestWithSystem(cfg, "system", "how big should web be?", false, true)
+	if err != nil {
+		t.Fatalf("replay run failed: %v", err)
+	}
+	if !strings.Contains(replayed, "web needs 3 replicas") || strings.TrimSpace(replayed) != strings.TrimSpace(recorded) {
+		t.Errorf("replayed answer %q differs from recorded %q", replayed, recorded)
+	}
+	if play.Remaining() != 0 {
+		t.Errorf("expected the whole cassette to be replayed, %d interactions left", play.Remaining())
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/mikhae1/kubectl-quackops/pkg/config"
 	"github.com/mikhae1/kubectl-quackops/pkg/formatter"
 	"github.com/mikhae1/kubectl-quackops/pkg/lib"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
 	"github.com/mikhae1/kubectl-quackops/pkg/logger"
 	"github.com/mikhae1/kubectl-quackops/pkg/mcp"
 	"github.com/tmc/langchaingo/llms"
@@ -141,17 +142,27 @@ func ChatWithSystemPrompt(cfg *config.Config, client llms.Model, systemPrompt st
 
 	generateOptions := []llms.CallOption{}
 
-	// Use default temperature values - no configuration needed
-	if strings.Contains(cfg.Model, "gpt-5") {
-		// gpt-5 models require temperature 1.0
-		generateOptions = append(generateOptions, llms.WithTemperature(1.0))
-	} else {
-		// All other models use temperature 0.0 for deterministic responses
-		// generateOptions = append(generateOptions, llms.WithTemperature(0.0))
+	// Apply the generation settings of the active task; provider quirks win over settings
+	// the model does not accept
+	var quirks provider.Quirks
+	if p, ok := provider.Get(cfg.Provider); ok {
+		quirks = providerQuirks(cfg, p, cfg.ProviderOptions())
+	}
+	gen := cfg.GenerationFor(cfg.ActiveTask)
+	generateOptions = append(generateOptions, generationCallOptions(cfg, gen, quirks)...)
+	if cfg.MCPClientEnabled && quirks.DisableTools {
+		logger.Log("warn", "%s/%s does not support tool calling; MCP tools are not exposed", cfg.Provider, cfg.Model)
+	}
+
+	// Structured requests expect a single JSON answer, and several providers cannot combine
+	// a response schema with function calling, so tools are withheld
+	structured := cfg.ResponseSchema != nil
+	if structured && quirks.JSONMode {
+		generateOptions = append(generateOptions, llms.WithJSONMode())
 	}
 
 	var mcpToolReserve int = 0
-	if cfg.MCPClientEnabled {
+	if cfg.MCPClientEnabled && !quirks.DisableTools && !structured {
 		var llmTools []llms.Tool
 		if cfg.MCPPromptServer != "" {
 			// When a prompt is active, filter tools to the same server as the prompt
@@ -160,6 +171,9 @@ func ChatWithSystemPrompt(cfg *config.Config, client llms.Model, systemPrompt st
 		} else {
 			llmTools = mcp.DiscoverLangchainTools(cfg)
 		}
+		if cfg.MetricsURL != "" {
+			llmTools = append(llmTools, metricsLangchainTool())
+		}
 		if len(llmTools) > 0 {
 			generateOptions = append(generateOptions, llms.WithTools(llmTools))
 			generateOptions = append(generateOptions, llms.WithToolChoice("auto"))
@@ -188,16 +202,21 @@ func ChatWithSystemPrompt(cfg *config.Config, client llms.Model, systemPrompt st
 		if availableForOutput < cfg.MinOutputTokens {
 			availableForOutput = cfg.MinOutputTokens
 		}
+		availableForOutput = capOutputTokens(availableForOutput, gen)
 
 		logger.Log("info", "Token allocation: limit=%d, input_reserve=%d, mcp_reserve=%d, available_output=%d",
 			limit, inputTokenReserve, mcpToolReserve, availableForOutput)
 
 		generateOptions = append(generateOptions, llms.WithMaxTokens(availableForOutput))
+	} else if gen.MaxOutputTokens > 0 {
+		generateOptions = append(generateOptions, llms.WithMaxTokens(gen.MaxOutputTokens))
 	}
 
 	outgoingTokens := lib.CountTokensWithConfig(cfg, userPrompt, cfg.ChatMessages)
 	cfg.LastOutgoingTokens = outgoingTokens
 	cfg.LastIncomingTokens = 0
+	cfg.LastCachedTokens = 0
+	cfg.LastResponseCached = false
 	cfg.SessionOutgoingTokens += outgoingTokens
 
 	var tokenMeter *lib.TokenMeter
@@ -364,6 +383,13 @@ func ChatWithSystemPrompt(cfg *config.Config, client llms.Model, systemPrompt st
 		return "", fmt.Errorf("no content generated from %s", cfg.Provider)
 	}
 
+	if tokenMeter != nil {
+		// Providers report cached prompt tokens with the final response, streamed or not
+		tokenMeter.SetCached(cfg.LastCachedTokens)
+		if useStreaming {
+			tokenMeter.AddIncomingSilent(lib.EstimateTokens(cfg, responseContent))
+		}
+	}
 	if !useStreaming {
 		if tokenMeter != nil {
 			tokenMeter.AddIncoming(lib.EstimateTokens(cfg, responseContent))
@@ -415,6 +441,10 @@ func ChatWithSystemPrompt(cfg *config.Config, client llms.Model, systemPrompt st
 		ToolCalls:  sessionToolCalls,
 		AIResponse: responseContent,
 	}
+	if len(cfg.PendingModelSwitches) > 0 {
+		event.ModelSwitches = cfg.PendingModelSwitches
+		cfg.PendingModelSwitches = nil
+	}
 	if n := len(cfg.SessionHistory); n > 0 {
 		last := cfg.SessionHistory[n-1]
 		if strings.TrimSpace(last.UserPrompt) == strings.TrimSpace(userPrompt) && strings.TrimSpace(last.AIResponse) == "" && len(last.ToolCalls) == 0 {
@@ -640,7 +670,7 @@ func executePreparedMCPCall(
 	}
 
 	logger.Log("info", "Executing MCP tool: %s with args: %v", prepared.ToolCall.FunctionCall.Name, prepared.Args)
-	toolResult, callErr := executeMCPTool(cfg, prepared.ToolCall.FunctionCall.Name, prepared.Args)
+	toolResult, callErr := executeToolCall(cfg, prepared.ToolCall.FunctionCall.Name, prepared.Args)
 	if callErr != nil {
 		logger.Log("warn", "MCP tool %s failed: %v", prepared.ToolCall.FunctionCall.Name, callErr)
 		toolResult = fmt.Sprintf("Error executing tool '%s': %v", prepared.ToolCall.FunctionCall.Name, callErr)
@@ -988,7 +1018,8 @@ func applyRetryDelayWithCountdown(
 }
 
 // generateWithRetries centralizes the retry, backoff, spinner, and throttling logic
-// for LLM content generation requests.
+// for LLM content generation requests. When a fallback chain is configured and the model
+// keeps failing, the request moves on to the next model in the chain.
 func generateWithRetries(
 	cfg *config.Config,
 	spinnerManager *lib.SpinnerManager,
@@ -999,6 +1030,41 @@ func generateWithRetries(
 	outgoingTokens int,
 	maxRetries int,
 	startEscBreaker func(cancel func()) func(),
+) (*llms.ContentResponse, string, error) {
+	fm, ok := client.(*failoverModel)
+	if !ok {
+		return generateWithRetriesOnModel(cfg, spinnerManager, client, messages, generateOptions, spinnerMessage, outgoingTokens, maxRetries, startEscBreaker)
+	}
+
+	tried := map[string]bool{modelRef(cfg.Provider, cfg.Model): true}
+	for {
+		if fm.switched {
+			messages = convertMessagesForFailover(messages)
+		}
+		resp, content, err := generateWithRetriesOnModel(cfg, spinnerManager, fm.Model, messages, generateOptions, spinnerMessage, outgoingTokens, maxRetries, startEscBreaker)
+		if !shouldFailover(err) {
+			return resp, content, err
+		}
+		extra, ok := switchToFallback(cfg, spinnerManager, fm, err, tried)
+		if !ok {
+			return resp, content, err
+		}
+		generateOptions = append(generateOptions, extra...)
+		spinnerManager.ShowLLM(spinnerMessage)
+	}
+}
+
+// generateWithRetriesOnModel runs the retry loop against a single model.
+func generateWithRetriesOnModel(
+	cfg *config.Config,
+	spinnerManager *lib.SpinnerManager,
+	client llms.Model,
+	messages []llms.MessageContent,
+	generateOptions []llms.CallOption,
+	spinnerMessage string,
+	outgoingTokens int,
+	maxRetries int,
+	startEscBreaker func(cancel func()) func(),
 ) (*llms.ContentResponse, string, error) {
 	backoffFactor := 3.0
 	initialBackoff := 10.0
@@ -1110,6 +1176,10 @@ func generateWithRetries(
 		updateResponseTime()
 
 		resp = r
+		recordPromptCacheUsage(cfg, resp)
+		if responseCacheHit(resp) {
+			cfg.LastResponseCached = true
+		}
 		if resp != nil && len(resp.Choices) > 0 {
 			responseContent = resp.Choices[0].Content
 			cfg.LastIncomingTokens = lib.EstimateTokens(cfg, responseContent)
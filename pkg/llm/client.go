@@ -12,6 +12,7 @@ import (
 	"github.com/mikhae1/kubectl-quackops/pkg/config"
 	"github.com/mikhae1/kubectl-quackops/pkg/formatter"
 	"github.com/mikhae1/kubectl-quackops/pkg/lib"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
 	"github.com/mikhae1/kubectl-quackops/pkg/logger"
 	"github.com/tmc/langchaingo/llms"
 )
@@ -31,6 +32,9 @@ var Request RequestFunc = func(cfg *config.Config, prompt string, stream bool, h
 
 // RequestWithSystem sends a request with separate system and user prompts
 var RequestWithSystem RequestWithSystemFunc = func(cfg *config.Config, systemPrompt string, userPrompt string, stream bool, history bool) (string, error) {
+	restoreModel := useTaskModel(cfg)
+	defer restoreModel()
+
 	truncUserPrompt := userPrompt
 	// Rude truncation of the prompt if it exceeds the maximum token length
 	maxWin := lib.EffectiveMaxTokens(cfg)
@@ -61,29 +65,62 @@ var RequestWithSystem RequestWithSystemFunc = func(cfg *config.Config, systemPro
 	}
 	logger.Log("llmIn", "History: %d messages, %d tokens", len(cfg.ChatMessages), historyTok)
 
+	restoreBudgetModel, err := enforceBudget(cfg, systemTok+userTok+historyTok)
+	if err != nil {
+		return "", err
+	}
+	defer restoreBudgetModel()
+
 	// Spinner lifecycle and throttling are managed inside Chat().
 
-	var err error
-	var answer string
-	switch cfg.Provider {
-	case "ollama":
-		answer, err = ollamaRequestWithChatSystem(cfg, systemPrompt, truncUserPrompt, stream, history)
-	case "openai":
-		answer, err = openaiRequestWithChatSystem(cfg, systemPrompt, truncUserPrompt, stream, history)
-	case "azopenai":
-		answer, err = azOpenAIRequestWithChatSystem(cfg, systemPrompt, truncUserPrompt, stream, history)
-	case "google":
-		answer, err = googleRequestWithChatSystem(cfg, systemPrompt, truncUserPrompt, stream, history)
-	case "anthropic":
-		answer, err = anthropicRequestWithChatSystem(cfg, systemPrompt, truncUserPrompt, stream, history)
-	default:
+	p, ok := provider.Get(cfg.Provider)
+	if !ok {
 		return "", fmt.Errorf("unsupported AI provider: %s", cfg.Provider)
 	}
+	opts := cfg.ProviderOptions()
+	client, err := newProviderModel(cfg, p, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s client: %w", p.Name(), err)
+	}
+	client = newResponseCacheModel(cfg, client, opts)
+	if providerQuirks(cfg, p, opts).DisableStreaming {
+		stream = false
+	}
+	answer, err := ChatWithSystemPrompt(cfg, newFailoverModel(cfg, client), systemPrompt, truncUserPrompt, stream, history)
+	// Answers from the response cache cost nothing
+	if err == nil && !cfg.LastResponseCached {
+		recordTaskUsage(cfg)
+		recordBudgetSpend(cfg)
+	}
 
 	logger.Log("llmOut", "[%s@%s]: %s", cfg.Provider, cfg.Model, answer)
 	return answer, err
 }
 
+// newProviderModel builds the chat client for p, recording its traffic to the open cassette
+// and the privacy report or, when replaying, serving recorded responses without contacting
+// the provider. Pseudonymization wraps outermost so the cassette and the report only see
+// what was actually sent.
+func newProviderModel(cfg *config.Config, p provider.Provider, opts provider.Options) (llms.Model, error) {
+	if cfg.Cassette.Replaying() {
+		return newPseudonymModel(cfg, cfg.Cassette.Model()), nil
+	}
+	client, err := p.NewModel(context.Background(), opts)
+	if err != nil {
+		return nil, err
+	}
+	return newPseudonymModel(cfg, newPrivacyModel(cfg, p, opts, cfg.Cassette.Wrap(client))), nil
+}
+
+// providerQuirks returns the request adjustments for p. Replays skip them because some
+// providers probe their endpoint to compute quirks.
+func providerQuirks(cfg *config.Config, p provider.Provider, opts provider.Options) provider.Quirks {
+	if cfg.Cassette.Replaying() {
+		return provider.Quirks{}
+	}
+	return p.Quirks(opts)
+}
+
 // logMultiline logs each non-empty line of content
 func logMultiline(level string, content string) {
 	if content == "" {
@@ -403,16 +440,19 @@ func summarizeCompactTranscript(cfg *config.Config, transcript string) (string,
 	origSpinnerMsg := cfg.SpinnerMessageOverride
 	origSuppressContent := cfg.SuppressContentPrint
 	origSuppressTools := cfg.SuppressToolPrint
+	origTask := cfg.ActiveTask
 
 	cfg.SpinnerMessageOverride = "Compacting conversation context…"
 	cfg.SuppressContentPrint = true
 	cfg.SuppressToolPrint = true
+	cfg.ActiveTask = config.TaskCompaction
 
 	summary, err := RequestWithSystem(cfg, systemPrompt, userPrompt, false, false)
 
 	cfg.SpinnerMessageOverride = origSpinnerMsg
 	cfg.SuppressContentPrint = origSuppressContent
 	cfg.SuppressToolPrint = origSuppressTools
+	cfg.ActiveTask = origTask
 
 	if err != nil {
 		return "", err
@@ -2,219 +2,60 @@ package llm
 
 import (
 	"context"
-	"fmt"
 	"math"
-	"os"
 	"sort"
 	"strings"
 
 	"github.com/mikhae1/kubectl-quackops/pkg/config"
 	"github.com/mikhae1/kubectl-quackops/pkg/lib"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
 	"github.com/mikhae1/kubectl-quackops/pkg/logger"
 
 	"github.com/tmc/langchaingo/embeddings"
-	"github.com/tmc/langchaingo/llms/googleai"
-	"github.com/tmc/langchaingo/llms/ollama"
-	"github.com/tmc/langchaingo/llms/openai"
 )
 
-// GoogleEmbedder is a wrapper around the GoogleAI client that provides embedding functionality
-type GoogleEmbedder struct {
-	client *googleai.GoogleAI
-	model  string
-}
-
-// EmbedDocuments implements the Embedder interface for GoogleEmbedder
-func (g *GoogleEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
-	// Use the native embedding functionality of googleai package
-	embeddings, err := g.client.CreateEmbedding(ctx, texts)
-	if err != nil {
-		return nil, fmt.Errorf("error creating embeddings: %w", err)
-	}
-	return embeddings, nil
-}
-
-// EmbedQuery implements the Embedder interface for GoogleEmbedder
-func (g *GoogleEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
-	// Use the CreateEmbedding function with a single text
-	embeddings, err := g.client.CreateEmbedding(ctx, []string{text})
-	if err != nil {
-		return nil, fmt.Errorf("error creating query embedding: %w", err)
-	}
-
-	if len(embeddings) == 0 || len(embeddings[0]) == 0 {
-		return nil, fmt.Errorf("empty embedding returned")
-	}
-
-	return embeddings[0], nil
-}
+// embeddingFallbackProviders lists providers tried for embeddings, in order, when the
+// configured provider has none (e.g. Anthropic) or its embedder cannot be created.
+var embeddingFallbackProviders = []string{"openai", "google", "ollama"}
 
 // GetEmbedder creates an embedder based on the provider configuration
 func GetEmbedder(cfg *config.Config) (embeddings.Embedder, error) {
 	logger.Log("info", "Creating embedder for %s provider", cfg.Provider)
-
-	// Provider-specific embedding handling
-	switch cfg.Provider {
-	case "openai":
-		// OpenAI has the best embedding models
-		if os.Getenv("OPENAI_API_KEY") != "" {
-			// Set the API key as an env var which is how langchaingo accesses it
-			openaiOpts := []openai.Option{
-				openai.WithModel(cfg.EmbeddingModel), // Use the configured embedding model
-			}
-			if baseURL := config.GetOpenAIBaseURL(); baseURL != "" {
-				openaiOpts = append(openaiOpts, openai.WithBaseURL(baseURL))
-			}
-			embedClient, err := openai.New(openaiOpts...)
-			if err != nil {
-				logger.Log("warn", "Failed to create OpenAI embedder: %v", err)
-			} else {
-				logger.Log("info", "Using OpenAI embeddings model: %s", cfg.EmbeddingModel)
-				return embeddings.NewEmbedder(embedClient)
-			}
-		}
-
-	case "ollama":
-		// Ollama has built-in embedding capability
-		serverURL := strings.TrimSuffix(cfg.OllamaApiURL, "/api")
-		// Try to use a dedicated embedding model if available, otherwise fall back to the configured model
-		embeddingModels := strings.Split(cfg.OllamaEmbeddingModels, ",")
-
-		// First try using a dedicated embedding model
-		for _, embModel := range embeddingModels {
-			embModel = strings.TrimSpace(embModel)
-			if embModel == "" {
-				continue
-			}
-
-			ollamaClient, err := ollama.New(
-				ollama.WithModel(embModel),
-				ollama.WithServerURL(serverURL),
-			)
-			if err == nil {
-				logger.Log("info", "Using Ollama embeddings model: %s", embModel)
-				return embeddings.NewEmbedder(ollamaClient)
-			}
-		}
-
-		// Fall back to using the configured model
-		ollamaClient, err := ollama.New(
-			ollama.WithModel(cfg.Model),
-			ollama.WithServerURL(serverURL),
-		)
-		if err == nil {
-			logger.Log("info", "Using Ollama LLM model for embeddings: %s", cfg.Model)
-			return embeddings.NewEmbedder(ollamaClient)
-		}
-
-		logger.Log("warn", "Failed to create Ollama embedder: %v", err)
-
-	case "google":
-		// Google has embedding models through their embedding API
-		googleAPIKey := config.GetGoogleAPIKey()
-		if googleAPIKey != "" {
-			// Initialize Google AI client
-			ctx := context.Background()
-
-			// Use the configured embedding model
-			embeddingModel := cfg.EmbeddingModel
-			googleClient, err := googleai.New(ctx,
-				googleai.WithAPIKey(googleAPIKey),
-				googleai.WithDefaultEmbeddingModel(embeddingModel),
-			)
-
-			if err != nil {
-				logger.Log("warn", "Failed to create Google AI client: %v", err)
-			} else {
-				logger.Log("info", "Using Google AI embedding model: %s", embeddingModel)
-				// Return custom Google embedder
-				return &GoogleEmbedder{
-					client: googleClient,
-					model:  embeddingModel,
-				}, nil
-			}
-		} else {
-			logger.Log("warn", "Google API key not found in environment (GOOGLE_API_KEY or GEMINI_API_KEY)")
-		}
-
-	case "anthropic":
-		// Anthropic doesn't have specialized embedding models in langchaingo yet
-		// We'll fall back to other providers
-		logger.Log("info", "Anthropic embedding model not directly supported, falling back")
+	if cfg.Cassette.Replaying() {
+		// Replays must not reach embedding APIs
+		return createSimpleEmbedder(), nil
 	}
+	ctx := context.Background()
+	opts := cfg.ProviderOptions()
 
-	// Fallback logic - try each major embedding provider in order
-
-	// 1. Try OpenAI's dedicated embedding model as primary fallback
-	if os.Getenv("OPENAI_API_KEY") != "" {
-		openaiOpts := []openai.Option{openai.WithModel(cfg.EmbeddingModel)}
-		if baseURL := config.GetOpenAIBaseURL(); baseURL != "" {
-			openaiOpts = append(openaiOpts, openai.WithBaseURL(baseURL))
-		}
-		embedClient, err := openai.New(openaiOpts...)
+	if p, ok := provider.Get(cfg.Provider); ok {
+		embedder, err := p.NewEmbedder(ctx, opts)
 		if err == nil {
-			logger.Log("info", "Using fallback OpenAI embeddings model: %s", cfg.EmbeddingModel)
-			return embeddings.NewEmbedder(embedClient)
+			logger.Log("info", "Using %s embeddings model: %s", p.Name(), opts.EmbeddingModel)
+			return newPseudonymEmbedder(cfg, newPrivacyEmbedder(cfg, p, opts, embedder)), nil
 		}
-		logger.Log("warn", "Failed to create fallback OpenAI embedder: %v", err)
+		logger.Log("warn", "Failed to create %s embedder: %v, falling back", p.Name(), err)
 	}
 
-	// 2. Try Google AI as another fallback if available
-	googleAPIKey := config.GetGoogleAPIKey()
-	if googleAPIKey != "" {
-		ctx := context.Background()
-		embeddingModel := cfg.EmbeddingModel
-		googleClient, err := googleai.New(ctx,
-			googleai.WithAPIKey(googleAPIKey),
-			googleai.WithDefaultEmbeddingModel(embeddingModel),
-		)
-
-		if err == nil {
-			logger.Log("info", "Using fallback Google AI embedding model: %s", embeddingModel)
-			return &GoogleEmbedder{
-				client: googleClient,
-				model:  embeddingModel,
-			}, nil
+	// Fallback logic - try each major embedding provider in order
+	for _, name := range embeddingFallbackProviders {
+		if name == cfg.Provider {
+			continue
 		}
-		logger.Log("warn", "Failed to create fallback Google AI embedder: %v", err)
-	}
-
-	// 3. Try Ollama as another fallback option
-	if cfg.OllamaApiURL != "" {
-		serverURL := strings.TrimSuffix(cfg.OllamaApiURL, "/api")
-		// Try standard embedding models
-		embeddingModels := strings.Split(cfg.OllamaEmbeddingModels, ",")
-
-		for _, embModel := range embeddingModels {
-			embModel = strings.TrimSpace(embModel)
-			if embModel == "" {
-				continue
-			}
-
-			ollamaClient, err := ollama.New(
-				ollama.WithModel(embModel),
-				ollama.WithServerURL(serverURL),
-			)
-			if err == nil {
-				logger.Log("info", "Using fallback Ollama embeddings model: %s", embModel)
-				return embeddings.NewEmbedder(ollamaClient)
-			}
+		p, ok := provider.Get(name)
+		if !ok {
+			continue
 		}
-
-		// Try with Llama3 if available as a last resort
-		for _, model := range []string{"llama3.1", "llama3", "llama2"} {
-			ollamaClient, err := ollama.New(
-				ollama.WithModel(model),
-				ollama.WithServerURL(serverURL),
-			)
-			if err == nil {
-				logger.Log("info", "Using fallback Ollama LLM model for embeddings: %s", model)
-				return embeddings.NewEmbedder(ollamaClient)
-			}
+		embedder, err := p.NewEmbedder(ctx, opts)
+		if err != nil {
+			logger.Log("debug", "Fallback %s embedder unavailable: %v", name, err)
+			continue
 		}
+		logger.Log("info", "Using fallback %s embeddings", name)
+		return newPseudonymEmbedder(cfg, newPrivacyEmbedder(cfg, p, opts, embedder)), nil
 	}
 
-	// 4. Last resort - if we couldn't create any embedder, use a simple implementation
+	// Last resort - if we couldn't create any embedder, use a simple implementation
 	// that performs basic word matching
 	logger.Log("warn", "No suitable embedder found, using simple keyword matcher")
 	return createSimpleEmbedder(), nil
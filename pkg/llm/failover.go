@@ -0,0 +1,187 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/lib"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/mikhae1/kubectl-quackops/pkg/logger"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// failoverModel wraps the active client so generateWithRetries can swap it for the next
+// model in the fallback chain; callers keep using the same llms.Model value afterwards.
+type failoverModel struct {
+	llms.Model
+	switched bool
+}
+
+// newFailoverModel wraps client when a fallback chain is configured.
+func newFailoverModel(cfg *config.Config, client llms.Model) llms.Model {
+	if len(fallbackChain(cfg)) == 0 {
+		return client
+	}
+	return &failoverModel{Model: client}
+}
+
+// modelRef formats a provider/model pair the way fallback chains are configured.
+func modelRef(providerName, model string) string {
+	return providerName + "/" + model
+}
+
+// parseModelRef splits "provider/model"; the model part may itself contain slashes
+// (e.g. openai/anthropic/claude-sonnet-4 for OpenRouter).
+func parseModelRef(ref string) (string, string, bool) {
+	providerName, model, ok := strings.Cut(strings.TrimSpace(ref), "/")
+	providerName = strings.ToLower(strings.TrimSpace(providerName))
+	model = strings.TrimSpace(model)
+	if !ok || providerName == "" || model == "" {
+		return "", "", false
+	}
+	return providerName, model, true
+}
+
+// fallbackChain returns the valid entries of cfg.FallbackModels.
+func fallbackChain(cfg *config.Config) []string {
+	var chain []string
+	for _, ref := range cfg.FallbackModels {
+		if _, _, ok := parseModelRef(ref); ok {
+			chain = append(chain, strings.TrimSpace(ref))
+		}
+	}
+	return chain
+}
+
+// shouldFailover reports whether an error warrants moving to the next model: transient
+// failures that survived all retries, or a prompt that does not fit the context window.
+func shouldFailover(err error) bool {
+	if err == nil || lib.IsUserCancel(err) {
+		return false
+	}
+	return lib.IsContextLengthError(err) || lib.IsRetryableError(err)
+}
+
+// nextFallback returns the first chain entry after the active model that has not been
+// tried in this request.
+func nextFallback(cfg *config.Config, tried map[string]bool) (string, bool) {
+	chain := fallbackChain(cfg)
+	current := modelRef(cfg.Provider, cfg.Model)
+	start := 0
+	for i, ref := range chain {
+		if ref == current {
+			start = i + 1
+			break
+		}
+	}
+	for _, ref := range append(chain[start:], chain[:start]...) {
+		if ref != current && !tried[ref] {
+			return ref, true
+		}
+	}
+	return "", false
+}
+
+// switchToFallback points cfg and the wrapped client at the next usable model in the chain
+// and records the switch for the session. It returns call options that replace the generation
+// settings of the previous model with those of the new one.
+func switchToFallback(cfg *config.Config, spinnerManager *lib.SpinnerManager, fm *failoverModel, cause error, tried map[string]bool) ([]llms.CallOption, bool) {
+	for {
+		ref, ok := nextFallback(cfg, tried)
+		if !ok {
+			return nil, false
+		}
+		tried[ref] = true
+		providerName, model, _ := parseModelRef(ref)
+		p, ok := provider.Get(providerName)
+		if !ok {
+			logger.Log("warn", "Skipping fallback %s: unknown provider", ref)
+			continue
+		}
+		opts := cfg.ProviderOptions()
+		opts.Model = model
+		client, err := newProviderModel(cfg, p, opts)
+		if err != nil {
+			logger.Log("warn", "Skipping fallback %s: %v", ref, err)
+			continue
+		}
+
+		from := modelRef(cfg.Provider, cfg.Model)
+		reason := lib.GetErrorMessage(cause)
+		if reason == "" {
+			reason = cause.Error()
+		}
+		logger.Log("warn", "Failing over from %s to %s: %v", from, ref, cause)
+		if spinnerManager != nil {
+			spinnerManager.Hide()
+		}
+		fmt.Printf("%s %s %s %s %s\n",
+			config.Colors.Warn.Sprint("⚠ Switching model:"),
+			config.Colors.Dim.Sprint(from),
+			config.Colors.Dim.Sprint("→"),
+			config.Colors.Model.Sprint(ref),
+			config.Colors.Dim.Sprintf("(%s)", truncateReason(reason, 120)))
+
+		cfg.Provider = providerName
+		cfg.Model = model
+		if pd := p.Defaults(); pd.MaxTokens > 0 {
+			cfg.DefaultMaxTokens = pd.MaxTokens
+		}
+		cfg.ConfigDetectMaxTokens()
+		cfg.PendingModelSwitches = append(cfg.PendingModelSwitches, config.ModelSwitch{From: from, To: ref, Reason: reason})
+
+		// The fallback answers through the response cache like the model it replaces
+		fm.Model = newResponseCacheModel(cfg, client, opts)
+		fm.switched = true
+
+		// Replace the generation settings built for the previous provider with the fallback's
+		// own, so provider-specific ones such as a thinking budget do not carry over
+		extra := []llms.CallOption{resetGenerationOptions}
+		extra = append(extra, generationCallOptions(cfg, cfg.GenerationFor(cfg.ActiveTask), providerQuirks(cfg, p, opts))...)
+		return extra, true
+	}
+}
+
+func truncateReason(reason string, max int) string {
+	reason = strings.Join(strings.Fields(reason), " ")
+	if len(reason) > max {
+		return reason[:max-3] + "..."
+	}
+	return reason
+}
+
+// convertMessagesForFailover rewrites provider-specific tool call and tool result parts as
+// plain text and merges consecutive same-role messages, so history produced by one provider
+// is accepted by any other.
+func convertMessagesForFailover(messages []llms.MessageContent) []llms.MessageContent {
+	out := make([]llms.MessageContent, 0, len(messages))
+	for _, msg := range messages {
+		role := msg.Role
+		parts := make([]llms.ContentPart, 0, len(msg.Parts))
+		for _, part := range msg.Parts {
+			switch p := part.(type) {
+			case llms.ToolCall:
+				name, args := "", ""
+				if p.FunctionCall != nil {
+					name, args = p.FunctionCall.Name, p.FunctionCall.Arguments
+				}
+				parts = append(parts, llms.TextContent{Text: fmt.Sprintf("[Called tool %s with arguments %s]", name, args)})
+			case llms.ToolCallResponse:
+				role = llms.ChatMessageTypeHuman
+				parts = append(parts, llms.TextContent{Text: fmt.Sprintf("[Result of tool %s]\n%s", p.Name, p.Content)})
+			default:
+				parts = append(parts, part)
+			}
+		}
+		if role == llms.ChatMessageTypeTool {
+			role = llms.ChatMessageTypeHuman
+		}
+		if n := len(out); n > 0 && out[n-1].Role == role {
+			out[n-1].Parts = append(out[n-1].Parts, parts...)
+			continue
+		}
+		out = append(out, llms.MessageContent{Role: role, Parts: parts})
+	}
+	return out
+}
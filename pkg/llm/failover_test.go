@@ -0,0 +1,206 @@
+package llm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/filter"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// namedFakeProvider registers a fakeProvider under a different name.
+type namedFakeProvider struct {
+	fakeProvider
+	name string
+}
+
+func (p namedFakeProvider) Name() string { return p.name }
+
+func TestFailoverSwitchesToNextModel(t *testing.T) {
+	primary := NewMockLLMClient([]MockResponse{{Error: errors.New("400 This model's maximum context length is 8192 tokens")}})
+	fallback := NewMockLLMClient([]MockResponse{{Content: "answer from fallback"}})
+	provider.Register(namedFakeProvider{fakeProvider{client: primary}, "fake-primary"})
+	provider.Register(namedFakeProvider{fakeProvider{client: fallback}, "fake-fallback"})
+
+	cfg := CreateTestConfig()
+	cfg.AutoDetectMaxTokens = false
+	cfg.Provider = "fake-primary"
+	cfg.Model = "small"
+	cfg.FallbackModels = []string{"fake-primary/small", "fake-fallback/large"}
+
+	answer, err := RequestWithSystem(cfg, "system", "hi", false, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(answer, "answer from fallback") {
+		t.Errorf("unexpected answer: %q", answer)
+	}
+	if cfg.Provider != "fake-fallback" || cfg.Model != "large" || cfg.DefaultMaxTokens != 1234 {
+		t.Errorf("config not switched: %s/%s %d", cfg.Provider, cfg.Model, cfg.DefaultMaxTokens)
+	}
+	if len(fallback.GetCallHistory()) != 1 {
+		t.Errorf("expected one call to the fallback, got %d", len(fallback.GetCallHistory()))
+	}
+	if n := len(cfg.SessionHistory); n == 0 || len(cfg.SessionHistory[n-1].ModelSwitches) != 1 {
+		t.Fatalf("expected model switch recorded in session, got %+v", cfg.SessionHistory)
+	}
+	sw := cfg.SessionHistory[len(cfg.SessionHistory)-1].ModelSwitches[0]
+	if sw.From != "fake-primary/small" || sw.To != "fake-fallback/large" || !strings.Contains(sw.Reason, "maximum context length") {
+		t.Errorf("unexpected switch record: %+v", sw)
+	}
+	if len(cfg.PendingModelSwitches) != 0 {
+		t.Error("pending switches should be cleared once recorded")
+	}
+}
+
+func TestFailoverAppliesFallbackGeneration(t *testing.T) {
+	primary := NewMockLLMClient([]MockResponse{{Error: errors.New("400 This model's maximum context length is 8192 tokens")}})
+	fallback := NewMockLLMClient([]MockResponse{{Content: "answer from fallback"}})
+	provider.Register(namedFakeProvider{fakeProvider{client: primary}, "fake-thinking"})
+	provider.Register(namedFakeProvider{fakeProvider{client: fallback}, "fake-plain"})
+
+	cfg := CreateTestConfig()
+	cfg.AutoDetectMaxTokens = false
+	cfg.Provider = "fake-thinking"
+	cfg.Model = "small"
+	cfg.FallbackModels = []string{"fake-plain/large"}
+	cfg.ProviderGeneration = map[string]provider.Generation{}
+	cfg.ProviderGeneration["fake-thinking"], _ = provider.ParseGeneration("thinking_budget=4096,top_p=0.5")
+	cfg.ProviderGeneration["fake-plain"], _ = provider.ParseGeneration("temperature=0.3")
+
+	if _, err := RequestWithSystem(cfg, "system", "hi", false, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	calls := fallback.GetCallHistory()
+	if len(calls) != 1 {
+		t.Fatalf("expected one call to the fallback, got %d", len(calls))
+	}
+	var opts llms.CallOptions
+	for _, opt := range calls[0].Options {
+		opt(&opts)
+	}
+	if llms.GetThinkingConfig(&opts) != nil || opts.TopP != 0 {
+		t.Errorf("primary generation settings carried over: top_p=%v thinking=%+v", opts.TopP, llms.GetThinkingConfig(&opts))
+	}
+	if opts.Temperature != 0.3 {
+		t.Errorf("fallback generation settings not applied: temperature=%v", opts.Temperature)
+	}
+}
+
+func TestFailoverPseudonymizesFallback(t *testing.T) {
+	primary := NewMockLLMClient([]MockResponse{{Error: errors.New("400 This model's maximum context length is 8192 tokens")}})
+	fallback := NewMockLLMClient([]MockResponse{{Content: "kubectl logs pod-1 -n ns-1"}})
+	provider.Register(namedFakeProvider{fakeProvider{client: primary}, "fake-pseudonym-primary"})
+	provider.Register(namedFakeProvider{fakeProvider{client: fallback}, "fake-pseudonym-fallback"})
+
+	cfg := CreateTestConfig()
+	cfg.AutoDetectMaxTokens = false
+	cfg.Provider = "fake-pseudonym-primary"
+	cfg.Model = "small"
+	cfg.FallbackModels = []string{"fake-pseudonym-fallback/large"}
+	cfg.Pseudonymize = true
+	cfg.Pseudonyms = filter.NewPseudonymizer()
+	cfg.Pseudonyms.Learn(filter.PseudonymNamespace, "payments")
+	cfg.Pseudonyms.Learn(filter.PseudonymPod, "checkout-abc")
+	cfg.Pseudonyms.MarkLearned()
+
+	answer, err := RequestWithSystem(cfg, "", "why does checkout-abc in payments fail?", false, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Provider != "fake-pseudonym-fallback" {
+		t.Fatalf("expected failover to the fallback, still on %s", cfg.Provider)
+	}
+	for name, mock := range map[string]*MockLLMClient{"primary": primary, "fallback": fallback} {
+		calls := mock.GetCallHistory()
+		if len(calls) != 1 {
+			t.Fatalf("expected one call to the %s, got %d", name, len(calls))
+		}
+		for _, msg := range calls[0].Messages {
+			for _, part := range msg.Parts {
+				if text, ok := part.(llms.TextContent); ok && (strings.Contains(text.Text, "checkout-abc") || strings.Contains(text.Text, "payments")) {
+					t.Errorf("identifier reached the %s: %q", name, text.Text)
+				}
+			}
+		}
+	}
+	if !strings.Contains(answer, "kubectl logs checkout-abc -n payments") {
+		t.Errorf("fallback answer not restored: %q", answer)
+	}
+}
+
+func TestFailoverNotTriggeredForAuthErrors(t *testing.T) {
+	primary := NewMockLLMClient([]MockResponse{{Error: errors.New("401 Unauthorized")}})
+	fallback := NewMockLLMClient([]MockResponse{{Content: "unused"}})
+	provider.Register(namedFakeProvider{fakeProvider{client: primary}, "fake-auth"})
+	provider.Register(namedFakeProvider{fakeProvider{client: fallback}, "fake-auth-fallback"})
+
+	cfg := CreateTestConfig()
+	cfg.AutoDetectMaxTokens = false
+	cfg.Provider = "fake-auth"
+	cfg.Model = "m"
+	cfg.FallbackModels = []string{"fake-auth-fallback/m"}
+
+	if _, err := RequestWithSystem(cfg, "", "hi", false, false); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(fallback.GetCallHistory()) != 0 || cfg.Provider != "fake-auth" {
+		t.Error("auth errors must not fail over")
+	}
+}
+
+func TestNextFallback(t *testing.T) {
+	cfg := CreateTestConfig()
+	cfg.Provider = "openai"
+	cfg.Model = "gpt-5-mini"
+	cfg.FallbackModels = []string{"anthropic/claude-sonnet-4", "openai/gpt-5-mini", "ollama/llama3.1", "bogus"}
+
+	tried := map[string]bool{"openai/gpt-5-mini": true}
+	if ref, _ := nextFallback(cfg, tried); ref != "ollama/llama3.1" {
+		t.Errorf("expected entry after the current model, got %q", ref)
+	}
+	tried["ollama/llama3.1"] = true
+	if ref, _ := nextFallback(cfg, tried); ref != "anthropic/claude-sonnet-4" {
+		t.Errorf("expected wrap-around to the start of the chain, got %q", ref)
+	}
+	tried["anthropic/claude-sonnet-4"] = true
+	if ref, ok := nextFallback(cfg, tried); ok {
+		t.Errorf("expected chain exhausted, got %q", ref)
+	}
+
+	if p, m, ok := parseModelRef("OpenAI/anthropic/claude-sonnet-4"); !ok || p != "openai" || m != "anthropic/claude-sonnet-4" {
+		t.Errorf("unexpected parse: %s %s %t", p, m, ok)
+	}
+}
+
+func TestConvertMessagesForFailover(t *testing.T) {
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "sys"),
+		llms.TextParts(llms.ChatMessageTypeHuman, "list pods"),
+		{Role: llms.ChatMessageTypeAI, Parts: []llms.ContentPart{llms.ToolCall{
+			ID: "1", Type: "function", FunctionCall: &llms.FunctionCall{Name: "kubectl", Arguments: `{"cmd":"get pods"}`},
+		}}},
+		{Role: llms.ChatMessageTypeTool, Parts: []llms.ContentPart{llms.ToolCallResponse{ToolCallID: "1", Name: "kubectl", Content: "pod-a Running"}}},
+		llms.TextParts(llms.ChatMessageTypeHuman, "anything broken?"),
+	}
+
+	got := convertMessagesForFailover(messages)
+	if len(got) != 4 {
+		t.Fatalf("expected tool result merged into the following human turn, got %d messages", len(got))
+	}
+	for _, msg := range got {
+		for _, part := range msg.Parts {
+			if _, ok := part.(llms.TextContent); !ok {
+				t.Errorf("non-text part left after conversion: %T", part)
+			}
+		}
+	}
+	if got[2].Role != llms.ChatMessageTypeAI || !strings.Contains(got[2].Parts[0].(llms.TextContent).Text, `kubectl with arguments {"cmd":"get pods"}`) {
+		t.Errorf("unexpected tool call conversion: %+v", got[2])
+	}
+	if got[3].Role != llms.ChatMessageTypeHuman || len(got[3].Parts) != 2 || !strings.Contains(got[3].Parts[0].(llms.TextContent).Text, "pod-a Running") {
+		t.Errorf("unexpected tool result conversion: %+v", got[3])
+	}
+}
@@ -0,0 +1,81 @@
+package llm
+
+import (
+	"net/http"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/mikhae1/kubectl-quackops/pkg/logger"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// reasoningEffortBudgets maps reasoning effort to a thinking token budget for providers that
+// take a budget instead of an effort level (Anthropic, Gemini).
+var reasoningEffortBudgets = map[string]int{
+	"minimal": 1024,
+	"low":     2048,
+	"medium":  8192,
+	"high":    24576,
+}
+
+// generationCallOptions maps generation settings to call options. Quirks win over settings a
+// model does not accept, such as the fixed temperature of gpt-5 models.
+func generationCallOptions(cfg *config.Config, gen provider.Generation, quirks provider.Quirks) []llms.CallOption {
+	var options []llms.CallOption
+	if t := quirks.Temperature; t != nil {
+		if gen.Temperature != nil && *gen.Temperature != *t {
+			logger.Log("warn", "%s/%s only accepts temperature %g; ignoring configured %g", cfg.Provider, cfg.Model, *t, *gen.Temperature)
+		}
+		options = append(options, llms.WithTemperature(*t))
+	} else if gen.Temperature != nil {
+		options = append(options, llms.WithTemperature(*gen.Temperature))
+	}
+	if gen.TopP != nil {
+		options = append(options, llms.WithTopP(*gen.TopP))
+	}
+	if gen.Seed != nil {
+		options = append(options, llms.WithSeed(*gen.Seed))
+	}
+	if budget := thinkingBudget(gen); budget > 0 {
+		mode := llms.ThinkingModeAuto
+		if gen.ReasoningEffort != "" && gen.ReasoningEffort != "minimal" {
+			mode = llms.ThinkingMode(gen.ReasoningEffort)
+		}
+		options = append(options, llms.WithThinkingMode(mode), llms.WithThinkingBudget(budget))
+	}
+	return options
+}
+
+// resetGenerationOptions clears everything generationCallOptions sets. Options apply in
+// order, so it lets a later set of generation options replace an earlier one.
+func resetGenerationOptions(opts *llms.CallOptions) {
+	opts.Temperature = 0
+	opts.TopP = 0
+	opts.Seed = 0
+	delete(opts.Metadata, "thinking_config")
+}
+
+// thinkingBudget returns the explicit thinking budget, or the one implied by reasoning effort.
+func thinkingBudget(gen provider.Generation) int {
+	if gen.ThinkingBudget > 0 {
+		return gen.ThinkingBudget
+	}
+	return reasoningEffortBudgets[gen.ReasoningEffort]
+}
+
+// capOutputTokens applies the configured output limit to the computed output allowance.
+func capOutputTokens(available int, gen provider.Generation) int {
+	if gen.MaxOutputTokens > 0 && (available <= 0 || gen.MaxOutputTokens < available) {
+		return gen.MaxOutputTokens
+	}
+	return available
+}
+
+// reasoningEffortClient returns an HTTP client that sends reasoning_effort, or nil when the
+// settings do not ask for one.
+func reasoningEffortClient(gen provider.Generation) *bodyFieldsDoer {
+	if gen.ReasoningEffort == "" {
+		return nil
+	}
+	return &bodyFieldsDoer{client: http.DefaultClient, fields: map[string]any{"reasoning_effort": gen.ReasoningEffort}}
+}
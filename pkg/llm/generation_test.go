@@ -0,0 +1,149 @@
+package llm
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestGenerationForLayers(t *testing.T) {
+	parse := func(spec string) provider.Generation {
+		g, err := provider.ParseGeneration(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return g
+	}
+	cfg := &config.Config{
+		Provider:   "anthropic",
+		Generation: parse("temperature=0.7,top_p=0.9"),
+		ProviderGeneration: map[string]provider.Generation{
+			"anthropic": parse("temperature=0.5,thinking_budget=2048"),
+			"openai":    parse("seed=1"),
+		},
+		TaskGeneration: map[string]provider.Generation{
+			config.TaskCommands: parse("temperature=0,max_output_tokens=512"),
+		},
+	}
+	tests := []struct {
+		task string
+		want string
+	}{
+		{task: config.TaskCommands, want: "temperature=0,top_p=0.9,max_output_tokens=512,thinking_budget=2048"},
+		{task: config.TaskAnswer, want: "temperature=0.5,top_p=0.9,thinking_budget=2048"},
+		{task: "", want: "temperature=0.5,top_p=0.9,thinking_budget=2048"},
+	}
+	for _, tt := range tests {
+		if got := cfg.GenerationFor(tt.task).String(); got != tt.want {
+			t.Errorf("GenerationFor(%q) = %q, want %q", tt.task, got, tt.want)
+		}
+	}
+}
+
+func TestGenerationCallOptions(t *testing.T) {
+	cfg := &config.Config{Provider: "openai", Model: "gpt-5-mini"}
+	gen, _ := provider.ParseGeneration("temperature=0.2,top_p=0.8,seed=42,reasoning_effort=medium")
+	fixed := 1.0
+
+	tests := []struct {
+		name     string
+		quirks   provider.Quirks
+		wantTemp float64
+	}{
+		{name: "configured temperature", wantTemp: 0.2},
+		{name: "fixed model temperature wins", quirks: provider.Quirks{Temperature: &fixed}, wantTemp: 1.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts llms.CallOptions
+			for _, opt := range generationCallOptions(cfg, gen, tt.quirks) {
+				opt(&opts)
+			}
+			if opts.Temperature != tt.wantTemp || opts.TopP != 0.8 || opts.Seed != 42 {
+				t.Fatalf("unexpected sampling options: %+v", opts)
+			}
+			thinking := llms.GetThinkingConfig(&opts)
+			if thinking == nil || thinking.Mode != llms.ThinkingModeMedium || thinking.BudgetTokens != 8192 {
+				t.Fatalf("unexpected thinking config: %+v", thinking)
+			}
+		})
+	}
+
+	var opts llms.CallOptions
+	for _, opt := range generationCallOptions(cfg, provider.Generation{}, provider.Quirks{}) {
+		opt(&opts)
+	}
+	if opts.Temperature != 0 || llms.GetThinkingConfig(&opts) != nil {
+		t.Fatalf("unset settings must not produce options: %+v", opts)
+	}
+}
+
+func TestCapOutputTokens(t *testing.T) {
+	tests := []struct {
+		available int
+		max       int
+		want      int
+	}{
+		{available: 4000, max: 0, want: 4000},
+		{available: 4000, max: 1000, want: 1000},
+		{available: 4000, max: 8000, want: 4000},
+		{available: 0, max: 1000, want: 1000},
+	}
+	for _, tt := range tests {
+		if got := capOutputTokens(tt.available, provider.Generation{MaxOutputTokens: tt.max}); got != tt.want {
+			t.Errorf("capOutputTokens(%d, %d) = %d, want %d", tt.available, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestReasoningEffortClientAddsField(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("invalid body %q: %v", body, err)
+		}
+	}))
+	defer srv.Close()
+
+	if reasoningEffortClient(provider.Generation{}) != nil {
+		t.Fatal("client without reasoning effort should be nil")
+	}
+	doer := reasoningEffortClient(provider.Generation{ReasoningEffort: "low"})
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/v1/chat/completions", strings.NewReader(`{"model":"gpt-5-mini"}`))
+	resp, err := doer.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got["reasoning_effort"] != "low" || got["model"] != "gpt-5-mini" {
+		t.Fatalf("unexpected request body: %v", got)
+	}
+}
+
+func TestChatAppliesTaskGeneration(t *testing.T) {
+	cfg := CreateTestConfig()
+	cfg.Generation, _ = provider.ParseGeneration("temperature=0.7")
+	cfg.TaskGeneration = map[string]provider.Generation{}
+	cfg.TaskGeneration[config.TaskCompaction], _ = provider.ParseGeneration("temperature=0.1,max_output_tokens=256")
+	cfg.ActiveTask = config.TaskCompaction
+
+	mock := NewMockLLMClient([]MockResponse{{Content: "summary"}})
+	if _, err := Chat(cfg, mock, "summarize", false, false); err != nil {
+		t.Fatal(err)
+	}
+	var opts llms.CallOptions
+	for _, opt := range mock.callHistory[0].Options {
+		opt(&opts)
+	}
+	if opts.Temperature != 0.1 || opts.MaxTokens != 256 {
+		t.Fatalf("task settings not applied: temperature=%v max_tokens=%d", opts.Temperature, opts.MaxTokens)
+	}
+}
@@ -32,11 +32,11 @@ func GenKubectlCmds(cfg *config.Config, prompt string, userMsgCount int) ([]stri
 	augPromptBuilder.WriteString("\n\nIssue description: ")
 	augPromptBuilder.WriteString(prompt)
 	if cfg.KubectlReturnJSON {
-		augPromptBuilder.WriteString("\n\nRespond ONLY with a JSON array of strings (no prose, no code fences). ")
+		augPromptBuilder.WriteString("\n\nRespond ONLY with a JSON object holding a \"commands\" array of strings (no prose, no code fences). ")
 		if cfg.KubectlMaxSuggestions > 0 {
-			augPromptBuilder.WriteString(fmt.Sprintf("Return at most %d items.", cfg.KubectlMaxSuggestions))
+			augPromptBuilder.WriteString(fmt.Sprintf("Return at most %d commands.", cfg.KubectlMaxSuggestions))
 		}
-		augPromptBuilder.WriteString(" Example: [\"kubectl get pods -A -o wide\", \"kubectl get events -A -o json\"].")
+		augPromptBuilder.WriteString(" Example: {\"commands\": [\"kubectl get pods -A -o wide\", \"kubectl get events -A -o json\"]}.")
 	} else {
 		augPromptBuilder.WriteString("\n\nProvide commands as a plain list without descriptions or backticks.")
 		if cfg.KubectlMaxSuggestions > 0 {
@@ -50,8 +50,33 @@ func GenKubectlCmds(cfg *config.Config, prompt string, userMsgCount int) ([]stri
 	cancelSpinner := spinnerManager.ShowGeneration("🛠️ " + config.Colors.Info.Sprint("Generating") + " " + config.Colors.Dim.Sprint("diagnostic commands..."))
 	defer cancelSpinner()
 
+	origTask := cfg.ActiveTask
+	cfg.ActiveTask = config.TaskCommands
+	defer func() { cfg.ActiveTask = origTask }()
+
 	// Execute request without updating the conversation history, silently
-	response, err := RequestSilent(cfg, augPrompt, false, false)
+	var suggestions kubectlSuggestions
+	var response string
+	var err error
+	if cfg.KubectlReturnJSON {
+		response, err = requestStructured(cfg, structuredRequest{
+			Name:     "kubectl_commands",
+			Prompt:   augPrompt,
+			Out:      &suggestions,
+			Validate: suggestions.problems,
+			Send: func(prompt string) (string, error) {
+				return RequestSilent(cfg, prompt, false, false)
+			},
+		})
+		// Malformed answers still go through the lenient parsers below
+		var schemaErr *StructuredOutputError
+		if errors.As(err, &schemaErr) {
+			logger.Log("warn", "Falling back to lenient command parsing: %v", err)
+			err = nil
+		}
+	} else {
+		response, err = RequestSilent(cfg, augPrompt, false, false)
+	}
 
 	if err != nil {
 		return nil, fmt.Errorf("error requesting kubectl diagnostics: %w", err)
@@ -97,19 +122,10 @@ func GenKubectlCmds(cfg *config.Config, prompt string, userMsgCount int) ([]stri
 
 	var filteredCmds []string
 	if cfg.KubectlReturnJSON {
-		// Try to parse the response as JSON array of strings
-		var arr []string
-		if err := json.Unmarshal([]byte(response), &arr); err != nil {
-			// Try to locate a JSON array in the text
-			start := strings.Index(response, "[")
-			end := strings.LastIndex(response, "]")
-			if start >= 0 && end > start {
-				var arr2 []string
-				sub := response[start : end+1]
-				if err := json.Unmarshal([]byte(sub), &arr2); err == nil {
-					arr = arr2
-				}
-			}
+		arr := suggestions.Commands
+		// Some models still answer with a bare JSON array of strings
+		if len(arr) == 0 {
+			arr = parseJSONStringArray(response)
 		}
 		filteredCmds = filterAndValidate(arr)
 	}
@@ -131,6 +147,36 @@ func GenKubectlCmds(cfg *config.Config, prompt string, userMsgCount int) ([]stri
 	return filteredCmds, nil
 }
 
+// kubectlSuggestions is the JSON document the model returns for command suggestions.
+type kubectlSuggestions struct {
+	Commands []string `json:"commands" desc:"Read-only kubectl commands, one per item"`
+}
+
+// problems reports suggestion content the schema cannot rule out.
+func (s *kubectlSuggestions) problems() []string {
+	if len(s.Commands) == 0 {
+		return []string{"$.commands: no commands suggested"}
+	}
+	return nil
+}
+
+// parseJSONStringArray parses response as a JSON array of strings, or the first such array
+// embedded in surrounding text.
+func parseJSONStringArray(response string) []string {
+	var arr []string
+	if err := json.Unmarshal([]byte(response), &arr); err == nil {
+		return arr
+	}
+	start := strings.Index(response, "[")
+	end := strings.LastIndex(response, "]")
+	if start >= 0 && end > start {
+		if err := json.Unmarshal([]byte(response[start:end+1]), &arr); err == nil {
+			return arr
+		}
+	}
+	return nil
+}
+
 // genKubectlPrompt generates a context-aware prompt based on the user's query
 func genKubectlPrompt(cfg *config.Config, prompt string) string {
 	// Function to create formatted command strings
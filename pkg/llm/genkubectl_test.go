@@ -2,6 +2,7 @@ package llm
 
 import (
 	"os"
+	"reflect"
 	"regexp"
 	"testing"
 
@@ -89,3 +90,61 @@ func TestGenKubectlCmdsDoesNotUpdateHistory(t *testing.T) {
 			expectedLastMessage, lastMessage)
 	}
 }
+
+func TestGenKubectlCmdsStructuredJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []string
+		wantCalls int
+		want      []string
+	}{
+		{
+			name:      "schema object",
+			responses: []string{`{"commands":["kubectl get pods -A","rm -rf /","kubectl delete pod x"]}`},
+			wantCalls: 1,
+			want:      []string{"kubectl get pods -A"},
+		},
+		{
+			name:      "repaired after empty list",
+			responses: []string{`{"commands":[]}`, `{"commands":["kubectl describe pod web"]}`},
+			wantCalls: 2,
+			want:      []string{"kubectl describe pod web"},
+		},
+		{
+			name:      "bare array after retries",
+			responses: []string{`["kubectl get pods"]`, `["kubectl get pods"]`},
+			wantCalls: 2,
+			want:      []string{"kubectl get pods"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalRequest := Request
+			defer func() { Request = originalRequest }()
+			calls := 0
+			Request = func(cfg *config.Config, prompt string, stream bool, history bool) (string, error) {
+				calls++
+				return tt.responses[calls-1], nil
+			}
+
+			cfg := &config.Config{
+				AllowedKubectlCmds:      []string{"get", "describe"},
+				KubectlShortPrompt:      "Generate kubectl commands",
+				KubectlReturnJSON:       true,
+				StructuredOutput:        true,
+				StructuredOutputRetries: 1,
+				SpinnerTimeout:          80,
+			}
+			cmds, err := GenKubectlCmds(cfg, "pods are crashing", 2)
+			if err != nil {
+				t.Fatalf("GenKubectlCmds returned an error: %v", err)
+			}
+			if !reflect.DeepEqual(cmds, tt.want) {
+				t.Errorf("GenKubectlCmds() = %q, want %q", cmds, tt.want)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("expected %d requests, got %d", tt.wantCalls, calls)
+			}
+		})
+	}
+}
@@ -2,14 +2,19 @@ package llm
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"strings"
+	"sync"
+	"time"
 
 	"iter"
 
+	"github.com/mikhae1/kubectl-quackops/pkg/logger"
 	"github.com/tmc/langchaingo/callbacks"
 	"github.com/tmc/langchaingo/llms"
 	"google.golang.org/genai"
@@ -21,6 +26,12 @@ type GoogleNative struct {
 	CallbacksHandler callbacks.Handler
 	client           *genai.Client
 	defaultModel     string
+
+	// ResponseSchema, when set, constrains responses to this JSON schema via responseSchema.
+	ResponseSchema map[string]any
+	// PromptCache moves a large first turn, with the system instruction and tools, into
+	// Gemini cached content that later requests of the conversation reference.
+	PromptCache bool
 }
 
 var _ llms.Model = &GoogleNative{}
@@ -71,6 +82,15 @@ func (g *GoogleNative) GenerateContent(
 	if err != nil {
 		return nil, err
 	}
+	// Gemini rejects responseSchema combined with function calling
+	if g.ResponseSchema != nil && len(config.Tools) == 0 {
+		schema, err := buildSchema(g.ResponseSchema)
+		if err != nil {
+			return nil, fmt.Errorf("response schema: %w", err)
+		}
+		config.ResponseMIMEType = "application/json"
+		config.ResponseSchema = schema
+	}
 
 	var response *llms.ContentResponse
 	if len(messages) == 1 {
@@ -128,7 +148,15 @@ func convertCandidates(candidates []*genai.Candidate, usage *genai.GenerateConte
 				}
 			}
 		}
-		contentResponse.Choices = append(contentResponse.Choices, &llms.ContentChoice{Content: buf.String(), ToolCalls: toolCalls})
+		choice := &llms.ContentChoice{Content: buf.String(), ToolCalls: toolCalls}
+		if usage != nil {
+			choice.GenerationInfo = map[string]any{
+				"PromptTokens":       int(usage.PromptTokenCount),
+				"CompletionTokens":   int(usage.CandidatesTokenCount),
+				"PromptCachedTokens": int(usage.CachedContentTokenCount),
+			}
+		}
+		contentResponse.Choices = append(contentResponse.Choices, choice)
 	}
 	return &contentResponse, nil
 }
@@ -151,6 +179,15 @@ func buildGenerateConfig(opts llms.CallOptions) (*genai.GenerateContentConfig, e
 	cfg.TopP = genai.Ptr(float32(opts.TopP))
 	cfg.TopK = genai.Ptr(float32(opts.TopK))
 	cfg.StopSequences = opts.StopWords
+	if opts.JSONMode {
+		cfg.ResponseMIMEType = "application/json"
+	}
+	if opts.Seed != 0 {
+		cfg.Seed = genai.Ptr(int32(opts.Seed))
+	}
+	if tc := llms.GetThinkingConfig(&opts); tc != nil && tc.BudgetTokens > 0 {
+		cfg.ThinkingConfig = &genai.ThinkingConfig{ThinkingBudget: genai.Ptr(int32(tc.BudgetTokens))}
+	}
 
 	tools, err := convertTools(opts.Tools)
 	if err != nil {
@@ -246,6 +283,9 @@ func (g *GoogleNative) generateFromMessages(ctx context.Context, modelName strin
 	if len(contents) == 0 {
 		return nil, errors.New("no user/model messages provided")
 	}
+	if g.PromptCache {
+		contents = g.useCachedPrefix(ctx, modelName, contents, cfg)
+	}
 	if opts.StreamingFunc == nil {
 		resp, err := g.client.Models.GenerateContent(ctx, modelName, contents, cfg)
 		if err != nil {
@@ -387,3 +427,82 @@ func convertToolSchemaType(ty string) genai.Type {
 		return genai.TypeUnspecified
 	}
 }
+
+const (
+	// geminiCacheMinChars approximates the smallest prefix (about 4096 tokens) Gemini
+	// accepts as cached content for every model.
+	geminiCacheMinChars = 16000
+	geminiCacheTTL      = 15 * time.Minute
+)
+
+// geminiCacheEntry is a cached content resource; an empty name records a failed attempt.
+type geminiCacheEntry struct {
+	name    string
+	expires time.Time
+}
+
+// geminiCaches maps a prefix key to the cached content created for it.
+var geminiCaches sync.Map
+
+// useCachedPrefix references cached content holding the system instruction, tools and first
+// turn of a conversation, creating it on first use. The baseline cluster context sent with
+// the first question dominates request size, and every later request repeats it verbatim.
+// It returns the contents left to send; cfg is updated to reference the cache. On any
+// failure the request is left untouched.
+func (g *GoogleNative) useCachedPrefix(ctx context.Context, modelName string, contents []*genai.Content, cfg *genai.GenerateContentConfig) []*genai.Content {
+	if len(contents) < 2 || contents[0].Role != "user" || cfg.CachedContent != "" {
+		return contents
+	}
+	size := contentChars(contents[0])
+	if cfg.SystemInstruction != nil {
+		size += contentChars(cfg.SystemInstruction)
+	}
+	if size < geminiCacheMinChars {
+		return contents
+	}
+
+	keyData, err := json.Marshal([]any{modelName, cfg.SystemInstruction, cfg.Tools, contents[0]})
+	if err != nil {
+		return contents
+	}
+	sum := sha256.Sum256(keyData)
+	key := hex.EncodeToString(sum[:])
+
+	now := time.Now()
+	name := ""
+	if v, ok := geminiCaches.Load(key); ok && v.(geminiCacheEntry).expires.After(now.Add(time.Minute)) {
+		name = v.(geminiCacheEntry).name
+	} else {
+		cached, err := g.client.Caches.Create(ctx, modelName, &genai.CreateCachedContentConfig{
+			TTL:               geminiCacheTTL,
+			DisplayName:       "kubectl-quackops",
+			Contents:          contents[:1],
+			SystemInstruction: cfg.SystemInstruction,
+			Tools:             cfg.Tools,
+		})
+		if err == nil && cached != nil {
+			name = cached.Name
+		} else if err != nil {
+			logger.Log("warn", "Gemini prompt cache unavailable for %s: %v", modelName, err)
+		}
+		geminiCaches.Store(key, geminiCacheEntry{name: name, expires: now.Add(geminiCacheTTL)})
+	}
+	if name == "" {
+		return contents
+	}
+
+	// Cached content already carries the system instruction and tools; the API rejects both
+	// being sent again
+	cfg.CachedContent = name
+	cfg.SystemInstruction = nil
+	cfg.Tools = nil
+	return contents[1:]
+}
+
+func contentChars(c *genai.Content) int {
+	n := 0
+	for _, part := range c.Parts {
+		n += len(part.Text)
+	}
+	return n
+}
@@ -56,7 +56,10 @@ type ModelMetadata struct {
 	Description   string  `json:"description,omitempty"`
 	InputPrice    float64 `json:"input_price,omitempty"`  // USD per token for input/prompt
 	OutputPrice   float64 `json:"output_price,omitempty"` // USD per token for output/completion
-	PricePretty   string  `json:"price_pretty,omitempty"` // Formatted price string like "↑$1.25/↓$10.0"
+	// CachedInputPrice is the USD per token for prompt tokens read from the provider's
+	// prompt cache; zero when the provider does not publish it.
+	CachedInputPrice float64 `json:"cached_input_price,omitempty"`
+	PricePretty      string  `json:"price_pretty,omitempty"` // Formatted price string like "↑$1.25/↓$10.0"
 }
 
 // OpenRouterModelsResponse represents the OpenRouter API models response
@@ -68,8 +71,9 @@ type OpenRouterModelsResponse struct {
 		MaxCompletionTokens int    `json:"max_completion_tokens"`
 		Description         string `json:"description,omitempty"`
 		Pricing             struct {
-			Prompt     string `json:"prompt"`     // USD per token for input
-			Completion string `json:"completion"` // USD per token for output
+			Prompt         string `json:"prompt"`           // USD per token for input
+			Completion     string `json:"completion"`       // USD per token for output
+			InputCacheRead string `json:"input_cache_read"` // USD per token for cached input
 		} `json:"pricing,omitempty"`
 	} `json:"data"`
 }
@@ -182,6 +186,33 @@ func formatPricePretty(inputPrice, outputPrice float64) string {
 	return strings.Join(parts, "/")
 }
 
+// Source fetches model metadata for one provider. Providers register a Source so the
+// service can resolve context lengths and model lists without knowing every provider.
+type Source interface {
+	ModelMetadata(ms *MetadataService, model, baseURL string) (*ModelMetadata, error)
+	ModelList(ms *MetadataService, baseURL string) ([]*ModelMetadata, error)
+}
+
+var (
+	sourcesMu sync.RWMutex
+	sources   = map[string]Source{}
+)
+
+// RegisterSource makes a metadata source available for the named provider, replacing any
+// previously registered source with the same name.
+func RegisterSource(provider string, source Source) {
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	sources[provider] = source
+}
+
+func lookupSource(provider string) (Source, bool) {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+	source, ok := sources[provider]
+	return source, ok
+}
+
 // MetadataService provides model metadata detection
 type MetadataService struct {
 	httpClient *http.Client
@@ -268,29 +299,11 @@ func (ms *MetadataService) GetModelContextLength(provider, model, baseURL string
 	}
 	ms.mu.RUnlock()
 
-	var metadata *ModelMetadata
-	var err error
-
-	switch provider {
-	case "openai":
-		if isOpenRouterURL(baseURL) {
-			metadata, err = ms.fetchOpenRouterMetadata(model, baseURL)
-		} else {
-			metadata, err = ms.fetchOpenAIMetadata(model, baseURL)
-		}
-	case "azopenai":
-		// Azure OpenAI uses the same API structure as OpenAI
-		metadata, err = ms.fetchAzureOpenAIMetadata(model, baseURL)
-	case "google":
-		metadata, err = ms.fetchGoogleMetadata(model, baseURL)
-	case "anthropic":
-		metadata, err = ms.fetchAnthropicMetadata(model, baseURL)
-	case "ollama":
-		metadata, err = ms.fetchOllamaMetadata(model, baseURL)
-	default:
+	source, ok := lookupSource(provider)
+	if !ok {
 		return 0, fmt.Errorf("unsupported provider: %s", provider)
 	}
-
+	metadata, err := source.ModelMetadata(ms, model, baseURL)
 	if err != nil {
 		return 0, err
 	}
@@ -309,29 +322,15 @@ func (ms *MetadataService) GetModelContextLength(provider, model, baseURL string
 
 // GetModelList retrieves a list of available models from the provider
 func (ms *MetadataService) GetModelList(provider, baseURL string) ([]*ModelMetadata, error) {
-	switch provider {
-	case "openai":
-		if isOpenRouterURL(baseURL) {
-			return ms.fetchOpenRouterModelList(baseURL)
-		} else {
-			return ms.fetchOpenAIModelList(baseURL)
-		}
-	case "azopenai":
-		// Azure OpenAI uses the same API structure as OpenAI
-		return ms.fetchAzureOpenAIModelList(baseURL)
-	case "google":
-		return ms.fetchGoogleModelList(baseURL)
-	case "anthropic":
-		return ms.fetchAnthropicModelList(baseURL)
-	case "ollama":
-		return ms.fetchOllamaModelList(baseURL)
-	default:
+	source, ok := lookupSource(provider)
+	if !ok {
 		return nil, fmt.Errorf("unsupported provider: %s", provider)
 	}
+	return source.ModelList(ms, baseURL)
 }
 
-// fetchOpenRouterMetadata fetches model metadata from OpenRouter API
-func (ms *MetadataService) fetchOpenRouterMetadata(model, baseURL string) (*ModelMetadata, error) {
+// FetchOpenRouterMetadata fetches model metadata from OpenRouter API
+func (ms *MetadataService) FetchOpenRouterMetadata(model, baseURL string) (*ModelMetadata, error) {
 	// Handle case where baseURL already includes /api/v1
 	var apiURL string
 	if strings.HasSuffix(baseURL, "/api/v1") {
@@ -359,12 +358,13 @@ func (ms *MetadataService) fetchOpenRouterMetadata(model, baseURL string) (*Mode
 			completionPrice := parsePriceString(modelData.Pricing.Completion)
 
 			return &ModelMetadata{
-				ID:            modelData.ID,
-				ContextLength: modelData.ContextLength,
-				MaxTokens:     modelData.ContextLength, // Use context length for max tokens
-				InputPrice:    promptPrice,
-				OutputPrice:   completionPrice,
-				PricePretty:   formatPricePretty(promptPrice, completionPrice),
+				ID:               modelData.ID,
+				ContextLength:    modelData.ContextLength,
+				MaxTokens:        modelData.ContextLength, // Use context length for max tokens
+				InputPrice:       promptPrice,
+				OutputPrice:      completionPrice,
+				CachedInputPrice: parsePriceString(modelData.Pricing.InputCacheRead),
+				PricePretty:      formatPricePretty(promptPrice, completionPrice),
 			}, nil
 		}
 	}
@@ -372,8 +372,8 @@ func (ms *MetadataService) fetchOpenRouterMetadata(model, baseURL string) (*Mode
 	return nil, fmt.Errorf("model %s not found", model)
 }
 
-// fetchOpenAIMetadata fetches model metadata from OpenAI API
-func (ms *MetadataService) fetchOpenAIMetadata(model, baseURL string) (*ModelMetadata, error) {
+// FetchOpenAIMetadata fetches model metadata from OpenAI API
+func (ms *MetadataService) FetchOpenAIMetadata(model, baseURL string) (*ModelMetadata, error) {
 	if baseURL == "" {
 		baseURL = "https://api.openai.com"
 	}
@@ -424,8 +424,8 @@ func (ms *MetadataService) fetchOpenAIMetadata(model, baseURL string) (*ModelMet
 	return nil, fmt.Errorf("model %s not found", model)
 }
 
-// fetchAzureOpenAIMetadata fetches model metadata from Azure OpenAI API
-func (ms *MetadataService) fetchAzureOpenAIMetadata(model, baseURL string) (*ModelMetadata, error) {
+// FetchAzureOpenAIMetadata fetches model metadata from Azure OpenAI API
+func (ms *MetadataService) FetchAzureOpenAIMetadata(model, baseURL string) (*ModelMetadata, error) {
 	apiVersion := getAzOpenAIAPIVersion()
 
 	if baseURL == "" {
@@ -490,8 +490,8 @@ func (ms *MetadataService) fetchAzureOpenAIMetadata(model, baseURL string) (*Mod
 	}, nil
 }
 
-// fetchAzureOpenAIModelList fetches the list of available models from Azure OpenAI
-func (ms *MetadataService) fetchAzureOpenAIModelList(baseURL string) ([]*ModelMetadata, error) {
+// FetchAzureOpenAIModelList fetches the list of available models from Azure OpenAI
+func (ms *MetadataService) FetchAzureOpenAIModelList(baseURL string) ([]*ModelMetadata, error) {
 	apiVersion := getAzOpenAIAPIVersion()
 
 	if baseURL == "" {
@@ -626,8 +626,8 @@ type GoogleModelResponse struct {
 	OutputTokenLimit int    `json:"outputTokenLimit"`
 }
 
-// fetchGoogleMetadata fetches model metadata from Google Generative Language API
-func (ms *MetadataService) fetchGoogleMetadata(model, baseURL string) (*ModelMetadata, error) {
+// FetchGoogleMetadata fetches model metadata from Google Generative Language API
+func (ms *MetadataService) FetchGoogleMetadata(model, baseURL string) (*ModelMetadata, error) {
 	if baseURL == "" {
 		baseURL = "https://generativelanguage.googleapis.com"
 	}
@@ -689,7 +689,8 @@ type anthropicModelsResponse struct {
 	} `json:"data"`
 }
 
-func (ms *MetadataService) fetchAnthropicMetadata(model, baseURL string) (*ModelMetadata, error) {
+// FetchAnthropicMetadata fetches model metadata from the Anthropic models API
+func (ms *MetadataService) FetchAnthropicMetadata(model, baseURL string) (*ModelMetadata, error) {
 	if baseURL == "" {
 		baseURL = "https://api.anthropic.com"
 	}
@@ -774,7 +775,8 @@ type ollamaShowResponse struct {
 	ModelInfo  map[string]any `json:"model_info"`
 }
 
-func (ms *MetadataService) fetchOllamaMetadata(model, baseURL string) (*ModelMetadata, error) {
+// FetchOllamaMetadata fetches model metadata from the Ollama show API
+func (ms *MetadataService) FetchOllamaMetadata(model, baseURL string) (*ModelMetadata, error) {
 	if baseURL == "" {
 		baseURL = "http://localhost:11434"
 	}
@@ -830,13 +832,13 @@ func (ms *MetadataService) fetchOllamaMetadata(model, baseURL string) (*ModelMet
 	return &ModelMetadata{ID: id, ContextLength: ctx, MaxTokens: ctx}, nil
 }
 
-// isOpenRouterURL checks if the base URL belongs to OpenRouter
-func isOpenRouterURL(baseURL string) bool {
+// IsOpenRouterURL checks if the base URL belongs to OpenRouter
+func IsOpenRouterURL(baseURL string) bool {
 	return strings.Contains(strings.ToLower(baseURL), "openrouter.ai")
 }
 
-// fetchOpenRouterModelList fetches the list of available models from OpenRouter
-func (ms *MetadataService) fetchOpenRouterModelList(baseURL string) ([]*ModelMetadata, error) {
+// FetchOpenRouterModelList fetches the list of available models from OpenRouter
+func (ms *MetadataService) FetchOpenRouterModelList(baseURL string) ([]*ModelMetadata, error) {
 	var apiURL string
 	if strings.HasSuffix(baseURL, "/api/v1") {
 		apiURL = strings.TrimSuffix(baseURL, "/") + "/models"
@@ -862,21 +864,22 @@ func (ms *MetadataService) fetchOpenRouterModelList(baseURL string) ([]*ModelMet
 		completionPrice := parsePriceString(modelData.Pricing.Completion)
 
 		models = append(models, &ModelMetadata{
-			ID:            modelData.ID,
-			ContextLength: modelData.ContextLength,
-			MaxTokens:     modelData.ContextLength, // Use context length for max tokens
-			Description:   modelData.Description,
-			InputPrice:    promptPrice,
-			OutputPrice:   completionPrice,
-			PricePretty:   formatPricePretty(promptPrice, completionPrice),
+			ID:               modelData.ID,
+			ContextLength:    modelData.ContextLength,
+			MaxTokens:        modelData.ContextLength, // Use context length for max tokens
+			Description:      modelData.Description,
+			InputPrice:       promptPrice,
+			OutputPrice:      completionPrice,
+			CachedInputPrice: parsePriceString(modelData.Pricing.InputCacheRead),
+			PricePretty:      formatPricePretty(promptPrice, completionPrice),
 		})
 	}
 
 	return models, nil
 }
 
-// fetchOpenAIModelList fetches the list of available models from OpenAI
-func (ms *MetadataService) fetchOpenAIModelList(baseURL string) ([]*ModelMetadata, error) {
+// FetchOpenAIModelList fetches the list of available models from OpenAI
+func (ms *MetadataService) FetchOpenAIModelList(baseURL string) ([]*ModelMetadata, error) {
 	if baseURL == "" {
 		baseURL = "https://api.openai.com"
 	}
@@ -914,8 +917,8 @@ func (ms *MetadataService) fetchOpenAIModelList(baseURL string) ([]*ModelMetadat
 	return models, nil
 }
 
-// fetchGoogleModelList fetches the list of available models from Google
-func (ms *MetadataService) fetchGoogleModelList(baseURL string) ([]*ModelMetadata, error) {
+// FetchGoogleModelList fetches the list of available models from Google
+func (ms *MetadataService) FetchGoogleModelList(baseURL string) ([]*ModelMetadata, error) {
 	if baseURL == "" {
 		baseURL = "https://generativelanguage.googleapis.com"
 	}
@@ -962,8 +965,8 @@ func (ms *MetadataService) fetchGoogleModelList(baseURL string) ([]*ModelMetadat
 	return models, nil
 }
 
-// fetchAnthropicModelList fetches the list of available models from Anthropic
-func (ms *MetadataService) fetchAnthropicModelList(baseURL string) ([]*ModelMetadata, error) {
+// FetchAnthropicModelList fetches the list of available models from Anthropic
+func (ms *MetadataService) FetchAnthropicModelList(baseURL string) ([]*ModelMetadata, error) {
 	if baseURL == "" {
 		baseURL = "https://api.anthropic.com"
 	}
@@ -1017,8 +1020,8 @@ type OllamaModelsResponse struct {
 	} `json:"models"`
 }
 
-// fetchOllamaModelList fetches the list of available models from Ollama
-func (ms *MetadataService) fetchOllamaModelList(baseURL string) ([]*ModelMetadata, error) {
+// FetchOllamaModelList fetches the list of available models from Ollama
+func (ms *MetadataService) FetchOllamaModelList(baseURL string) ([]*ModelMetadata, error) {
 	if baseURL == "" {
 		baseURL = "http://localhost:11434"
 	}
@@ -1064,3 +1067,83 @@ func (ms *MetadataService) fetchOllamaModelList(baseURL string) ([]*ModelMetadat
 
 	return models, nil
 }
+
+// OpenAICompatibleModelsResponse is the /v1/models response of self-hosted OpenAI-compatible
+// servers. Each server reports the context window in its own field.
+type OpenAICompatibleModelsResponse struct {
+	Data []struct {
+		ID               string `json:"id"`
+		OwnedBy          string `json:"owned_by"`
+		MaxModelLen      int    `json:"max_model_len"`      // vLLM
+		MaxContextLength int    `json:"max_context_length"` // LM Studio
+		ContextLength    int    `json:"context_length"`     // LocalAI, TGI and others
+		Meta             *struct {
+			NCtxTrain int `json:"n_ctx_train"` // llama.cpp
+		} `json:"meta,omitempty"`
+	} `json:"data"`
+}
+
+// OpenAICompatibleModelsURL returns the models endpoint for an OpenAI-compatible base URL,
+// which may or may not already include the /v1 prefix.
+func OpenAICompatibleModelsURL(baseURL string) string {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if strings.HasSuffix(baseURL, "/v1") {
+		return baseURL + "/models"
+	}
+	return baseURL + "/v1/models"
+}
+
+// FetchOpenAICompatibleModelList fetches models from an OpenAI-compatible server such as
+// vLLM, llama.cpp or LM Studio. Description carries the server's owned_by value.
+func (ms *MetadataService) FetchOpenAICompatibleModelList(baseURL string) ([]*ModelMetadata, error) {
+	headers := map[string]string{"Content-Type": "application/json"}
+	if apiKey := os.Getenv("QU_OPENAI_COMPAT_API_KEY"); apiKey != "" {
+		headers["Authorization"] = "Bearer " + apiKey
+	}
+	var r OpenAICompatibleModelsResponse
+	if err := ms.getJSON("GET", OpenAICompatibleModelsURL(baseURL), nil, headers, &r); err != nil {
+		return nil, fmt.Errorf("failed to fetch models: %w", err)
+	}
+
+	var models []*ModelMetadata
+	for _, m := range r.Data {
+		ctx := m.MaxModelLen
+		if ctx == 0 {
+			ctx = m.MaxContextLength
+		}
+		if ctx == 0 {
+			ctx = m.ContextLength
+		}
+		if ctx == 0 && m.Meta != nil {
+			ctx = m.Meta.NCtxTrain
+		}
+		if ctx == 0 {
+			ctx = getDefaultContextLengthForModel(m.ID)
+		}
+		models = append(models, &ModelMetadata{
+			ID:            m.ID,
+			ContextLength: ctx,
+			MaxTokens:     ctx,
+			Description:   m.OwnedBy,
+		})
+	}
+	return models, nil
+}
+
+// FetchOpenAICompatibleMetadata returns metadata for model, or for the first served model
+// when model is empty (single-model servers are the norm for vLLM and llama.cpp).
+func (ms *MetadataService) FetchOpenAICompatibleMetadata(model, baseURL string) (*ModelMetadata, error) {
+	models, err := ms.FetchOpenAICompatibleModelList(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range models {
+		if model == "" || m.ID == model {
+			if m.ContextLength == 0 {
+				return nil, fmt.Errorf("server does not report a context length for model %s", m.ID)
+			}
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("model %s not found", model)
+}
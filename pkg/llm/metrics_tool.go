@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/metrics"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// metricsToolName is the built-in tool exposed alongside MCP tools when a metrics backend is configured.
+const metricsToolName = "metrics_query"
+
+// newMetricsClient returns a client for the configured metrics backend, or nil when none is set.
+func newMetricsClient(cfg *config.Config) *metrics.Client {
+	return metrics.NewClient(cfg.MetricsURL, cfg.MetricsToken, time.Duration(cfg.MetricsTimeout)*time.Second)
+}
+
+// metricsLangchainTool describes the curated metrics queries as a function the model can call.
+func metricsLangchainTool() llms.Tool {
+	return llms.Tool{
+		Type: "function",
+		Function: &llms.FunctionDefinition{
+			Name: metricsToolName,
+			Description: "Query cluster metrics from Prometheus. Use a curated query for CPU throttling, " +
+				"memory working set vs. limits, container restart rates or ingress 5xx ratios; results are summarized as a table.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query": map[string]any{
+						"type":        "string",
+						"description": "Curated query name",
+						"enum":        metrics.QueryNames(),
+					},
+					"namespace": map[string]any{
+						"type":        "string",
+						"description": "Optional namespace to scope the query to",
+					},
+				},
+				"required": []string{"query"},
+			},
+		},
+	}
+}
+
+// executeMetricsTool runs a curated metrics query requested by the model.
+func executeMetricsTool(cfg *config.Config, args map[string]any) (string, error) {
+	client := newMetricsClient(cfg)
+	if client == nil {
+		return "", fmt.Errorf("metrics backend is not configured (set QU_METRICS_URL)")
+	}
+	name, _ := args["query"].(string)
+	spec, ok := metrics.Library[strings.TrimSpace(name)]
+	if !ok {
+		return "", fmt.Errorf("unknown metrics query %q (available: %s)", name, strings.Join(metrics.QueryNames(), ", "))
+	}
+	var namespaces []string
+	if ns, _ := args["namespace"].(string); strings.TrimSpace(ns) != "" {
+		namespaces = []string{strings.TrimSpace(ns)}
+	}
+	samples, err := client.Query(context.Background(), spec.Render(namespaces))
+	if err != nil {
+		return "", err
+	}
+	return metrics.FormatTable(spec, samples, cfg.MetricsMaxRows), nil
+}
+
+// collectMetrics runs the curated queries for the prompt context. The section is recorded
+// to the cassette like a tool call so replays need no metrics backend.
+func collectMetrics(cfg *config.Config, client *metrics.Client, sel metrics.Selection) string {
+	args := map[string]any{"queries": sel.Queries, "namespaces": sel.Namespaces}
+	if section, _, ok := cfg.Cassette.ReplayTool("metrics_collect", args); ok || cfg.Cassette.Replaying() {
+		return section
+	}
+	section := metrics.Collect(context.Background(), client, sel, cfg.MetricsMaxRows)
+	cfg.Cassette.RecordTool("metrics_collect", args, section, nil)
+	return section
+}
+
+// executeToolCall dispatches a model tool call to the built-in metrics tool or to MCP.
+func executeToolCall(cfg *config.Config, name string, args map[string]any) (string, error) {
+	if cfg.Cassette.Replaying() {
+		if result, err, ok := cfg.Cassette.ReplayTool(name, args); ok {
+			return result, err
+		}
+		return "", fmt.Errorf("tool call %s not found in cassette", name)
+	}
+	var result string
+	var err error
+	if name == metricsToolName && cfg.MetricsURL != "" {
+		result, err = executeMetricsTool(cfg, args)
+	} else {
+		result, err = executeMCPTool(cfg, name, args)
+	}
+	cfg.Cassette.RecordTool(name, args, result, err)
+	return result, err
+}
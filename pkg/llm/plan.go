@@ -22,10 +22,38 @@ import (
 
 // PlanStep represents a single planned action.
 type PlanStep struct {
-	StepNumber    int      `json:"step_number"`
-	Action        string   `json:"action"`
-	Reasoning     string   `json:"reasoning"`
-	RequiredTools []string `json:"required_tools,omitempty"`
+	StepNumber    int      `json:"step_number,omitempty" desc:"1-based position of the step"`
+	Action        string   `json:"action" desc:"What to do in this step"`
+	Reasoning     string   `json:"reasoning" desc:"Why the step is needed"`
+	RequiredTools []string `json:"required_tools,omitempty" desc:"Tools the step needs, e.g. kubectl"`
+}
+
+// planPayload is the JSON document the model returns for a plan.
+type planPayload struct {
+	Steps []PlanStep `json:"steps" desc:"Ordered plan steps"`
+}
+
+// problems reports plan content the schema cannot rule out.
+func (p *planPayload) problems() []string {
+	if len(p.Steps) == 0 {
+		return []string{"$.steps: the plan has no steps"}
+	}
+	var problems []string
+	for i, step := range p.Steps {
+		if strings.TrimSpace(step.Action) == "" {
+			problems = append(problems, fmt.Sprintf("$.steps[%d].action: must not be empty", i))
+		}
+	}
+	return problems
+}
+
+// numberSteps fills in missing step numbers from the step order.
+func (p *planPayload) numberSteps() {
+	for i := range p.Steps {
+		if p.Steps[i].StepNumber == 0 {
+			p.Steps[i].StepNumber = i + 1
+		}
+	}
 }
 
 // PlanResult holds the parsed plan and the raw model output.
@@ -102,13 +130,16 @@ func GeneratePlan(ctx context.Context, cfg *config.Config, prompt string, adjust
 
 	// Codex-style plan-mode spinner message + minimal checklist while the plan is generated.
 	origSpinnerOverride := cfg.SpinnerMessageOverride
+	origTask := cfg.ActiveTask
 	cfg.SpinnerMessageOverride = "Asking clarifying questions…"
+	cfg.ActiveTask = config.TaskPlan
 	spinnerManager := lib.GetSpinnerManager(cfg)
 	spinnerManager.SetDetailsLines([]string{
 		"  └ ☐ Drafting plan",
 	})
 	defer func() {
 		cfg.SpinnerMessageOverride = origSpinnerOverride
+		cfg.ActiveTask = origTask
 		spinnerManager.ClearDetailsLines()
 	}()
 
@@ -120,21 +151,29 @@ func GeneratePlan(ctx context.Context, cfg *config.Config, prompt string, adjust
 		cfg.SuppressContentPrint = true
 		cfg.SuppressToolPrint = true
 	}
-	raw, err := RequestWithSystem(cfg, planSystemPrompt, userPrompt, false, false)
+	var payload planPayload
+	raw, err := requestStructured(cfg, structuredRequest{
+		Name:     "plan",
+		Prompt:   userPrompt,
+		Out:      &payload,
+		Validate: payload.problems,
+		Send: func(prompt string) (string, error) {
+			return RequestWithSystem(cfg, planSystemPrompt, prompt, false, false)
+		},
+	})
 	cfg.SuppressContentPrint = origSuppress
 	cfg.SuppressToolPrint = origSuppressTools
 	if err != nil {
+		var schemaErr *StructuredOutputError
+		if errors.As(err, &schemaErr) {
+			logger.Log("warn", "Failed to parse plan JSON: %v", err)
+			return PlanResult{}, fmt.Errorf("failed to parse plan: %w", err)
+		}
 		return PlanResult{}, err
 	}
 
-	plan, err := parsePlan(raw)
-	if err != nil {
-		logger.Log("warn", "Failed to parse plan JSON: %v", err)
-		return PlanResult{}, fmt.Errorf("failed to parse plan: %w", err)
-	}
-
-	plan.Raw = raw
-	return plan, nil
+	payload.numberSteps()
+	return PlanResult{Steps: payload.Steps, Raw: raw}, nil
 }
 
 // ExecutePlan runs each plan step sequentially using the LLM.
@@ -650,18 +689,11 @@ func parsePlan(raw string) (PlanResult, error) {
 		return PlanResult{}, err
 	}
 
-	var payload struct {
-		Steps []PlanStep `json:"steps"`
-	}
+	var payload planPayload
 	if err := json.Unmarshal([]byte(jsonBlob), &payload); err != nil {
 		return PlanResult{}, err
 	}
-
-	for i := range payload.Steps {
-		if payload.Steps[i].StepNumber == 0 {
-			payload.Steps[i].StepNumber = i + 1
-		}
-	}
+	payload.numberSteps()
 
 	return PlanResult{Steps: payload.Steps, Raw: raw}, nil
 }
@@ -143,3 +143,29 @@ func TestRunPlanFlowManualEditReapprovesEditedPlan(t *testing.T) {
 		t.Fatalf("expected 1 plan generation call, got %d", genCalls)
 	}
 }
+
+func TestGeneratePlanRepairsMalformedPlan(t *testing.T) {
+	orig := RequestWithSystem
+	t.Cleanup(func() { RequestWithSystem = orig })
+
+	var prompts []string
+	RequestWithSystem = func(cfg *config.Config, systemPrompt string, userPrompt string, stream bool, history bool) (string, error) {
+		prompts = append(prompts, userPrompt)
+		if len(prompts) == 1 {
+			return `{"steps":[{"action":"","reasoning":"check status"}]}`, nil
+		}
+		return `{"steps":[{"action":"inspect pods","reasoning":"check status"}]}`, nil
+	}
+
+	cfg := config.LoadConfig()
+	plan, err := GeneratePlan(context.Background(), cfg, "inspect cluster", "")
+	if err != nil {
+		t.Fatalf("GeneratePlan returned error: %v", err)
+	}
+	if len(prompts) != 2 || !strings.Contains(prompts[1], "$.steps[0].action: must not be empty") {
+		t.Fatalf("expected a repair request listing the violation, got %q", prompts)
+	}
+	if len(plan.Steps) != 1 || plan.Steps[0].Action != "inspect pods" {
+		t.Fatalf("unexpected plan: %+v", plan.Steps)
+	}
+}
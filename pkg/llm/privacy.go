@@ -0,0 +1,123 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/lib"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/mikhae1/kubectl-quackops/pkg/privacy"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Purposes of the uploads recorded in the privacy report.
+const (
+	uploadChat       = "chat"
+	uploadEmbeddings = "embeddings"
+)
+
+// privacyModel records every request that leaves the machine in the privacy report of the
+// turn. It sits directly on the provider client, so it sees what is actually sent: after
+// pseudonymization and failover rewriting, and never for cache hits or replays.
+type privacyModel struct {
+	llms.Model
+	cfg    *config.Config
+	upload privacy.Upload
+}
+
+func newPrivacyModel(cfg *config.Config, p provider.Provider, opts provider.Options, client llms.Model) llms.Model {
+	return &privacyModel{
+		Model: client,
+		cfg:   cfg,
+		upload: privacy.Upload{
+			Provider: p.Name(),
+			Endpoint: p.BaseURL(opts),
+			Model:    opts.Model,
+			Purpose:  uploadChat,
+		},
+	}
+}
+
+func (m *privacyModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func (m *privacyModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	var opts llms.CallOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+	payload := requestPayload(messages, opts.Tools)
+	up := m.upload
+	up.Bytes = len(payload)
+	up.Tokens = lib.EstimateTokens(m.cfg, payload)
+	m.cfg.Privacy.AddUpload(up)
+	return m.Model.GenerateContent(ctx, messages, options...)
+}
+
+// requestPayload concatenates the content of a request: message text, tool calls, tool
+// results and tool definitions.
+func requestPayload(messages []llms.MessageContent, tools []llms.Tool) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		for _, part := range msg.Parts {
+			switch p := part.(type) {
+			case llms.TextContent:
+				b.WriteString(p.Text)
+			case llms.ToolCall:
+				if p.FunctionCall != nil {
+					b.WriteString(p.FunctionCall.Name)
+					b.WriteString(p.FunctionCall.Arguments)
+				}
+			case llms.ToolCallResponse:
+				b.WriteString(p.Content)
+			}
+		}
+	}
+	if len(tools) > 0 {
+		if data, err := json.Marshal(tools); err == nil {
+			b.Write(data)
+		}
+	}
+	return b.String()
+}
+
+// privacyEmbedder records the texts sent to an embeddings API in the privacy report.
+type privacyEmbedder struct {
+	embeddings.Embedder
+	cfg    *config.Config
+	upload privacy.Upload
+}
+
+func newPrivacyEmbedder(cfg *config.Config, p provider.Provider, opts provider.Options, embedder embeddings.Embedder) embeddings.Embedder {
+	return &privacyEmbedder{
+		Embedder: embedder,
+		cfg:      cfg,
+		upload: privacy.Upload{
+			Provider: p.Name(),
+			Endpoint: p.BaseURL(opts),
+			Model:    opts.EmbeddingModel,
+			Purpose:  uploadEmbeddings,
+		},
+	}
+}
+
+func (e *privacyEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	e.record(strings.Join(texts, ""))
+	return e.Embedder.EmbedDocuments(ctx, texts)
+}
+
+func (e *privacyEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	e.record(text)
+	return e.Embedder.EmbedQuery(ctx, text)
+}
+
+func (e *privacyEmbedder) record(payload string) {
+	up := e.upload
+	up.Bytes = len(payload)
+	up.Tokens = lib.EstimateTokens(e.cfg, payload)
+	e.cfg.Privacy.AddUpload(up)
+}
@@ -0,0 +1,35 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/mikhae1/kubectl-quackops/pkg/privacy"
+)
+
+func TestRequestRecordsUpload(t *testing.T) {
+	mock := NewMockLLMClient([]MockResponse{{Content: "ok"}})
+	provider.Register(namedFakeProvider{fakeProvider{client: mock}, "fake-privacy"})
+
+	cfg := CreateTestConfig()
+	cfg.Provider = "fake-privacy"
+	cfg.Privacy = privacy.NewReport()
+	cfg.Privacy.Reset("why is web failing?")
+
+	system, prompt := "You are a Kubernetes expert.", "why is web failing?"
+	if _, err := RequestWithSystem(cfg, system, prompt, false, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	uploads := cfg.Privacy.Uploads()
+	if len(uploads) != 1 {
+		t.Fatalf("expected one endpoint, got %+v", uploads)
+	}
+	up := uploads[0]
+	if up.Provider != "fake-privacy" || up.Endpoint != "http://fake.invalid" || up.Purpose != uploadChat || up.Requests != 1 {
+		t.Errorf("unexpected upload %+v", up)
+	}
+	if up.Bytes != len(system+prompt) || up.Tokens <= 0 {
+		t.Errorf("expected %d bytes and some tokens, got %+v", len(system+prompt), up)
+	}
+}
@@ -0,0 +1,49 @@
+package llm
+
+import (
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/logger"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// promptCacheTokens returns the prompt tokens a response reports as read from the provider's
+// prompt cache. OpenAI and Gemini report PromptCachedTokens, Anthropic CacheReadInputTokens.
+func promptCacheTokens(resp *llms.ContentResponse) int {
+	if resp == nil || len(resp.Choices) == 0 || resp.Choices[0] == nil {
+		return 0
+	}
+	info := resp.Choices[0].GenerationInfo
+	for _, key := range []string{"PromptCachedTokens", "CacheReadInputTokens"} {
+		if n := intFromAny(info[key]); n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// recordPromptCacheUsage adds the cached prompt tokens of resp to the request and session totals.
+func recordPromptCacheUsage(cfg *config.Config, resp *llms.ContentResponse) {
+	n := promptCacheTokens(resp)
+	if n == 0 {
+		return
+	}
+	cfg.LastCachedTokens += n
+	cfg.SessionCachedTokens += n
+	logger.Log("info", "Prompt cache hit: %d prompt tokens read from the %s cache", n, cfg.Provider)
+}
+
+// intFromAny converts numeric generation info values; replayed cassettes decode them as float64.
+func intFromAny(v any) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestPromptCacheTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		resp *llms.ContentResponse
+		want int
+	}{
+		{name: "nil response"},
+		{name: "no usage", resp: &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: "ok"}}}},
+		{
+			name: "openai",
+			resp: &llms.ContentResponse{Choices: []*llms.ContentChoice{{GenerationInfo: map[string]any{"PromptTokens": 3000, "PromptCachedTokens": 2048}}}},
+			want: 2048,
+		},
+		{
+			name: "anthropic",
+			resp: &llms.ContentResponse{Choices: []*llms.ContentChoice{{GenerationInfo: map[string]any{"InputTokens": 12, "CacheReadInputTokens": 4100}}}},
+			want: 4100,
+		},
+		{
+			name: "replayed cassette",
+			resp: &llms.ContentResponse{Choices: []*llms.ContentChoice{{GenerationInfo: map[string]any{"PromptCachedTokens": float64(1024)}}}},
+			want: 1024,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := promptCacheTokens(tt.resp); got != tt.want {
+				t.Fatalf("promptCacheTokens() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordPromptCacheUsage(t *testing.T) {
+	cfg := &config.Config{SessionCachedTokens: 100}
+	resp := &llms.ContentResponse{Choices: []*llms.ContentChoice{{GenerationInfo: map[string]any{"CacheReadInputTokens": 50}}}}
+	recordPromptCacheUsage(cfg, resp)
+	recordPromptCacheUsage(cfg, resp)
+	if cfg.LastCachedTokens != 100 || cfg.SessionCachedTokens != 200 {
+		t.Fatalf("unexpected totals: last=%d session=%d", cfg.LastCachedTokens, cfg.SessionCachedTokens)
+	}
+}
+
+func TestCacheBreakpointModelMarksFirstAndLastHumanMessages(t *testing.T) {
+	mock := NewMockLLMClient([]MockResponse{{Content: "ok"}})
+	model := &cacheBreakpointModel{Model: mock}
+	messages := []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "system"),
+		llms.TextParts(llms.ChatMessageTypeHuman, "baseline context", "first question"),
+		llms.TextParts(llms.ChatMessageTypeAI, "answer"),
+		llms.TextParts(llms.ChatMessageTypeHuman, "follow-up"),
+	}
+
+	if _, err := model.GenerateContent(context.Background(), messages); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := mock.callHistory[0].Messages
+	cached := func(msg llms.MessageContent) []bool {
+		var marks []bool
+		for _, part := range msg.Parts {
+			_, ok := part.(llms.CachedContent)
+			marks = append(marks, ok)
+		}
+		return marks
+	}
+	if got := cached(sent[1]); len(got) != 2 || got[0] || !got[1] {
+		t.Errorf("first human message should be marked on its last part: %v", got)
+	}
+	if got := cached(sent[3]); len(got) != 1 || !got[0] {
+		t.Errorf("last human message should be marked: %v", got)
+	}
+	if got := cached(sent[0]); got[0] {
+		t.Error("system message must not be marked")
+	}
+	if _, ok := messages[1].Parts[1].(llms.TextContent); !ok {
+		t.Error("caller messages were modified")
+	}
+}
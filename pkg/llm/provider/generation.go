@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ReasoningEfforts lists the accepted reasoning effort levels, lowest first.
+var ReasoningEfforts = []string{"minimal", "low", "medium", "high"}
+
+// Generation holds sampling and reasoning settings for a request. Unset fields (nil or zero)
+// leave the provider default in place.
+type Generation struct {
+	Temperature     *float64
+	TopP            *float64
+	MaxOutputTokens int
+	// ReasoningEffort is sent as reasoning_effort to OpenAI-style APIs and mapped to a
+	// thinking budget for Anthropic, Gemini and Ollama.
+	ReasoningEffort string
+	// ThinkingBudget is an explicit thinking token budget; it takes precedence over the
+	// budget derived from ReasoningEffort.
+	ThinkingBudget int
+	Seed           *int
+}
+
+// generationKeys lists the setting names accepted by Set, in display order.
+var generationKeys = []string{"temperature", "top_p", "max_output_tokens", "reasoning_effort", "thinking_budget", "seed"}
+
+// GenerationKeys returns the setting names accepted by Generation.Set.
+func GenerationKeys() []string {
+	return append([]string(nil), generationKeys...)
+}
+
+// ParseGeneration parses a comma-separated list of key=value settings, e.g.
+// "temperature=0.2,reasoning_effort=high".
+func ParseGeneration(spec string) (Generation, error) {
+	var g Generation
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return Generation{}, fmt.Errorf("invalid setting %q: expected key=value", item)
+		}
+		if err := g.Set(key, value); err != nil {
+			return Generation{}, err
+		}
+	}
+	return g, nil
+}
+
+// Set assigns one setting by name. An empty value unsets it.
+func (g *Generation) Set(key, value string) error {
+	key = strings.ToLower(strings.TrimSpace(key))
+	value = strings.TrimSpace(value)
+	parseFloat := func(lo, hi float64) (*float64, error) {
+		if value == "" {
+			return nil, nil
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || f < lo || f > hi {
+			return nil, fmt.Errorf("%s must be a number between %g and %g, got %q", key, lo, hi, value)
+		}
+		return &f, nil
+	}
+	parseInt := func() (int, error) {
+		if value == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%s must be a non-negative integer, got %q", key, value)
+		}
+		return n, nil
+	}
+
+	var err error
+	switch key {
+	case "temperature":
+		g.Temperature, err = parseFloat(0, 2)
+	case "top_p":
+		g.TopP, err = parseFloat(0, 1)
+	case "max_output_tokens", "max_tokens":
+		g.MaxOutputTokens, err = parseInt()
+	case "reasoning_effort", "effort":
+		value = strings.ToLower(value)
+		if value != "" && !slices.Contains(ReasoningEfforts, value) {
+			return fmt.Errorf("reasoning_effort must be one of %s, got %q", strings.Join(ReasoningEfforts, ", "), value)
+		}
+		g.ReasoningEffort = value
+	case "thinking_budget":
+		g.ThinkingBudget, err = parseInt()
+	case "seed":
+		if value == "" {
+			g.Seed = nil
+			return nil
+		}
+		var n int
+		if n, err = strconv.Atoi(value); err != nil {
+			return fmt.Errorf("seed must be an integer, got %q", value)
+		}
+		g.Seed = &n
+	default:
+		return fmt.Errorf("unknown setting %q (known: %s)", key, strings.Join(generationKeys, ", "))
+	}
+	return err
+}
+
+// Merge returns g with every setting that is set in o overriding it.
+func (g Generation) Merge(o Generation) Generation {
+	if o.Temperature != nil {
+		g.Temperature = o.Temperature
+	}
+	if o.TopP != nil {
+		g.TopP = o.TopP
+	}
+	if o.MaxOutputTokens > 0 {
+		g.MaxOutputTokens = o.MaxOutputTokens
+	}
+	if o.ReasoningEffort != "" {
+		g.ReasoningEffort = o.ReasoningEffort
+	}
+	if o.ThinkingBudget > 0 {
+		g.ThinkingBudget = o.ThinkingBudget
+	}
+	if o.Seed != nil {
+		g.Seed = o.Seed
+	}
+	return g
+}
+
+// IsZero reports whether no setting is set.
+func (g Generation) IsZero() bool {
+	return g == Generation{}
+}
+
+// String formats the set settings in ParseGeneration syntax.
+func (g Generation) String() string {
+	values := map[string]string{}
+	if g.Temperature != nil {
+		values["temperature"] = strconv.FormatFloat(*g.Temperature, 'g', -1, 64)
+	}
+	if g.TopP != nil {
+		values["top_p"] = strconv.FormatFloat(*g.TopP, 'g', -1, 64)
+	}
+	if g.MaxOutputTokens > 0 {
+		values["max_output_tokens"] = strconv.Itoa(g.MaxOutputTokens)
+	}
+	if g.ReasoningEffort != "" {
+		values["reasoning_effort"] = g.ReasoningEffort
+	}
+	if g.ThinkingBudget > 0 {
+		values["thinking_budget"] = strconv.Itoa(g.ThinkingBudget)
+	}
+	if g.Seed != nil {
+		values["seed"] = strconv.Itoa(*g.Seed)
+	}
+	parts := make([]string, 0, len(values))
+	for _, key := range generationKeys {
+		if v, ok := values[key]; ok {
+			parts = append(parts, key+"="+v)
+		}
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,44 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseGeneration(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    string
+		wantErr string
+	}{
+		{name: "empty", spec: ""},
+		{
+			name: "all settings",
+			spec: "seed=7, temperature=0.2,top_p=0.9,max_tokens=2048,effort=HIGH,thinking_budget=4096",
+			want: "temperature=0.2,top_p=0.9,max_output_tokens=2048,reasoning_effort=high,thinking_budget=4096,seed=7",
+		},
+		{name: "unset", spec: "temperature=0.5,temperature=", want: ""},
+		{name: "temperature out of range", spec: "temperature=3", wantErr: "temperature must be a number between 0 and 2"},
+		{name: "unknown effort", spec: "reasoning_effort=max", wantErr: "reasoning_effort must be one of"},
+		{name: "unknown key", spec: "top_k=5", wantErr: `unknown setting "top_k"`},
+		{name: "missing value", spec: "temperature", wantErr: "expected key=value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := ParseGeneration(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := g.String(); got != tt.want {
+				t.Fatalf("ParseGeneration(%q) = %q, want %q", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
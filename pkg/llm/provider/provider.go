@@ -0,0 +1,115 @@
+// Package provider defines the LLM provider interface and the registry used to look
+// providers up by name. Built-in providers register themselves from package llm; third
+// parties can add their own by calling Register from an init function.
+package provider
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/metadata"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// ErrEmbeddingsUnsupported is returned by NewEmbedder when a provider has no embedding API.
+var ErrEmbeddingsUnsupported = errors.New("embeddings are not supported by this provider")
+
+// Options carries the configuration a provider needs to build clients.
+type Options struct {
+	Model           string   // Chat model
+	EmbeddingModel  string   // Embedding model
+	ServerURL       string   // Explicit API URL from --api-url / QU_OLLAMA_BASE_URL
+	APIVersion      string   // API version for versioned APIs (Azure OpenAI)
+	EmbeddingModels []string // Local embedding model candidates, tried in order
+
+	// ResponseSchema asks for output matching a JSON schema through the provider's native
+	// structured output support; nil for free-form text.
+	ResponseSchema *ResponseSchema
+	// Generation carries the sampling and reasoning settings for the request. Most map to
+	// call options; providers apply the ones langchaingo does not send.
+	Generation Generation
+	// PromptCache asks providers with explicit prompt caching to cache the stable prefix
+	// of a request: system prompt, tool definitions and the first turn.
+	PromptCache bool
+}
+
+// ResponseSchema is a named JSON schema a structured response must follow.
+type ResponseSchema struct {
+	Name   string
+	Schema map[string]any
+}
+
+// Defaults groups default values applied when the user does not configure them.
+type Defaults struct {
+	MaxTokens      int
+	Model          string
+	EmbeddingModel string
+}
+
+// Quirks describes provider- or model-specific request adjustments.
+type Quirks struct {
+	DisableStreaming bool     // Endpoint mishandles streamed responses
+	DisableTools     bool     // Endpoint rejects tool definitions
+	JSONMode         bool     // Endpoint honors response_format json_object
+	Temperature      *float64 // Model only accepts this temperature
+}
+
+// Provider is an LLM backend: client construction, embeddings, model metadata and defaults.
+type Provider interface {
+	metadata.Source
+
+	// Name is the value users pass via --provider / QU_LLM_PROVIDER.
+	Name() string
+	// Defaults returns the default model, embedding model and context window.
+	Defaults() Defaults
+	// BaseURL returns the API base URL, applying environment overrides; empty when unknown.
+	BaseURL(opts Options) string
+	// NewModel builds a chat client.
+	NewModel(ctx context.Context, opts Options) (llms.Model, error)
+	// NewEmbedder builds an embedder, or returns ErrEmbeddingsUnsupported.
+	NewEmbedder(ctx context.Context, opts Options) (embeddings.Embedder, error)
+	// Quirks returns request adjustments for the configured model and endpoint.
+	Quirks(opts Options) Quirks
+}
+
+var (
+	mu        sync.RWMutex
+	providers = map[string]Provider{}
+)
+
+// Register adds a provider to the registry, replacing any provider with the same name,
+// and registers it as the metadata source for that name.
+func Register(p Provider) {
+	name := strings.ToLower(strings.TrimSpace(p.Name()))
+	if name == "" {
+		panic("provider: Register called with an empty provider name")
+	}
+	mu.Lock()
+	providers[name] = p
+	mu.Unlock()
+	metadata.RegisterSource(name, p)
+}
+
+// Get returns the provider registered under name.
+func Get(name string) (Provider, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	p, ok := providers[strings.ToLower(strings.TrimSpace(name))]
+	return p, ok
+}
+
+// Names returns the registered provider names in sorted order.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
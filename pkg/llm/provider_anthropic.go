@@ -0,0 +1,155 @@
+package llm
+
+import (
+	"context"
+	"os"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/metadata"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/anthropic"
+)
+
+func init() {
+	provider.Register(anthropicProvider{})
+}
+
+// anthropicProvider talks to the Anthropic Messages API.
+type anthropicProvider struct{}
+
+func (anthropicProvider) Name() string { return "anthropic" }
+
+func (anthropicProvider) Defaults() provider.Defaults {
+	return provider.Defaults{MaxTokens: 200000, Model: "claude-3-7-sonnet-latest", EmbeddingModel: "nomic-embed-text"}
+}
+
+func (anthropicProvider) BaseURL(opts provider.Options) string {
+	if baseURL := os.Getenv("QU_ANTHROPIC_BASE_URL"); baseURL != "" {
+		return baseURL
+	}
+	return "https://api.anthropic.com"
+}
+
+func (anthropicProvider) NewModel(ctx context.Context, opts provider.Options) (llms.Model, error) {
+	client, err := anthropic.New()
+	if err != nil {
+		return nil, err
+	}
+	var model llms.Model = client
+	if opts.PromptCache {
+		model = &cacheBreakpointModel{Model: model}
+	}
+	if opts.ResponseSchema != nil {
+		model = &toolOutputModel{Model: model, schema: opts.ResponseSchema}
+	}
+	return model, nil
+}
+
+// NewEmbedder is unsupported: Anthropic has no embedding models in langchaingo.
+func (anthropicProvider) NewEmbedder(ctx context.Context, opts provider.Options) (embeddings.Embedder, error) {
+	return nil, provider.ErrEmbeddingsUnsupported
+}
+
+func (anthropicProvider) Quirks(opts provider.Options) provider.Quirks {
+	return provider.Quirks{}
+}
+
+func (anthropicProvider) ModelMetadata(ms *metadata.MetadataService, model, baseURL string) (*metadata.ModelMetadata, error) {
+	return ms.FetchAnthropicMetadata(model, baseURL)
+}
+
+func (anthropicProvider) ModelList(ms *metadata.MetadataService, baseURL string) ([]*metadata.ModelMetadata, error) {
+	return ms.FetchAnthropicModelList(baseURL)
+}
+
+// toolOutputModel gets structured output from Anthropic, which has no JSON mode: the schema
+// is offered as the only tool and the tool call arguments become the response content.
+// langchaingo cannot set tool_choice for Anthropic, so a plain text answer is passed through
+// and left to schema validation.
+type toolOutputModel struct {
+	llms.Model
+	schema *provider.ResponseSchema
+}
+
+func (m *toolOutputModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func (m *toolOutputModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	tool := llms.Tool{
+		Type: "function",
+		Function: &llms.FunctionDefinition{
+			Name:        m.schema.Name,
+			Description: "Always call this tool to return your final answer as structured data.",
+			Parameters:  m.schema.Schema,
+		},
+	}
+	options = append(options, llms.WithTools([]llms.Tool{tool}))
+	resp, err := m.Model.GenerateContent(ctx, messages, options...)
+	if err != nil || resp == nil {
+		return resp, err
+	}
+	for _, choice := range resp.Choices {
+		for _, tc := range choice.ToolCalls {
+			if tc.FunctionCall != nil && tc.FunctionCall.Name == m.schema.Name {
+				choice.Content = tc.FunctionCall.Arguments
+				choice.ToolCalls = nil
+				choice.FuncCall = nil
+				break
+			}
+		}
+	}
+	return resp, nil
+}
+
+// cacheBreakpointModel marks Anthropic prompt cache breakpoints. Anthropic caches the request
+// prefix up to each cache_control marker, and tools and the system prompt come first, so a
+// breakpoint on the first user message covers tools, system prompt and the baseline context
+// gathered for the first question. A second breakpoint on the latest user message lets the
+// next turn reuse the whole conversation so far.
+type cacheBreakpointModel struct {
+	llms.Model
+}
+
+func (m *cacheBreakpointModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func (m *cacheBreakpointModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	return m.Model.GenerateContent(ctx, withCacheBreakpoints(messages), options...)
+}
+
+// withCacheBreakpoints returns a copy of messages whose first and last human messages carry an
+// ephemeral cache_control marker on their last text part.
+func withCacheBreakpoints(messages []llms.MessageContent) []llms.MessageContent {
+	first, last := -1, -1
+	for i, msg := range messages {
+		if msg.Role != llms.ChatMessageTypeHuman {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		last = i
+	}
+	if first < 0 {
+		return messages
+	}
+	marks := []int{first}
+	if last != first {
+		marks = append(marks, last)
+	}
+	out := append([]llms.MessageContent(nil), messages...)
+	for _, i := range marks {
+		parts := append([]llms.ContentPart(nil), out[i].Parts...)
+		for j := len(parts) - 1; j >= 0; j-- {
+			if text, ok := parts[j].(llms.TextContent); ok {
+				parts[j] = llms.WithCacheControl(text, anthropic.EphemeralCache())
+				break
+			}
+		}
+		out[i].Parts = parts
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package llm
+
+import (
+	"context"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/metadata"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+func init() {
+	provider.Register(azOpenAIProvider{})
+}
+
+// azOpenAIProvider talks to an Azure OpenAI deployment.
+type azOpenAIProvider struct{}
+
+func (azOpenAIProvider) Name() string { return "azopenai" }
+
+func (azOpenAIProvider) Defaults() provider.Defaults {
+	return provider.Defaults{MaxTokens: 128000, Model: "gpt-4o-mini", EmbeddingModel: "text-embedding-3-small"}
+}
+
+// BaseURL has no sensible default for Azure; it must be provided by the user.
+func (azOpenAIProvider) BaseURL(opts provider.Options) string {
+	return config.GetAzOpenAIBaseURL()
+}
+
+func (azOpenAIProvider) NewModel(ctx context.Context, opts provider.Options) (llms.Model, error) {
+	llmOptions := []openai.Option{
+		openai.WithAPIType(openai.APITypeAzure),
+		openai.WithModel(opts.Model),
+		openai.WithAPIVersion(opts.APIVersion),
+	}
+	if baseURL := config.GetAzOpenAIBaseURL(); baseURL != "" {
+		llmOptions = append(llmOptions, openai.WithBaseURL(baseURL))
+	}
+	if apiKey := config.GetAzOpenAIAPIKey(); apiKey != "" {
+		llmOptions = append(llmOptions, openai.WithToken(apiKey))
+	}
+	if opts.EmbeddingModel != "" {
+		llmOptions = append(llmOptions, openai.WithEmbeddingModel(opts.EmbeddingModel))
+	}
+	if opts.ResponseSchema != nil {
+		llmOptions = append(llmOptions, openai.WithResponseFormat(openAIResponseFormat(opts.ResponseSchema)))
+	}
+	if doer := reasoningEffortClient(opts.Generation); doer != nil {
+		llmOptions = append(llmOptions, openai.WithHTTPClient(doer))
+	}
+	return openai.New(llmOptions...)
+}
+
+// NewEmbedder is unsupported: embedding deployments are separate from chat deployments,
+// so RAG trimming falls back to other embedding providers.
+func (azOpenAIProvider) NewEmbedder(ctx context.Context, opts provider.Options) (embeddings.Embedder, error) {
+	return nil, provider.ErrEmbeddingsUnsupported
+}
+
+func (azOpenAIProvider) Quirks(opts provider.Options) provider.Quirks {
+	q := openaiModelQuirks(opts.Model)
+	q.JSONMode = true
+	return q
+}
+
+func (azOpenAIProvider) ModelMetadata(ms *metadata.MetadataService, model, baseURL string) (*metadata.ModelMetadata, error) {
+	return ms.FetchAzureOpenAIMetadata(model, baseURL)
+}
+
+func (azOpenAIProvider) ModelList(ms *metadata.MetadataService, baseURL string) ([]*metadata.ModelMetadata, error) {
+	return ms.FetchAzureOpenAIModelList(baseURL)
+}
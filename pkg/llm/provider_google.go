@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/metadata"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/mikhae1/kubectl-quackops/pkg/logger"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/googleai"
+)
+
+func init() {
+	provider.Register(googleProvider{})
+}
+
+// googleProvider talks to the Gemini API, preferring the native genai client.
+type googleProvider struct{}
+
+func (googleProvider) Name() string { return "google" }
+
+func (googleProvider) Defaults() provider.Defaults {
+	return provider.Defaults{MaxTokens: 128000, Model: "gemini-2.5-flash-preview-04-17", EmbeddingModel: "models/text-embedding-004"}
+}
+
+func (googleProvider) BaseURL(opts provider.Options) string {
+	if baseURL := os.Getenv("QU_GOOGLE_BASE_URL"); baseURL != "" {
+		return baseURL
+	}
+	if baseURL := os.Getenv("GOOGLE_GEMINI_BASE_URL"); baseURL != "" {
+		return baseURL
+	}
+	return "https://generativelanguage.googleapis.com"
+}
+
+func (googleProvider) NewModel(ctx context.Context, opts provider.Options) (llms.Model, error) {
+	apiKey := config.GetGoogleAPIKey()
+	if apiKey != "" {
+		if custom, err := New(ctx, apiKey, opts.Model); err == nil {
+			if opts.ResponseSchema != nil {
+				custom.ResponseSchema = opts.ResponseSchema.Schema
+			}
+			custom.PromptCache = opts.PromptCache
+			return custom, nil
+		}
+	}
+	// Fallback to stock googleai client
+	return googleai.New(ctx,
+		googleai.WithAPIKey(apiKey),
+		googleai.WithDefaultModel(opts.Model),
+	)
+}
+
+func (googleProvider) NewEmbedder(ctx context.Context, opts provider.Options) (embeddings.Embedder, error) {
+	apiKey := config.GetGoogleAPIKey()
+	if apiKey == "" {
+		return nil, fmt.Errorf("Google API key not found in environment (GOOGLE_API_KEY or GEMINI_API_KEY)")
+	}
+	client, err := googleai.New(ctx,
+		googleai.WithAPIKey(apiKey),
+		googleai.WithDefaultEmbeddingModel(opts.EmbeddingModel),
+	)
+	if err != nil {
+		return nil, err
+	}
+	logger.Log("info", "Using Google AI embedding model: %s", opts.EmbeddingModel)
+	return &GoogleEmbedder{client: client, model: opts.EmbeddingModel}, nil
+}
+
+func (googleProvider) Quirks(opts provider.Options) provider.Quirks {
+	return provider.Quirks{JSONMode: true}
+}
+
+func (googleProvider) ModelMetadata(ms *metadata.MetadataService, model, baseURL string) (*metadata.ModelMetadata, error) {
+	return ms.FetchGoogleMetadata(model, baseURL)
+}
+
+func (googleProvider) ModelList(ms *metadata.MetadataService, baseURL string) ([]*metadata.ModelMetadata, error) {
+	return ms.FetchGoogleModelList(baseURL)
+}
+
+// GoogleEmbedder is a wrapper around the GoogleAI client that provides embedding functionality
+type GoogleEmbedder struct {
+	client *googleai.GoogleAI
+	model  string
+}
+
+// EmbedDocuments implements the Embedder interface for GoogleEmbedder
+func (g *GoogleEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	// Use the native embedding functionality of googleai package
+	embeddings, err := g.client.CreateEmbedding(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("error creating embeddings: %w", err)
+	}
+	return embeddings, nil
+}
+
+// EmbedQuery implements the Embedder interface for GoogleEmbedder
+func (g *GoogleEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	// Use the CreateEmbedding function with a single text
+	embeddings, err := g.client.CreateEmbedding(ctx, []string{text})
+	if err != nil {
+		return nil, fmt.Errorf("error creating query embedding: %w", err)
+	}
+
+	if len(embeddings) == 0 || len(embeddings[0]) == 0 {
+		return nil, fmt.Errorf("empty embedding returned")
+	}
+
+	return embeddings[0], nil
+}
@@ -0,0 +1,98 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/metadata"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/mikhae1/kubectl-quackops/pkg/logger"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/ollama"
+)
+
+func init() {
+	provider.Register(ollamaProvider{})
+}
+
+// ollamaProvider talks to a local or remote Ollama server.
+type ollamaProvider struct{}
+
+func (ollamaProvider) Name() string { return "ollama" }
+
+func (ollamaProvider) Defaults() provider.Defaults {
+	return provider.Defaults{MaxTokens: 4096, Model: "llama3.1", EmbeddingModel: "models/text-embedding-large-exp"}
+}
+
+func (ollamaProvider) BaseURL(opts provider.Options) string {
+	if opts.ServerURL != "" {
+		return opts.ServerURL
+	}
+	return "http://localhost:11434"
+}
+
+func (p ollamaProvider) NewModel(ctx context.Context, opts provider.Options) (llms.Model, error) {
+	options := []ollama.Option{
+		ollama.WithModel(opts.Model),
+		ollama.WithServerURL(strings.TrimSuffix(p.BaseURL(opts), "/api")),
+	}
+	// Ollama accepts a JSON schema as the request format; langchaingo only sends "json"
+	if opts.ResponseSchema != nil {
+		options = append(options, ollama.WithHTTPClient(&http.Client{Transport: ollamaFormatDoer(opts.ResponseSchema)}))
+	}
+	return ollama.New(options...)
+}
+
+// ollamaFormatDoer replaces the format of chat requests with the response schema.
+func ollamaFormatDoer(rs *provider.ResponseSchema) *bodyFieldsDoer {
+	return &bodyFieldsDoer{
+		client:   http.DefaultClient,
+		path:     "/api/chat",
+		fields:   map[string]any{"format": rs.Schema},
+		override: true,
+	}
+}
+
+// NewEmbedder prefers a dedicated embedding model and falls back to the chat model.
+func (ollamaProvider) NewEmbedder(ctx context.Context, opts provider.Options) (embeddings.Embedder, error) {
+	if opts.ServerURL == "" {
+		return nil, fmt.Errorf("ollama server URL is not configured")
+	}
+	serverURL := strings.TrimSuffix(opts.ServerURL, "/api")
+	candidates := append([]string{}, opts.EmbeddingModels...)
+	if opts.Model != "" {
+		candidates = append(candidates, opts.Model)
+	}
+	var lastErr error
+	for _, model := range candidates {
+		client, err := ollama.New(
+			ollama.WithModel(model),
+			ollama.WithServerURL(serverURL),
+		)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		logger.Log("info", "Using Ollama embeddings model: %s", model)
+		return embeddings.NewEmbedder(client)
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no ollama embedding model configured")
+	}
+	return nil, lastErr
+}
+
+func (ollamaProvider) Quirks(opts provider.Options) provider.Quirks {
+	return provider.Quirks{JSONMode: true}
+}
+
+func (ollamaProvider) ModelMetadata(ms *metadata.MetadataService, model, baseURL string) (*metadata.ModelMetadata, error) {
+	return ms.FetchOllamaMetadata(model, baseURL)
+}
+
+func (ollamaProvider) ModelList(ms *metadata.MetadataService, baseURL string) ([]*metadata.ModelMetadata, error) {
+	return ms.FetchOllamaModelList(baseURL)
+}
@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/metadata"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+func init() {
+	provider.Register(openaiProvider{})
+}
+
+// openaiProvider talks to OpenAI or any OpenAI-compatible endpoint set via QU_OPENAI_BASE_URL.
+type openaiProvider struct{}
+
+func (openaiProvider) Name() string { return "openai" }
+
+func (openaiProvider) Defaults() provider.Defaults {
+	return provider.Defaults{MaxTokens: 128000, Model: "gpt-5-mini", EmbeddingModel: "text-embedding-3-small"}
+}
+
+func (openaiProvider) BaseURL(opts provider.Options) string {
+	if baseURL := config.GetOpenAIBaseURL(); baseURL != "" {
+		return baseURL
+	}
+	if strings.Contains(opts.Model, "/") || strings.Contains(opts.Model, "openrouter") {
+		return "https://openrouter.ai/api/v1"
+	}
+	return "https://api.openai.com"
+}
+
+func (openaiProvider) NewModel(ctx context.Context, opts provider.Options) (llms.Model, error) {
+	llmOptions := []openai.Option{
+		openai.WithModel(opts.Model),
+	}
+	// Support custom OpenAI-compatible base URL
+	if baseURL := config.GetOpenAIBaseURL(); baseURL != "" {
+		llmOptions = append(llmOptions, openai.WithBaseURL(baseURL))
+	} else if opts.ResponseSchema != nil {
+		llmOptions = append(llmOptions, openai.WithResponseFormat(openAIResponseFormat(opts.ResponseSchema)))
+	}
+	if doer := reasoningEffortClient(opts.Generation); doer != nil {
+		llmOptions = append(llmOptions, openai.WithHTTPClient(doer))
+	}
+	return openai.New(llmOptions...)
+}
+
+func (openaiProvider) NewEmbedder(ctx context.Context, opts provider.Options) (embeddings.Embedder, error) {
+	if os.Getenv("OPENAI_API_KEY") == "" {
+		return nil, fmt.Errorf("OPENAI_API_KEY is not set")
+	}
+	openaiOpts := []openai.Option{openai.WithModel(opts.EmbeddingModel)}
+	if baseURL := config.GetOpenAIBaseURL(); baseURL != "" {
+		openaiOpts = append(openaiOpts, openai.WithBaseURL(baseURL))
+	}
+	client, err := openai.New(openaiOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return embeddings.NewEmbedder(client)
+}
+
+func (openaiProvider) Quirks(opts provider.Options) provider.Quirks {
+	q := openaiModelQuirks(opts.Model)
+	// Only disable streaming for known problematic endpoints
+	if strings.Contains(config.GetOpenAIBaseURL(), "openrouter.ai") {
+		q.DisableStreaming = true
+	}
+	// Custom endpoints may not implement response_format; use openai-compatible to probe them
+	q.JSONMode = config.GetOpenAIBaseURL() == ""
+	return q
+}
+
+func (openaiProvider) ModelMetadata(ms *metadata.MetadataService, model, baseURL string) (*metadata.ModelMetadata, error) {
+	if metadata.IsOpenRouterURL(baseURL) {
+		return ms.FetchOpenRouterMetadata(model, baseURL)
+	}
+	return ms.FetchOpenAIMetadata(model, baseURL)
+}
+
+func (openaiProvider) ModelList(ms *metadata.MetadataService, baseURL string) ([]*metadata.ModelMetadata, error) {
+	if metadata.IsOpenRouterURL(baseURL) {
+		return ms.FetchOpenRouterModelList(baseURL)
+	}
+	return ms.FetchOpenAIModelList(baseURL)
+}
+
+// openaiModelQuirks applies model-family restrictions shared by OpenAI and Azure OpenAI.
+func openaiModelQuirks(model string) provider.Quirks {
+	var q provider.Quirks
+	if strings.Contains(model, "gpt-5") {
+		// gpt-5 models require temperature 1.0
+		t := 1.0
+		q.Temperature = &t
+	}
+	return q
+}
+
+// openAIResponseFormat converts a response schema to an OpenAI json_schema response format.
+// Strict mode is off because it requires every property to be listed as required.
+func openAIResponseFormat(rs *provider.ResponseSchema) *openai.ResponseFormat {
+	return &openai.ResponseFormat{
+		Type: "json_schema",
+		JSONSchema: &openai.ResponseFormatJSONSchema{
+			Name:   rs.Name,
+			Schema: openAISchemaProperty(rs.Schema),
+		},
+	}
+}
+
+func openAISchemaProperty(m map[string]any) *openai.ResponseFormatJSONSchemaProperty {
+	prop := &openai.ResponseFormatJSONSchemaProperty{}
+	prop.Type, _ = m["type"].(string)
+	prop.Description, _ = m["description"].(string)
+	prop.Required, _ = m["required"].([]string)
+	if items, ok := m["items"].(map[string]any); ok {
+		prop.Items = openAISchemaProperty(items)
+	}
+	if props, ok := m["properties"].(map[string]any); ok {
+		prop.Properties = make(map[string]*openai.ResponseFormatJSONSchemaProperty, len(props))
+		for name, p := range props {
+			if pm, ok := p.(map[string]any); ok {
+				prop.Properties[name] = openAISchemaProperty(pm)
+			}
+		}
+	}
+	return prop
+}
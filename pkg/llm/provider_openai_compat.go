@@ -0,0 +1,236 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/metadata"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/mikhae1/kubectl-quackops/pkg/logger"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+func init() {
+	provider.Register(openaiCompatProvider{})
+}
+
+const openaiCompatDefaultBaseURL = "http://localhost:8000/v1"
+
+// compatProbeTimeout bounds each capability probe request.
+var compatProbeTimeout = 10 * time.Second
+
+// openaiCompatProvider talks to self-hosted OpenAI-compatible servers such as vLLM, llama.cpp
+// and LM Studio. Unlike openai with a custom base URL, it probes which features the server
+// actually supports and lets QU_OPENAI_COMPAT_QUIRKS override the result.
+type openaiCompatProvider struct{}
+
+func (openaiCompatProvider) Name() string { return "openai-compatible" }
+
+// Defaults leaves the model empty: the first model served by the endpoint is used.
+func (openaiCompatProvider) Defaults() provider.Defaults {
+	return provider.Defaults{MaxTokens: 8192}
+}
+
+func (openaiCompatProvider) BaseURL(opts provider.Options) string {
+	if baseURL := config.GetOpenAICompatBaseURL(); baseURL != "" {
+		return compatAPIBase(baseURL)
+	}
+	return openaiCompatDefaultBaseURL
+}
+
+func (p openaiCompatProvider) NewModel(ctx context.Context, opts provider.Options) (llms.Model, error) {
+	baseURL := p.BaseURL(opts)
+	model, err := resolveCompatModel(baseURL, opts.Model)
+	if err != nil {
+		return nil, err
+	}
+	llmOptions := []openai.Option{
+		openai.WithModel(model),
+		openai.WithBaseURL(baseURL),
+		openai.WithToken(compatAPIKey()),
+	}
+	// Servers that honor response_format generally accept json_schema too (vLLM, llama.cpp, LM Studio)
+	if opts.ResponseSchema != nil && p.Quirks(opts).JSONMode {
+		llmOptions = append(llmOptions, openai.WithResponseFormat(openAIResponseFormat(opts.ResponseSchema)))
+	}
+	if doer := reasoningEffortClient(opts.Generation); doer != nil {
+		llmOptions = append(llmOptions, openai.WithHTTPClient(doer))
+	}
+	return openai.New(llmOptions...)
+}
+
+// NewEmbedder is only available when QU_EMBEDDING_MODEL names an embedding model the server hosts.
+func (p openaiCompatProvider) NewEmbedder(ctx context.Context, opts provider.Options) (embeddings.Embedder, error) {
+	if strings.TrimSpace(opts.EmbeddingModel) == "" {
+		return nil, provider.ErrEmbeddingsUnsupported
+	}
+	client, err := openai.New(
+		openai.WithEmbeddingModel(opts.EmbeddingModel),
+		openai.WithBaseURL(p.BaseURL(opts)),
+		openai.WithToken(compatAPIKey()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return embeddings.NewEmbedder(client)
+}
+
+func (p openaiCompatProvider) Quirks(opts provider.Options) provider.Quirks {
+	flags := map[string]bool{}
+	for _, f := range config.GetOpenAICompatQuirks() {
+		switch f {
+		case "no-streaming", "no-tools", "no-json-mode", "no-probe":
+			flags[f] = true
+		default:
+			logger.Log("warn", "Ignoring unknown QU_OPENAI_COMPAT_QUIRKS flag: %s", f)
+		}
+	}
+
+	caps := compatCapabilities{Streaming: true, Tools: true, JSONMode: true}
+	if !flags["no-probe"] {
+		baseURL := p.BaseURL(opts)
+		if model, err := resolveCompatModel(baseURL, opts.Model); err == nil {
+			caps = probeOpenAICompat(baseURL, model)
+		}
+	}
+	return provider.Quirks{
+		DisableStreaming: !caps.Streaming || flags["no-streaming"],
+		DisableTools:     !caps.Tools || flags["no-tools"],
+		JSONMode:         caps.JSONMode && !flags["no-json-mode"],
+	}
+}
+
+func (openaiCompatProvider) ModelMetadata(ms *metadata.MetadataService, model, baseURL string) (*metadata.ModelMetadata, error) {
+	return ms.FetchOpenAICompatibleMetadata(model, baseURL)
+}
+
+func (openaiCompatProvider) ModelList(ms *metadata.MetadataService, baseURL string) ([]*metadata.ModelMetadata, error) {
+	return ms.FetchOpenAICompatibleModelList(baseURL)
+}
+
+// compatAPIBase appends the /v1 prefix the OpenAI client expects when it is missing.
+func compatAPIBase(baseURL string) string {
+	baseURL = strings.TrimSuffix(strings.TrimSpace(baseURL), "/")
+	if strings.HasSuffix(baseURL, "/v1") {
+		return baseURL
+	}
+	return baseURL + "/v1"
+}
+
+// compatAPIKey returns the configured key; most local servers ignore it but the OpenAI
+// client refuses to start without one.
+func compatAPIKey() string {
+	if key := config.GetOpenAICompatAPIKey(); key != "" {
+		return key
+	}
+	return "not-needed"
+}
+
+var compatModels sync.Map // baseURL -> first served model
+
+// resolveCompatModel returns model, or the first model served at baseURL when it is empty.
+func resolveCompatModel(baseURL, model string) (string, error) {
+	if strings.TrimSpace(model) != "" {
+		return model, nil
+	}
+	if v, ok := compatModels.Load(baseURL); ok {
+		return v.(string), nil
+	}
+	ms := metadata.NewMetadataService(compatProbeTimeout, 0)
+	models, err := ms.FetchOpenAICompatibleModelList(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("no model configured and listing models failed: %w", err)
+	}
+	if len(models) == 0 {
+		return "", fmt.Errorf("no model configured and %s serves no models", baseURL)
+	}
+	logger.Log("info", "Using first model served by %s: %s (%s)", baseURL, models[0].ID, models[0].Description)
+	compatModels.Store(baseURL, models[0].ID)
+	return models[0].ID, nil
+}
+
+// compatCapabilities is what an OpenAI-compatible server was found to support.
+type compatCapabilities struct {
+	Streaming bool
+	Tools     bool
+	JSONMode  bool
+}
+
+var compatProbes sync.Map // baseURL|model -> compatCapabilities
+
+// probeOpenAICompat sends tiny chat requests to find out whether the server streams, accepts
+// tool definitions and honors JSON mode. Results are cached per server and model; an
+// unreachable server is not cached and is assumed capable so the real request reports the error.
+func probeOpenAICompat(baseURL, model string) compatCapabilities {
+	key := baseURL + "|" + model
+	if v, ok := compatProbes.Load(key); ok {
+		return v.(compatCapabilities)
+	}
+
+	client := &http.Client{Timeout: compatProbeTimeout}
+	request := func(extra map[string]any) (*http.Response, error) {
+		body := map[string]any{
+			"model":      model,
+			"max_tokens": 1,
+			"messages":   []map[string]string{{"role": "user", "content": "Reply with an empty JSON object."}},
+		}
+		for k, v := range extra {
+			body[k] = v
+		}
+		data, _ := json.Marshal(body)
+		req, err := http.NewRequest(http.MethodPost, baseURL+"/chat/completions", bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+compatAPIKey())
+		return client.Do(req)
+	}
+	supported := func(extra map[string]any, check func(*http.Response) bool) (bool, bool) {
+		resp, err := request(extra)
+		if err != nil {
+			return false, false
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode < 300 && (check == nil || check(resp)), true
+	}
+
+	streaming, reached := supported(map[string]any{"stream": true}, isEventStream)
+	if !reached {
+		logger.Log("warn", "Capability probe of %s failed: server unreachable", baseURL)
+		return compatCapabilities{Streaming: true, Tools: true, JSONMode: true}
+	}
+	tools, _ := supported(map[string]any{"tools": []map[string]any{{
+		"type": "function",
+		"function": map[string]any{
+			"name":        "ping",
+			"description": "Capability probe",
+			"parameters":  map[string]any{"type": "object", "properties": map[string]any{}},
+		},
+	}}}, nil)
+	jsonMode, _ := supported(map[string]any{"response_format": map[string]string{"type": "json_object"}}, nil)
+
+	caps := compatCapabilities{Streaming: streaming, Tools: tools, JSONMode: jsonMode}
+	logger.Log("info", "Probed %s (%s): streaming=%t tools=%t json_mode=%t", baseURL, model, caps.Streaming, caps.Tools, caps.JSONMode)
+	compatProbes.Store(key, caps)
+	return caps
+}
+
+// isEventStream reports whether a response is a server-sent event stream.
+func isEventStream(resp *http.Response) bool {
+	if strings.Contains(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return true
+	}
+	line, _ := bufio.NewReader(resp.Body).ReadString('\n')
+	return strings.HasPrefix(strings.TrimSpace(line), "data:")
+}
@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/metadata"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// fakeVLLM emulates a vLLM server started without --enable-auto-tool-choice.
+func fakeVLLM(t *testing.T, chatCalls *int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/models":
+			w.Write([]byte(`{"object":"list","data":[{"id":"qwen2.5-7b","owned_by":"vllm","max_model_len":32768}]}`))
+		case "/v1/chat/completions":
+			atomic.AddInt32(chatCalls, 1)
+			var body map[string]any
+			json.NewDecoder(r.Body).Decode(&body)
+			if _, ok := body["tools"]; ok {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"object":"error","message":"\"auto\" tool choice requires --enable-auto-tool-choice"}`))
+				return
+			}
+			if body["stream"] == true {
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"{}\"}}]}\n\ndata: [DONE]\n\n"))
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id":"1","object":"chat.completion","model":"qwen2.5-7b","choices":[{"index":0,"message":{"role":"assistant","content":"all pods healthy"},"finish_reason":"stop"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestOpenAICompatProbesCapabilities(t *testing.T) {
+	var chatCalls int32
+	srv := fakeVLLM(t, &chatCalls)
+	defer srv.Close()
+	t.Setenv("QU_OPENAI_COMPAT_BASE_URL", srv.URL)
+	t.Setenv("QU_OPENAI_COMPAT_QUIRKS", "")
+
+	p, ok := provider.Get("openai-compatible")
+	if !ok {
+		t.Fatal("openai-compatible provider not registered")
+	}
+	if got := p.BaseURL(provider.Options{}); got != srv.URL+"/v1" {
+		t.Errorf("unexpected base URL: %s", got)
+	}
+
+	q := p.Quirks(provider.Options{})
+	if q.DisableStreaming || !q.DisableTools || !q.JSONMode {
+		t.Errorf("unexpected probed quirks: %+v", q)
+	}
+	probes := atomic.LoadInt32(&chatCalls)
+	if probes != 3 {
+		t.Errorf("expected three probe requests, got %d", probes)
+	}
+	p.Quirks(provider.Options{Model: "qwen2.5-7b"})
+	if atomic.LoadInt32(&chatCalls) != probes {
+		t.Error("probe results should be cached per server and model")
+	}
+
+	t.Setenv("QU_OPENAI_COMPAT_QUIRKS", "no-streaming, no-json-mode")
+	if q := p.Quirks(provider.Options{}); !q.DisableStreaming || q.JSONMode || !q.DisableTools {
+		t.Errorf("quirk flags should override probes: %+v", q)
+	}
+
+	client, err := p.NewModel(context.Background(), provider.Options{})
+	if err != nil {
+		t.Fatalf("NewModel: %v", err)
+	}
+	resp, err := client.GenerateContent(context.Background(), []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "status?")})
+	if err != nil || resp.Choices[0].Content != "all pods healthy" {
+		t.Fatalf("unexpected response: %+v, %v", resp, err)
+	}
+}
+
+func TestOpenAICompatMetadata(t *testing.T) {
+	var chatCalls int32
+	srv := fakeVLLM(t, &chatCalls)
+	defer srv.Close()
+
+	ms := metadata.NewMetadataService(0, 0)
+	if n, err := ms.GetModelContextLength("openai-compatible", "", srv.URL+"/v1"); err != nil || n != 32768 {
+		t.Errorf("expected max_model_len from /v1/models, got %d, %v", n, err)
+	}
+	models, err := ms.GetModelList("openai-compatible", srv.URL)
+	if err != nil || len(models) != 1 || models[0].ID != "qwen2.5-7b" || models[0].Description != "vllm" {
+		t.Errorf("unexpected model list: %+v, %v", models, err)
+	}
+	if _, err := ms.FetchOpenAICompatibleMetadata("missing", srv.URL); err == nil {
+		t.Error("expected error for unknown model")
+	}
+	if !strings.HasSuffix(metadata.OpenAICompatibleModelsURL("http://x:1234/"), "/v1/models") {
+		t.Error("models URL should add the /v1 prefix")
+	}
+}
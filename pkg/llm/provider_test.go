@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/metadata"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// fakeProvider is a third-party style provider backed by MockLLMClient.
+type fakeProvider struct {
+	client *MockLLMClient
+}
+
+func (fakeProvider) Name() string { return "fake-test" }
+func (fakeProvider) Defaults() provider.Defaults {
+	return provider.Defaults{MaxTokens: 1234, Model: "fake-model"}
+}
+func (fakeProvider) BaseURL(opts provider.Options) string { return "http://fake.invalid" }
+func (f fakeProvider) NewModel(ctx context.Context, opts provider.Options) (llms.Model, error) {
+	return f.client, nil
+}
+func (fakeProvider) NewEmbedder(ctx context.Context, opts provider.Options) (embeddings.Embedder, error) {
+	return nil, provider.ErrEmbeddingsUnsupported
+}
+func (fakeProvider) Quirks(opts provider.Options) provider.Quirks {
+	return provider.Quirks{DisableStreaming: true}
+}
+func (fakeProvider) ModelMetadata(ms *metadata.MetadataService, model, baseURL string) (*metadata.ModelMetadata, error) {
+	return &metadata.ModelMetadata{ID: model, ContextLength: 4321}, nil
+}
+func (fakeProvider) ModelList(ms *metadata.MetadataService, baseURL string) ([]*metadata.ModelMetadata, error) {
+	return []*metadata.ModelMetadata{{ID: "fake-model"}}, nil
+}
+
+func TestBuiltinProvidersRegistered(t *testing.T) {
+	names := strings.Join(provider.Names(), ",")
+	for _, want := range []string{"anthropic", "azopenai", "google", "ollama", "openai"} {
+		if !strings.Contains(names, want) {
+			t.Errorf("provider %q not registered (have %s)", want, names)
+		}
+	}
+	p, ok := provider.Get("OpenAI")
+	if !ok || p.Defaults().Model != "gpt-5-mini" {
+		t.Fatalf("unexpected openai provider: %v %+v", ok, p)
+	}
+	if _, ok := provider.Get("nope"); ok {
+		t.Error("unexpected provider for unknown name")
+	}
+}
+
+func TestOpenAIProviderBaseURLAndQuirks(t *testing.T) {
+	t.Setenv("QU_OPENAI_BASE_URL", "")
+	t.Setenv("OPENAI_BASE_URL", "")
+	p, _ := provider.Get("openai")
+
+	if got := p.BaseURL(provider.Options{Model: "gpt-4o"}); got != "https://api.openai.com" {
+		t.Errorf("unexpected default base URL: %s", got)
+	}
+	if got := p.BaseURL(provider.Options{Model: "anthropic/claude-sonnet-4"}); got != "https://openrouter.ai/api/v1" {
+		t.Errorf("expected OpenRouter for vendor-prefixed model, got %s", got)
+	}
+	if q := p.Quirks(provider.Options{Model: "gpt-5-mini"}); q.Temperature == nil || *q.Temperature != 1.0 || q.DisableStreaming {
+		t.Errorf("unexpected gpt-5 quirks: %+v", q)
+	}
+
+	t.Setenv("QU_OPENAI_BASE_URL", "https://openrouter.ai/api/v1")
+	if q := p.Quirks(provider.Options{Model: "gpt-4o"}); !q.DisableStreaming || q.Temperature != nil {
+		t.Errorf("expected streaming disabled for OpenRouter: %+v", q)
+	}
+}
+
+func TestRegisteredProviderIsUsedEverywhere(t *testing.T) {
+	mock := NewMockLLMClient([]MockResponse{{Content: "hello from fake"}})
+	provider.Register(fakeProvider{client: mock})
+
+	cfg := CreateTestConfig()
+	cfg.Provider = "fake-test"
+	cfg.Model = "fake-model"
+
+	answer, err := RequestWithSystem(cfg, "system", "hi", true, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(answer, "hello from fake") {
+		t.Errorf("unexpected answer: %q", answer)
+	}
+	if len(mock.GetCallHistory()) != 1 {
+		t.Errorf("expected one call to the fake client, got %d", len(mock.GetCallHistory()))
+	}
+
+	if got := config.GetProviderBaseURL(cfg); got != "http://fake.invalid" {
+		t.Errorf("unexpected base URL: %s", got)
+	}
+	ms := metadata.NewMetadataService(0, 0)
+	if n, err := ms.GetModelContextLength("fake-test", "fake-model", ""); err != nil || n != 4321 {
+		t.Errorf("expected metadata from registered source, got %d, %v", n, err)
+	}
+
+	cfg.Provider = "does-not-exist"
+	if _, err := RequestWithSystem(cfg, "", "hi", false, false); err == nil || !strings.Contains(err.Error(), "unsupported AI provider") {
+		t.Errorf("expected unsupported provider error, got %v", err)
+	}
+}
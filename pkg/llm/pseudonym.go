@@ -0,0 +1,243 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/exec"
+	"github.com/mikhae1/kubectl-quackops/pkg/filter"
+	"github.com/mikhae1/kubectl-quackops/pkg/logger"
+	"github.com/tmc/langchaingo/embeddings"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// pseudonymInventoryTTL is how long the learned cluster identifiers are trusted before they
+// are listed again, so pods created during the session are covered too.
+const pseudonymInventoryTTL = time.Minute
+
+// Inventory commands listing the identifiers to pseudonymize. Each line is a name followed
+// by the addresses that belong to it; ingress lines hold addresses only.
+const (
+	namespaceInventoryCmd = `kubectl get namespaces -o jsonpath='{range .items[*]}{.metadata.name}{"\n"}{end}'`
+	nodeInventoryCmd      = `kubectl get nodes -o jsonpath='{range .items[*]}{.metadata.name}{range .status.addresses[*]}{" "}{.type}={.address}{end}{"\n"}{end}'`
+	podInventoryCmd       = `kubectl get pods -A -o jsonpath='{range .items[*]}{.metadata.name}{range .status.podIPs[*]}{" "}{.ip}{end}{"\n"}{end}'`
+	ingressInventoryCmd   = `kubectl get ingresses -A -o jsonpath='{range .items[*]}{range .spec.rules[*]}{" "}Hostname={.host}{end}{range .status.loadBalancer.ingress[*]}{" "}Hostname={.hostname}{" "}IP={.ip}{end}{"\n"}{end}'`
+)
+
+// pseudonymModel sends cluster identifiers to the model as stable tokens and turns the
+// tokens in its answers, streamed chunks and tool-call arguments back into identifiers, so
+// history, generated commands and tool calls only ever hold real names.
+type pseudonymModel struct {
+	llms.Model
+	p *filter.Pseudonymizer
+}
+
+// newPseudonymModel wraps client when pseudonymization is enabled.
+func newPseudonymModel(cfg *config.Config, client llms.Model) llms.Model {
+	if !cfg.Pseudonymize {
+		return client
+	}
+	return &pseudonymModel{Model: client, p: sessionPseudonyms(cfg)}
+}
+
+// sessionPseudonyms returns the session mapping, learning the cluster identifiers first if
+// they are missing or stale.
+func sessionPseudonyms(cfg *config.Config) *filter.Pseudonymizer {
+	if cfg.Pseudonyms == nil {
+		cfg.Pseudonyms = filter.NewPseudonymizer()
+	}
+	if cfg.Pseudonyms.Stale(pseudonymInventoryTTL) {
+		learnClusterIdentifiers(cfg, cfg.Pseudonyms)
+	}
+	return cfg.Pseudonyms
+}
+
+// learnClusterIdentifiers registers the namespaces, nodes with their addresses, pods with
+// their IPv4 and IPv6 addresses, and ingress hosts and load balancer addresses of the
+// current cluster. Failed listings are skipped; IP addresses are still mapped on sight.
+func learnClusterIdentifiers(cfg *config.Config, p *filter.Pseudonymizer) {
+	inventory := []struct {
+		cmd   string
+		what  string
+		class string // class of the leading name; "" when a line holds addresses only
+	}{
+		{namespaceInventoryCmd, "namespace", filter.PseudonymNamespace},
+		{nodeInventoryCmd, "node", filter.PseudonymNode},
+		{podInventoryCmd, "pod", filter.PseudonymPod},
+		{ingressInventoryCmd, "ingress", ""},
+	}
+	for _, inv := range inventory {
+		res := exec.ExecKubectlCmd(cfg, inv.cmd)
+		if res.Err != nil {
+			logger.Log("warn", "[Pseudonymize] Failed to list %s identifiers: %v", inv.what, res.Err)
+			continue
+		}
+		for _, line := range strings.Split(res.Out, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			if inv.class != "" {
+				p.Learn(inv.class, fields[0])
+				fields = fields[1:]
+			}
+			for _, addr := range fields {
+				learnAddress(p, addr)
+			}
+		}
+	}
+	p.MarkLearned()
+	logger.Log("info", "[Pseudonymize] %d cluster identifiers mapped", p.Len())
+}
+
+// learnAddress registers a node or ingress address ("InternalIP=10.0.0.5",
+// "Hostname=worker-1") or a bare pod IP. Wildcard hosts are learned without the "*.".
+func learnAddress(p *filter.Pseudonymizer, addr string) {
+	kind, value, ok := strings.Cut(addr, "=")
+	if !ok {
+		kind, value = "InternalIP", addr
+	}
+	value = strings.TrimPrefix(value, "*.")
+	if value == "" {
+		return
+	}
+	if strings.HasSuffix(kind, "IP") {
+		p.Learn(filter.PseudonymIP, value)
+	} else {
+		p.Learn(filter.PseudonymHost, value)
+	}
+}
+
+func (m *pseudonymModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func (m *pseudonymModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	var opts llms.CallOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+	var stream *restoringStream
+	if opts.StreamingFunc != nil {
+		stream = &restoringStream{p: m.p, fn: opts.StreamingFunc}
+		options = append(options[:len(options):len(options)], llms.WithStreamingFunc(stream.write))
+	}
+
+	resp, err := m.Model.GenerateContent(ctx, m.pseudonymizeMessages(messages), options...)
+	if stream != nil {
+		if flushErr := stream.flush(ctx); err == nil {
+			err = flushErr
+		}
+	}
+	if err != nil || resp == nil {
+		return resp, err
+	}
+	for _, c := range resp.Choices {
+		if c == nil {
+			continue
+		}
+		c.Content = m.p.Restore(c.Content)
+		c.ReasoningContent = m.p.Restore(c.ReasoningContent)
+		for i := range c.ToolCalls {
+			if fc := c.ToolCalls[i].FunctionCall; fc != nil {
+				fc.Arguments = m.p.Restore(fc.Arguments)
+			}
+		}
+		if c.FuncCall != nil {
+			c.FuncCall.Arguments = m.p.Restore(c.FuncCall.Arguments)
+		}
+	}
+	return resp, nil
+}
+
+// pseudonymizeMessages returns a copy of messages with identifiers replaced in text, tool
+// calls and tool results; the caller's history is left untouched.
+func (m *pseudonymModel) pseudonymizeMessages(messages []llms.MessageContent) []llms.MessageContent {
+	out := make([]llms.MessageContent, len(messages))
+	for i, msg := range messages {
+		parts := make([]llms.ContentPart, len(msg.Parts))
+		for j, part := range msg.Parts {
+			switch p := part.(type) {
+			case llms.TextContent:
+				parts[j] = llms.TextContent{Text: m.p.Pseudonymize(p.Text)}
+			case llms.ToolCall:
+				if p.FunctionCall != nil {
+					fc := *p.FunctionCall
+					fc.Arguments = m.p.Pseudonymize(fc.Arguments)
+					p.FunctionCall = &fc
+				}
+				parts[j] = p
+			case llms.ToolCallResponse:
+				p.Content = m.p.Pseudonymize(p.Content)
+				parts[j] = p
+			default:
+				parts[j] = part
+			}
+		}
+		out[i] = llms.MessageContent{Role: msg.Role, Parts: parts}
+	}
+	return out
+}
+
+// pseudonymEmbedder pseudonymizes the texts sent to a remote embeddings API. Vectors
+// carry no names, so nothing needs restoring.
+type pseudonymEmbedder struct {
+	embeddings.Embedder
+	p *filter.Pseudonymizer
+}
+
+func newPseudonymEmbedder(cfg *config.Config, embedder embeddings.Embedder) embeddings.Embedder {
+	if !cfg.Pseudonymize {
+		return embedder
+	}
+	return &pseudonymEmbedder{Embedder: embedder, p: sessionPseudonyms(cfg)}
+}
+
+func (e *pseudonymEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	masked := make([]string, len(texts))
+	for i, text := range texts {
+		masked[i] = e.p.Pseudonymize(text)
+	}
+	return e.Embedder.EmbedDocuments(ctx, masked)
+}
+
+func (e *pseudonymEmbedder) EmbedQuery(ctx context.Context, text string) ([]float32, error) {
+	return e.Embedder.EmbedQuery(ctx, e.p.Pseudonymize(text))
+}
+
+// restoringStream restores tokens in streamed chunks. A token may be split across chunks,
+// so the trailing identifier-like characters of each chunk are held back until the next.
+type restoringStream struct {
+	p       *filter.Pseudonymizer
+	fn      func(ctx context.Context, chunk []byte) error
+	pending string
+}
+
+func (s *restoringStream) write(ctx context.Context, chunk []byte) error {
+	s.pending += string(chunk)
+	cut := len(s.pending)
+	for cut > 0 && isIdentifierByte(s.pending[cut-1]) {
+		cut--
+	}
+	if cut == 0 {
+		return nil
+	}
+	out := s.p.Restore(s.pending[:cut])
+	s.pending = s.pending[cut:]
+	return s.fn(ctx, []byte(out))
+}
+
+func (s *restoringStream) flush(ctx context.Context) error {
+	if s.pending == "" {
+		return nil
+	}
+	out := s.p.Restore(s.pending)
+	s.pending = ""
+	return s.fn(ctx, []byte(out))
+}
+
+func isIdentifierByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '-' || b == '.'
+}
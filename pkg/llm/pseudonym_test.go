@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/filter"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestRequestPseudonymizesIdentifiers(t *testing.T) {
+	mock := NewMockLLMClient([]MockResponse{{Content: "Check the logs:\nkubectl logs pod-1 -n ns-1\nThe node ip-2 is fine."}})
+	provider.Register(namedFakeProvider{fakeProvider{client: mock}, "fake-pseudonym"})
+
+	cfg := CreateTestConfig()
+	cfg.Provider = "fake-pseudonym"
+	cfg.Pseudonymize = true
+	cfg.Pseudonyms = filter.NewPseudonymizer()
+	cfg.Pseudonyms.Learn(filter.PseudonymNamespace, "payments")
+	cfg.Pseudonyms.Learn(filter.PseudonymPod, "checkout-7d9f8c6b5-x2x9z")
+	cfg.Pseudonyms.MarkLearned()
+
+	answer, err := RequestWithSystem(cfg, "", "why does checkout-7d9f8c6b5-x2x9z in payments fail to reach 10.0.4.7 and 10.0.4.8?", false, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	calls := mock.GetCallHistory()
+	if len(calls) != 1 {
+		t.Fatalf("expected one model call, got %d", len(calls))
+	}
+	var sent strings.Builder
+	for _, msg := range calls[0].Messages {
+		for _, part := range msg.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				sent.WriteString(text.Text)
+			}
+		}
+	}
+	for _, real := range []string{"checkout-7d9f8c6b5-x2x9z", "payments", "10.0.4.7"} {
+		if strings.Contains(sent.String(), real) {
+			t.Errorf("identifier %q reached the model: %s", real, sent.String())
+		}
+	}
+	if !strings.Contains(sent.String(), "pod-1 in ns-1 fail to reach ip-1 and ip-2") {
+		t.Errorf("unexpected prompt sent to the model: %s", sent.String())
+	}
+	if !strings.Contains(answer, "kubectl logs checkout-7d9f8c6b5-x2x9z -n payments") || !strings.Contains(answer, "node 10.0.4.8 is fine") {
+		t.Errorf("answer not restored: %q", answer)
+	}
+	for _, msg := range cfg.ChatMessages {
+		if strings.Contains(msg.GetContent(), "pod-1") {
+			t.Errorf("history should hold real names, got %q", msg.GetContent())
+		}
+	}
+}
+
+func TestLearnClusterIdentifiers(t *testing.T) {
+	dir := t.TempDir()
+	kubectl := filepath.Join(dir, "kubectl")
+	script := `#!/bin/sh
+case "$2" in
+namespaces) echo payments ;;
+nodes) echo "worker-1 InternalIP=10.0.0.5 InternalIP=fd00::5 Hostname=worker-1.corp.example" ;;
+pods) echo "checkout-abc 10.244.1.7 fd00:10:244::7" ;;
+ingresses) echo " Hostname=shop.example.com Hostname=*.apps.example.com Hostname=lb-123.elb.amazonaws.com IP= Hostname= IP=203.0.113.9" ;;
+esac
+`
+	if err := os.WriteFile(kubectl, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := CreateTestConfig()
+	cfg.KubectlBinaryPath = kubectl
+
+	p := filter.NewPseudonymizer()
+	learnClusterIdentifiers(cfg, p)
+
+	text := "payments pod checkout-abc (10.244.1.7, fd00:10:244::7) on worker-1 (fd00::5, worker-1.corp.example) " +
+		"behind shop.example.com, api.apps.example.com, lb-123.elb.amazonaws.com and 203.0.113.9"
+	masked := p.Pseudonymize(text)
+	for _, real := range []string{"payments", "checkout-abc", "10.244.1.7", "fd00:10:244::7", "fd00::5", "worker-1",
+		"corp.example", "shop.example.com", "apps.example.com", "lb-123.elb.amazonaws.com", "203.0.113.9"} {
+		if strings.Contains(masked, real) {
+			t.Errorf("identifier %q not pseudonymized: %s", real, masked)
+		}
+	}
+	if back := p.Restore(masked); back != text {
+		t.Errorf("Restore() = %q, want %q", back, text)
+	}
+}
+
+func TestRestoringStreamSplitTokens(t *testing.T) {
+	p := filter.NewPseudonymizer()
+	p.Learn(filter.PseudonymNamespace, "payments")
+	p.Learn(filter.PseudonymPod, "checkout-abc")
+
+	var out strings.Builder
+	s := &restoringStream{p: p, fn: func(ctx context.Context, chunk []byte) error {
+		out.Write(chunk)
+		return nil
+	}}
+	for _, chunk := range []string{"kubectl logs po", "d-1 -n n", "s-", "1", "\nDone."} {
+		if err := s.write(context.Background(), []byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "kubectl logs checkout-abc -n payments\nDone." {
+		t.Errorf("unexpected streamed output %q", got)
+	}
+}
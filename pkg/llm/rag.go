@@ -9,9 +9,10 @@ import (
 	"github.com/mikhae1/kubectl-quackops/pkg/config"
 	"github.com/mikhae1/kubectl-quackops/pkg/diag"
 	"github.com/mikhae1/kubectl-quackops/pkg/exec"
-	"github.com/mikhae1/kubectl-quackops/pkg/filter"
 	"github.com/mikhae1/kubectl-quackops/pkg/lib"
 	"github.com/mikhae1/kubectl-quackops/pkg/logger"
+	"github.com/mikhae1/kubectl-quackops/pkg/metrics"
+	"github.com/mikhae1/kubectl-quackops/pkg/privacy"
 )
 
 // RetrieveRAG retrieves the data for RAG
@@ -45,6 +46,22 @@ func RetrieveRAG(cfg *config.Config, prompt string, lastTextPrompt string, userM
 	// Prepend baseline commands only for the first user query when enabled
 	if !cfg.DisableBaseline && userMsgCount == 1 {
 		base := diag.BaselineCommands(cfg)
+		if len(base) > 0 {
+			// Discover which operator and Gateway API CRDs are installed before fetching their instances
+			disc := exec.ExecKubectlCmd(cfg, diag.CRDDiscoveryCommand)
+			if disc.Err != nil {
+				logger.Log("warn", "CRD discovery failed: %v", disc.Err)
+			} else {
+				if crdCmds := diag.InstalledCRDCommands(cfg, disc.Out); len(crdCmds) > 0 {
+					logger.Log("info", "CRD analysis: %d installed resource type(s)", len(crdCmds))
+					base = append(base, crdCmds...)
+				}
+				if gwCmds := diag.InstalledGatewayAPICommands(disc.Out); len(gwCmds) > 0 {
+					logger.Log("info", "Gateway API detected: %d resource type(s)", len(gwCmds))
+					base = append(base, gwCmds...)
+				}
+			}
+		}
 		if len(base) > 0 {
 			logger.Log("info", "Baseline enabled: running %d command(s)", len(base))
 			// Run baseline first and append to results so they can be reused without re-running
@@ -57,6 +74,15 @@ func RetrieveRAG(cfg *config.Config, prompt string, lastTextPrompt string, userM
 		}
 	}
 
+	// Questions about Helm releases need release history even after the first (baseline) turn
+	if cfg.HelmAnalysisEnabled && diag.MentionsHelm(prompt, nil) && !hasCmdResult(cmdResults, diag.HelmSecretsCommand) {
+		if res := exec.ExecKubectlCmd(cfg, diag.HelmSecretsCommand); res.Err == nil {
+			cmdResults = append(cmdResults, res)
+		} else {
+			logger.Log("warn", "Helm release lookup failed: %v", res.Err)
+		}
+	}
+
 	if !(cfg.MCPClientEnabled && cfg.MCPStrict) && len(cmds) > 0 {
 		// Create spinner for diagnostic information gathering only if we're not in safe mode
 		// In safe mode, the spinner will be managed by execDiagCmds for each command
@@ -113,6 +139,15 @@ func formatCommandResultsForRAG(cfg *config.Config, prompt string, cmdResults []
 			}
 		}
 	}
+	// Helm release secrets are only ever consumed through the decoder below
+	baselineSet[diag.HelmSecretsCommand] = true
+	crdByCmd := map[string]string{}
+	for _, r := range append(diag.CRDResources(cfg), diag.GatewayAPIResources()...) {
+		crdByCmd[diag.CRDCommand(r)] = r
+		if !cfg.DisableBaseline {
+			baselineSet[diag.CRDCommand(r)] = true
+		}
+	}
 
 	// Collect raw outputs for non-baseline commands only
 	var commandSections []string
@@ -126,27 +161,19 @@ func formatCommandResultsForRAG(cfg *config.Config, prompt string, cmdResults []
 			continue
 		}
 
-		// Filter sensitive data if enabled
-		output := cmd.Out
-		if !cfg.DisableSecretFilter {
-			output = filter.SensitiveData(output)
+		// Include only successful outputs or timeouts; skip other errors
+		if cmd.Err != nil && !strings.Contains(cmd.Err.Error(), "timed out") {
+			continue
 		}
 
-		// Include only successful outputs or timeouts; skip other errors
+		// Filter sensitive data if enabled
+		output := cfg.RedactFrom(privacy.SourceCommand, c, cmd.Out)
+
 		var sb strings.Builder
 		sb.WriteString("Command: ")
 		sb.WriteString(cmd.Cmd)
 		sb.WriteString("\n\nOutput:\n")
-		if cmd.Err != nil {
-			if strings.Contains(cmd.Err.Error(), "timed out") {
-				sb.WriteString(output)
-			} else {
-				// Skip non-timeout errors
-				continue
-			}
-		} else {
-			sb.WriteString(output)
-		}
+		sb.WriteString(output)
 		commandSections = append(commandSections, sb.String())
 	}
 
@@ -154,9 +181,19 @@ func formatCommandResultsForRAG(cfg *config.Config, prompt string, cmdResults []
 	// Extract relevant JSON blobs by command for analyzers
 	var podsJSON, svcsJSON, epsJSON, esJSON, eventsJSON, nodesJSON, hpaJSON, readyz, livez string
 	var depJSON, ingJSON, pvcJSON, pvJSON string
+	var vwhJSON, mwhJSON, apiSvcJSON, helmJSON string
+	crdJSON := map[string]string{}
 	for _, cmd := range cmdResults {
 		c := strings.TrimSpace(cmd.Cmd)
-		if strings.HasPrefix(c, "kubectl get pods ") {
+		if r, ok := crdByCmd[c]; ok {
+			if cmd.Err == nil {
+				crdJSON[r] = cmd.Out
+			}
+		} else if c == diag.HelmSecretsCommand {
+			if cmd.Err == nil {
+				helmJSON = cmd.Out
+			}
+		} else if strings.HasPrefix(c, "kubectl get pods ") {
 			podsJSON = cmd.Out
 		} else if strings.HasPrefix(c, "kubectl get services ") {
 			svcsJSON = cmd.Out
@@ -178,6 +215,12 @@ func formatCommandResultsForRAG(cfg *config.Config, prompt string, cmdResults []
 			pvcJSON = cmd.Out
 		} else if strings.HasPrefix(c, "kubectl get pv ") {
 			pvJSON = cmd.Out
+		} else if strings.HasPrefix(c, "kubectl get validatingwebhookconfigurations ") {
+			vwhJSON = cmd.Out
+		} else if strings.HasPrefix(c, "kubectl get mutatingwebhookconfigurations ") {
+			mwhJSON = cmd.Out
+		} else if strings.HasPrefix(c, "kubectl get apiservices ") {
+			apiSvcJSON = cmd.Out
 		} else if strings.Contains(c, "/readyz?verbose") {
 			readyz = cmd.Out
 		} else if strings.Contains(c, "/livez?verbose") {
@@ -186,9 +229,10 @@ func formatCommandResultsForRAG(cfg *config.Config, prompt string, cmdResults []
 	}
 
 	findings := make([]diag.Finding, 0, 8)
-	logger.Log("info", "Analyzer inputs: pods=%t svcs=%t eps=%t es=%t nodes=%t hpa=%t events=%t dep=%t ing=%t pvc=%t pv=%t readyz=%t livez=%t",
+	logger.Log("info", "Analyzer inputs: pods=%t svcs=%t eps=%t es=%t nodes=%t hpa=%t events=%t dep=%t ing=%t pvc=%t pv=%t readyz=%t livez=%t webhooks=%t apiservices=%t crds=%d helm=%t",
 		podsJSON != "", svcsJSON != "", epsJSON != "", esJSON != "", nodesJSON != "", hpaJSON != "",
-		eventsJSON != "", depJSON != "", ingJSON != "", pvcJSON != "", pvJSON != "", readyz != "", livez != "")
+		eventsJSON != "", depJSON != "", ingJSON != "", pvcJSON != "", pvJSON != "", readyz != "", livez != "",
+		vwhJSON != "" || mwhJSON != "", apiSvcJSON != "", len(crdJSON), helmJSON != "")
 	if podsJSON != "" {
 		findings = append(findings, diag.AnalyzePods(podsJSON)...)
 	}
@@ -210,6 +254,30 @@ func formatCommandResultsForRAG(cfg *config.Config, prompt string, cmdResults []
 	if pvcJSON != "" && pvJSON != "" {
 		findings = append(findings, diag.AnalyzePVCsPVs(pvcJSON, pvJSON)...)
 	}
+	if (vwhJSON != "" || mwhJSON != "") && svcsJSON != "" {
+		findings = append(findings, diag.AnalyzeWebhooks(vwhJSON, mwhJSON, svcsJSON, epsJSON, esJSON)...)
+	}
+	if apiSvcJSON != "" {
+		findings = append(findings, diag.AnalyzeAPIServices(apiSvcJSON)...)
+	}
+	for _, r := range diag.CRDResources(cfg) {
+		if out := crdJSON[r]; out != "" {
+			findings = append(findings, diag.AnalyzeCustomResources(r, out)...)
+		}
+	}
+	if helmJSON != "" {
+		findings = append(findings, diag.AnalyzeHelmReleases(helmJSON)...)
+	}
+	if gwJSON := crdJSON["gateways.gateway.networking.k8s.io"]; gwJSON != "" {
+		findings = append(findings, diag.AnalyzeGatewayAPI(
+			crdJSON["gatewayclasses.gateway.networking.k8s.io"],
+			gwJSON,
+			crdJSON["httproutes.gateway.networking.k8s.io"],
+			crdJSON["grpcroutes.gateway.networking.k8s.io"],
+			crdJSON["referencegrants.gateway.networking.k8s.io"],
+			svcsJSON,
+		)...)
+	}
 	findings = append(findings, diag.AnalyzeAPIServerHealth(readyz, "readyz")...)
 	findings = append(findings, diag.AnalyzeAPIServerHealth(livez, "livez")...)
 
@@ -267,6 +335,22 @@ func formatCommandResultsForRAG(cfg *config.Config, prompt string, cmdResults []
 		fb.WriteString(diag.FormatFindings(findings))
 		sections = append(sections, fb.String())
 	}
+	if client := newMetricsClient(cfg); client != nil {
+		if sel := metrics.Select(findings, prompt); len(sel.Queries) > 0 {
+			logger.Log("info", "Querying metrics backend: queries=%v namespaces=%v", sel.Queries, sel.Namespaces)
+			if section := collectMetrics(cfg, client, sel); section != "" {
+				sections = append(sections, section)
+			}
+		}
+	}
+	if helmJSON != "" && diag.MentionsHelm(prompt, diag.HelmReleaseNames(helmJSON)) {
+		redactHelm := func(text string) string {
+			return cfg.RedactFrom(privacy.SourceHelm, diag.HelmSecretsCommand, text)
+		}
+		if helmCtx := diag.HelmReleaseContext(helmJSON, prompt, redactHelm); helmCtx != "" {
+			sections = append(sections, helmCtx)
+		}
+	}
 	if len(commandSections) > 0 {
 		// Tag the first command section with the common header
 		commandSections[0] = "## Command Outputs\n\n" + commandSections[0]
@@ -317,6 +401,16 @@ func formatCommandResultsForRAG(cfg *config.Config, prompt string, cmdResults []
 	return augPrompt
 }
 
+// hasCmdResult reports whether a command has already been executed in this request.
+func hasCmdResult(results []config.CmdRes, command string) bool {
+	for _, r := range results {
+		if strings.TrimSpace(r.Cmd) == command {
+			return true
+		}
+	}
+	return false
+}
+
 // trimAllSectionsProportionally reduces the size of all sections proportionally to fit within maxTokens
 // It preserves the command and the beginning of each output which is often the most important part
 func trimAllSectionsProportionally(sections []string, maxTokens int) []string {
@@ -0,0 +1,256 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/mikhae1/kubectl-quackops/pkg/logger"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// responseCacheHitKey marks responses served from the cache in their generation info, so
+// usage and spend accounting can skip them.
+const responseCacheHitKey = "ResponseCacheHit"
+
+// cacheableTasks are the task types whose requests are deterministic enough to be answered
+// from the response cache; diagnosis answers depend on live tool calls and are never cached.
+var cacheableTasks = []string{config.TaskCommands, config.TaskPlan}
+
+// cachedResponse is the on-disk form of a cached model response.
+type cachedResponse struct {
+	CreatedAt time.Time      `json:"created_at"`
+	Provider  string         `json:"provider"`
+	Model     string         `json:"model"`
+	Task      string         `json:"task"`
+	Choices   []cachedChoice `json:"choices"`
+}
+
+type cachedChoice struct {
+	Content          string `json:"content"`
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+	StopReason       string `json:"stop_reason,omitempty"`
+}
+
+// responseCacheModel serves identical requests from a content-addressed cache directory.
+// The key covers the provider options, cluster identity, messages and call options, so
+// teammates sharing a cache directory only hit entries produced for the same request.
+type responseCacheModel struct {
+	llms.Model
+	dir   string
+	ttl   time.Duration
+	scope []byte
+	entry cachedResponse
+}
+
+// newResponseCacheModel wraps client with the response cache when the active task is
+// cacheable. Recording and replaying sessions bypass the cache so cassettes stay complete.
+func newResponseCacheModel(cfg *config.Config, client llms.Model, opts provider.Options) llms.Model {
+	if cfg.DisableResponseCache || cfg.ResponseCacheDir == "" || cfg.ResponseCacheTTL <= 0 {
+		return client
+	}
+	if cfg.Cassette.Recording() || cfg.Cassette.Replaying() || !slices.Contains(cacheableTasks, cfg.ActiveTask) {
+		return client
+	}
+	scope, err := json.Marshal(struct {
+		Provider string
+		Options  provider.Options
+		Cluster  string
+	}{cfg.Provider, opts, clusterStateKey(cfg)})
+	if err != nil {
+		logger.Log("debug", "[ResponseCache] Disabled for request: %v", err)
+		return client
+	}
+	return &responseCacheModel{
+		Model: client,
+		dir:   cfg.ResponseCacheDir,
+		ttl:   cfg.ResponseCacheTTL,
+		scope: scope,
+		entry: cachedResponse{Provider: cfg.Provider, Model: cfg.Model, Task: cfg.ActiveTask},
+	}
+}
+
+func (m *responseCacheModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func (m *responseCacheModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	var opts llms.CallOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+	key, keyErr := m.key(messages, opts)
+	if keyErr == nil {
+		if resp, ok := m.load(key); ok {
+			logger.Log("info", "[ResponseCache] Hit %s for %s/%s (%s)", key[:12], m.entry.Provider, m.entry.Model, m.entry.Task)
+			if opts.StreamingFunc != nil {
+				if err := opts.StreamingFunc(ctx, []byte(resp.Choices[0].Content)); err != nil {
+					return nil, err
+				}
+			}
+			resp.Choices[0].GenerationInfo = map[string]any{responseCacheHitKey: true}
+			return resp, nil
+		}
+	} else {
+		logger.Log("debug", "[ResponseCache] Skipping uncacheable request: %v", keyErr)
+	}
+
+	resp, err := m.Model.GenerateContent(ctx, messages, options...)
+	if err == nil && keyErr == nil {
+		m.store(key, resp)
+	}
+	return resp, err
+}
+
+// key hashes the request. MaxTokens is left out because it follows from the history size,
+// which the messages already cover.
+func (m *responseCacheModel) key(messages []llms.MessageContent, opts llms.CallOptions) (string, error) {
+	opts.MaxTokens = 0
+	payload, err := json.Marshal(struct {
+		Messages []llms.MessageContent
+		Options  llms.CallOptions
+	}{messages, opts})
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write(m.scope)
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (m *responseCacheModel) path(key string) string {
+	return filepath.Join(m.dir, key+".json")
+}
+
+// load returns the cached response for key; expired or unreadable entries are removed.
+func (m *responseCacheModel) load(key string) (*llms.ContentResponse, bool) {
+	data, err := os.ReadFile(m.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry cachedResponse
+	if err := json.Unmarshal(data, &entry); err != nil || len(entry.Choices) == 0 || time.Since(entry.CreatedAt) > m.ttl {
+		_ = os.Remove(m.path(key))
+		return nil, false
+	}
+	resp := &llms.ContentResponse{}
+	for _, c := range entry.Choices {
+		resp.Choices = append(resp.Choices, &llms.ContentChoice{
+			Content:          c.Content,
+			ReasoningContent: c.ReasoningContent,
+			StopReason:       c.StopReason,
+		})
+	}
+	return resp, true
+}
+
+// store caches resp under key. Responses with tool calls or without content are skipped:
+// they depend on live tool results rather than on the request alone.
+func (m *responseCacheModel) store(key string, resp *llms.ContentResponse) {
+	if resp == nil || len(resp.Choices) == 0 {
+		return
+	}
+	entry := m.entry
+	entry.CreatedAt = time.Now().UTC()
+	for _, c := range resp.Choices {
+		if c == nil || len(c.ToolCalls) > 0 || c.FuncCall != nil || strings.TrimSpace(c.Content) == "" {
+			return
+		}
+		entry.Choices = append(entry.Choices, cachedChoice{Content: c.Content, ReasoningContent: c.ReasoningContent, StopReason: c.StopReason})
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := writeCacheFile(m.dir, m.path(key), data); err != nil {
+		logger.Log("warn", "[ResponseCache] Failed to store response: %v", err)
+	}
+}
+
+// writeCacheFile writes data through a temp file so concurrent readers never see a partial
+// entry.
+func writeCacheFile(dir, path string, data []byte) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// responseCacheHit reports whether resp was served from the response cache.
+func responseCacheHit(resp *llms.ContentResponse) bool {
+	if resp == nil || len(resp.Choices) == 0 || resp.Choices[0] == nil {
+		return false
+	}
+	hit, _ := resp.Choices[0].GenerationInfo[responseCacheHitKey].(bool)
+	return hit
+}
+
+// clusterStateKey identifies the cluster the request is about and the state of its
+// workloads: the current kubectl context, its API server and default namespace, and a
+// fingerprint of workload controller resourceVersions so a rollout invalidates cached
+// answers. Pods, events and other objects are left out because they change constantly;
+// changes to them are only picked up once an entry expires after ResponseCacheTTL. It is
+// empty when kubectl is unavailable.
+func clusterStateKey(cfg *config.Config) string {
+	identity, err := kubectlOutput(cfg, "config", "view", "--minify",
+		"-o", "jsonpath={.current-context}|{.clusters[0].cluster.server}|{.contexts[0].context.namespace}")
+	if err != nil {
+		return ""
+	}
+	return identity + "|" + workloadFingerprint(cfg)
+}
+
+// workloadFingerprint hashes the resourceVersions of Deployments, StatefulSets and
+// DaemonSets in all namespaces. It is empty when they cannot be listed.
+func workloadFingerprint(cfg *config.Config) string {
+	out, err := kubectlOutput(cfg, "get", "deployments,statefulsets,daemonsets", "-A",
+		"-o", `jsonpath={range .items[*]}{.metadata.uid}={.metadata.resourceVersion}{"\n"}{end}`)
+	if err != nil {
+		return ""
+	}
+	versions := strings.Fields(out)
+	slices.Sort(versions)
+	sum := sha256.Sum256([]byte(strings.Join(versions, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+func kubectlOutput(cfg *config.Config, args ...string) (string, error) {
+	if cfg.KubectlBinaryPath == "" {
+		return "", exec.ErrNotFound
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, cfg.KubectlBinaryPath, args...).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
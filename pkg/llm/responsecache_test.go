@@ -0,0 +1,180 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestResponseCacheServesIdenticalRequests(t *testing.T) {
+	mock := NewMockLLMClient([]MockResponse{
+		{Content: "kubectl get pods -A"},
+		{Content: "kubectl get pods -n default"},
+		{Content: "diagnosis"},
+		{Content: "diagnosis again"},
+	})
+	provider.Register(namedFakeProvider{fakeProvider{client: mock}, "fake-cache"})
+
+	cfg := CreateTestConfig()
+	cfg.AutoDetectMaxTokens = false
+	cfg.Provider = "fake-cache"
+	cfg.ResponseCacheDir = t.TempDir()
+	cfg.ResponseCacheTTL = time.Minute
+	cfg.ActiveTask = config.TaskCommands
+
+	first, err := RequestWithSystem(cfg, "system", "list pods", false, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := RequestWithSystem(cfg, "system", "list pods", false, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first != second || len(mock.GetCallHistory()) != 1 {
+		t.Fatalf("identical request should be served from cache: %q vs %q, %d calls", first, second, len(mock.GetCallHistory()))
+	}
+	if len(cfg.ModelUsage) != 1 || cfg.ModelUsage[0].Requests != 1 {
+		t.Errorf("cache hits must not count as model usage, got %+v", cfg.ModelUsage)
+	}
+
+	cfg.DisableResponseCache = true
+	if _, err := RequestWithSystem(cfg, "system", "list pods", false, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(mock.GetCallHistory()) != 2 {
+		t.Fatal("--no-cache should bypass the cache")
+	}
+
+	cfg.DisableResponseCache = false
+	cfg.ActiveTask = ""
+	for i := 0; i < 2; i++ {
+		if _, err := RequestWithSystem(cfg, "system", "why?", false, false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(mock.GetCallHistory()) != 4 {
+		t.Errorf("answers must not be cached, got %d calls", len(mock.GetCallHistory()))
+	}
+}
+
+func TestResponseCacheAfterFailover(t *testing.T) {
+	primary := NewMockLLMClient([]MockResponse{{Error: errors.New("400 This model's maximum context length is 8192 tokens")}})
+	fallback := NewMockLLMClient([]MockResponse{{Content: "kubectl get pods -A"}})
+	provider.Register(namedFakeProvider{fakeProvider{client: primary}, "fake-cache-primary"})
+	provider.Register(namedFakeProvider{fakeProvider{client: fallback}, "fake-cache-fallback"})
+
+	cfg := CreateTestConfig()
+	cfg.AutoDetectMaxTokens = false
+	cfg.Provider = "fake-cache-primary"
+	cfg.Model = "small"
+	cfg.FallbackModels = []string{"fake-cache-fallback/large"}
+	cfg.ResponseCacheDir = t.TempDir()
+	cfg.ResponseCacheTTL = time.Minute
+	cfg.ActiveTask = config.TaskCommands
+
+	first, err := RequestWithSystem(cfg, "system", "list pods", false, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := RequestWithSystem(cfg, "system", "list pods", false, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first != second || len(fallback.GetCallHistory()) != 1 || !cfg.LastResponseCached {
+		t.Errorf("the fallback's answer should be cached: %q vs %q, %d calls", first, second, len(fallback.GetCallHistory()))
+	}
+}
+
+func TestResponseCacheEntries(t *testing.T) {
+	messages := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "hi")}
+
+	tests := []struct {
+		name      string
+		response  MockResponse
+		age       time.Duration
+		wantCalls int
+	}{
+		{name: "fresh entry is reused", response: MockResponse{Content: "ok"}, wantCalls: 1},
+		{name: "expired entry is refreshed", response: MockResponse{Content: "ok"}, age: 2 * time.Minute, wantCalls: 2},
+		{name: "tool calls are not cached", response: MockResponse{Content: "ok", ToolCalls: []llms.ToolCall{{ID: "1", Type: "function", FunctionCall: &llms.FunctionCall{Name: "kubectl"}}}}, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockLLMClient([]MockResponse{tt.response, tt.response})
+			cfg := CreateTestConfig()
+			cfg.ResponseCacheDir = t.TempDir()
+			cfg.ResponseCacheTTL = time.Minute
+			cfg.ActiveTask = config.TaskPlan
+			model := newResponseCacheModel(cfg, mock, cfg.ProviderOptions())
+
+			if _, err := model.GenerateContent(context.Background(), messages); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.age > 0 {
+				ageEntries(t, cfg.ResponseCacheDir, tt.age)
+			}
+			if _, err := model.GenerateContent(context.Background(), messages); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := len(mock.GetCallHistory()); got != tt.wantCalls {
+				t.Errorf("expected %d model calls, got %d", tt.wantCalls, got)
+			}
+		})
+	}
+}
+
+// ageEntries moves the creation time of every cached entry in dir back by age.
+func ageEntries(t *testing.T, dir string, age time.Duration) {
+	t.Helper()
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(paths) == 0 {
+		t.Fatal("expected a cached entry")
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var entry cachedResponse
+		if err := json.Unmarshal(data, &entry); err != nil {
+			t.Fatal(err)
+		}
+		entry.CreatedAt = entry.CreatedAt.Add(-age)
+		data, _ = json.Marshal(entry)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestClusterStateKeyTracksWorkloads(t *testing.T) {
+	dir := t.TempDir()
+	state := filepath.Join(dir, "workloads")
+	kubectl := filepath.Join(dir, "kubectl")
+	script := "#!/bin/sh\nif [ \"$1\" = config ]; then echo 'prod|https://api|default'; else cat " + state + "; fi\n"
+	if err := os.WriteFile(kubectl, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cfg := CreateTestConfig()
+	cfg.KubectlBinaryPath = kubectl
+
+	os.WriteFile(state, []byte("uid-a=100\nuid-b=200\n"), 0o600)
+	before := clusterStateKey(cfg)
+	os.WriteFile(state, []byte("uid-b=200\nuid-a=100\n"), 0o600)
+	if got := clusterStateKey(cfg); got != before {
+		t.Errorf("listing order must not change the key: %q vs %q", got, before)
+	}
+	os.WriteFile(state, []byte("uid-a=101\nuid-b=200\n"), 0o600)
+	if got := clusterStateKey(cfg); got == before || !strings.HasPrefix(got, "prod|https://api|default|") {
+		t.Errorf("a rollout should change the key: %q vs %q", got, before)
+	}
+}
@@ -0,0 +1,126 @@
+package llm
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/mikhae1/kubectl-quackops/pkg/mcp"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func init() {
+	mcp.RegisterSampler(sampleForMCP)
+}
+
+// sessionUsageMu guards the session usage and spend, which sampling requests merge back
+// from the goroutine of the MCP server.
+var sessionUsageMu sync.Mutex
+
+// sampleForMCP answers a sampling request from an MCP server with the model of the sampling
+// task. The request runs apart from the conversation and without tools, and goes through
+// the usual budget checks and privacy wrappers. It works on a private copy of cfg, so the
+// conversation running alongside it is untouched; only its usage and spend are merged back.
+func sampleForMCP(cfg *config.Config, req *mcp.SampleRequest) (string, string, error) {
+	model := cfg.Model
+	if ref := cfg.TaskModel(config.TaskSampling); ref != "" {
+		_, model = resolveTaskModel(cfg, ref)
+	}
+
+	// The last message is the prompt; the ones before it stand in for the history
+	var history []llms.ChatMessage
+	if req.SystemPrompt != "" {
+		history = append(history, llms.SystemChatMessage{Content: req.SystemPrompt})
+	}
+	for _, m := range req.Messages[:len(req.Messages)-1] {
+		if m.Role == "assistant" {
+			history = append(history, llms.AIChatMessage{Content: m.Text})
+		} else {
+			history = append(history, llms.HumanChatMessage{Content: m.Text})
+		}
+	}
+
+	sc := samplingConfig(cfg)
+	base := *sc
+	base.ModelUsage = append([]config.ModelUsage(nil), sc.ModelUsage...)
+
+	sc.ChatMessages = history
+	sc.ActiveTask = config.TaskSampling
+	sc.MCPClientEnabled = false
+	sc.SpinnerMessageOverride = fmt.Sprintf("Answering %s…", req.Server)
+	sc.SuppressContentPrint = true
+	sc.SuppressToolPrint = true
+	// The server's token limit caps the configured one
+	if limit := sc.GenerationFor(config.TaskSampling).MaxOutputTokens; req.MaxTokens > 0 && (limit <= 0 || req.MaxTokens < limit) {
+		g := sc.TaskGeneration[config.TaskSampling]
+		g.MaxOutputTokens = req.MaxTokens
+		sc.TaskGeneration[config.TaskSampling] = g
+	}
+
+	answer, err := RequestWithSystem(sc, "", req.Messages[len(req.Messages)-1].Text, false, false)
+	mergeSamplingUsage(cfg, &base, sc)
+
+	if err != nil {
+		return "", model, err
+	}
+	return answer, model, nil
+}
+
+// samplingConfig copies cfg for a sampling request under sessionUsageMu. The maps and the
+// usage slice the request writes to are copied as well, so nothing is shared with the
+// conversation. The copy has no cassette: servers only sample while a tool runs live, so a
+// recorded sampling exchange would be replayed as the next answer of the conversation.
+func samplingConfig(cfg *config.Config) *config.Config {
+	sessionUsageMu.Lock()
+	defer sessionUsageMu.Unlock()
+
+	sc := *cfg
+	sc.Cassette = nil
+	sc.TaskGeneration = make(map[string]provider.Generation, len(cfg.TaskGeneration)+1)
+	for task, g := range cfg.TaskGeneration {
+		sc.TaskGeneration[task] = g
+	}
+	sc.BudgetWarned = make(map[string]bool, len(cfg.BudgetWarned))
+	for key, warned := range cfg.BudgetWarned {
+		sc.BudgetWarned[key] = warned
+	}
+	sc.ModelUsage = append([]config.ModelUsage(nil), cfg.ModelUsage...)
+	sc.SessionHistory = nil
+	sc.PendingModelSwitches = nil
+	return &sc
+}
+
+// mergeSamplingUsage adds what the sampling request in sc used since base to the session
+// usage and spend of cfg.
+func mergeSamplingUsage(cfg, base, sc *config.Config) {
+	sessionUsageMu.Lock()
+	defer sessionUsageMu.Unlock()
+
+	cfg.SessionOutgoingTokens += sc.SessionOutgoingTokens - base.SessionOutgoingTokens
+	cfg.SessionIncomingTokens += sc.SessionIncomingTokens - base.SessionIncomingTokens
+	cfg.SessionCachedTokens += sc.SessionCachedTokens - base.SessionCachedTokens
+	cfg.SessionCostUSD += sc.SessionCostUSD - base.SessionCostUSD
+	// A sampling request records at most one use of its model
+	for _, u := range sc.ModelUsage {
+		for _, b := range base.ModelUsage {
+			if b.Task == u.Task && b.Provider == u.Provider && b.Model == u.Model {
+				u.Requests -= b.Requests
+				u.InputTokens -= b.InputTokens
+				u.OutputTokens -= b.OutputTokens
+				u.CachedTokens -= b.CachedTokens
+			}
+		}
+		if u.Requests > 0 {
+			cfg.RecordModelUsage(u.Task, u.Provider, u.Model, u.InputTokens, u.OutputTokens, u.CachedTokens)
+		}
+	}
+	for key, warned := range sc.BudgetWarned {
+		if warned {
+			if cfg.BudgetWarned == nil {
+				cfg.BudgetWarned = map[string]bool{}
+			}
+			cfg.BudgetWarned[key] = true
+		}
+	}
+}
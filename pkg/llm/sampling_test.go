@@ -0,0 +1,82 @@
+package llm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/mikhae1/kubectl-quackops/pkg/mcp"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestSampleForMCP(t *testing.T) {
+	orig := RequestWithSystem
+	t.Cleanup(func() { RequestWithSystem = orig })
+
+	conversation := []llms.ChatMessage{llms.HumanChatMessage{Content: "why is web crashing?"}}
+	cfg := &config.Config{
+		Provider:         "openai",
+		Model:            "gpt-5",
+		MCPClientEnabled: true,
+		ChatMessages:     conversation,
+		ActiveTask:       config.TaskAnswer,
+		TaskModels:       map[string]string{config.TaskSampling: "gpt-5-nano"},
+		TaskGeneration:   map[string]provider.Generation{},
+	}
+
+	shared := cfg
+	RequestWithSystem = func(cfg *config.Config, systemPrompt string, userPrompt string, stream bool, history bool) (string, error) {
+		if cfg == shared || !shared.MCPClientEnabled || shared.ActiveTask != config.TaskAnswer || !reflect.DeepEqual(shared.ChatMessages, conversation) {
+			t.Error("sampling must not touch the shared config while the request runs")
+		}
+		if cfg.MCPClientEnabled || history || cfg.ActiveTask != config.TaskSampling {
+			t.Errorf("sampling must run as a tool-less sampling task without history: mcp=%v history=%v task=%q", cfg.MCPClientEnabled, history, cfg.ActiveTask)
+		}
+		want := []llms.ChatMessage{
+			llms.SystemChatMessage{Content: "You size deployments."},
+			llms.HumanChatMessage{Content: "web serves 900 rps"},
+			llms.AIChatMessage{Content: "Each pod handles 300 rps."},
+		}
+		if !reflect.DeepEqual(cfg.ChatMessages, want) || userPrompt != "How many replicas?" {
+			t.Errorf("unexpected request: history=%v prompt=%q", cfg.ChatMessages, userPrompt)
+		}
+		if got := cfg.GenerationFor(config.TaskSampling).MaxOutputTokens; got != 200 {
+			t.Errorf("max output tokens = %d, want 200", got)
+		}
+		cfg.Provider, cfg.Model = "openai", "gpt-5-nano"
+		cfg.LastOutgoingTokens, cfg.LastIncomingTokens = 40, 5
+		cfg.SessionOutgoingTokens += 40
+		cfg.SessionIncomingTokens += 5
+		recordTaskUsage(cfg)
+		return "3 replicas", nil
+	}
+
+	text, model, err := sampleForMCP(cfg, &mcp.SampleRequest{
+		Server:       "guide",
+		SystemPrompt: "You size deployments.",
+		MaxTokens:    200,
+		Messages: []mcp.SampleMessage{
+			{Role: "user", Text: "web serves 900 rps"},
+			{Role: "assistant", Text: "Each pod handles 300 rps."},
+			{Role: "user", Text: "How many replicas?"},
+		},
+	})
+	if err != nil || text != "3 replicas" || model != "gpt-5-nano" {
+		t.Errorf("sampleForMCP() = %q, %q, %v", text, model, err)
+	}
+
+	if !cfg.MCPClientEnabled || cfg.ActiveTask != config.TaskAnswer || !reflect.DeepEqual(cfg.ChatMessages, conversation) {
+		t.Error("the conversation settings were not restored")
+	}
+	if _, ok := cfg.TaskGeneration[config.TaskSampling]; ok {
+		t.Error("the token limit of the server leaked into the settings")
+	}
+	if cfg.Model != "gpt-5" || cfg.LastOutgoingTokens != 0 {
+		t.Errorf("the sampling model or token counts leaked into the conversation: %s %d", cfg.Model, cfg.LastOutgoingTokens)
+	}
+	want := []config.ModelUsage{{Task: config.TaskSampling, Provider: "openai", Model: "gpt-5-nano", Requests: 1, InputTokens: 40, OutputTokens: 5}}
+	if cfg.SessionOutgoingTokens != 40 || cfg.SessionIncomingTokens != 5 || !reflect.DeepEqual(cfg.ModelUsage, want) {
+		t.Errorf("sampling usage not merged: %d/%d %+v", cfg.SessionOutgoingTokens, cfg.SessionIncomingTokens, cfg.ModelUsage)
+	}
+}
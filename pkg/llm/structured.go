@@ -0,0 +1,314 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/mikhae1/kubectl-quackops/pkg/logger"
+)
+
+// structuredRequest describes a request whose answer must decode into a Go struct.
+type structuredRequest struct {
+	// Name identifies the schema to providers (letters, digits, _ and - only).
+	Name string
+	// Prompt is the user prompt; the JSON schema is appended to it.
+	Prompt string
+	// Out is a pointer to the struct the answer decodes into; its type defines the schema.
+	Out any
+	// Validate reports semantic problems the schema cannot express, such as empty steps.
+	Validate func() []string
+	// Send performs one model request.
+	Send func(prompt string) (string, error)
+}
+
+// StructuredOutputError is returned when the answer still violates its schema after all
+// repair attempts. Raw holds the last answer so callers can fall back to lenient parsing.
+type StructuredOutputError struct {
+	Name     string
+	Raw      string
+	Problems []string
+}
+
+func (e *StructuredOutputError) Error() string {
+	return fmt.Sprintf("response does not match the %s schema: %s", e.Name, strings.Join(e.Problems, "; "))
+}
+
+// requestStructured sends req and decodes the answer into req.Out. The schema derived from
+// req.Out is enforced natively by providers that support it (see cfg.ResponseSchema) and is
+// always validated locally; violations are sent back to the model for repair up to
+// cfg.StructuredOutputRetries times. It returns the raw answer.
+func requestStructured(cfg *config.Config, req structuredRequest) (string, error) {
+	schema := jsonSchemaFor(reflect.TypeOf(req.Out).Elem())
+	if cfg.StructuredOutput {
+		origSchema := cfg.ResponseSchema
+		cfg.ResponseSchema = &provider.ResponseSchema{Name: req.Name, Schema: schema}
+		defer func() { cfg.ResponseSchema = origSchema }()
+	}
+
+	schemaJSON, _ := json.Marshal(schema)
+	basePrompt := req.Prompt + "\n\nThe response must be a single JSON object matching this JSON schema:\n" + string(schemaJSON)
+	prompt := basePrompt
+	for attempt := 0; ; attempt++ {
+		raw, err := req.Send(prompt)
+		if err != nil {
+			return raw, err
+		}
+
+		problems := decodeStructured(raw, schema, req.Out)
+		if len(problems) == 0 && req.Validate != nil {
+			problems = req.Validate()
+		}
+		if len(problems) == 0 {
+			return raw, nil
+		}
+		if attempt >= cfg.StructuredOutputRetries {
+			return raw, &StructuredOutputError{Name: req.Name, Raw: raw, Problems: problems}
+		}
+		logger.Log("warn", "Structured %s response violates its schema (attempt %d/%d): %s",
+			req.Name, attempt+1, cfg.StructuredOutputRetries+1, strings.Join(problems, "; "))
+		prompt = repairPrompt(basePrompt, raw, problems)
+	}
+}
+
+// repairPrompt re-asks the original question with the rejected answer and its violations.
+func repairPrompt(prompt, raw string, problems []string) string {
+	const maxEcho = 2000
+	if len(raw) > maxEcho {
+		raw = raw[:maxEcho] + "..."
+	}
+	var b strings.Builder
+	b.WriteString(prompt)
+	b.WriteString("\n\nYour previous response was rejected:\n")
+	b.WriteString(raw)
+	b.WriteString("\n\nProblems:\n")
+	for _, p := range problems {
+		b.WriteString("- " + p + "\n")
+	}
+	b.WriteString("Return only the corrected JSON object, with no prose or code fences.")
+	return b.String()
+}
+
+// decodeStructured extracts the JSON object from raw, validates it against schema and
+// decodes it into out. It returns the schema violations found.
+func decodeStructured(raw string, schema map[string]any, out any) []string {
+	// Clear results of a previous attempt; json.Unmarshal keeps fields absent from the input
+	v := reflect.ValueOf(out).Elem()
+	v.SetZero()
+
+	blob, err := extractJSON(raw)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var doc any
+	if err := json.Unmarshal([]byte(blob), &doc); err != nil {
+		return []string{"invalid JSON: " + err.Error()}
+	}
+	if problems := validateSchema(schema, doc, "$"); len(problems) > 0 {
+		return problems
+	}
+	if err := json.Unmarshal([]byte(blob), out); err != nil {
+		return []string{"invalid JSON: " + err.Error()}
+	}
+	return nil
+}
+
+// jsonSchemaFor derives a JSON schema from a Go type. Struct fields are named by their json
+// tag, fields without omitempty are required and a desc tag becomes the description.
+func jsonSchemaFor(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		props := map[string]any{}
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			prop := jsonSchemaFor(f.Type)
+			if desc := f.Tag.Get("desc"); desc != "" {
+				prop["description"] = desc
+			}
+			props[name] = prop
+			if !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		schema := map[string]any{"type": "object", "properties": props}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": jsonSchemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	default:
+		return map[string]any{}
+	}
+}
+
+// validateSchema checks a decoded JSON document against the subset of JSON schema produced
+// by jsonSchemaFor and returns one message per violation.
+func validateSchema(schema map[string]any, doc any, path string) []string {
+	want, _ := schema["type"].(string)
+	switch want {
+	case "object":
+		obj, ok := doc.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected object, got %s", path, jsonTypeName(doc))}
+		}
+		var problems []string
+		required, _ := schema["required"].([]string)
+		for _, name := range required {
+			if v, ok := obj[name]; !ok || v == nil {
+				problems = append(problems, fmt.Sprintf("%s: missing required field %q", path, name))
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		names := make([]string, 0, len(props))
+		for name := range props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			v, ok := obj[name]
+			if !ok || v == nil {
+				continue
+			}
+			if ps, ok := props[name].(map[string]any); ok {
+				problems = append(problems, validateSchema(ps, v, path+"."+name)...)
+			}
+		}
+		return problems
+	case "array":
+		arr, ok := doc.([]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected array, got %s", path, jsonTypeName(doc))}
+		}
+		items, _ := schema["items"].(map[string]any)
+		var problems []string
+		for i, v := range arr {
+			problems = append(problems, validateSchema(items, v, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return problems
+	case "integer":
+		if n, ok := doc.(float64); !ok || n != float64(int64(n)) {
+			return []string{fmt.Sprintf("%s: expected integer, got %s", path, jsonTypeName(doc))}
+		}
+	case "number":
+		if _, ok := doc.(float64); !ok {
+			return []string{fmt.Sprintf("%s: expected number, got %s", path, jsonTypeName(doc))}
+		}
+	case "string", "boolean":
+		if got := jsonTypeName(doc); got != want {
+			return []string{fmt.Sprintf("%s: expected %s, got %s", path, want, got)}
+		}
+	}
+	return nil
+}
+
+func jsonTypeName(v any) string {
+	switch n := v.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if n == float64(int64(n)) {
+			return "integer"
+		}
+		return "number"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// bodyFieldsDoer adds top-level fields to JSON requests sent to path, "/chat/completions"
+// by default. It sends settings the langchaingo clients have no option for, such as
+// reasoning_effort or an Ollama format schema. Fields the client already set are kept
+// unless override is true.
+type bodyFieldsDoer struct {
+	client   *http.Client
+	path     string
+	fields   map[string]any
+	override bool
+}
+
+func (d *bodyFieldsDoer) Do(req *http.Request) (*http.Response, error) {
+	if err := d.rewrite(req); err != nil {
+		return nil, err
+	}
+	return d.client.Do(req)
+}
+
+// RoundTrip lets the doer serve as the transport of clients that take an *http.Client.
+func (d *bodyFieldsDoer) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if err := d.rewrite(req); err != nil {
+		return nil, err
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func (d *bodyFieldsDoer) rewrite(req *http.Request) error {
+	path := d.path
+	if path == "" {
+		path = "/chat/completions"
+	}
+	if req.Body == nil || req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, path) {
+		return nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(body, &payload); err == nil {
+		for k, v := range d.fields {
+			if _, ok := payload[k]; !ok || d.override {
+				payload[k] = v
+			}
+		}
+		if merged, err := json.Marshal(payload); err == nil {
+			body = merged
+		}
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	return nil
+}
@@ -0,0 +1,241 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestJSONSchemaFor(t *testing.T) {
+	schema := jsonSchemaFor(reflect.TypeOf(planPayload{}))
+	if got := schema["required"]; !reflect.DeepEqual(got, []string{"steps"}) {
+		t.Fatalf("unexpected root required: %v", got)
+	}
+	steps := schema["properties"].(map[string]any)["steps"].(map[string]any)
+	if steps["type"] != "array" || steps["description"] != "Ordered plan steps" {
+		t.Fatalf("unexpected steps schema: %v", steps)
+	}
+	item := steps["items"].(map[string]any)
+	if got := item["required"]; !reflect.DeepEqual(got, []string{"action", "reasoning"}) {
+		t.Fatalf("unexpected step required: %v", got)
+	}
+	props := item["properties"].(map[string]any)
+	if props["step_number"].(map[string]any)["type"] != "integer" {
+		t.Errorf("step_number should be an integer: %v", props["step_number"])
+	}
+	tools := props["required_tools"].(map[string]any)
+	if tools["type"] != "array" || tools["items"].(map[string]any)["type"] != "string" {
+		t.Errorf("unexpected required_tools schema: %v", tools)
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	schema := jsonSchemaFor(reflect.TypeOf(planPayload{}))
+	tests := []struct {
+		name string
+		doc  string
+		want []string
+	}{
+		{
+			name: "valid",
+			doc:  `{"steps":[{"step_number":1,"action":"a","reasoning":"r","required_tools":["kubectl"]}]}`,
+		},
+		{
+			name: "missing steps",
+			doc:  `{"plan":[]}`,
+			want: []string{`$: missing required field "steps"`},
+		},
+		{
+			name: "wrong types",
+			doc:  `{"steps":[{"step_number":"1","action":"a","reasoning":"r","required_tools":"kubectl"}]}`,
+			want: []string{
+				"$.steps[0].required_tools: expected array, got string",
+				"$.steps[0].step_number: expected integer, got string",
+			},
+		},
+		{
+			name: "fractional step number",
+			doc:  `{"steps":[{"step_number":1.5,"action":"a","reasoning":"r"}]}`,
+			want: []string{"$.steps[0].step_number: expected integer, got number"},
+		},
+		{
+			name: "missing step fields",
+			doc:  `{"steps":[{"action":null}]}`,
+			want: []string{
+				`$.steps[0]: missing required field "action"`,
+				`$.steps[0]: missing required field "reasoning"`,
+			},
+		},
+		{
+			name: "root is array",
+			doc:  `[]`,
+			want: []string{"$: expected object, got array"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc any
+			if err := json.Unmarshal([]byte(tt.doc), &doc); err != nil {
+				t.Fatal(err)
+			}
+			got := validateSchema(schema, doc, "$")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("validateSchema() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestStructuredRepairsViolations(t *testing.T) {
+	cfg := &config.Config{StructuredOutput: true, StructuredOutputRetries: 2}
+	responses := []string{
+		`{"steps":[{"step_number":"one","action":"inspect","reasoning":"r"}]}`,
+		"Sure! ```json\n{\"steps\":[{\"step_number\":1,\"action\":\"inspect pods\",\"reasoning\":\"r\"}]}\n```",
+	}
+	var prompts []string
+	var payload planPayload
+	raw, err := requestStructured(cfg, structuredRequest{
+		Name:     "plan",
+		Prompt:   "make a plan",
+		Out:      &payload,
+		Validate: payload.problems,
+		Send: func(prompt string) (string, error) {
+			if cfg.ResponseSchema == nil || cfg.ResponseSchema.Name != "plan" {
+				t.Errorf("response schema not set during request: %+v", cfg.ResponseSchema)
+			}
+			prompts = append(prompts, prompt)
+			return responses[len(prompts)-1], nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("requestStructured returned error: %v", err)
+	}
+	if cfg.ResponseSchema != nil {
+		t.Error("response schema was not restored")
+	}
+	if raw != responses[1] || len(payload.Steps) != 1 || payload.Steps[0].Action != "inspect pods" {
+		t.Fatalf("unexpected result %q %+v", raw, payload)
+	}
+	if len(prompts) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(prompts))
+	}
+	if !strings.Contains(prompts[0], `"required":["steps"]`) {
+		t.Errorf("schema missing from prompt: %s", prompts[0])
+	}
+	if !strings.Contains(prompts[1], "$.steps[0].step_number: expected integer, got string") {
+		t.Errorf("repair prompt does not list the violation: %s", prompts[1])
+	}
+}
+
+func TestRequestStructuredGivesUp(t *testing.T) {
+	cfg := &config.Config{StructuredOutputRetries: 1}
+	calls := 0
+	var payload planPayload
+	_, err := requestStructured(cfg, structuredRequest{
+		Name:     "plan",
+		Prompt:   "make a plan",
+		Out:      &payload,
+		Validate: payload.problems,
+		Send: func(prompt string) (string, error) {
+			calls++
+			if cfg.ResponseSchema != nil {
+				t.Error("native schema requested although structured output is disabled")
+			}
+			return `{"steps":[]}`, nil
+		},
+	})
+	var schemaErr *StructuredOutputError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("expected StructuredOutputError, got %v", err)
+	}
+	if calls != 2 || schemaErr.Raw != `{"steps":[]}` || schemaErr.Problems[0] != "$.steps: the plan has no steps" {
+		t.Fatalf("unexpected give-up state: calls=%d err=%+v", calls, schemaErr)
+	}
+}
+
+func TestToolOutputModelReturnsToolArguments(t *testing.T) {
+	args := `{"commands":["kubectl get pods"]}`
+	mock := NewMockLLMClient([]MockResponse{{
+		ToolCalls: []llms.ToolCall{{
+			ID:           "call_1",
+			Type:         "function",
+			FunctionCall: &llms.FunctionCall{Name: "kubectl_commands", Arguments: args},
+		}},
+	}})
+	schema := jsonSchemaFor(reflect.TypeOf(kubectlSuggestions{}))
+	model := &toolOutputModel{Model: mock, schema: &provider.ResponseSchema{Name: "kubectl_commands", Schema: schema}}
+
+	resp, err := model.GenerateContent(context.Background(), []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "diagnose")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Choices[0]; got.Content != args || len(got.ToolCalls) != 0 {
+		t.Fatalf("unexpected choice: %+v", got)
+	}
+
+	var opts llms.CallOptions
+	for _, opt := range mock.callHistory[0].Options {
+		opt(&opts)
+	}
+	if len(opts.Tools) != 1 || opts.Tools[0].Function.Name != "kubectl_commands" {
+		t.Fatalf("schema tool not offered: %+v", opts.Tools)
+	}
+}
+
+func TestOpenAIResponseFormat(t *testing.T) {
+	rs := &provider.ResponseSchema{Name: "plan", Schema: jsonSchemaFor(reflect.TypeOf(planPayload{}))}
+	format := openAIResponseFormat(rs)
+	if format.Type != "json_schema" || format.JSONSchema.Name != "plan" {
+		t.Fatalf("unexpected format: %+v", format)
+	}
+	item := format.JSONSchema.Schema.Properties["steps"].Items
+	if item.Type != "object" || item.Properties["action"].Description != "What to do in this step" {
+		t.Fatalf("unexpected step schema: %+v", item)
+	}
+	if !reflect.DeepEqual(item.Required, []string{"action", "reasoning"}) {
+		t.Fatalf("unexpected required: %v", item.Required)
+	}
+}
+
+func TestOllamaSendsSchemaAsFormat(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/chat" {
+			body, _ := io.ReadAll(r.Body)
+			if err := json.Unmarshal(body, &got); err != nil {
+				t.Errorf("invalid body %q: %v", body, err)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"model":"llama3.1","message":{"role":"assistant","content":"{\"steps\":[]}"},"done":true}`)
+	}))
+	defer srv.Close()
+
+	schema := jsonSchemaFor(reflect.TypeOf(planPayload{}))
+	model, err := ollamaProvider{}.NewModel(context.Background(), provider.Options{
+		Model:          "llama3.1",
+		ServerURL:      srv.URL,
+		ResponseSchema: &provider.ResponseSchema{Name: "plan", Schema: schema},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "plan")}
+	if _, err := model.GenerateContent(context.Background(), messages, llms.WithJSONMode()); err != nil {
+		t.Fatal(err)
+	}
+	format, ok := got["format"].(map[string]any)
+	if !ok || format["type"] != "object" || format["properties"].(map[string]any)["steps"] == nil {
+		t.Fatalf("schema not sent as format: %v", got["format"])
+	}
+}
@@ -0,0 +1,71 @@
+package llm
+
+import (
+	"strings"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+	"github.com/mikhae1/kubectl-quackops/pkg/logger"
+)
+
+// resolveTaskModel splits a task model reference into provider and model. A reference whose
+// prefix is not a registered provider is a bare model on the active provider, so OpenRouter
+// ids such as anthropic/claude-sonnet-4 keep working.
+func resolveTaskModel(cfg *config.Config, ref string) (string, string) {
+	if providerName, model, ok := parseModelRef(ref); ok {
+		if _, known := provider.Get(providerName); known {
+			return providerName, model
+		}
+	}
+	return cfg.Provider, strings.TrimSpace(ref)
+}
+
+// useTaskModel points cfg at the model configured for the active task and returns a func
+// that restores the main model.
+func useTaskModel(cfg *config.Config) func() {
+	ref := cfg.TaskModel(cfg.ActiveTask)
+	if ref == "" {
+		return func() {}
+	}
+	providerName, model := resolveTaskModel(cfg, ref)
+	if providerName == cfg.Provider && model == cfg.Model {
+		return func() {}
+	}
+
+	logger.Log("debug", "[Request] Using %s for %s task", modelRef(providerName, model), taskName(cfg.ActiveTask))
+	return swapModel(cfg, providerName, model)
+}
+
+// swapModel points cfg at providerName/model and returns a func that restores the previous
+// model. The context window falls back to the provider default instead of being detected,
+// which would cost a metadata lookup per request.
+func swapModel(cfg *config.Config, providerName, model string) func() {
+	origProvider, origModel, origMaxTokens := cfg.Provider, cfg.Model, cfg.DefaultMaxTokens
+	if providerName != cfg.Provider {
+		if p, ok := provider.Get(providerName); ok {
+			if pd := p.Defaults(); pd.MaxTokens > 0 {
+				cfg.DefaultMaxTokens = pd.MaxTokens
+			}
+		}
+	}
+	cfg.Provider, cfg.Model = providerName, model
+	return func() {
+		cfg.Provider, cfg.Model, cfg.DefaultMaxTokens = origProvider, origModel, origMaxTokens
+	}
+}
+
+// recordTaskUsage adds the tokens of the request that just finished to the session's
+// per-task usage, under the model that answered it.
+func recordTaskUsage(cfg *config.Config) {
+	sessionUsageMu.Lock()
+	defer sessionUsageMu.Unlock()
+	cfg.RecordModelUsage(cfg.ActiveTask, cfg.Provider, cfg.Model,
+		cfg.LastOutgoingTokens, cfg.LastIncomingTokens, cfg.LastCachedTokens)
+}
+
+func taskName(task string) string {
+	if task == "" {
+		return config.TaskAnswer
+	}
+	return task
+}
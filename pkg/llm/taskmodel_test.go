@@ -0,0 +1,78 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/llm/provider"
+)
+
+func TestResolveTaskModel(t *testing.T) {
+	cfg := CreateTestConfig()
+	cfg.Provider = "openrouter-like"
+
+	tests := []struct {
+		ref          string
+		wantProvider string
+		wantModel    string
+	}{
+		{"anthropic/claude-haiku-4-5", "anthropic", "claude-haiku-4-5"},
+		{"openai/anthropic/claude-sonnet-4", "openai", "anthropic/claude-sonnet-4"},
+		{"gpt-5-nano", "openrouter-like", "gpt-5-nano"},
+		{"meta-llama/llama-3.1-8b", "openrouter-like", "meta-llama/llama-3.1-8b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			p, m := resolveTaskModel(cfg, tt.ref)
+			if p != tt.wantProvider || m != tt.wantModel {
+				t.Errorf("resolveTaskModel(%q) = %s, %s; want %s, %s", tt.ref, p, m, tt.wantProvider, tt.wantModel)
+			}
+		})
+	}
+}
+
+func TestRequestUsesTaskModel(t *testing.T) {
+	main := NewMockLLMClient([]MockResponse{{Content: "analysis"}})
+	router := NewMockLLMClient([]MockResponse{{Content: "kubectl get pods"}})
+	provider.Register(namedFakeProvider{fakeProvider{client: main}, "fake-strong"})
+	provider.Register(namedFakeProvider{fakeProvider{client: router}, "fake-router"})
+
+	cfg := CreateTestConfig()
+	cfg.AutoDetectMaxTokens = false
+	cfg.Provider = "fake-strong"
+	cfg.Model = "large"
+	cfg.DefaultMaxTokens = 200000
+	cfg.TaskModels = map[string]string{config.TaskCommands: "fake-router/nano"}
+
+	cfg.ActiveTask = config.TaskCommands
+	answer, err := RequestWithSystem(cfg, "", "which command?", false, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(answer, "kubectl get pods") || len(router.GetCallHistory()) != 1 || len(main.GetCallHistory()) != 0 {
+		t.Fatalf("commands task should use the router model, got %q", answer)
+	}
+	if cfg.Provider != "fake-strong" || cfg.Model != "large" || cfg.DefaultMaxTokens != 200000 {
+		t.Errorf("main model not restored: %s/%s %d", cfg.Provider, cfg.Model, cfg.DefaultMaxTokens)
+	}
+
+	cfg.ActiveTask = ""
+	if _, err := RequestWithSystem(cfg, "", "why is it failing?", false, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(main.GetCallHistory()) != 1 {
+		t.Fatal("answer task should use the main model")
+	}
+
+	if len(cfg.ModelUsage) != 2 {
+		t.Fatalf("expected usage for two task/model pairs, got %+v", cfg.ModelUsage)
+	}
+	commands, answerUsage := cfg.ModelUsage[0], cfg.ModelUsage[1]
+	if commands.Task != config.TaskCommands || commands.Provider != "fake-router" || commands.Model != "nano" || commands.Requests != 1 || commands.InputTokens == 0 {
+		t.Errorf("unexpected commands usage: %+v", commands)
+	}
+	if answerUsage.Task != config.TaskAnswer || answerUsage.Provider != "fake-strong" || answerUsage.Model != "large" {
+		t.Errorf("unexpected answer usage: %+v", answerUsage)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mikhae1/kubectl-quackops/pkg/config"
@@ -168,6 +169,8 @@ func (tp *TurnProcessor) Process() (*TurnProcessorResult, error) {
 	if tp == nil {
 		return nil, fmt.Errorf("nil turn processor")
 	}
+	// Callers read the turn's token totals from cfg once Process returns
+	defer tp.finishTokenAnimation()
 	for tp.state != TurnProcessorStateDone {
 		var (
 			next TurnProcessorState
@@ -364,7 +367,20 @@ func (tp *TurnProcessor) handlePlanState() (TurnProcessorState, error) {
 }
 
 func (tp *TurnProcessor) handleExecuteState() (TurnProcessorState, error) {
+	// ESC cancels the running tool calls; their servers get notifications/cancelled
+	var canceled atomic.Bool
+	stopEsc := func() {}
+	if tp.startEscBreaker != nil {
+		stopEsc = tp.startEscBreaker(func() {
+			canceled.Store(true)
+			mcp.CancelToolCalls()
+		})
+	}
 	executedCalls, batchErr := executePreparedMCPCalls(tp.cfg, tp.spinnerManager, tp.preparedCalls, tp.toolResultCache, tp.trackToolTokenProgress)
+	stopEsc()
+	if canceled.Load() {
+		return TurnProcessorStateDone, lib.NewUserCancelError("canceled by user")
+	}
 	if batchErr != nil {
 		if lib.IsUserCancel(batchErr) {
 			return TurnProcessorStateDone, lib.NewUserCancelError("canceled by user")
@@ -669,6 +685,21 @@ func (tp *TurnProcessor) scheduleTokenCounterAnimationLocked() (string, bool) {
 	return msg, true
 }
 
+// finishTokenAnimation stops a running counter animation and leaves the turn's final token
+// totals in cfg instead of the values the animation last displayed.
+func (tp *TurnProcessor) finishTokenAnimation() {
+	if tp.cfg == nil {
+		return
+	}
+	tp.tokenFlowMu.Lock()
+	defer tp.tokenFlowMu.Unlock()
+	tp.tokenAnimSeq++
+	tp.displayedOutgoingTokens = tp.outgoingTokens
+	tp.displayedIncomingTokens = tp.incomingTokens
+	tp.cfg.LastOutgoingTokens = tp.outgoingTokens
+	tp.cfg.LastIncomingTokens = tp.incomingTokens
+}
+
 func (tp *TurnProcessor) runTokenCounterAnimation(seq uint64, startOut int, startIn int, targetOut int, targetIn int, steps int, duration time.Duration) {
 	if tp == nil || steps <= 0 {
 		return
@@ -694,8 +725,11 @@ func (tp *TurnProcessor) runTokenCounterAnimation(seq uint64, startOut int, star
 		tp.displayedOutgoingTokens = curOut
 		tp.displayedIncomingTokens = curIn
 		if tp.cfg != nil {
+			// The animation runs while tools do; sampling copies cfg under sessionUsageMu
+			sessionUsageMu.Lock()
 			tp.cfg.LastOutgoingTokens = curOut
 			tp.cfg.LastIncomingTokens = curIn
+			sessionUsageMu.Unlock()
 		}
 
 		msg := ""
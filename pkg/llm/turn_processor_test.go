@@ -6,6 +6,7 @@ import (
 	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/mikhae1/kubectl-quackops/pkg/config"
 	"github.com/tmc/langchaingo/llms"
@@ -321,6 +322,23 @@ func TestTurnProcessor_IncomingTokensStayCumulativeAcrossToolRounds(t *testing.T
 	}
 }
 
+func TestTurnProcessor_FinishTokenAnimationLeavesFinalTotals(t *testing.T) {
+	cfg := CreateTestConfig()
+	processor := NewTurnProcessor(TurnProcessorParams{Cfg: cfg, OutgoingTokens: 1000})
+	processor.incomingTokens = 400
+
+	// An animation still counting up from zero must not overwrite the totals afterwards
+	go processor.runTokenCounterAnimation(processor.tokenAnimSeq, 0, 0, 1000, 400, 2, 100*time.Millisecond)
+	processor.finishTokenAnimation()
+	time.Sleep(150 * time.Millisecond)
+
+	processor.tokenFlowMu.Lock()
+	defer processor.tokenFlowMu.Unlock()
+	if cfg.LastOutgoingTokens != 1000 || cfg.LastIncomingTokens != 400 {
+		t.Fatalf("expected final totals 1000/400, got %d/%d", cfg.LastOutgoingTokens, cfg.LastIncomingTokens)
+	}
+}
+
 func contentPartsToText(parts []llms.ContentPart) string {
 	var b strings.Builder
 	for _, part := range parts {
@@ -0,0 +1,82 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/mikhae1/kubectl-quackops/pkg/logger"
+)
+
+// ArgumentError reports model-supplied tool arguments that do not match the tool's input
+// schema. Its message is a JSON object with the schema, so the model can correct the call.
+type ArgumentError struct {
+	Tool    string
+	Message string
+	Schema  *jsonschema.Schema
+}
+
+func (e *ArgumentError) Error() string {
+	data, err := json.Marshal(map[string]any{
+		"error":        "invalid_arguments",
+		"tool":         e.Tool,
+		"message":      e.Message,
+		"hint":         "The call was not sent to the server. Fix the arguments to match input_schema and call the tool again.",
+		"input_schema": e.Schema,
+	})
+	if err != nil {
+		return fmt.Sprintf("invalid arguments for tool '%s': %s", e.Tool, e.Message)
+	}
+	return string(data)
+}
+
+// resolvedSchemas caches resolved input schemas by schema pointer; a schema is never
+// changed after discovery.
+var resolvedSchemas sync.Map // *jsonschema.Schema -> *jsonschema.Resolved
+
+func resolveSchema(schema *jsonschema.Schema) (*jsonschema.Resolved, error) {
+	if rs, ok := resolvedSchemas.Load(schema); ok {
+		return rs.(*jsonschema.Resolved), nil
+	}
+	rs, err := schema.Resolve(nil)
+	if err != nil {
+		return nil, err
+	}
+	resolvedSchemas.Store(schema, rs)
+	return rs, nil
+}
+
+// prepareToolArgs validates args against the input schema of info and returns a copy with
+// the schema defaults applied. Schemas that cannot be resolved, such as ones with remote
+// references, are left to the server.
+func prepareToolArgs(info *ToolInfo, args map[string]any) (map[string]any, error) {
+	if info == nil || info.InputSchema == nil {
+		return args, nil
+	}
+	rs, err := resolveSchema(info.InputSchema)
+	if err != nil {
+		logger.Log("debug", "[MCP] Skipping argument validation for %s: %v", info.Name, err)
+		return args, nil
+	}
+
+	// Work on a JSON copy: defaults must not leak into the caller's map, and the validator
+	// expects JSON types
+	prepared := map[string]any{}
+	if len(args) > 0 {
+		data, err := json.Marshal(args)
+		if err != nil {
+			return nil, &ArgumentError{Tool: info.Name, Message: err.Error(), Schema: info.InputSchema}
+		}
+		if err := json.Unmarshal(data, &prepared); err != nil {
+			return nil, &ArgumentError{Tool: info.Name, Message: err.Error(), Schema: info.InputSchema}
+		}
+	}
+	if err := rs.ApplyDefaults(&prepared); err != nil {
+		logger.Log("debug", "[MCP] Failed to apply defaults for %s: %v", info.Name, err)
+	}
+	if err := rs.Validate(prepared); err != nil {
+		return nil, &ArgumentError{Tool: info.Name, Message: err.Error(), Schema: info.InputSchema}
+	}
+	return prepared, nil
+}
@@ -0,0 +1,91 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+func TestPrepareToolArgs(t *testing.T) {
+	schema := toJSONSchema(map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"namespace": map[string]any{"type": "string", "default": "default"},
+			"replicas":  map[string]any{"type": "integer", "minimum": 0},
+			"name":      map[string]any{"type": "string"},
+		},
+		"required":             []any{"name"},
+		"additionalProperties": false,
+	})
+	info := &ToolInfo{Name: "scale", InputSchema: schema}
+
+	tests := []struct {
+		name     string
+		info     *ToolInfo
+		args     map[string]any
+		expected map[string]any
+		errPart  string
+	}{
+		{"defaults applied", info, map[string]any{"name": "web", "replicas": 2}, map[string]any{"name": "web", "replicas": float64(2), "namespace": "default"}, ""},
+		{"explicit value kept", info, map[string]any{"name": "web", "namespace": "prod"}, map[string]any{"name": "web", "namespace": "prod"}, ""},
+		{"wrong type", info, map[string]any{"name": "web", "replicas": "2"}, nil, "replicas"},
+		{"missing required", info, map[string]any{"replicas": 1}, nil, "name"},
+		{"unknown property", info, map[string]any{"name": "web", "raw": "{"}, nil, "raw"},
+		{"no schema", &ToolInfo{Name: "bash"}, map[string]any{"command": 1}, map[string]any{"command": 1}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := prepareToolArgs(tt.info, tt.args)
+			if tt.errPart != "" {
+				var argErr *ArgumentError
+				if !errors.As(err, &argErr) {
+					t.Fatalf("expected ArgumentError, got %v", err)
+				}
+				var payload map[string]any
+				if err := json.Unmarshal([]byte(argErr.Error()), &payload); err != nil {
+					t.Fatalf("error is not JSON: %v", err)
+				}
+				if payload["error"] != "invalid_arguments" || payload["input_schema"] == nil {
+					t.Errorf("unexpected error payload: %v", payload)
+				}
+				if !strings.Contains(argErr.Message, tt.errPart) {
+					t.Errorf("message %q does not mention %q", argErr.Message, tt.errPart)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(tt.expected)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("prepareToolArgs() = %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+
+	// The caller's map is left alone
+	args := map[string]any{"name": "web"}
+	if _, err := prepareToolArgs(info, args); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := args["namespace"]; ok {
+		t.Error("defaults leaked into the caller's arguments")
+	}
+}
+
+func TestPrepareToolArgsUnresolvableSchema(t *testing.T) {
+	info := &ToolInfo{Name: "remote", InputSchema: &jsonschema.Schema{Ref: "https://example.invalid/schema.json"}}
+	args := map[string]any{"anything": true}
+	got, err := prepareToolArgs(info, args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got["anything"] != true {
+		t.Errorf("expected arguments to pass through, got %v", got)
+	}
+}
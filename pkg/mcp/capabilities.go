@@ -0,0 +1,426 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/lib"
+	"github.com/mikhae1/kubectl-quackops/pkg/logger"
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// SampleMessage is one message of a sampling request.
+type SampleMessage struct {
+	Role string // "user" or "assistant"
+	Text string
+}
+
+// SampleRequest is a completion an MCP server asked QuackOps' model for.
+type SampleRequest struct {
+	Server       string
+	SystemPrompt string
+	Messages     []SampleMessage
+	MaxTokens    int
+}
+
+// SamplerFunc completes a sampling request and returns the text and the model that
+// produced it.
+type SamplerFunc func(cfg *config.Config, req *SampleRequest) (text string, model string, err error)
+
+// sampler answers sampling requests; the llm package registers it, which avoids an import
+// cycle. Sampling is not offered to servers while it is nil.
+var sampler SamplerFunc
+
+// RegisterSampler sets the function that answers sampling/createMessage requests.
+func RegisterSampler(f SamplerFunc) {
+	sampler = f
+}
+
+var (
+	// formInput is where elicitation forms read their answers; tests replace it.
+	formInput io.Reader = os.Stdin
+
+	// currentKubeContext returns the active kubeconfig context; tests replace it.
+	currentKubeContext = func(cfg *config.Config) string {
+		timeout := time.Duration(cfg.Timeout) * time.Second
+		if timeout <= 0 {
+			timeout = 5 * time.Second
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, cfg.KubectlBinaryPath, "config", "current-context").Output()
+		if err != nil {
+			logger.Log("debug", "[MCP] Unable to read the current kube context: %v", err)
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	}
+)
+
+// withClientCapabilities adds the sampling and elicitation handlers enabled in cfg to opts,
+// along with the progress and log notification handlers.
+func withClientCapabilities(cfg *config.Config, server string, opts *sdkmcp.ClientOptions) *sdkmcp.ClientOptions {
+	opts.ProgressNotificationHandler = func(ctx context.Context, req *sdkmcp.ProgressNotificationClientRequest) {
+		handleProgress(req.Params)
+	}
+	opts.LoggingMessageHandler = func(ctx context.Context, req *sdkmcp.LoggingMessageRequest) {
+		handleServerLog(cfg, server, req.Params)
+	}
+	if cfg.MCPSampling && sampler != nil {
+		opts.CreateMessageHandler = func(ctx context.Context, req *sdkmcp.CreateMessageRequest) (*sdkmcp.CreateMessageResult, error) {
+			return handleCreateMessage(cfg, server, req.Params)
+		}
+	}
+	if cfg.MCPElicitation {
+		opts.ElicitationHandler = func(ctx context.Context, req *sdkmcp.ElicitRequest) (*sdkmcp.ElicitResult, error) {
+			return handleElicit(cfg, server, req.Params)
+		}
+	}
+	return opts
+}
+
+// addClientRoots advertises the working directory and the kubeconfig files to the servers
+// of client when roots are enabled.
+func addClientRoots(cfg *config.Config, client *sdkmcp.Client) {
+	if cfg.MCPRoots {
+		client.AddRoots(clientRoots(cfg)...)
+	}
+}
+
+// clientRoots returns the working directory and each kubeconfig file as file:// roots. The
+// kubeconfig roots are named after the current context so servers can default to it.
+func clientRoots(cfg *config.Config) []*sdkmcp.Root {
+	var roots []*sdkmcp.Root
+	if wd, err := os.Getwd(); err == nil {
+		roots = append(roots, &sdkmcp.Root{URI: fileURI(wd), Name: "working directory"})
+	}
+
+	var kubeconfigs []string
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		for _, path := range filepath.SplitList(env) {
+			if path != "" && !slices.Contains(kubeconfigs, path) {
+				kubeconfigs = append(kubeconfigs, path)
+			}
+		}
+	} else if home, err := os.UserHomeDir(); err == nil {
+		kubeconfigs = append(kubeconfigs, filepath.Join(home, ".kube", "config"))
+	}
+	if len(kubeconfigs) == 0 {
+		return roots
+	}
+
+	name := "kubeconfig"
+	if kubeCtx := currentKubeContext(cfg); kubeCtx != "" {
+		name = fmt.Sprintf("kubeconfig (context %s)", kubeCtx)
+	}
+	for _, path := range kubeconfigs {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		roots = append(roots, &sdkmcp.Root{URI: fileURI(path), Name: name})
+	}
+	return roots
+}
+
+func fileURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// handleCreateMessage asks the user to approve a sampling request from server, answers it
+// with the configured model and lets the user review the answer before it is returned.
+// Budgets apply as to any other request.
+func handleCreateMessage(cfg *config.Config, server string, params *sdkmcp.CreateMessageParams) (*sdkmcp.CreateMessageResult, error) {
+	req := &SampleRequest{Server: server, SystemPrompt: params.SystemPrompt, MaxTokens: int(params.MaxTokens)}
+	for _, m := range params.Messages {
+		text, ok := m.Content.(*sdkmcp.TextContent)
+		if !ok {
+			return nil, fmt.Errorf("sampling request from %s has %T content; only text is supported", server, m.Content)
+		}
+		req.Messages = append(req.Messages, SampleMessage{Role: string(m.Role), Text: text.Text})
+	}
+	if len(req.Messages) == 0 || req.Messages[len(req.Messages)-1].Role != "user" {
+		return nil, fmt.Errorf("sampling request from %s must end with a user message", server)
+	}
+
+	approvalMu.Lock()
+	key := askApproval(cfg, samplingQuestion(req))
+	approvalMu.Unlock()
+	if key != 'y' {
+		writeMCPLog(map[string]any{"event": "sampling_declined", "server": server})
+		return nil, fmt.Errorf("sampling request from %s declined by user", server)
+	}
+
+	text, model, err := sampler(cfg, req)
+	writeMCPLog(map[string]any{"event": "sampling", "server": server, "messages": len(req.Messages), "model": model, "error": errString(err)})
+	if err != nil {
+		return nil, err
+	}
+
+	// The user sees the answer before the server does
+	approvalMu.Lock()
+	key = askApproval(cfg, fmt.Sprintf("%s\nSend this answer to %s? (y/N): ", strings.TrimSpace(text), server))
+	approvalMu.Unlock()
+	if key != 'y' {
+		writeMCPLog(map[string]any{"event": "sampling_withheld", "server": server})
+		return nil, fmt.Errorf("sampling answer for %s withheld by user", server)
+	}
+	return &sdkmcp.CreateMessageResult{
+		Role:       "assistant",
+		Content:    &sdkmcp.TextContent{Text: text},
+		Model:      model,
+		StopReason: "endTurn",
+	}, nil
+}
+
+// samplingQuestion builds the approval prompt for a sampling request, previewing the last
+// message the model would answer.
+func samplingQuestion(req *SampleRequest) string {
+	last := strings.Join(strings.Fields(req.Messages[len(req.Messages)-1].Text), " ")
+	if len(last) > 200 {
+		last = last[:197] + "..."
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "MCP server %s asks the model (%d message(s)", req.Server, len(req.Messages))
+	if req.SystemPrompt != "" {
+		b.WriteString(", with a system prompt")
+	}
+	if req.MaxTokens > 0 {
+		fmt.Fprintf(&b, ", up to %d tokens", req.MaxTokens)
+	}
+	fmt.Fprintf(&b, "): %q. Allow? (y/N): ", last)
+	return b.String()
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// formField is one property of an elicitation schema.
+type formField struct {
+	name     string
+	schema   *jsonschema.Schema
+	required bool
+}
+
+// handleElicit renders the form requested by server in the terminal. The user can fill it
+// in, decline, or cancel with ESC or end of input.
+func handleElicit(cfg *config.Config, server string, params *sdkmcp.ElicitParams) (*sdkmcp.ElicitResult, error) {
+	schema := toJSONSchema(params.RequestedSchema)
+	if schema == nil {
+		return nil, fmt.Errorf("elicitation request from %s has no valid schema", server)
+	}
+
+	approvalMu.Lock()
+	defer approvalMu.Unlock()
+	// The form reads stdin, which an ESC watcher must not compete for
+	defer lib.SuspendEscWatcher()()
+
+	lib.GetSpinnerManager(cfg).Hide()
+	fmt.Printf("\n%s %s\n", config.Colors.Warn.Sprintf("MCP server %s asks:", server), params.Message)
+	switch askApproval(cfg, "Respond? (y=fill in, N=decline, ESC=cancel): ") {
+	case 'y':
+	case 27:
+		writeMCPLog(map[string]any{"event": "elicitation", "server": server, "action": "cancel"})
+		return &sdkmcp.ElicitResult{Action: "cancel"}, nil
+	default:
+		writeMCPLog(map[string]any{"event": "elicitation", "server": server, "action": "decline"})
+		return &sdkmcp.ElicitResult{Action: "decline"}, nil
+	}
+
+	content, err := fillForm(bufio.NewReader(formInput), formFields(schema))
+	if errors.Is(err, io.EOF) {
+		writeMCPLog(map[string]any{"event": "elicitation", "server": server, "action": "cancel"})
+		return &sdkmcp.ElicitResult{Action: "cancel"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	writeMCPLog(map[string]any{"event": "elicitation", "server": server, "action": "accept"})
+	return &sdkmcp.ElicitResult{Action: "accept", Content: content}, nil
+}
+
+// formFields lists the properties of schema, required ones first in their declared order.
+func formFields(schema *jsonschema.Schema) []formField {
+	var fields []formField
+	seen := map[string]bool{}
+	for _, name := range schema.Required {
+		if prop, ok := schema.Properties[name]; ok && !seen[name] {
+			fields = append(fields, formField{name: name, schema: prop, required: true})
+			seen[name] = true
+		}
+	}
+	var optional []string
+	for name := range schema.Properties {
+		if !seen[name] {
+			optional = append(optional, name)
+		}
+	}
+	sort.Strings(optional)
+	for _, name := range optional {
+		fields = append(fields, formField{name: name, schema: schema.Properties[name]})
+	}
+	return fields
+}
+
+// fillForm asks for each field until it gets a valid value. An empty answer takes the
+// default, or skips an optional field. It returns io.EOF when the input ends.
+func fillForm(in *bufio.Reader, fields []formField) (map[string]any, error) {
+	content := map[string]any{}
+	for _, f := range fields {
+		fmt.Print(fieldPrompt(f))
+		for {
+			line, err := in.ReadString('\n')
+			if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+				return nil, io.EOF
+			}
+			value, ok, perr := parseFieldValue(f, strings.TrimSpace(line))
+			if perr == nil {
+				if ok {
+					content[f.name] = value
+				}
+				break
+			}
+			if err != nil {
+				return nil, io.EOF
+			}
+			fmt.Printf("  %s\n%s", config.Colors.Error.Sprint(perr.Error()), fieldPrompt(f))
+		}
+	}
+	return content, nil
+}
+
+// fieldPrompt renders the label, description, choices and default of a form field.
+func fieldPrompt(f formField) string {
+	s := f.schema
+	var b strings.Builder
+	label := f.name
+	if s.Title != "" {
+		label = s.Title
+	}
+	b.WriteString("  " + label)
+	if f.required {
+		b.WriteString(" *")
+	}
+	if s.Description != "" {
+		fmt.Fprintf(&b, " (%s)", s.Description)
+	}
+	switch {
+	case len(s.Enum) > 0:
+		names := enumNames(s)
+		for i, v := range s.Enum {
+			fmt.Fprintf(&b, "\n    %d) %v", i+1, v)
+			if names[i] != "" {
+				fmt.Fprintf(&b, " - %s", names[i])
+			}
+		}
+		fmt.Fprintf(&b, "\n  choose 1-%d", len(s.Enum))
+	case s.Type == "boolean":
+		b.WriteString(" [y/n]")
+	case s.Type == "number" || s.Type == "integer":
+		fmt.Fprintf(&b, " [%s]", s.Type)
+	}
+	if len(s.Default) > 0 {
+		fmt.Fprintf(&b, " default %s", s.Default)
+	}
+	b.WriteString(": ")
+	return b.String()
+}
+
+func enumNames(s *jsonschema.Schema) []string {
+	names := make([]string, len(s.Enum))
+	if raw, ok := s.Extra["enumNames"].([]any); ok && len(raw) == len(s.Enum) {
+		for i, n := range raw {
+			names[i], _ = n.(string)
+		}
+	}
+	return names
+}
+
+// parseFieldValue converts an answer to the type of the field. ok is false when the field
+// is left out of the result.
+func parseFieldValue(f formField, answer string) (value any, ok bool, err error) {
+	s := f.schema
+	if answer == "" {
+		if len(s.Default) > 0 {
+			if err := json.Unmarshal(s.Default, &value); err == nil {
+				return value, true, nil
+			}
+		}
+		if f.required {
+			return nil, false, errors.New("a value is required")
+		}
+		return nil, false, nil
+	}
+
+	if len(s.Enum) > 0 {
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(s.Enum) {
+			return s.Enum[n-1], true, nil
+		}
+		for _, v := range s.Enum {
+			if fmt.Sprint(v) == answer {
+				return v, true, nil
+			}
+		}
+		return nil, false, fmt.Errorf("choose one of 1-%d", len(s.Enum))
+	}
+
+	switch s.Type {
+	case "boolean":
+		switch strings.ToLower(answer) {
+		case "y", "yes", "true":
+			return true, true, nil
+		case "n", "no", "false":
+			return false, true, nil
+		}
+		return nil, false, errors.New("answer y or n")
+	case "integer":
+		n, err := strconv.ParseInt(answer, 10, 64)
+		if err != nil {
+			return nil, false, errors.New("enter a whole number")
+		}
+		// Numbers are float64 in decoded JSON, which the result is validated as
+		return float64(n), true, checkRange(s, float64(n))
+	case "number":
+		n, err := strconv.ParseFloat(answer, 64)
+		if err != nil {
+			return nil, false, errors.New("enter a number")
+		}
+		return n, true, checkRange(s, n)
+	}
+
+	if s.MinLength != nil && len([]rune(answer)) < *s.MinLength {
+		return nil, false, fmt.Errorf("enter at least %d characters", *s.MinLength)
+	}
+	if s.MaxLength != nil && len([]rune(answer)) > *s.MaxLength {
+		return nil, false, fmt.Errorf("enter at most %d characters", *s.MaxLength)
+	}
+	return answer, true, nil
+}
+
+func checkRange(s *jsonschema.Schema, n float64) error {
+	if s.Minimum != nil && n < *s.Minimum {
+		return fmt.Errorf("enter a value of at least %g", *s.Minimum)
+	}
+	if s.Maximum != nil && n > *s.Maximum {
+		return fmt.Errorf("enter a value of at most %g", *s.Maximum)
+	}
+	return nil
+}
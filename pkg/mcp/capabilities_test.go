@@ -0,0 +1,181 @@
+package mcp
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// connectCapabilityServer connects a bare server to a client with the capabilities enabled
+// in cfg and returns the server's side of the session.
+func connectCapabilityServer(t *testing.T, cfg *config.Config) *sdkmcp.ServerSession {
+	t.Helper()
+	server := sdkmcp.NewServer(&sdkmcp.Implementation{Name: "guide", Version: "test"}, nil)
+	var roots []*sdkmcp.Root
+	if cfg.MCPRoots {
+		roots = clientRoots(cfg)
+	}
+	connectTestServer(t, server, withClientCapabilities(cfg, "guide", &sdkmcp.ClientOptions{}), roots...)
+	for session := range server.Sessions() {
+		return session
+	}
+	t.Fatal("server has no session")
+	return nil
+}
+
+func TestCreateMessage(t *testing.T) {
+	oldSampler, oldAsk := sampler, askApproval
+	defer func() { sampler, askApproval = oldSampler, oldAsk }()
+
+	var got *SampleRequest
+	sampler = func(cfg *config.Config, req *SampleRequest) (string, string, error) {
+		got = req
+		return "Scale it to 3 replicas.", "test-model", nil
+	}
+	var answers []byte
+	askApproval = func(*config.Config, string) byte {
+		key := answers[0]
+		answers = answers[1:]
+		return key
+	}
+	session := connectCapabilityServer(t, &config.Config{MCPSampling: true})
+	params := &sdkmcp.CreateMessageParams{
+		SystemPrompt: "You size deployments.",
+		MaxTokens:    200,
+		Messages: []*sdkmcp.SamplingMessage{
+			{Role: "user", Content: &sdkmcp.TextContent{Text: "web serves 900 rps"}},
+			{Role: "assistant", Content: &sdkmcp.TextContent{Text: "Each pod handles 300 rps."}},
+			{Role: "user", Content: &sdkmcp.TextContent{Text: "How many replicas?"}},
+		},
+	}
+
+	answers = []byte{'y', 'y'}
+	res, err := session.CreateMessage(context.Background(), params)
+	if err != nil {
+		t.Fatalf("CreateMessage() error: %v", err)
+	}
+	if text, ok := res.Content.(*sdkmcp.TextContent); !ok || text.Text != "Scale it to 3 replicas." || res.Model != "test-model" || res.Role != "assistant" {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	want := &SampleRequest{
+		Server:       "guide",
+		SystemPrompt: "You size deployments.",
+		MaxTokens:    200,
+		Messages: []SampleMessage{
+			{"user", "web serves 900 rps"},
+			{"assistant", "Each pod handles 300 rps."},
+			{"user", "How many replicas?"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sampler got %+v, want %+v", got, want)
+	}
+
+	// Declining either the request or the answer fails the request
+	for _, keys := range [][]byte{{'n'}, {'y', 'n'}} {
+		got, answers = nil, keys
+		if _, err := session.CreateMessage(context.Background(), params); err == nil {
+			t.Errorf("answers %q: expected an error", keys)
+		}
+		if keys[0] == 'n' && got != nil {
+			t.Error("the model was asked despite the refusal")
+		}
+	}
+}
+
+func TestCreateMessageDisabled(t *testing.T) {
+	oldSampler := sampler
+	defer func() { sampler = oldSampler }()
+	sampler = func(*config.Config, *SampleRequest) (string, string, error) { return "", "", nil }
+
+	session := connectCapabilityServer(t, &config.Config{})
+	_, err := session.CreateMessage(context.Background(), &sdkmcp.CreateMessageParams{
+		Messages: []*sdkmcp.SamplingMessage{{Role: "user", Content: &sdkmcp.TextContent{Text: "hi"}}},
+	})
+	if err == nil {
+		t.Error("expected sampling to be unsupported")
+	}
+}
+
+func TestElicit(t *testing.T) {
+	oldAsk, oldInput := askApproval, formInput
+	defer func() { askApproval, formInput = oldAsk, oldInput }()
+
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"deployment": map[string]any{"type": "string", "minLength": 1},
+			"replicas":   map[string]any{"type": "integer", "minimum": 1, "default": 2},
+			"strategy":   map[string]any{"type": "string", "enum": []any{"rolling", "recreate"}},
+			"dry_run":    map[string]any{"type": "boolean"},
+			"note":       map[string]any{"type": "string"},
+		},
+		"required": []any{"deployment", "strategy"},
+	}
+
+	tests := []struct {
+		name     string
+		key      byte
+		input    string
+		action   string
+		expected map[string]any
+	}{
+		// deployment, strategy, dry_run, note, replicas; invalid answers are asked again
+		{"accept", 'y', "\nweb\n3\n2\nmaybe\nyes\n\n0\n\n", "accept", map[string]any{"deployment": "web", "strategy": "recreate", "dry_run": true, "replicas": float64(2)}},
+		{"decline", 'n', "", "decline", nil},
+		{"cancel", 27, "", "cancel", nil},
+		{"end of input", 'y', "web\n", "cancel", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			askApproval = func(*config.Config, string) byte { return tt.key }
+			formInput = strings.NewReader(tt.input)
+			session := connectCapabilityServer(t, &config.Config{MCPElicitation: true})
+
+			res, err := session.Elicit(context.Background(), &sdkmcp.ElicitParams{Message: "Which deployment?", RequestedSchema: schema})
+			if err != nil {
+				t.Fatalf("Elicit() error: %v", err)
+			}
+			if res.Action != tt.action || !reflect.DeepEqual(res.Content, tt.expected) {
+				t.Errorf("Elicit() = %s %v, want %s %v", res.Action, res.Content, tt.action, tt.expected)
+			}
+		})
+	}
+}
+
+func TestClientRoots(t *testing.T) {
+	oldContext := currentKubeContext
+	defer func() { currentKubeContext = oldContext }()
+	currentKubeContext = func(*config.Config) string { return "prod-eu" }
+
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")
+	t.Setenv("KUBECONFIG", first+string(filepath.ListSeparator)+second+string(filepath.ListSeparator)+first)
+
+	session := connectCapabilityServer(t, &config.Config{MCPRoots: true})
+	res, err := session.ListRoots(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListRoots() error: %v", err)
+	}
+	names := map[string]string{}
+	for _, r := range res.Roots {
+		names[r.URI] = r.Name
+	}
+	for _, path := range []string{first, second} {
+		if got := names[fileURI(path)]; got != "kubeconfig (context prod-eu)" {
+			t.Errorf("root %s named %q", fileURI(path), got)
+		}
+	}
+	if len(res.Roots) != 3 {
+		t.Errorf("expected the working directory and two kubeconfigs, got %+v", res.Roots)
+	}
+
+	if res, err := connectCapabilityServer(t, &config.Config{}).ListRoots(context.Background(), nil); err != nil || len(res.Roots) != 0 {
+		t.Errorf("expected no roots when disabled, got %v, %v", res, err)
+	}
+}
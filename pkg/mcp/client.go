@@ -10,13 +10,16 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/lib"
 	"github.com/mikhae1/kubectl-quackops/pkg/logger"
+	"github.com/mikhae1/kubectl-quackops/pkg/privacy"
 	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
 	"gopkg.in/yaml.v3"
 )
@@ -30,6 +33,7 @@ type ServerSpec struct {
 	Args    []string          `yaml:"args" json:"args"`
 	URL     string            `yaml:"url" json:"url"`
 	Env     map[string]string `yaml:"env" json:"env"`
+	Trust   string            `yaml:"trust" json:"trust"` // untrusted, default or trusted; see TrustDefault
 	Auth    *struct {
 		Type  string `yaml:"type" json:"type"`
 		Token string `yaml:"token" json:"token"`
@@ -48,6 +52,7 @@ type ToolInfo struct {
 	Description string
 	InputSchema *jsonschema.Schema
 	Server      string
+	Annotations *sdkmcp.ToolAnnotations // Behavior hints from the server; nil when it sent none
 }
 
 // PromptInfo represents a discovered MCP prompt and its arguments
@@ -70,18 +75,20 @@ type ResourceInfo struct {
 
 // ServerConnection represents a connection to an MCP server
 type ServerConnection struct {
-	Spec            *ServerSpec
-	Session         *sdkmcp.ClientSession
-	Tools           []string
-	ToolInfos       []ToolInfo
-	PromptInfos     []PromptInfo
-	ResourceInfos   []ResourceInfo
-	Connected       bool
-	LastError       error
-	LastHealthCheck time.Time
-	Process         *exec.Cmd
-	ctx             context.Context
-	cancel          context.CancelFunc
+	Spec          *ServerSpec
+	Session       *sdkmcp.ClientSession
+	Tools         []string
+	ToolInfos     []ToolInfo
+	PromptInfos   []PromptInfo
+	ResourceInfos []ResourceInfo
+	// Resource templates, completed through the server for @server:uri mentions
+	ResourceTemplates []ResourceTemplateInfo
+	Connected         bool
+	LastError         error
+	LastHealthCheck   time.Time
+	Process           *exec.Cmd
+	ctx               context.Context
+	cancel            context.CancelFunc
 }
 
 // ServerRegistry manages multiple MCP server connections
@@ -390,9 +397,16 @@ func (r *ServerRegistry) GetAllTools() []string {
 func (r *ServerRegistry) GetAllToolInfos() []ToolInfo {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+	// Iterate servers by name: tool definitions lead every request, and an order that changes
+	// between requests defeats provider prompt caching
+	names := make([]string, 0, len(r.servers))
+	for name := range r.servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 	var allToolInfos []ToolInfo
-	for _, conn := range r.servers {
-		if conn.Connected {
+	for _, name := range names {
+		if conn := r.servers[name]; conn.Connected {
 			allToolInfos = append(allToolInfos, conn.ToolInfos...)
 		}
 	}
@@ -613,7 +627,7 @@ func (r *ServerRegistry) attemptReconnect(conn *ServerConnection, cfg *config.Co
 	client := sdkmcp.NewClient(&sdkmcp.Implementation{
 		Name:    "quackops-mcp-client",
 		Version: "v0.1.0",
-	}, &sdkmcp.ClientOptions{
+	}, withClientCapabilities(cfg, name, &sdkmcp.ClientOptions{
 		ToolListChangedHandler: func(ctx context.Context, req *sdkmcp.ToolListChangedRequest) {
 			// Refresh tools for this session
 			r.refreshToolsForSession(req.Session)
@@ -621,8 +635,15 @@ func (r *ServerRegistry) attemptReconnect(conn *ServerConnection, cfg *config.Co
 		PromptListChangedHandler: func(ctx context.Context, req *sdkmcp.PromptListChangedRequest) {
 			r.refreshPromptsForSession(req.Session)
 		},
+		ResourceListChangedHandler: func(ctx context.Context, req *sdkmcp.ResourceListChangedRequest) {
+			r.refreshResourcesForSession(req.Session)
+		},
+		ResourceUpdatedHandler: func(ctx context.Context, req *sdkmcp.ResourceUpdatedNotificationRequest) {
+			handleResourceUpdated(req.Session, req.Params.URI)
+		},
 		KeepAlive: 30 * time.Second,
-	})
+	}))
+	addClientRoots(cfg, client)
 
 	sess, err := client.Connect(ctx, transport, nil)
 	if err != nil {
@@ -657,6 +678,10 @@ func (r *ServerRegistry) attemptReconnect(conn *ServerConnection, cfg *config.Co
 	promptInfos := discoverAndCachePrompts(sess, name)
 	conn.PromptInfos = promptInfos
 
+	// Re-discover resources from the server
+	conn.ResourceInfos = discoverAndCacheResources(sess, name)
+	conn.ResourceTemplates = discoverAndCacheResourceTemplates(sess, name)
+
 	// Extract tool names for backward compatibility
 	var tools []string
 	for _, tool := range toolInfos {
@@ -670,6 +695,10 @@ func (r *ServerRegistry) attemptReconnect(conn *ServerConnection, cfg *config.Co
 	}
 	r.mu.Unlock()
 
+	// Subscriptions and the log level died with the old session
+	resubscribeAttachedResources(name)
+	enableServerLogs(cfg, name, sess)
+
 	logger.Log("info", "[MCP] Successfully reconnected server %s with %d tools", name, len(tools))
 	if cfg.MCPLogEnabled {
 		writeMCPLog(map[string]any{
@@ -1185,7 +1214,7 @@ func startServer(idx int, spec ServerSpec, cfg *config.Config) {
 	client := sdkmcp.NewClient(&sdkmcp.Implementation{
 		Name:    "kubectl-quackops-mcp-client",
 		Version: "v0.2.0",
-	}, &sdkmcp.ClientOptions{
+	}, withClientCapabilities(cfg, name, &sdkmcp.ClientOptions{
 		ToolListChangedHandler: func(ctx context.Context, req *sdkmcp.ToolListChangedRequest) {
 			logger.Log("info", "[MCP] Tool list changed for server %s, refreshing tools", name)
 			registry.refreshToolsForSession(req.Session)
@@ -1194,8 +1223,16 @@ func startServer(idx int, spec ServerSpec, cfg *config.Config) {
 			logger.Log("info", "[MCP] Prompt list changed for server %s, refreshing prompts", name)
 			registry.refreshPromptsForSession(req.Session)
 		},
+		ResourceListChangedHandler: func(ctx context.Context, req *sdkmcp.ResourceListChangedRequest) {
+			logger.Log("info", "[MCP] Resource list changed for server %s, refreshing resources", name)
+			registry.refreshResourcesForSession(req.Session)
+		},
+		ResourceUpdatedHandler: func(ctx context.Context, req *sdkmcp.ResourceUpdatedNotificationRequest) {
+			handleResourceUpdated(req.Session, req.Params.URI)
+		},
 		KeepAlive: 30 * time.Second,
-	})
+	}))
+	addClientRoots(cfg, client)
 
 	// Connect to create ClientSession as specified in requirements
 	sess, err := client.Connect(ctx, transport, nil)
@@ -1219,6 +1256,7 @@ func startServer(idx int, spec ServerSpec, cfg *config.Config) {
 	conn.Connected = true
 	conn.LastError = nil
 	conn.LastHealthCheck = time.Now()
+	enableServerLogs(cfg, name, sess)
 
 	// ListTools and cache: name, description/title, InputSchema as specified
 	toolInfos := discoverAndCacheToolInfos(sess, name)
@@ -1231,6 +1269,7 @@ func startServer(idx int, spec ServerSpec, cfg *config.Config) {
 	// Discover resources (best-effort; server may not support resources)
 	resourceInfos := discoverAndCacheResources(sess, name)
 	conn.ResourceInfos = resourceInfos
+	conn.ResourceTemplates = discoverAndCacheResourceTemplates(sess, name)
 
 	// Extract tool names for backward compatibility
 	var tools []string
@@ -1316,6 +1355,7 @@ func discoverAndCacheToolInfos(session *sdkmcp.ClientSession, serverName string)
 			Description: desc,
 			InputSchema: schema,
 			Server:      serverName,
+			Annotations: tool.Annotations,
 		}
 
 		tools = append(tools, toolInfo)
@@ -1517,18 +1557,22 @@ func ReconnectFailedServers(cfg *config.Config) {
 }
 
 // executeToolOnServer executes a tool on a specific MCP server with enhanced content handling
-func executeToolOnServer(conn *ServerConnection, toolName string, args map[string]any, timeout int) (string, error) {
+func executeToolOnServer(cfg *config.Config, conn *ServerConnection, toolName string, args map[string]any) (string, error) {
 	if !conn.Connected || conn.Session == nil {
 		return "", fmt.Errorf("server not connected")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.MCPToolTimeout)*time.Second)
 	defer cancel()
+	ctx, token, done := beginToolCall(ctx, conn.Spec.Name, toolName)
+	defer done()
 
 	params := &sdkmcp.CallToolParams{
 		Name:      toolName,
 		Arguments: args,
+		Meta:      sdkmcp.Meta{}, // SetProgressToken does not allocate it
 	}
+	params.SetProgressToken(token)
 
 	logger.Log("info", "[MCP] Executing tool %s on server %s with args: %v", toolName, conn.Spec.Name, args)
 	if mcpLogEnabled {
@@ -1540,6 +1584,15 @@ func executeToolOnServer(conn *ServerConnection, toolName string, args map[strin
 		})
 	}
 	res, err := conn.Session.CallTool(ctx, params)
+	if err != nil && toolCallCanceled(token) {
+		// The SDK has sent notifications/cancelled to the server
+		writeMCPLog(map[string]any{
+			"event":  "tool_call_canceled",
+			"server": conn.Spec.Name,
+			"tool":   toolName,
+		})
+		return "", lib.NewUserCancelError(fmt.Sprintf("tool '%s' canceled", toolName))
+	}
 	if err != nil {
 		if mcpLogEnabled {
 			writeMCPLog(map[string]any{
@@ -1567,7 +1620,7 @@ func executeToolOnServer(conn *ServerConnection, toolName string, args map[strin
 				}
 			}
 			if errorDetails.Len() > 0 {
-				errorMsg = fmt.Sprintf("tool error: %s", errorDetails.String())
+				errorMsg = fmt.Sprintf("tool error: %s", redactToolText(cfg, toolName, errorDetails.String()))
 			}
 		}
 		return "", fmt.Errorf("%s", errorMsg)
@@ -1575,8 +1628,9 @@ func executeToolOnServer(conn *ServerConnection, toolName string, args map[strin
 
 	// Enhanced content handling: prefer StructuredContent, then TextContent
 	if res.StructuredContent != nil {
+		content := cfg.RedactValueFrom(privacy.SourceTool, toolName, res.StructuredContent)
 		// Pretty print structured content for better readability
-		if data, err := json.MarshalIndent(res.StructuredContent, "", "  "); err == nil {
+		if data, err := json.MarshalIndent(content, "", "  "); err == nil {
 			logger.Log("info", "[MCP] Tool '%s' returned structured content (%d bytes)", toolName, len(data))
 			if mcpLogEnabled {
 				writeMCPLog(map[string]any{
@@ -1613,7 +1667,7 @@ func executeToolOnServer(conn *ServerConnection, toolName string, args map[strin
 		return "", fmt.Errorf("tool returned no usable content")
 	}
 
-	result := contentBuilder.String()
+	result := redactToolText(cfg, toolName, contentBuilder.String())
 	logger.Log("info", "[MCP] Tool '%s' returned %d text content item(s), total length: %d", toolName, textItemCount, len(result))
 	if mcpLogEnabled {
 		writeMCPLog(map[string]any{
@@ -1633,7 +1687,12 @@ func executeToolOnServer(conn *ServerConnection, toolName string, args map[strin
 // CallToolByName locates the MCP server for a given tool and executes it with the provided arguments
 func CallToolByName(cfg *config.Config, toolName string, args map[string]any) (string, error) {
 	loadOnce(cfg.MCPConfigPath)
-	if err := ensureToolAllowed(cfg, toolName); err != nil {
+	info, _ := lookupTool(toolName)
+	args, err := prepareToolArgs(info, args)
+	if err != nil {
+		return "", err
+	}
+	if err := ensureToolAllowed(cfg, toolName, args); err != nil {
 		return "", err
 	}
 	if registry == nil {
@@ -1643,13 +1702,20 @@ func CallToolByName(cfg *config.Config, toolName string, args map[string]any) (s
 	if !found || !conn.Connected {
 		return "", fmt.Errorf("tool '%s' not available via MCP", toolName)
 	}
-	return executeToolOnServer(conn, toolName, args, cfg.MCPToolTimeout)
+	return executeToolOnServer(cfg, conn, toolName, args)
+}
+
+// redactToolText applies the secret filter and redaction rules to tool output before it is
+// logged or handed to the model, unless --disable-secrets-filter is set, and records the
+// result in the privacy report.
+func redactToolText(cfg *config.Config, toolName, text string) string {
+	return cfg.RedactFrom(privacy.SourceTool, toolName, text)
 }
 
 // ExecShellViaMCP executes a raw shell command via bash -lc
 func ExecShellViaMCP(cfg *config.Config, shell string) (string, error) {
 	loadOnce(cfg.MCPConfigPath)
-	if err := ensureToolAllowed(cfg, "bash"); err != nil {
+	if err := ensureToolAllowed(cfg, "bash", map[string]any{"command": shell}); err != nil {
 		return "", err
 	}
 
@@ -1657,7 +1723,7 @@ func ExecShellViaMCP(cfg *config.Config, shell string) (string, error) {
 	if registry != nil {
 		// Look for a server that provides bash
 		if conn, found := registry.GetServerForTool("bash"); found && conn.Connected {
-			result, err := executeToolOnServer(conn, "bash", map[string]any{"command": shell}, cfg.MCPToolTimeout)
+			result, err := executeToolOnServer(cfg, conn, "bash", map[string]any{"command": shell})
 			if err == nil {
 				return result, nil
 			}
@@ -1682,52 +1748,6 @@ func execViaShell(cfg *config.Config, cmd string) (string, error) {
 	return string(out), err
 }
 
-func ensureToolAllowed(cfg *config.Config, tool string) error {
-	tool = strings.TrimSpace(tool)
-	// Denylist takes precedence (supports wildcards)
-	for _, d := range cfg.DeniedTools {
-		d = strings.TrimSpace(d)
-		if d == "" {
-			continue
-		}
-		if wildcardMatch(d, tool) {
-			return fmt.Errorf("tool '%s' is denied by policy", tool)
-		}
-	}
-	// Allow if allowlist is empty
-	if len(cfg.AllowedTools) == 0 {
-		return nil
-	}
-	// Allow if any allowlist entry matches (supports wildcards)
-	for _, a := range cfg.AllowedTools {
-		a = strings.TrimSpace(a)
-		if a == "" {
-			continue
-		}
-		if wildcardMatch(a, tool) {
-			return nil
-		}
-	}
-	// Not explicitly allowed: prompt for confirmation when in safe mode; otherwise allow with warning
-	if cfg.SafeMode {
-		fmt.Printf("Allow MCP tool '%s'? (y/N): ", tool)
-		reader := bufio.NewReader(os.Stdin)
-		input, _ := reader.ReadString('\n')
-		input = strings.TrimSpace(strings.ToLower(input))
-		// Treat ESC as an explicit no (ESC appears as \x1b when read via stdin)
-		if strings.Contains(input, "\x1b") {
-			return fmt.Errorf("tool '%s' not allowed by user", tool)
-		}
-		if input != "y" && input != "yes" {
-			return fmt.Errorf("tool '%s' not allowed by user", tool)
-		}
-		return nil
-	}
-	// Non-safe mode: permit but inform via stderr
-	fmt.Fprintf(os.Stderr, "[quackops] warning: tool '%s' is not in allowlist; proceeding.\n", tool)
-	return nil
-}
-
 func wildcardMatch(pattern, s string) bool {
 	// Simple glob: '*' wildcard, case-insensitive
 	p := strings.ToLower(strings.TrimSpace(pattern))
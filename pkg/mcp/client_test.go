@@ -1,13 +1,16 @@
 package mcp
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
 func TestLoadOnce(t *testing.T) {
@@ -298,19 +301,19 @@ func TestEnsureToolAllowed(t *testing.T) {
 	}
 
 	// Test allowed tool
-	err := ensureToolAllowed(cfg, "kubectl")
+	err := ensureToolAllowed(cfg, "kubectl", nil)
 	if err != nil {
 		t.Errorf("Expected kubectl to be allowed, got error: %v", err)
 	}
 
 	// Test denied tool
-	err = ensureToolAllowed(cfg, "dangerous-tool")
+	err = ensureToolAllowed(cfg, "dangerous-tool", nil)
 	if err == nil {
 		t.Error("Expected dangerous-tool to be denied")
 	}
 
 	// Test tool not in allowlist (should be allowed with warning in non-safe mode)
-	err = ensureToolAllowed(cfg, "unknown-tool")
+	err = ensureToolAllowed(cfg, "unknown-tool", nil)
 	if err != nil {
 		t.Errorf("Expected unknown-tool to be allowed with warning, got error: %v", err)
 	}
@@ -337,3 +340,76 @@ func TestWildcardMatch(t *testing.T) {
 		}
 	}
 }
+
+// connectTestServer connects an in-memory client session with opts and roots to server.
+func connectTestServer(t *testing.T, server *sdkmcp.Server, opts *sdkmcp.ClientOptions, roots ...*sdkmcp.Root) *ServerConnection {
+	t.Helper()
+	ctx := context.Background()
+	serverTransport, clientTransport := sdkmcp.NewInMemoryTransports()
+	if _, err := server.Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatalf("server connect: %v", err)
+	}
+	client := sdkmcp.NewClient(&sdkmcp.Implementation{Name: "quackops-test", Version: "test"}, opts)
+	client.AddRoots(roots...)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect: %v", err)
+	}
+	t.Cleanup(func() { _ = session.Close() })
+	return &ServerConnection{Spec: &ServerSpec{Name: "test"}, Session: session, Connected: true}
+}
+
+// addTestTool registers a tool that returns result regardless of its arguments.
+func addTestTool(server *sdkmcp.Server, name string, result *sdkmcp.CallToolResult) {
+	server.AddTool(&sdkmcp.Tool{Name: name, InputSchema: map[string]any{"type": "object"}},
+		func(ctx context.Context, req *sdkmcp.CallToolRequest) (*sdkmcp.CallToolResult, error) {
+			return result, nil
+		})
+}
+
+func TestExecuteToolOnServerRedactsSecrets(t *testing.T) {
+	server := sdkmcp.NewServer(&sdkmcp.Implementation{Name: "k8s", Version: "test"}, nil)
+	addTestTool(server, "get_secret", &sdkmcp.CallToolResult{Content: []sdkmcp.Content{&sdkmcp.TextContent{
+		Text: "apiVersion: v1\nkind: Secret\nmetadata:\n  name: db\ndata:\n  password: c3VwZXJzZWNyZXQ=\n",
+	}}})
+	addTestTool(server, "list_secrets", &sdkmcp.CallToolResult{
+		Content: []sdkmcp.Content{&sdkmcp.TextContent{Text: "see structured content"}},
+		StructuredContent: map[string]any{
+			"kind": "List",
+			"items": []any{map[string]any{
+				"kind":     "Secret",
+				"metadata": map[string]any{"name": "api", "annotations": map[string]any{"kubectl.kubernetes.io/last-applied-configuration": `{"data":{"key":"c3VwZXJzZWNyZXQ="}}`}},
+				"data":     map[string]any{"key": "c3VwZXJzZWNyZXQ="},
+			}},
+			"config": map[string]any{"apiKey": "sk-live-1234"},
+		},
+	})
+	conn := connectTestServer(t, server, nil)
+
+	tests := []struct {
+		name     string
+		tool     string
+		disabled bool
+		leaked   bool
+	}{
+		{name: "text content", tool: "get_secret"},
+		{name: "structured content", tool: "list_secrets"},
+		{name: "filter disabled", tool: "get_secret", disabled: true, leaked: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{MCPToolTimeout: 5, DisableSecretFilter: tt.disabled}
+			result, err := executeToolOnServer(cfg, conn, tt.tool, map[string]any{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			leaked := strings.Contains(result, "c3VwZXJzZWNyZXQ=") || strings.Contains(result, "sk-live-1234")
+			if leaked != tt.leaked {
+				t.Errorf("leaked = %v, want %v:\n%s", leaked, tt.leaked, result)
+			}
+			if !tt.leaked && !strings.Contains(result, "***FILTERED***") {
+				t.Errorf("expected filtered placeholder in result:\n%s", result)
+			}
+		})
+	}
+}
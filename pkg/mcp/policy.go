@@ -0,0 +1,324 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/lib"
+	"github.com/mikhae1/kubectl-quackops/pkg/logger"
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+// Server trust levels set with the "trust" field of a server in the MCP config.
+const (
+	TrustUntrusted = "untrusted" // every call needs approval, which is never remembered
+	TrustDefault   = "default"   // annotations and the allowlist decide
+	TrustTrusted   = "trusted"   // non-destructive tools run without asking
+)
+
+// Safety classes of a tool derived from its MCP annotations.
+const (
+	SafetyReadOnly    = "read-only"
+	SafetyDestructive = "destructive"
+	SafetyAdditive    = "additive" // writes, but declares it only adds or updates
+)
+
+// Safety classifies the tool from its MCP annotations. Per the MCP spec a tool that is not
+// read-only is destructive unless it says otherwise, and so is one without annotations.
+func (t ToolInfo) Safety() string {
+	a := t.Annotations
+	switch {
+	case a == nil:
+		return SafetyDestructive
+	case a.ReadOnlyHint:
+		return SafetyReadOnly
+	case a.DestructiveHint == nil || *a.DestructiveHint:
+		return SafetyDestructive
+	default:
+		return SafetyAdditive
+	}
+}
+
+// hints describes the safety class and the idempotent and open-world hints of a tool.
+// Missing annotations take the spec defaults: not idempotent, open-world.
+func (t ToolInfo) hints() string {
+	a := t.Annotations
+	if a == nil {
+		a = &sdkmcp.ToolAnnotations{}
+	}
+	parts := []string{t.Safety()}
+	if a.IdempotentHint {
+		parts = append(parts, "idempotent")
+	}
+	if a.OpenWorldHint == nil || *a.OpenWorldHint {
+		parts = append(parts, "open-world")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// normalizeTrust maps a server trust setting to one of the Trust* levels. Unknown values
+// fall back to untrusted, the safe choice for a typo.
+func normalizeTrust(server, trust string) string {
+	switch t := strings.ToLower(strings.TrimSpace(trust)); t {
+	case "", TrustDefault:
+		return TrustDefault
+	case TrustTrusted, TrustUntrusted:
+		return t
+	default:
+		logger.Log("warn", "[MCP] Unknown trust level %q for server %s; treating it as untrusted", trust, server)
+		return TrustUntrusted
+	}
+}
+
+// PolicyGrant is a remembered "always allow" decision. Tool "*" covers every
+// non-destructive tool of the server.
+type PolicyGrant struct {
+	Server string `yaml:"server" json:"server"`
+	Tool   string `yaml:"tool" json:"tool"`
+}
+
+// ToolPolicy is the policy file that keeps "always allow" decisions across sessions.
+type ToolPolicy struct {
+	AlwaysAllow []PolicyGrant `yaml:"always_allow" json:"always_allow"`
+}
+
+// Allows reports whether a remembered decision covers tool on server.
+func (p *ToolPolicy) Allows(server, tool string) bool {
+	if p == nil {
+		return false
+	}
+	for _, g := range p.AlwaysAllow {
+		if strings.EqualFold(g.Server, server) && wildcardMatch(g.Tool, tool) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadToolPolicy reads a YAML or JSON policy file. A missing file is an empty policy.
+func LoadToolPolicy(path string) (*ToolPolicy, error) {
+	policy := &ToolPolicy{}
+	if strings.TrimSpace(path) == "" {
+		return policy, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return policy, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading tool policy %s: %w", path, err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, policy)
+	} else {
+		err = yaml.Unmarshal(data, policy)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing tool policy %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// Save writes the policy to path in YAML, or JSON for a .json path.
+func (p *ToolPolicy) Save(path string) error {
+	var (
+		data []byte
+		err  error
+	)
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		data, err = json.MarshalIndent(p, "", "  ")
+	} else {
+		data, err = yaml.Marshal(p)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// Outcomes of the tool policy for a call.
+type toolDecision int
+
+const (
+	decisionAllow   toolDecision = iota
+	decisionWarn                 // allowed, but not in the allowlist
+	decisionAsk                  // ask the user; "always" can be remembered
+	decisionConfirm              // ask the user every time (destructive tools, untrusted servers)
+	decisionDeny
+)
+
+// decideTool applies the tool policy to a call of tool. info is nil for tools that were
+// not discovered from a server, such as the local bash fallback.
+func decideTool(cfg *config.Config, tool string, info *ToolInfo, trust string, policy *ToolPolicy) toolDecision {
+	// Denylist takes precedence (supports wildcards)
+	for _, d := range cfg.DeniedTools {
+		if d = strings.TrimSpace(d); d != "" && wildcardMatch(d, tool) {
+			return decisionDeny
+		}
+	}
+
+	safety, server := "", ""
+	if info != nil {
+		safety, server = info.Safety(), info.Server
+	}
+	if safety == SafetyDestructive {
+		return decisionConfirm
+	}
+	switch {
+	case trust == TrustUntrusted:
+		return decisionConfirm
+	case server != "" && policy.Allows(server, tool):
+		return decisionAllow
+	case safety == SafetyReadOnly, trust == TrustTrusted:
+		return decisionAllow
+	}
+
+	// Allow if allowlist is empty or any entry matches (supports wildcards)
+	if len(cfg.AllowedTools) == 0 {
+		return decisionAllow
+	}
+	for _, a := range cfg.AllowedTools {
+		if a = strings.TrimSpace(a); a != "" && wildcardMatch(a, tool) {
+			return decisionAllow
+		}
+	}
+	// Not explicitly allowed: prompt for confirmation when in safe mode; otherwise allow with warning
+	if cfg.SafeMode {
+		return decisionAsk
+	}
+	return decisionWarn
+}
+
+var (
+	// approvalMu serializes approval prompts of tool calls running in parallel and the
+	// policy file updates they make.
+	approvalMu sync.Mutex
+
+	policyCache struct {
+		path   string
+		policy *ToolPolicy
+	}
+
+	// askApproval asks the user about a tool call and returns the key pressed; tests replace it.
+	askApproval = func(cfg *config.Config, question string) byte {
+		lib.GetSpinnerManager(cfg).Hide()
+		return lib.ReadSingleKey(question)
+	}
+)
+
+// toolPolicy returns the policy loaded from cfg.MCPToolPolicyFile, reading the file once.
+// The caller holds approvalMu.
+func toolPolicy(cfg *config.Config) *ToolPolicy {
+	if policyCache.policy != nil && policyCache.path == cfg.MCPToolPolicyFile {
+		return policyCache.policy
+	}
+	policy, err := LoadToolPolicy(cfg.MCPToolPolicyFile)
+	if err != nil {
+		logger.Log("warn", "[MCP] %v; remembered tool approvals are ignored", err)
+		policy = &ToolPolicy{}
+	}
+	policyCache.path, policyCache.policy = cfg.MCPToolPolicyFile, policy
+	return policy
+}
+
+// lookupTool returns the discovered info and the trust level of the server providing tool.
+func lookupTool(tool string) (*ToolInfo, string) {
+	if registry == nil {
+		return nil, TrustDefault
+	}
+	conn, ok := registry.GetServerForTool(tool)
+	if !ok {
+		return nil, TrustDefault
+	}
+	trust := TrustDefault
+	if conn.Spec != nil {
+		trust = normalizeTrust(conn.Spec.Name, conn.Spec.Trust)
+	}
+	for i := range conn.ToolInfos {
+		if conn.ToolInfos[i].Name == tool {
+			info := conn.ToolInfos[i]
+			return &info, trust
+		}
+	}
+	return nil, trust
+}
+
+// ensureToolAllowed applies the tool policy to a call of tool with args, asking the user
+// when the policy requires approval.
+func ensureToolAllowed(cfg *config.Config, tool string, args map[string]any) error {
+	tool = strings.TrimSpace(tool)
+	info, trust := lookupTool(tool)
+
+	approvalMu.Lock()
+	defer approvalMu.Unlock()
+	policy := toolPolicy(cfg)
+
+	switch decideTool(cfg, tool, info, trust, policy) {
+	case decisionAllow:
+		return nil
+	case decisionWarn:
+		// Non-safe mode: permit but inform via stderr
+		fmt.Fprintf(os.Stderr, "[quackops] warning: tool '%s' is not in allowlist; proceeding.\n", tool)
+		return nil
+	case decisionDeny:
+		return fmt.Errorf("tool '%s' is denied by policy", tool)
+	case decisionConfirm:
+		key := askApproval(cfg, approvalQuestion(tool, info, args, false))
+		if key != 'y' {
+			return fmt.Errorf("tool '%s' not allowed by user", tool)
+		}
+		return nil
+	}
+
+	key := askApproval(cfg, approvalQuestion(tool, info, args, info != nil))
+	switch {
+	case key == 'a' && info != nil:
+		policy.AlwaysAllow = append(policy.AlwaysAllow, PolicyGrant{Server: info.Server, Tool: tool})
+		if cfg.MCPToolPolicyFile != "" {
+			if err := policy.Save(cfg.MCPToolPolicyFile); err != nil {
+				logger.Log("warn", "[MCP] Failed to save tool policy %s: %v", cfg.MCPToolPolicyFile, err)
+			}
+		}
+		return nil
+	case key == 'y':
+		return nil
+	}
+	return fmt.Errorf("tool '%s' not allowed by user", tool)
+}
+
+// approvalQuestion builds the approval prompt for a tool call.
+func approvalQuestion(tool string, info *ToolInfo, args map[string]any, remember bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Allow MCP tool '%s'", tool)
+	if info != nil && info.Server != "" {
+		fmt.Fprintf(&b, " from %s", info.Server)
+	}
+	if info != nil {
+		fmt.Fprintf(&b, " [%s]", info.hints())
+	}
+	if len(args) > 0 {
+		if data, err := json.Marshal(args); err == nil {
+			s := string(data)
+			if len(s) > 200 {
+				s = s[:197] + "..."
+			}
+			fmt.Fprintf(&b, " with %s", s)
+		}
+	}
+	if remember {
+		b.WriteString("? (y/N/a=always): ")
+	} else {
+		b.WriteString("? (y/N): ")
+	}
+	return b.String()
+}
@@ -0,0 +1,108 @@
+package mcp
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestDecideTool(t *testing.T) {
+	readOnly := &ToolInfo{Name: "list_pods", Server: "kube", Annotations: &sdkmcp.ToolAnnotations{ReadOnlyHint: true}}
+	destructive := &ToolInfo{Name: "delete_pod", Server: "kube", Annotations: &sdkmcp.ToolAnnotations{}}
+	additive := &ToolInfo{Name: "scale", Server: "kube", Annotations: &sdkmcp.ToolAnnotations{DestructiveHint: boolPtr(false)}}
+	labeler := &ToolInfo{Name: "describe", Server: "kube", Annotations: &sdkmcp.ToolAnnotations{DestructiveHint: boolPtr(false)}}
+	plain := &ToolInfo{Name: "restart", Server: "kube"}
+	policy := &ToolPolicy{AlwaysAllow: []PolicyGrant{{Server: "kube", Tool: "scale"}, {Server: "kube", Tool: "delete_*"}}}
+
+	tests := []struct {
+		name     string
+		cfg      config.Config
+		tool     string
+		info     *ToolInfo
+		trust    string
+		policy   *ToolPolicy
+		expected toolDecision
+	}{
+		{"denylist wins", config.Config{DeniedTools: []string{"list_*"}}, "list_pods", readOnly, TrustTrusted, nil, decisionDeny},
+		{"read-only auto-allowed", config.Config{AllowedTools: []string{"kubectl"}, SafeMode: true}, "list_pods", readOnly, TrustDefault, nil, decisionAllow},
+		{"destructive always confirmed", config.Config{AllowedTools: []string{"*"}}, "delete_pod", destructive, TrustTrusted, policy, decisionConfirm},
+		{"untrusted confirmed", config.Config{AllowedTools: []string{"*"}}, "list_pods", readOnly, TrustUntrusted, nil, decisionConfirm},
+		{"untrusted ignores grants", config.Config{AllowedTools: []string{"*"}}, "scale", additive, TrustUntrusted, policy, decisionConfirm},
+		{"remembered grant", config.Config{AllowedTools: []string{"kubectl"}, SafeMode: true}, "scale", additive, TrustDefault, policy, decisionAllow},
+		{"trusted server", config.Config{AllowedTools: []string{"kubectl"}, SafeMode: true}, "scale", additive, TrustTrusted, nil, decisionAllow},
+		{"allowlisted", config.Config{AllowedTools: []string{"desc*"}, SafeMode: true}, "describe", labeler, TrustDefault, nil, decisionAllow},
+		{"safe mode asks", config.Config{AllowedTools: []string{"kubectl"}, SafeMode: true}, "describe", labeler, TrustDefault, nil, decisionAsk},
+		{"unlisted warns", config.Config{AllowedTools: []string{"kubectl"}}, "describe", labeler, TrustDefault, nil, decisionWarn},
+		{"unannotated is destructive", config.Config{}, "restart", plain, TrustDefault, nil, decisionConfirm},
+		{"unannotated ignores allowlist and trust", config.Config{AllowedTools: []string{"*"}}, "restart", plain, TrustTrusted, policy, decisionConfirm},
+		{"bash fallback", config.Config{AllowedTools: []string{"*"}}, "bash", nil, TrustDefault, nil, decisionAllow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decideTool(&tt.cfg, tt.tool, tt.info, tt.trust, tt.policy); got != tt.expected {
+				t.Errorf("decideTool() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEnsureToolAllowedRemembersApproval(t *testing.T) {
+	oldRegistry, oldAsk := registry, askApproval
+	defer func() {
+		registry, askApproval = oldRegistry, oldAsk
+		policyCache.path, policyCache.policy = "", nil
+	}()
+
+	conn := &ServerConnection{
+		Spec:      &ServerSpec{Name: "kube"},
+		Connected: true,
+		ToolInfos: []ToolInfo{
+			{Name: "scale", Server: "kube", Annotations: &sdkmcp.ToolAnnotations{DestructiveHint: boolPtr(false)}},
+			{Name: "delete_pod", Server: "kube", Annotations: &sdkmcp.ToolAnnotations{}},
+		},
+	}
+	registry = NewServerRegistry()
+	registry.toolToServer["scale"] = conn
+	registry.toolToServer["delete_pod"] = conn
+
+	asked := 0
+	askApproval = func(*config.Config, string) byte {
+		asked++
+		return 'a'
+	}
+	cfg := &config.Config{
+		AllowedTools:      []string{"kubectl"},
+		SafeMode:          true,
+		MCPToolPolicyFile: filepath.Join(t.TempDir(), "tool-policy.yaml"),
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := ensureToolAllowed(cfg, "scale", map[string]any{"replicas": 2}); err != nil {
+			t.Fatalf("call %d: unexpected error: %v", i, err)
+		}
+	}
+	if asked != 1 {
+		t.Errorf("expected one prompt, got %d", asked)
+	}
+
+	saved, err := LoadToolPolicy(cfg.MCPToolPolicyFile)
+	if err != nil {
+		t.Fatalf("LoadToolPolicy() error: %v", err)
+	}
+	if !saved.Allows("kube", "scale") {
+		t.Errorf("expected the approval to be saved, got %+v", saved.AlwaysAllow)
+	}
+
+	// "always" is not an answer for destructive tools
+	if err := ensureToolAllowed(cfg, "delete_pod", nil); err == nil {
+		t.Error("expected delete_pod to be refused")
+	}
+	if saved, _ := LoadToolPolicy(cfg.MCPToolPolicyFile); saved.Allows("kube", "delete_pod") {
+		t.Error("destructive approval must not be remembered")
+	}
+}
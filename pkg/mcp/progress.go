@@ -0,0 +1,209 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/lib"
+	"github.com/mikhae1/kubectl-quackops/pkg/logger"
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// toolCall is a tool call in flight, tracked for progress and cancellation.
+type toolCall struct {
+	server   string
+	tool     string
+	started  time.Time
+	cancel   context.CancelFunc
+	canceled bool
+
+	// Last progress notification
+	progress float64
+	total    float64
+	message  string
+	reported bool
+}
+
+// inflight tracks running tool calls by progress token.
+var inflight = struct {
+	mu    sync.Mutex
+	next  int64
+	calls map[string]*toolCall
+	shown bool // the spinner details show progress
+}{calls: map[string]*toolCall{}}
+
+// beginToolCall registers a call of tool on server. It returns a context that
+// CancelToolCalls cancels, the progress token to send with the call, and a func that
+// unregisters the call.
+func beginToolCall(ctx context.Context, server, tool string) (context.Context, string, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	inflight.mu.Lock()
+	inflight.next++
+	token := "quackops-" + strconv.FormatInt(inflight.next, 10)
+	inflight.calls[token] = &toolCall{server: server, tool: tool, started: time.Now(), cancel: cancel}
+	inflight.mu.Unlock()
+
+	return ctx, token, func() {
+		cancel()
+		inflight.mu.Lock()
+		delete(inflight.calls, token)
+		renderProgressLocked()
+		inflight.mu.Unlock()
+	}
+}
+
+// toolCallCanceled reports whether CancelToolCalls stopped the call with token.
+func toolCallCanceled(token string) bool {
+	inflight.mu.Lock()
+	defer inflight.mu.Unlock()
+	c, ok := inflight.calls[token]
+	return ok && c.canceled
+}
+
+// CancelToolCalls cancels every MCP tool call in flight, which sends notifications/cancelled
+// to their servers, and returns how many were canceled.
+func CancelToolCalls() int {
+	inflight.mu.Lock()
+	defer inflight.mu.Unlock()
+	n := 0
+	for _, c := range inflight.calls {
+		if !c.canceled {
+			c.canceled = true
+			c.cancel()
+			n++
+		}
+	}
+	return n
+}
+
+// handleProgress records a notifications/progress update and refreshes the spinner details.
+func handleProgress(params *sdkmcp.ProgressNotificationParams) {
+	if params == nil {
+		return
+	}
+	inflight.mu.Lock()
+	defer inflight.mu.Unlock()
+	c, ok := inflight.calls[fmt.Sprint(params.ProgressToken)]
+	if !ok {
+		return
+	}
+	c.progress, c.total, c.message, c.reported = params.Progress, params.Total, params.Message, true
+	writeMCPLog(map[string]any{
+		"event":    "tool_progress",
+		"server":   c.server,
+		"tool":     c.tool,
+		"progress": params.Progress,
+		"total":    params.Total,
+		"message":  params.Message,
+	})
+	renderProgressLocked()
+}
+
+// renderProgressLocked shows a line per call that reported progress under the spinner, and
+// clears the lines it set once no such call is left. The caller holds inflight.mu.
+func renderProgressLocked() {
+	var calls []*toolCall
+	for _, c := range inflight.calls {
+		if c.reported {
+			calls = append(calls, c)
+		}
+	}
+	if len(calls) == 0 {
+		if inflight.shown {
+			lib.GetSpinnerManager(nil).ClearDetailsLines()
+			inflight.shown = false
+		}
+		return
+	}
+	sort.Slice(calls, func(i, j int) bool { return calls[i].started.Before(calls[j].started) })
+	lines := make([]string, 0, len(calls))
+	for _, c := range calls {
+		lines = append(lines, formatProgress(c))
+	}
+	lib.GetSpinnerManager(nil).SetDetailsLines(lines)
+	inflight.shown = true
+}
+
+// formatProgress renders a progress line such as "kube/search_logs 40% (40/100) scanning prod".
+func formatProgress(c *toolCall) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "  %s %s", config.Colors.Dim.Sprint(c.server+"/"), config.Colors.Accent.Sprint(c.tool))
+	switch {
+	case c.total > 0:
+		fmt.Fprintf(&b, " %d%% (%s/%s)", int(c.progress*100/c.total), formatAmount(c.progress), formatAmount(c.total))
+	case c.progress > 0:
+		fmt.Fprintf(&b, " %s", formatAmount(c.progress))
+	}
+	if c.message != "" {
+		fmt.Fprintf(&b, " %s", config.Colors.Dim.Sprint(lib.TrimText(c.message, 80)))
+	}
+	return b.String()
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// handleServerLog records a notifications/message log entry from server and, in verbose
+// mode, prints it above the spinner.
+func handleServerLog(cfg *config.Config, server string, params *sdkmcp.LoggingMessageParams) {
+	if params == nil {
+		return
+	}
+	text, ok := params.Data.(string)
+	if !ok {
+		data, err := json.Marshal(params.Data)
+		if err != nil {
+			return
+		}
+		text = string(data)
+	}
+	// Log lines never reach the model, so they are masked without a privacy report entry
+	text = cfg.Redact(text)
+	writeMCPLog(map[string]any{
+		"event":  "server_log",
+		"server": server,
+		"level":  string(params.Level),
+		"logger": params.Logger,
+		"line":   text,
+	})
+	if !cfg.Verbose {
+		return
+	}
+
+	source := server
+	if params.Logger != "" {
+		source += "/" + params.Logger
+	}
+	sm := lib.GetSpinnerManager(cfg)
+	sctx := sm.GetContext()
+	active := sm.IsActive()
+	sm.Hide()
+	fmt.Fprintf(os.Stderr, "%s %s\n", config.Colors.Dim.Sprintf("[%s %s]", source, params.Level), text)
+	if active && sctx != nil {
+		sm.Show(sctx.Type, sctx.Message)
+	}
+}
+
+// enableServerLogs asks server to send log messages when they would be shown or recorded.
+func enableServerLogs(cfg *config.Config, server string, session *sdkmcp.ClientSession) {
+	if !cfg.Verbose && !cfg.MCPLogEnabled {
+		return
+	}
+	if res := session.InitializeResult(); res == nil || res.Capabilities == nil || res.Capabilities.Logging == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := session.SetLoggingLevel(ctx, &sdkmcp.SetLoggingLevelParams{Level: "info"}); err != nil {
+		logger.Log("debug", "[MCP] Failed to enable logs for server %s: %v", server, err)
+	}
+}
@@ -0,0 +1,116 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/lib"
+	"github.com/mikhae1/kubectl-quackops/pkg/privacy"
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestFormatProgress(t *testing.T) {
+	tests := []struct {
+		name     string
+		call     toolCall
+		expected []string
+	}{
+		{"total known", toolCall{server: "kube", tool: "scan", progress: 40, total: 100, message: "namespace prod"}, []string{"scan", "40% (40/100)", "namespace prod"}},
+		{"total unknown", toolCall{server: "kube", tool: "scan", progress: 1.5}, []string{"scan", " 1.5"}},
+		{"message only", toolCall{server: "kube", tool: "scan", message: "starting"}, []string{"scan", "starting"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatProgress(&tt.call)
+			for _, want := range tt.expected {
+				if !strings.Contains(got, want) {
+					t.Errorf("formatProgress() = %q, missing %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestToolCallProgressAndCancel(t *testing.T) {
+	started := make(chan struct{})
+	serverCanceled := make(chan struct{})
+	server := sdkmcp.NewServer(&sdkmcp.Implementation{Name: "kube", Version: "test"}, nil)
+	server.AddTool(&sdkmcp.Tool{Name: "scan", InputSchema: map[string]any{"type": "object"}},
+		func(ctx context.Context, req *sdkmcp.CallToolRequest) (*sdkmcp.CallToolResult, error) {
+			_ = req.Session.NotifyProgress(ctx, &sdkmcp.ProgressNotificationParams{
+				ProgressToken: req.Params.GetProgressToken(),
+				Progress:      1,
+				Total:         4,
+				Message:       "namespace default",
+			})
+			close(started)
+			<-ctx.Done()
+			close(serverCanceled)
+			return nil, ctx.Err()
+		})
+
+	cfg := &config.Config{MCPToolTimeout: 30}
+	conn := connectTestServer(t, server, withClientCapabilities(cfg, "kube", &sdkmcp.ClientOptions{}))
+	conn.Spec.Name = "kube"
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := executeToolOnServer(cfg, conn, "scan", map[string]any{})
+		errCh <- err
+	}()
+	<-started
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		inflight.mu.Lock()
+		var line string
+		for _, c := range inflight.calls {
+			if c.tool == "scan" && c.reported {
+				line = formatProgress(c)
+			}
+		}
+		inflight.mu.Unlock()
+		if strings.Contains(line, "25% (1/4)") && strings.Contains(line, "namespace default") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("progress not recorded, last line %q", line)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if n := CancelToolCalls(); n != 1 {
+		t.Errorf("CancelToolCalls() = %d, want 1", n)
+	}
+	select {
+	case err := <-errCh:
+		if !lib.IsUserCancel(err) {
+			t.Errorf("expected a user cancel error, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("tool call did not return after cancellation")
+	}
+	select {
+	case <-serverCanceled:
+	case <-time.After(2 * time.Second):
+		t.Error("server was not told about the cancellation")
+	}
+
+	inflight.mu.Lock()
+	left := len(inflight.calls)
+	inflight.mu.Unlock()
+	if left != 0 {
+		t.Errorf("expected no calls in flight, got %d", left)
+	}
+}
+
+func TestServerLogSkipsPrivacyReport(t *testing.T) {
+	cfg := &config.Config{Privacy: privacy.NewReport()}
+	handleServerLog(cfg, "kube", &sdkmcp.LoggingMessageParams{Level: "info", Data: "password=hunter2"})
+	if sources := cfg.Privacy.Sources(); len(sources) != 0 {
+		t.Errorf("server logs must not appear in the privacy report, got %+v", sources)
+	}
+}
@@ -104,6 +104,11 @@ func RenderSessionEvent(event config.SessionEvent, verbose bool, cfg *config.Con
 	// Format user prompt
 	sb.WriteString(config.Colors.Primary.Sprintf("❯ %s\n", event.UserPrompt))
 
+	// Format model failovers
+	for _, ms := range event.ModelSwitches {
+		sb.WriteString(config.Colors.Dim.Sprintf("  ⚠ switched model %s → %s (%s)\n", ms.From, ms.To, ms.Reason))
+	}
+
 	// Format tool calls
 	for _, tc := range event.ToolCalls {
 		if verbose {
@@ -0,0 +1,567 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	"github.com/mikhae1/kubectl-quackops/pkg/logger"
+	"github.com/mikhae1/kubectl-quackops/pkg/privacy"
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ResourceTemplateInfo represents a discovered MCP resource template (RFC 6570 URI template)
+type ResourceTemplateInfo struct {
+	Name        string
+	URITemplate string
+	Description string
+	MIMEType    string
+	Server      string
+}
+
+// ResourceMention is an @server:uri reference to an MCP resource in a prompt.
+type ResourceMention struct {
+	Server string
+	URI    string
+}
+
+func (m ResourceMention) String() string {
+	return "@" + m.Server + ":" + m.URI
+}
+
+// mentionPattern matches @server:uri at the start of a word.
+var mentionPattern = regexp.MustCompile(`(?:^|\s)@([A-Za-z0-9][A-Za-z0-9_.-]*):(\S+)`)
+
+// ParseResourceMentions returns the distinct @server:uri mentions in prompt, in order.
+func ParseResourceMentions(prompt string) []ResourceMention {
+	var mentions []ResourceMention
+	seen := map[ResourceMention]bool{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(prompt, -1) {
+		// Sentence punctuation after a mention is not part of the URI
+		uri := strings.TrimRight(m[2], ".,;:!?)\"'")
+		if uri == "" {
+			continue
+		}
+		mention := ResourceMention{Server: m[1], URI: uri}
+		if !seen[mention] {
+			seen[mention] = true
+			mentions = append(mentions, mention)
+		}
+	}
+	return mentions
+}
+
+// attachedResource is a resource mentioned in the session; its content is sent again when
+// the server reports it updated.
+type attachedResource struct {
+	ResourceMention
+	subscribed bool
+	updated    bool
+}
+
+var attachments struct {
+	mu   sync.Mutex
+	list []*attachedResource
+}
+
+// AttachedResources returns the resources attached in this session and whether each was
+// updated since its content was last sent.
+func AttachedResources() ([]ResourceMention, []bool) {
+	attachments.mu.Lock()
+	defer attachments.mu.Unlock()
+	mentions := make([]ResourceMention, 0, len(attachments.list))
+	updated := make([]bool, 0, len(attachments.list))
+	for _, a := range attachments.list {
+		mentions = append(mentions, a.ResourceMention)
+		updated = append(updated, a.updated)
+	}
+	return mentions, updated
+}
+
+// DetachResources drops all attached resources and their subscriptions, returning how
+// many were attached.
+func DetachResources(cfg *config.Config) int {
+	attachments.mu.Lock()
+	list := attachments.list
+	attachments.list = nil
+	attachments.mu.Unlock()
+	for _, a := range list {
+		if !a.subscribed {
+			continue
+		}
+		if conn := serverByName(a.Server); conn != nil && conn.Session != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.MCPToolTimeout)*time.Second)
+			if err := conn.Session.Unsubscribe(ctx, &sdkmcp.UnsubscribeParams{URI: a.URI}); err != nil {
+				logger.Log("debug", "[MCP] Unsubscribe from %s failed: %v", a, err)
+			}
+			cancel()
+		}
+	}
+	return len(list)
+}
+
+// ResourceContext attaches the resources mentioned in prompt and returns their content for
+// the model, together with fresh content of attached resources updated since the last
+// turn. Resources that cannot be read are reported in the error and not attached.
+func ResourceContext(cfg *config.Config, prompt string) (string, error) {
+	mentions := ParseResourceMentions(prompt)
+
+	attachments.mu.Lock()
+	var targets []*attachedResource
+	var fresh []bool
+	for _, m := range mentions {
+		var found *attachedResource
+		for _, a := range attachments.list {
+			if a.ResourceMention == m {
+				found = a
+				break
+			}
+		}
+		isNew := found == nil
+		if isNew {
+			found = &attachedResource{ResourceMention: m}
+			attachments.list = append(attachments.list, found)
+		}
+		targets = append(targets, found)
+		fresh = append(fresh, isNew)
+	}
+	for _, a := range attachments.list {
+		if a.updated && !containsAttachment(targets, a) {
+			targets = append(targets, a)
+			fresh = append(fresh, false)
+		}
+	}
+	for _, a := range targets {
+		a.updated = false
+	}
+	attachments.mu.Unlock()
+
+	if len(targets) == 0 {
+		return "", nil
+	}
+
+	var b strings.Builder
+	var errs []error
+	for i, a := range targets {
+		conn := serverByName(a.Server)
+		if conn == nil || !conn.Connected || conn.Session == nil {
+			errs = append(errs, fmt.Errorf("%s: MCP server %q is not connected", a, a.Server))
+			detach(a)
+			continue
+		}
+		content, mimeType, err := fetchResource(cfg, conn, a.URI)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", a, err))
+			detach(a)
+			continue
+		}
+		attachments.mu.Lock()
+		subscribed := a.subscribed
+		attachments.mu.Unlock()
+		if !subscribed {
+			subscribeResource(cfg, conn, a)
+		}
+		if b.Len() == 0 {
+			b.WriteString("## MCP Resources\n")
+			b.WriteString("Content of MCP resources attached by the user, current as of this message.\n")
+		}
+		heading := a.String()
+		if mimeType != "" {
+			heading += " (" + mimeType + ")"
+		}
+		if !fresh[i] && !containsMention(mentions, a.ResourceMention) {
+			heading += " (updated since it was last sent)"
+		}
+		fmt.Fprintf(&b, "\n### %s\n```\n%s\n```\n", heading, strings.TrimRight(content, "\n"))
+	}
+	return b.String(), errors.Join(errs...)
+}
+
+func containsAttachment(list []*attachedResource, a *attachedResource) bool {
+	for _, x := range list {
+		if x == a {
+			return true
+		}
+	}
+	return false
+}
+
+func containsMention(list []ResourceMention, m ResourceMention) bool {
+	for _, x := range list {
+		if x == m {
+			return true
+		}
+	}
+	return false
+}
+
+func detach(a *attachedResource) {
+	attachments.mu.Lock()
+	defer attachments.mu.Unlock()
+	for i, x := range attachments.list {
+		if x == a {
+			attachments.list = append(attachments.list[:i], attachments.list[i+1:]...)
+			return
+		}
+	}
+}
+
+// subscribeResource asks the server for resources/updated notifications about a, when the
+// server supports subscriptions.
+func subscribeResource(cfg *config.Config, conn *ServerConnection, a *attachedResource) {
+	init := conn.Session.InitializeResult()
+	if init == nil || init.Capabilities == nil || init.Capabilities.Resources == nil || !init.Capabilities.Resources.Subscribe {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.MCPToolTimeout)*time.Second)
+	defer cancel()
+	if err := conn.Session.Subscribe(ctx, &sdkmcp.SubscribeParams{URI: a.URI}); err != nil {
+		logger.Log("warn", "[MCP] Failed to subscribe to %s: %v", a, err)
+		return
+	}
+	attachments.mu.Lock()
+	a.subscribed = true
+	attachments.mu.Unlock()
+	logger.Log("debug", "[MCP] Subscribed to updates of %s", a)
+}
+
+// handleResourceUpdated marks the attached resources covered by a resources/updated
+// notification, so the next turn sends their new content.
+func handleResourceUpdated(session *sdkmcp.ClientSession, uri string) {
+	server := ""
+	if registry != nil {
+		registry.mu.RLock()
+		if conn, ok := registry.sessionToServer[session]; ok && conn.Spec != nil {
+			server = conn.Spec.Name
+		}
+		registry.mu.RUnlock()
+	}
+	attachments.mu.Lock()
+	defer attachments.mu.Unlock()
+	for _, a := range attachments.list {
+		if strings.EqualFold(a.Server, server) && coversURI(a.URI, uri) {
+			a.updated = true
+			logger.Log("debug", "[MCP] Attached resource %s was updated", a)
+		}
+	}
+}
+
+// coversURI reports whether an update of uri concerns the attached resource at attached:
+// the resource itself or a sub-resource below it, so file:///a covers file:///a/b but not
+// file:///ab.
+func coversURI(attached, uri string) bool {
+	return uri == attached || strings.HasPrefix(uri, strings.TrimSuffix(attached, "/")+"/")
+}
+
+// resubscribeAttachedResources makes the next turn re-read and re-subscribe the resources
+// attached from server, after its session was replaced.
+func resubscribeAttachedResources(server string) {
+	attachments.mu.Lock()
+	defer attachments.mu.Unlock()
+	for _, a := range attachments.list {
+		if strings.EqualFold(a.Server, server) {
+			a.subscribed = false
+			a.updated = true
+		}
+	}
+}
+
+// serverByName returns the connection of the named server (case-insensitive), or nil.
+func serverByName(name string) *ServerConnection {
+	if registry == nil {
+		return nil
+	}
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	if conn, ok := registry.servers[name]; ok {
+		return conn
+	}
+	for n, conn := range registry.servers {
+		if strings.EqualFold(n, name) {
+			return conn
+		}
+	}
+	return nil
+}
+
+// fetchResource reads uri from conn and renders it for the model: text with the secret
+// filter and redaction rules applied, capped at cfg.MCPResourceMaxBytes. Binary content is
+// described, not sent.
+func fetchResource(cfg *config.Config, conn *ServerConnection, uri string) (string, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.MCPToolTimeout)*time.Second)
+	defer cancel()
+	res, err := conn.Session.ReadResource(ctx, &sdkmcp.ReadResourceParams{URI: uri})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read resource: %w", err)
+	}
+
+	var b strings.Builder
+	mimeType := ""
+	for _, c := range res.Contents {
+		if c == nil {
+			continue
+		}
+		if mimeType == "" {
+			mimeType = c.MIMEType
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		if c.Text == "" && len(c.Blob) > 0 {
+			fmt.Fprintf(&b, "[binary content omitted: %d bytes", len(c.Blob))
+			if c.MIMEType != "" {
+				b.WriteString(", " + c.MIMEType)
+			}
+			b.WriteString("]")
+			continue
+		}
+		b.WriteString(c.Text)
+	}
+
+	name := conn.Spec.Name + ":" + uri
+	text := cfg.RedactFrom(privacy.SourceResource, name, b.String())
+	if limit := cfg.MCPResourceMaxBytes; limit > 0 && len(text) > limit {
+		// Back off to a rune boundary so the model never sees a split character
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut] + fmt.Sprintf("\n... [truncated %d bytes]", len(text)-cut)
+	}
+	return text, mimeType, nil
+}
+
+// discoverAndCacheResourceTemplates discovers resource templates exposed by an MCP server.
+// Best-effort: servers may not support resource templates.
+func discoverAndCacheResourceTemplates(session *sdkmcp.ClientSession, serverName string) []ResourceTemplateInfo {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var templates []ResourceTemplateInfo
+	for tmpl, err := range session.ResourceTemplates(ctx, &sdkmcp.ListResourceTemplatesParams{}) {
+		if err != nil {
+			logger.Log("debug", "[MCP] Error during resource template discovery for server %s: %v", serverName, err)
+			break
+		}
+		if tmpl == nil {
+			continue
+		}
+		name := tmpl.Title
+		if name == "" {
+			name = tmpl.Name
+		}
+		templates = append(templates, ResourceTemplateInfo{
+			Name:        name,
+			URITemplate: tmpl.URITemplate,
+			Description: tmpl.Description,
+			MIMEType:    tmpl.MIMEType,
+			Server:      serverName,
+		})
+		logger.Log("debug", "[MCP]   Resource template: %s (%s)", tmpl.Name, tmpl.URITemplate)
+	}
+	return templates
+}
+
+// refreshResourcesForSession re-discovers resources and templates after a
+// resources/list_changed notification.
+func (r *ServerRegistry) refreshResourcesForSession(session *sdkmcp.ClientSession) {
+	r.mu.Lock()
+	conn, ok := r.sessionToServer[session]
+	r.mu.Unlock()
+	if !ok || conn == nil {
+		return
+	}
+
+	resourceInfos := discoverAndCacheResources(session, conn.Spec.Name)
+	templates := discoverAndCacheResourceTemplates(session, conn.Spec.Name)
+
+	r.mu.Lock()
+	conn.ResourceInfos = resourceInfos
+	conn.ResourceTemplates = templates
+	r.mu.Unlock()
+
+	logger.Log("info", "[MCP] Refreshed resources for server %s: %d resource(s), %d template(s)", conn.Spec.Name, len(resourceInfos), len(templates))
+}
+
+// GetAllResourceTemplateInfos returns the resource templates of all connected servers
+func (r *ServerRegistry) GetAllResourceTemplateInfos() []ResourceTemplateInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.servers))
+	for name := range r.servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var all []ResourceTemplateInfo
+	for _, name := range names {
+		if conn := r.servers[name]; conn.Connected {
+			all = append(all, conn.ResourceTemplates...)
+		}
+	}
+	return all
+}
+
+// GetResourceTemplateInfos returns all available resource templates
+func GetResourceTemplateInfos(cfg *config.Config) []ResourceTemplateInfo {
+	loadOnce(cfg.MCPConfigPath)
+	if registry == nil {
+		return []ResourceTemplateInfo{}
+	}
+	return registry.GetAllResourceTemplateInfos()
+}
+
+// templatePart is a literal or a {variable} of a URI template.
+type templatePart struct {
+	literal  string
+	variable string
+}
+
+// parseURITemplate splits a URI template into literals and variables. Operator prefixes
+// such as {+path} are dropped; query and fragment expansions end the parse, since they
+// cannot be completed as a URI prefix.
+func parseURITemplate(tmpl string) []templatePart {
+	var parts []templatePart
+	for tmpl != "" {
+		open := strings.IndexByte(tmpl, '{')
+		if open < 0 {
+			parts = append(parts, templatePart{literal: tmpl})
+			break
+		}
+		if open > 0 {
+			parts = append(parts, templatePart{literal: tmpl[:open]})
+		}
+		end := strings.IndexByte(tmpl[open:], '}')
+		if end < 0 {
+			break
+		}
+		expr := tmpl[open+1 : open+end]
+		tmpl = tmpl[open+end+1:]
+		if expr == "" || strings.ContainsAny(expr[:1], "?&#") {
+			break
+		}
+		expr = strings.TrimLeft(expr, "+./;")
+		if i := strings.IndexAny(expr, ",:*"); i >= 0 {
+			expr = expr[:i]
+		}
+		parts = append(parts, templatePart{variable: expr})
+	}
+	return parts
+}
+
+// templateCompletion describes how a partially typed URI continues in a template.
+type templateCompletion struct {
+	literal  string            // the rest of a literal being typed
+	variable string            // the variable being typed, when literal is empty
+	prefix   string            // the partial URI before the variable value
+	value    string            // the partial value of the variable
+	resolved map[string]string // variables before the one being typed
+}
+
+// matchTemplatePrefix matches partial against the start of tmpl.
+func matchTemplatePrefix(tmpl, partial string) (templateCompletion, bool) {
+	parts := parseURITemplate(tmpl)
+	resolved := map[string]string{}
+	pos := 0
+	for i, p := range parts {
+		rest := partial[pos:]
+		if p.variable == "" {
+			if strings.HasPrefix(rest, p.literal) {
+				pos += len(p.literal)
+				continue
+			}
+			if strings.HasPrefix(p.literal, rest) {
+				return templateCompletion{literal: p.literal[len(rest):]}, true
+			}
+			return templateCompletion{}, false
+		}
+		// A variable runs up to the next literal
+		if i+1 < len(parts) && parts[i+1].literal != "" {
+			if idx := strings.Index(rest, parts[i+1].literal); idx >= 0 {
+				resolved[p.variable] = rest[:idx]
+				pos += idx
+				continue
+			}
+		}
+		return templateCompletion{variable: p.variable, prefix: partial[:pos], value: rest, resolved: resolved}, true
+	}
+	return templateCompletion{}, pos == len(partial)
+}
+
+// CompleteResourceURI returns URIs of resources on server that start with partial: listed
+// resources, and template URIs whose variable being typed is completed by the server.
+func CompleteResourceURI(cfg *config.Config, server, partial string) []string {
+	conn := serverByName(server)
+	if conn == nil || !conn.Connected {
+		return nil
+	}
+	registry.mu.RLock()
+	resources := append([]ResourceInfo(nil), conn.ResourceInfos...)
+	templates := append([]ResourceTemplateInfo(nil), conn.ResourceTemplates...)
+	registry.mu.RUnlock()
+
+	var out []string
+	seen := map[string]bool{}
+	add := func(uri string) {
+		if uri != partial && strings.HasPrefix(uri, partial) && !seen[uri] {
+			seen[uri] = true
+			out = append(out, uri)
+		}
+	}
+	for _, ri := range resources {
+		add(ri.URI)
+	}
+	for _, t := range templates {
+		m, ok := matchTemplatePrefix(t.URITemplate, partial)
+		if !ok {
+			continue
+		}
+		if m.literal != "" {
+			add(partial + m.literal)
+			continue
+		}
+		if m.variable == "" {
+			continue
+		}
+		for _, v := range completeTemplateArgument(cfg, conn, t.URITemplate, m) {
+			add(m.prefix + v)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// completeTemplateArgument asks the server to complete a template variable, when it
+// supports completions.
+func completeTemplateArgument(cfg *config.Config, conn *ServerConnection, tmpl string, m templateCompletion) []string {
+	if conn.Session == nil {
+		return nil
+	}
+	init := conn.Session.InitializeResult()
+	if init == nil || init.Capabilities == nil || init.Capabilities.Completions == nil {
+		return nil
+	}
+	// Completion runs on Tab, so it gets a short deadline
+	timeout := 3 * time.Second
+	if t := time.Duration(cfg.MCPToolTimeout) * time.Second; t > 0 && t < timeout {
+		timeout = t
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	res, err := conn.Session.Complete(ctx, &sdkmcp.CompleteParams{
+		Ref:      &sdkmcp.CompleteReference{Type: "ref/resource", URI: tmpl},
+		Argument: sdkmcp.CompleteParamsArgument{Name: m.variable, Value: m.value},
+		Context:  &sdkmcp.CompleteContext{Arguments: m.resolved},
+	})
+	if err != nil {
+		logger.Log("debug", "[MCP] Completion of %s in %s failed: %v", m.variable, tmpl, err)
+		return nil
+	}
+	return res.Completion.Values
+}
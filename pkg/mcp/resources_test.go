@@ -0,0 +1,227 @@
+package mcp
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/config"
+	sdkmcp "github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestParseResourceMentions(t *testing.T) {
+	tests := []struct {
+		name     string
+		prompt   string
+		expected []ResourceMention
+	}{
+		{"none", "why is web crashing?", nil},
+		{"single", "@kube:k8s://pods/default/web is crashing", []ResourceMention{{"kube", "k8s://pods/default/web"}}},
+		{"trailing punctuation", "compare @kube:file:///etc/app.yaml, and @docs:runbook://oom.", []ResourceMention{{"kube", "file:///etc/app.yaml"}, {"docs", "runbook://oom"}}},
+		{"duplicates", "@kube:a://x then @kube:a://x", []ResourceMention{{"kube", "a://x"}}},
+		{"not a mention", "mail ops@example.com:8080 or @ alone", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseResourceMentions(tt.prompt); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ParseResourceMentions() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMatchTemplatePrefix(t *testing.T) {
+	const tmpl = "k8s://pods/{namespace}/{name}{?container}"
+	tests := []struct {
+		name     string
+		partial  string
+		ok       bool
+		expected templateCompletion
+	}{
+		{"literal", "k8s://po", true, templateCompletion{literal: "ds/"}},
+		{"first variable", "k8s://pods/def", true, templateCompletion{variable: "namespace", prefix: "k8s://pods/", value: "def", resolved: map[string]string{}}},
+		{"second variable", "k8s://pods/default/w", true, templateCompletion{variable: "name", prefix: "k8s://pods/default/", value: "w", resolved: map[string]string{"namespace": "default"}}},
+		{"other scheme", "file:///", false, templateCompletion{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := matchTemplatePrefix(tmpl, tt.partial)
+			if ok != tt.ok || !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("matchTemplatePrefix() = %+v, %v; want %+v, %v", got, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+// newResourceServer returns a server with a subscribable config resource, a pod template
+// and namespace completion.
+func newResourceServer(content *string) *sdkmcp.Server {
+	server := sdkmcp.NewServer(&sdkmcp.Implementation{Name: "kube", Version: "test"}, &sdkmcp.ServerOptions{
+		SubscribeHandler:   func(context.Context, *sdkmcp.SubscribeRequest) error { return nil },
+		UnsubscribeHandler: func(context.Context, *sdkmcp.UnsubscribeRequest) error { return nil },
+		CompletionHandler: func(ctx context.Context, req *sdkmcp.CompleteRequest) (*sdkmcp.CompleteResult, error) {
+			var values []string
+			for _, ns := range []string{"default", "payments", "prod"} {
+				if req.Params.Argument.Name == "namespace" && strings.HasPrefix(ns, req.Params.Argument.Value) {
+					values = append(values, ns)
+				}
+			}
+			return &sdkmcp.CompleteResult{Completion: sdkmcp.CompletionResultDetails{Values: values}}, nil
+		},
+	})
+	server.AddResource(&sdkmcp.Resource{URI: "k8s://config", Name: "config", MIMEType: "text/plain"},
+		func(ctx context.Context, req *sdkmcp.ReadResourceRequest) (*sdkmcp.ReadResourceResult, error) {
+			return &sdkmcp.ReadResourceResult{Contents: []*sdkmcp.ResourceContents{{URI: req.Params.URI, MIMEType: "text/plain", Text: *content}}}, nil
+		})
+	server.AddResourceTemplate(&sdkmcp.ResourceTemplate{Name: "pod", URITemplate: "k8s://pods/{namespace}/{name}"},
+		func(ctx context.Context, req *sdkmcp.ReadResourceRequest) (*sdkmcp.ReadResourceResult, error) {
+			return &sdkmcp.ReadResourceResult{Contents: []*sdkmcp.ResourceContents{{URI: req.Params.URI, Text: "pod " + req.Params.URI}}}, nil
+		})
+	return server
+}
+
+// connectResourceServer connects server as "kube" in a fresh registry, with the client
+// handlers used in production.
+func connectResourceServer(t *testing.T, server *sdkmcp.Server) {
+	t.Helper()
+	oldRegistry := registry
+	t.Cleanup(func() {
+		DetachResources(&config.Config{MCPToolTimeout: 5})
+		registry = oldRegistry
+	})
+	registry = NewServerRegistry()
+
+	conn := connectTestServer(t, server, &sdkmcp.ClientOptions{
+		ResourceUpdatedHandler: func(ctx context.Context, req *sdkmcp.ResourceUpdatedNotificationRequest) {
+			handleResourceUpdated(req.Session, req.Params.URI)
+		},
+	})
+	registry.AddServer("kube", &ServerConnection{
+		Spec:              &ServerSpec{Name: "kube"},
+		Session:           conn.Session,
+		Connected:         true,
+		ResourceInfos:     discoverAndCacheResources(conn.Session, "kube"),
+		ResourceTemplates: discoverAndCacheResourceTemplates(conn.Session, "kube"),
+	})
+}
+
+func TestResourceContext(t *testing.T) {
+	content := "endpoint: https://api.internal\npassword: hunter2\n"
+	server := newResourceServer(&content)
+	connectResourceServer(t, server)
+	cfg := &config.Config{MCPToolTimeout: 5, MCPResourceMaxBytes: 1000}
+
+	got, err := ResourceContext(cfg, "why does @kube:k8s://config fail?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(got, "### @kube:k8s://config (text/plain)") || !strings.Contains(got, "https://api.internal") {
+		t.Errorf("resource content missing:\n%s", got)
+	}
+	if strings.Contains(got, "hunter2") {
+		t.Errorf("secret leaked:\n%s", got)
+	}
+
+	// Nothing changed: the next turn sends nothing
+	if got, _ := ResourceContext(cfg, "and now?"); got != "" {
+		t.Errorf("expected no resource context, got:\n%s", got)
+	}
+
+	content = "endpoint: https://api.v2.internal\n"
+	if err := server.ResourceUpdated(context.Background(), &sdkmcp.ResourceUpdatedNotificationParams{URI: "k8s://config"}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, updated := AttachedResources(); len(updated) == 1 && updated[0] {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("resources/updated notification not received")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	got, err = ResourceContext(cfg, "and now?")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(got, "updated since it was last sent") || !strings.Contains(got, "api.v2.internal") {
+		t.Errorf("refreshed content missing:\n%s", got)
+	}
+
+	// Unknown servers and unreadable resources are reported and not attached
+	if _, err := ResourceContext(cfg, "@nope:x://y"); err == nil {
+		t.Error("expected an error for an unknown server")
+	}
+	if attached, _ := AttachedResources(); len(attached) != 1 {
+		t.Errorf("expected only the config resource attached, got %v", attached)
+	}
+}
+
+func TestResourceContextTruncates(t *testing.T) {
+	content := strings.Repeat("x", 100)
+	connectResourceServer(t, newResourceServer(&content))
+	cfg := &config.Config{MCPToolTimeout: 5, MCPResourceMaxBytes: 10}
+
+	got, err := ResourceContext(cfg, "@kube:k8s://config")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(got, strings.Repeat("x", 11)) || !strings.Contains(got, "[truncated 90 bytes]") {
+		t.Errorf("expected truncated content:\n%s", got)
+	}
+
+	// A multi-byte character straddling the limit is dropped whole
+	content = "xxxxxxxxx€xx"
+	DetachResources(cfg)
+	got, err = ResourceContext(cfg, "@kube:k8s://config")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !utf8.ValidString(got) || !strings.Contains(got, "xxxxxxxxx\n... [truncated 5 bytes]") {
+		t.Errorf("expected truncation at a rune boundary:\n%q", got)
+	}
+}
+
+func TestCoversURI(t *testing.T) {
+	for _, tt := range []struct {
+		attached, uri string
+		want          bool
+	}{
+		{"file:///a", "file:///a", true},
+		{"file:///a", "file:///a/b", true},
+		{"file:///a/", "file:///a/b", true},
+		{"file:///a", "file:///ab", false},
+		{"k8s://pods/prod", "k8s://pods/production/web", false},
+	} {
+		if got := coversURI(tt.attached, tt.uri); got != tt.want {
+			t.Errorf("coversURI(%q, %q) = %t, want %t", tt.attached, tt.uri, got, tt.want)
+		}
+	}
+}
+
+func TestCompleteResourceURI(t *testing.T) {
+	content := ""
+	connectResourceServer(t, newResourceServer(&content))
+	cfg := &config.Config{MCPToolTimeout: 5}
+
+	tests := []struct {
+		partial  string
+		expected []string
+	}{
+		{"k8s://c", []string{"k8s://config"}},
+		{"k8s://", []string{"k8s://config", "k8s://pods/"}},
+		{"k8s://pods/p", []string{"k8s://pods/payments", "k8s://pods/prod"}},
+		{"file://", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.partial, func(t *testing.T) {
+			if got := CompleteResourceURI(cfg, "kube", tt.partial); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("CompleteResourceURI(%q) = %v, want %v", tt.partial, got, tt.expected)
+			}
+		})
+	}
+}
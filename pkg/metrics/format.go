@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FormatTable summarizes query results as a compact Markdown table sorted by value and
+// capped at maxRows rows, so metrics add little to the prompt budget.
+func FormatTable(spec QuerySpec, samples []Sample, maxRows int) string {
+	var b strings.Builder
+	b.WriteString("### " + spec.Title + "\n")
+	if len(samples) == 0 {
+		b.WriteString("no series above threshold")
+		return b.String()
+	}
+
+	columns := spec.Columns
+	if len(columns) == 0 {
+		columns = sampleColumns(samples)
+	}
+	sortSamples(samples)
+
+	b.WriteString("| " + strings.Join(columns, " | ") + " | value |\n")
+	b.WriteString("|" + strings.Repeat(" --- |", len(columns)+1) + "\n")
+	shown := samples
+	if maxRows > 0 && len(shown) > maxRows {
+		shown = shown[:maxRows]
+	}
+	for _, s := range shown {
+		row := make([]string, 0, len(columns)+1)
+		for _, c := range columns {
+			v := s.Labels[c]
+			if v == "" {
+				v = "-"
+			}
+			row = append(row, v)
+		}
+		row = append(row, formatValue(s.Value, spec.Unit))
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+	}
+	if len(samples) > len(shown) {
+		b.WriteString(fmt.Sprintf("... %d more series omitted\n", len(samples)-len(shown)))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// sampleColumns returns the label names present in the samples, excluding __name__.
+func sampleColumns(samples []Sample) []string {
+	seen := map[string]bool{}
+	var cols []string
+	for _, s := range samples {
+		for k := range s.Labels {
+			if k != "__name__" && !seen[k] {
+				seen[k] = true
+				cols = append(cols, k)
+			}
+		}
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+func formatValue(v float64, unit string) string {
+	switch unit {
+	case "percent":
+		return fmt.Sprintf("%.1f%%", v*100)
+	case "count":
+		return fmt.Sprintf("%.0f", v)
+	case "bytes":
+		const mi = 1024 * 1024
+		return fmt.Sprintf("%.0fMi", v/mi)
+	default:
+		return fmt.Sprintf("%.4g", v)
+	}
+}
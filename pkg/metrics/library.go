@@ -0,0 +1,179 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/diag"
+)
+
+// QuerySpec is a curated PromQL query. "$selector" in PromQL is replaced with a namespace
+// matcher on NamespaceLabel so the same query can be scoped to the namespaces in findings.
+type QuerySpec struct {
+	Name           string
+	Title          string
+	PromQL         string
+	NamespaceLabel string
+	Columns        []string
+	Unit           string
+}
+
+// Library is the curated query set, keyed by name. Queries rely on cAdvisor, kube-state-metrics
+// and ingress-nginx metric names, which are the de-facto defaults in kube-prometheus stacks.
+var Library = map[string]QuerySpec{
+	"cpu_throttling": {
+		Name:  "cpu_throttling",
+		Title: "CPU throttling (share of CFS periods throttled, 5m)",
+		PromQL: `topk(20, sum by (namespace, pod, container) (rate(container_cpu_cfs_throttled_periods_total{$selector,container!=""}[5m]))` +
+			` / sum by (namespace, pod, container) (rate(container_cpu_cfs_periods_total{$selector,container!=""}[5m])) > 0.05)`,
+		NamespaceLabel: "namespace",
+		Columns:        []string{"namespace", "pod", "container"},
+		Unit:           "percent",
+	},
+	"memory_vs_limit": {
+		Name:  "memory_vs_limit",
+		Title: "Memory working set vs. limit",
+		PromQL: `topk(20, max by (namespace, pod, container) (container_memory_working_set_bytes{$selector,container!=""})` +
+			` / max by (namespace, pod, container) (kube_pod_container_resource_limits{$selector,resource="memory"}) > 0.5)`,
+		NamespaceLabel: "namespace",
+		Columns:        []string{"namespace", "pod", "container"},
+		Unit:           "percent",
+	},
+	"restart_rate": {
+		Name:           "restart_rate",
+		Title:          "Container restarts (last 1h)",
+		PromQL:         `topk(20, sum by (namespace, pod, container) (increase(kube_pod_container_status_restarts_total{$selector}[1h])) > 0)`,
+		NamespaceLabel: "namespace",
+		Columns:        []string{"namespace", "pod", "container"},
+		Unit:           "count",
+	},
+	"ingress_5xx": {
+		Name:  "ingress_5xx",
+		Title: "Ingress 5xx ratio (5m)",
+		PromQL: `topk(20, sum by (exported_namespace, ingress) (rate(nginx_ingress_controller_requests{$selector,status=~"5.."}[5m]))` +
+			` / sum by (exported_namespace, ingress) (rate(nginx_ingress_controller_requests{$selector}[5m])) > 0)`,
+		NamespaceLabel: "exported_namespace",
+		Columns:        []string{"exported_namespace", "ingress"},
+		Unit:           "percent",
+	},
+}
+
+// QueryNames returns the curated query names in sorted order.
+func QueryNames() []string {
+	names := make([]string, 0, len(Library))
+	for name := range Library {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// findingQueries maps finding kinds to the queries that help explain them.
+var findingQueries = map[string][]string{
+	"Pod":        {"restart_rate", "memory_vs_limit"},
+	"Deployment": {"cpu_throttling", "memory_vs_limit"},
+	"HPA":        {"cpu_throttling"},
+	"Ingress":    {"ingress_5xx"},
+}
+
+// promptQueries maps performance-related wording in user prompts to queries.
+var promptQueries = []struct {
+	re    *regexp.Regexp
+	query string
+}{
+	{regexp.MustCompile(`(?i)\b(cpu|throttl\w*|slow\w*|latency|performance|perf)\b`), "cpu_throttling"},
+	{regexp.MustCompile(`(?i)\b(memory|mem|oom\w*|working set)\b`), "memory_vs_limit"},
+	{regexp.MustCompile(`(?i)\b(restart\w*|crash\w*)\b`), "restart_rate"},
+	{regexp.MustCompile(`(?i)(\b5xx\b|\b50[234]\b|error rate|\bingress\w*)`), "ingress_5xx"},
+}
+
+// Selection is the set of curated queries chosen for a request and the namespaces to scope them to.
+type Selection struct {
+	Queries    []string
+	Namespaces []string
+}
+
+// Select picks curated queries from analyzer findings and the user prompt. Namespaces are
+// taken from namespaced finding IDs; prompt-only selections run cluster-wide.
+func Select(findings []diag.Finding, prompt string) Selection {
+	var sel Selection
+	seenQuery := map[string]bool{}
+	seenNS := map[string]bool{}
+	add := func(q string) {
+		if !seenQuery[q] {
+			seenQuery[q] = true
+			sel.Queries = append(sel.Queries, q)
+		}
+	}
+	promptSelected := false
+	for _, pq := range promptQueries {
+		if pq.re.MatchString(prompt) {
+			add(pq.query)
+			promptSelected = true
+		}
+	}
+	for _, f := range findings {
+		if f.Severity == "info" {
+			continue
+		}
+		queries := findingQueries[f.Kind]
+		if f.Kind == "Pod" && strings.Contains(strings.ToLower(f.Summary), "oom") {
+			queries = []string{"memory_vs_limit"}
+		}
+		for _, q := range queries {
+			add(q)
+		}
+		if ns, _, ok := strings.Cut(f.ID, "/"); ok && len(queries) > 0 && !seenNS[ns] {
+			seenNS[ns] = true
+			sel.Namespaces = append(sel.Namespaces, ns)
+		}
+	}
+	// A performance question is usually about the whole cluster, not just the namespaces with findings
+	if promptSelected {
+		sel.Namespaces = nil
+	}
+	sort.Strings(sel.Namespaces)
+	return sel
+}
+
+// Render returns the PromQL for a spec scoped to the given namespaces (all when empty).
+func (q QuerySpec) Render(namespaces []string) string {
+	selector := q.NamespaceLabel + `!=""`
+	if len(namespaces) > 0 {
+		quoted := make([]string, len(namespaces))
+		for i, ns := range namespaces {
+			quoted[i] = regexp.QuoteMeta(ns)
+		}
+		selector = fmt.Sprintf(`%s=~"%s"`, q.NamespaceLabel, strings.Join(quoted, "|"))
+	}
+	return strings.ReplaceAll(q.PromQL, "$selector", selector)
+}
+
+// Collect runs the selected curated queries and returns a compact prompt section.
+// Failed queries are noted inline so the model knows the data is missing rather than healthy.
+func Collect(ctx context.Context, client *Client, sel Selection, maxRows int) string {
+	if client == nil || len(sel.Queries) == 0 {
+		return ""
+	}
+	var tables []string
+	for _, name := range sel.Queries {
+		spec, ok := Library[name]
+		if !ok {
+			continue
+		}
+		samples, err := client.Query(ctx, spec.Render(sel.Namespaces))
+		if err != nil {
+			tables = append(tables, fmt.Sprintf("### %s\nquery failed: %v", spec.Title, err))
+			continue
+		}
+		tables = append(tables, FormatTable(spec, samples, maxRows))
+	}
+	header := "## Metrics (Prometheus)"
+	if len(sel.Namespaces) > 0 {
+		header += " for namespaces " + strings.Join(sel.Namespaces, ", ")
+	}
+	return header + "\n\n" + strings.Join(tables, "\n\n")
+}
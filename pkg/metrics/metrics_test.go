@@ -0,0 +1,146 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/diag"
+)
+
+// fakePrometheus serves /api/v1/query and answers based on the metric named in the query.
+func fakePrometheus(t *testing.T, seen *[]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query().Get("query")
+		*seen = append(*seen, q)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.Contains(q, "kube_pod_container_status_restarts_total"):
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"namespace":"shop","pod":"api-1","container":"api"},"value":[1700000000,"4"]},
+				{"metric":{"namespace":"shop","pod":"api-2","container":"api"},"value":[1700000000,"12"]},
+				{"metric":{"namespace":"shop","pod":"worker-1","container":"worker"},"value":[1700000000,"1"]}]}}`))
+		case strings.Contains(q, "container_memory_working_set_bytes"):
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"unknown metric"}`))
+		case q == "scalar(1)":
+			w.Write([]byte(`{"status":"success","data":{"resultType":"scalar","result":[1700000000,"1"]}}`))
+		default:
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+		}
+	}))
+}
+
+func TestClientQuery(t *testing.T) {
+	var seen []string
+	srv := fakePrometheus(t, &seen)
+	defer srv.Close()
+
+	if NewClient("  ", "", time.Second) != nil {
+		t.Fatal("expected nil client for empty URL")
+	}
+	c := NewClient(srv.URL+"/", "", time.Second)
+
+	samples, err := c.Query(context.Background(), `kube_pod_container_status_restarts_total`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(samples) != 3 || samples[1].Labels["pod"] != "api-2" || samples[1].Value != 12 {
+		t.Errorf("unexpected samples: %+v", samples)
+	}
+
+	if s, err := c.Query(context.Background(), "scalar(1)"); err != nil || len(s) != 1 || s[0].Value != 1 {
+		t.Errorf("unexpected scalar result: %+v, %v", s, err)
+	}
+
+	_, err = c.Query(context.Background(), "container_memory_working_set_bytes")
+	if err == nil || !strings.Contains(err.Error(), "unknown metric") {
+		t.Errorf("expected API error, got %v", err)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	findings := []diag.Finding{
+		{Kind: "Pod", ID: "shop/api-1", Severity: "error", Summary: "container api in CrashLoopBackOff"},
+		{Kind: "Ingress", ID: "web/frontend", Severity: "warn", Summary: "backend service not found"},
+		{Kind: "Node", ID: "node-1", Severity: "error", Summary: "NotReady"},
+		{Kind: "Deployment", ID: "info/ok", Severity: "info", Summary: "rollout complete"},
+	}
+	sel := Select(findings, "why is checkout failing?")
+	if strings.Join(sel.Queries, ",") != "restart_rate,memory_vs_limit,ingress_5xx" {
+		t.Errorf("unexpected queries: %v", sel.Queries)
+	}
+	if strings.Join(sel.Namespaces, ",") != "shop,web" {
+		t.Errorf("unexpected namespaces: %v", sel.Namespaces)
+	}
+
+	sel = Select(findings, "is anything being CPU throttled?")
+	if sel.Queries[0] != "cpu_throttling" || sel.Namespaces != nil {
+		t.Errorf("prompt selection should come first and run cluster-wide: %+v", sel)
+	}
+
+	if sel := Select(nil, "list pods"); len(sel.Queries) != 0 {
+		t.Errorf("expected no queries, got %v", sel.Queries)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	var seen []string
+	srv := fakePrometheus(t, &seen)
+	defer srv.Close()
+	c := NewClient(srv.URL, "", time.Second)
+
+	out := Collect(context.Background(), c, Selection{
+		Queries:    []string{"restart_rate", "memory_vs_limit", "cpu_throttling"},
+		Namespaces: []string{"shop"},
+	}, 2)
+
+	for _, want := range []string{
+		"## Metrics (Prometheus) for namespaces shop",
+		"| namespace | pod | container | value |",
+		"| shop | api-2 | api | 12 |",
+		"| shop | api-1 | api | 4 |",
+		"... 1 more series omitted",
+		"### Memory working set vs. limit\nquery failed",
+		"### CPU throttling (share of CFS periods throttled, 5m)\nno series above threshold",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "api-2") > strings.Index(out, "api-1") {
+		t.Errorf("rows should be sorted by value:\n%s", out)
+	}
+	if len(seen) != 3 || !strings.Contains(seen[0], `namespace=~"shop"`) {
+		t.Errorf("expected namespace-scoped queries, got %v", seen)
+	}
+	if Collect(context.Background(), nil, Selection{Queries: []string{"restart_rate"}}, 5) != "" {
+		t.Error("expected empty output without a client")
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		v    float64
+		unit string
+		want string
+	}{
+		{0.4567, "percent", "45.7%"},
+		{3.2, "count", "3"},
+		{256 * 1024 * 1024, "bytes", "256Mi"},
+		{0.000123, "", "0.000123"},
+	}
+	for _, tt := range tests {
+		if got := formatValue(tt.v, tt.unit); got != tt.want {
+			t.Errorf("formatValue(%v, %q) = %q, want %q", tt.v, tt.unit, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sample is a single instant-vector element returned by a Prometheus query.
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// Client queries a Prometheus-compatible HTTP API (Prometheus, Thanos, Mimir, VictoriaMetrics).
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a metrics client for the given base URL. It returns nil when the URL is
+// empty so callers can treat a missing metrics backend as "not configured".
+func NewClient(baseURL, token string, timeout time.Duration) *Client {
+	baseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if baseURL == "" {
+		return nil
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &Client{
+		baseURL:    baseURL,
+		token:      strings.TrimSpace(token),
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Query runs an instant query via /api/v1/query and returns vector or scalar results.
+func (c *Client) Query(ctx context.Context, promql string) ([]Sample, error) {
+	endpoint := c.baseURL + "/api/v1/query?" + url.Values{"query": {promql}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var payload struct {
+		Status    string `json:"status"`
+		ErrorType string `json:"errorType"`
+		Error     string `json:"error"`
+		Data      struct {
+			ResultType string          `json:"resultType"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("metrics api returned status %d", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if payload.Status != "success" {
+		return nil, fmt.Errorf("query failed (%s): %s", payload.ErrorType, payload.Error)
+	}
+
+	switch payload.Data.ResultType {
+	case "vector":
+		var vector []struct {
+			Metric map[string]string `json:"metric"`
+			Value  []any             `json:"value"`
+		}
+		if err := json.Unmarshal(payload.Data.Result, &vector); err != nil {
+			return nil, fmt.Errorf("failed to parse vector: %w", err)
+		}
+		samples := make([]Sample, 0, len(vector))
+		for _, v := range vector {
+			val, ok := sampleValue(v.Value)
+			if !ok {
+				continue
+			}
+			samples = append(samples, Sample{Labels: v.Metric, Value: val})
+		}
+		return samples, nil
+	case "scalar":
+		var scalar []any
+		if err := json.Unmarshal(payload.Data.Result, &scalar); err != nil {
+			return nil, fmt.Errorf("failed to parse scalar: %w", err)
+		}
+		if val, ok := sampleValue(scalar); ok {
+			return []Sample{{Labels: map[string]string{}, Value: val}}, nil
+		}
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported result type %q (use an instant vector query)", payload.Data.ResultType)
+	}
+}
+
+// sampleValue extracts the float from a [timestamp, "value"] pair.
+func sampleValue(pair []any) (float64, bool) {
+	if len(pair) != 2 {
+		return 0, false
+	}
+	s, ok := pair[1].(string)
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// sortSamples orders samples by value (highest first), then by label set for stable output.
+func sortSamples(samples []Sample) {
+	sort.SliceStable(samples, func(i, j int) bool {
+		if samples[i].Value != samples[j].Value {
+			return samples[i].Value > samples[j].Value
+		}
+		return labelKey(samples[i].Labels) < labelKey(samples[j].Labels)
+	})
+}
+
+func labelKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k + "=" + labels[k] + ",")
+	}
+	return b.String()
+}
@@ -0,0 +1,177 @@
+// Package privacy keeps the per-turn audit of what the redaction filters masked in command
+// and tool output and of what was sent to which LLM provider endpoint.
+package privacy
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/filter"
+)
+
+// Kinds of data sources whose output is redacted before it reaches the model.
+const (
+	SourceCommand  = "command"
+	SourceTool     = "tool"
+	SourceHelm     = "helm"
+	SourceResource = "resource"
+)
+
+// Source is the redaction audit of one command or tool within a turn.
+type Source struct {
+	Kind  string
+	Name  string
+	Calls int
+	Bytes int // output bytes after redaction
+	Hits  filter.Hits
+}
+
+// Masked returns the number of values masked in the output of the source.
+func (s Source) Masked() int {
+	return s.Hits.Total()
+}
+
+// Filters returns the names of the filters that fired for a source, most hits first.
+func (s Source) Filters() []string {
+	names := make([]string, 0, len(s.Hits))
+	for name, n := range s.Hits {
+		if n > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if s.Hits[names[i]] != s.Hits[names[j]] {
+			return s.Hits[names[i]] > s.Hits[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// Upload is what was sent to one provider endpoint within a turn.
+type Upload struct {
+	Provider string
+	Endpoint string
+	Model    string
+	Purpose  string // "chat" or "embeddings"
+	Requests int
+	Bytes    int
+	Tokens   int
+}
+
+// Report collects the audit of the current turn. It is safe for concurrent use, and a nil
+// Report records nothing.
+type Report struct {
+	mu      sync.Mutex
+	prompt  string
+	started time.Time
+	sources []*Source
+	uploads []*Upload
+}
+
+// NewReport returns an empty report.
+func NewReport() *Report {
+	return &Report{}
+}
+
+// Reset starts the audit of a new turn for prompt.
+func (r *Report) Reset(prompt string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prompt = prompt
+	r.started = time.Now()
+	r.sources = nil
+	r.uploads = nil
+}
+
+// AddSource records the redacted output of a command or tool; repeated calls are summed.
+func (r *Report) AddSource(kind, name string, bytes int, hits filter.Hits) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var src *Source
+	for _, s := range r.sources {
+		if s.Kind == kind && s.Name == name {
+			src = s
+			break
+		}
+	}
+	if src == nil {
+		src = &Source{Kind: kind, Name: name, Hits: filter.Hits{}}
+		r.sources = append(r.sources, src)
+	}
+	src.Calls++
+	src.Bytes += bytes
+	for f, n := range hits {
+		src.Hits[f] += n
+	}
+}
+
+// AddUpload records a request sent to a provider endpoint; requests to the same endpoint,
+// model and purpose are summed.
+func (r *Report) AddUpload(u Upload) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, up := range r.uploads {
+		if up.Provider == u.Provider && up.Endpoint == u.Endpoint && up.Model == u.Model && up.Purpose == u.Purpose {
+			up.Requests++
+			up.Bytes += u.Bytes
+			up.Tokens += u.Tokens
+			return
+		}
+	}
+	u.Requests = 1
+	r.uploads = append(r.uploads, &u)
+}
+
+// Prompt returns the prompt of the audited turn and when it started.
+func (r *Report) Prompt() (string, time.Time) {
+	if r == nil {
+		return "", time.Time{}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.prompt, r.started
+}
+
+// Sources returns a copy of the audited sources in the order they were first seen.
+func (r *Report) Sources() []Source {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Source, 0, len(r.sources))
+	for _, s := range r.sources {
+		c := *s
+		c.Hits = filter.Hits{}
+		for f, n := range s.Hits {
+			c.Hits[f] = n
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// Uploads returns a copy of the recorded uploads in the order they were first seen.
+func (r *Report) Uploads() []Upload {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Upload, 0, len(r.uploads))
+	for _, u := range r.uploads {
+		out = append(out, *u)
+	}
+	return out
+}
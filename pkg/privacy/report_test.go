@@ -0,0 +1,58 @@
+package privacy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mikhae1/kubectl-quackops/pkg/filter"
+)
+
+func TestReport(t *testing.T) {
+	r := NewReport()
+	r.Reset("why is web failing?")
+	r.AddSource(SourceCommand, "kubectl get secrets -o yaml", 120, filter.Hits{"secret-data": 2})
+	r.AddSource(SourceTool, "list_pods", 40, filter.Hits{})
+	r.AddSource(SourceCommand, "kubectl get secrets -o yaml", 80, filter.Hits{"secret-data": 1, "jwt": 1})
+	r.AddUpload(Upload{Provider: "openai", Endpoint: "https://api.openai.com", Model: "gpt-4o", Purpose: "chat", Bytes: 900, Tokens: 220})
+	r.AddUpload(Upload{Provider: "openai", Endpoint: "https://api.openai.com", Model: "gpt-4o", Purpose: "chat", Bytes: 100, Tokens: 30})
+	r.AddUpload(Upload{Provider: "openai", Endpoint: "https://api.openai.com", Model: "text-embedding-3-small", Purpose: "embeddings", Bytes: 50, Tokens: 12})
+
+	sources := r.Sources()
+	if len(sources) != 2 {
+		t.Fatalf("expected 2 sources, got %+v", sources)
+	}
+	secrets := sources[0]
+	if secrets.Calls != 2 || secrets.Bytes != 200 || secrets.Masked() != 4 {
+		t.Errorf("unexpected aggregated source %+v", secrets)
+	}
+	if got := secrets.Filters(); !reflect.DeepEqual(got, []string{"secret-data", "jwt"}) {
+		t.Errorf("Filters() = %v", got)
+	}
+	if sources[1].Masked() != 0 || len(sources[1].Filters()) != 0 {
+		t.Errorf("expected nothing masked for %+v", sources[1])
+	}
+
+	uploads := r.Uploads()
+	if len(uploads) != 2 || uploads[0].Requests != 2 || uploads[0].Bytes != 1000 || uploads[0].Tokens != 250 || uploads[1].Purpose != "embeddings" {
+		t.Errorf("unexpected uploads %+v", uploads)
+	}
+
+	// Copies must not alias the report
+	sources[0].Hits["secret-data"] = 99
+	if r.Sources()[0].Hits["secret-data"] != 3 {
+		t.Error("Sources() returned hits shared with the report")
+	}
+
+	r.Reset("next")
+	if prompt, _ := r.Prompt(); prompt != "next" || len(r.Sources()) != 0 || len(r.Uploads()) != 0 {
+		t.Errorf("Reset did not clear the report")
+	}
+
+	var none *Report
+	none.Reset("x")
+	none.AddSource(SourceTool, "t", 1, nil)
+	none.AddUpload(Upload{})
+	if none.Sources() != nil || none.Uploads() != nil {
+		t.Error("nil report should record nothing")
+	}
+}
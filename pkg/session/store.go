@@ -52,6 +52,9 @@ type Snapshot struct {
 	LastIncomingTokens    int                   `json:"last_incoming_tokens,omitempty"`
 	SessionOutgoingTokens int                   `json:"session_outgoing_tokens,omitempty"`
 	SessionIncomingTokens int                   `json:"session_incoming_tokens,omitempty"`
+	SessionCachedTokens   int                   `json:"session_cached_tokens,omitempty"`
+	ModelUsage            []config.ModelUsage   `json:"model_usage,omitempty"`
+	SessionCostUSD        float64               `json:"session_cost_usd,omitempty"`
 	ChatMessages          []Message             `json:"chat_messages"`
 	SessionHistory        []config.SessionEvent `json:"session_history,omitempty"`
 	StoredUserCmdResults  []CommandResult       `json:"stored_user_cmd_results,omitempty"`
@@ -109,6 +112,9 @@ func FromConfig(cfg *config.Config) Snapshot {
 		LastIncomingTokens:    cfg.LastIncomingTokens,
 		SessionOutgoingTokens: cfg.SessionOutgoingTokens,
 		SessionIncomingTokens: cfg.SessionIncomingTokens,
+		SessionCachedTokens:   cfg.SessionCachedTokens,
+		ModelUsage:            append([]config.ModelUsage(nil), cfg.ModelUsage...),
+		SessionCostUSD:        cfg.SessionCostUSD,
 		ChatMessages:          serializeChatMessages(cfg.ChatMessages),
 		SessionHistory:        sessionHistory,
 		StoredUserCmdResults:  serializeCommandResults(cfg.StoredUserCmdResults),
@@ -136,6 +142,9 @@ func (s Snapshot) Apply(cfg *config.Config) error {
 	cfg.LastIncomingTokens = s.LastIncomingTokens
 	cfg.SessionOutgoingTokens = s.SessionOutgoingTokens
 	cfg.SessionIncomingTokens = s.SessionIncomingTokens
+	cfg.SessionCachedTokens = s.SessionCachedTokens
+	cfg.ModelUsage = append([]config.ModelUsage(nil), s.ModelUsage...)
+	cfg.SessionCostUSD = s.SessionCostUSD
 	cfg.ChatMessages = chatMessages
 	cfg.SessionHistory = append([]config.SessionEvent(nil), s.SessionHistory...)
 	cfg.StoredUserCmdResults = deserializeCommandResults(s.StoredUserCmdResults)
@@ -358,8 +367,8 @@ func validateSnapshot(snapshot Snapshot) error {
 	if snapshot.UserMsgCount < 0 {
 		return fmt.Errorf("%w: user_msg_count must be non-negative", ErrInvalidSessionSchema)
 	}
-	if snapshot.SessionOutgoingTokens < 0 || snapshot.SessionIncomingTokens < 0 {
-		return fmt.Errorf("%w: session token totals must be non-negative", ErrInvalidSessionSchema)
+	if snapshot.SessionOutgoingTokens < 0 || snapshot.SessionIncomingTokens < 0 || snapshot.SessionCachedTokens < 0 || snapshot.SessionCostUSD < 0 {
+		return fmt.Errorf("%w: session token and cost totals must be non-negative", ErrInvalidSessionSchema)
 	}
 	for _, msg := range snapshot.ChatMessages {
 		if err := validateMessage(msg); err != nil {
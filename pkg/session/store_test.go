@@ -25,6 +25,10 @@ func TestStoreSaveLoadRoundTrip(t *testing.T) {
 		LastIncomingTokens:      456,
 		SessionOutgoingTokens:   789,
 		SessionIncomingTokens:   654,
+		SessionCachedTokens:     321,
+		ModelUsage: []config.ModelUsage{
+			{Task: "commands", Provider: "openai", Model: "gpt-5-nano", Requests: 2, InputTokens: 500, OutputTokens: 40},
+		},
 		ChatMessages: []llms.ChatMessage{
 			llms.SystemChatMessage{Content: "system"},
 			llms.HumanChatMessage{Content: "user"},
@@ -78,8 +82,11 @@ func TestStoreSaveLoadRoundTrip(t *testing.T) {
 	if restored.UserMsgCount != cfg.UserMsgCount {
 		t.Fatalf("expected user message count %d, got %d", cfg.UserMsgCount, restored.UserMsgCount)
 	}
-	if restored.SessionOutgoingTokens != cfg.SessionOutgoingTokens || restored.SessionIncomingTokens != cfg.SessionIncomingTokens {
-		t.Fatalf("expected session token totals to round-trip, got out=%d in=%d", restored.SessionOutgoingTokens, restored.SessionIncomingTokens)
+	if restored.SessionOutgoingTokens != cfg.SessionOutgoingTokens || restored.SessionIncomingTokens != cfg.SessionIncomingTokens || restored.SessionCachedTokens != cfg.SessionCachedTokens {
+		t.Fatalf("expected session token totals to round-trip, got out=%d in=%d cached=%d", restored.SessionOutgoingTokens, restored.SessionIncomingTokens, restored.SessionCachedTokens)
+	}
+	if len(restored.ModelUsage) != 1 || restored.ModelUsage[0] != cfg.ModelUsage[0] {
+		t.Fatalf("expected model usage to round-trip, got %+v", restored.ModelUsage)
 	}
 	if len(restored.ChatMessages) != len(cfg.ChatMessages) {
 		t.Fatalf("expected %d chat messages, got %d", len(cfg.ChatMessages), len(restored.ChatMessages))